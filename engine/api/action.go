@@ -161,6 +161,11 @@ func updateActionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *
 		return
 	}
 
+	if !stepConditionsValid(a) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
 	// Check that action  already exists
 	//actionDB, err := action.LoadPublicAction(db, name, action.WithClearPasswords())
 	actionDB, err := action.LoadPublicAction(db, name)
@@ -227,6 +232,11 @@ func addActionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *con
 		return
 	}
 
+	if !stepConditionsValid(a) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
 	// Check that action does not already exists
 	conflict, err := action.Exists(db, a.Name)
 	if err != nil {
@@ -299,3 +309,13 @@ func getActionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *con
 
 	WriteJSON(w, r, a, http.StatusOK)
 }
+
+// stepConditionsValid returns false if a step of the action has an invalid condition
+func stepConditionsValid(a *sdk.Action) bool {
+	for _, child := range a.Actions {
+		if !sdk.IsValidCondition(child.Condition) {
+			return false
+		}
+	}
+	return true
+}
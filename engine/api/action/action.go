@@ -63,21 +63,7 @@ func InsertAction(tx *sql.Tx, a *sdk.Action, public bool) error {
 	}
 
 	// Requirements of children are requirement of parent
-	for _, c := range a.Actions {
-		// Now for each requirement of child, check if it exists in parent
-		for _, cr := range c.Requirements {
-			found := false
-			for _, pr := range a.Requirements {
-				if pr.Type == cr.Type && pr.Value == cr.Value {
-					found = true
-					break
-				}
-			}
-			if !found {
-				a.Requirements = append(a.Requirements, cr)
-			}
-		}
-	}
+	AddChildrenRequirements(a)
 	for i := range a.Requirements {
 		if err = InsertActionRequirement(tx, a.ID, a.Requirements[i]); err != nil {
 			return err
@@ -188,21 +174,7 @@ func loadAction(db database.Querier, s database.Scanner) (*sdk.Action, error) {
 	}
 
 	// Requirements of children are requirement of parent
-	for _, c := range a.Actions {
-		// Now for each requirement of child, check if it exists in parent
-		for _, cr := range c.Requirements {
-			found := false
-			for _, pr := range a.Requirements {
-				if pr.Type == cr.Type && pr.Value == cr.Value {
-					found = true
-					break
-				}
-			}
-			if !found {
-				a.Requirements = append(a.Requirements, cr)
-			}
-		}
-	}
+	AddChildrenRequirements(a)
 
 	return a, nil
 }
@@ -260,21 +232,7 @@ func UpdateActionDB(tx *sql.Tx, a *sdk.Action, userID int64) error {
 		return err
 	}
 	// Requirements of children are requirement of parent
-	for _, c := range a.Actions {
-		// Now for each requirement of child, check if it exists in parent
-		for _, cr := range c.Requirements {
-			found := false
-			for _, pr := range a.Requirements {
-				if pr.Type == cr.Type && pr.Value == cr.Value {
-					found = true
-					break
-				}
-			}
-			if !found {
-				a.Requirements = append(a.Requirements, cr)
-			}
-		}
-	}
+	AddChildrenRequirements(a)
 	for i := range a.Requirements {
 		err = InsertActionRequirement(tx, a.ID, a.Requirements[i])
 		if err != nil {
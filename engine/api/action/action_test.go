@@ -72,3 +72,21 @@ func TestLoadAction(t *testing.T) {
 		t.Fatalf("Expected 1 step, got %d", len(a2.Actions))
 	}
 }
+
+func TestAddChildrenRequirements(t *testing.T) {
+	node := sdk.NewScriptAction("npm test")
+	node.Parameters = append(node.Parameters, sdk.Parameter{Name: sdk.ScriptImageParameter, Value: "node:6"})
+	python := sdk.NewScriptAction("tox")
+	python.Parameters = append(python.Parameters, sdk.Parameter{Name: sdk.ScriptImageParameter, Value: "python:3"})
+	python.Requirements = []sdk.Requirement{{Name: "network", Type: sdk.NetworkAccessRequirement, Value: "pypi.python.org:443"}}
+
+	a := &sdk.Action{Name: "test", Type: sdk.JoinedAction, Actions: []sdk.Action{sdk.NewScriptAction("make"), node, python}}
+	AddChildrenRequirements(a)
+
+	if len(a.Requirements) != 2 {
+		t.Fatalf("Expected docker and network requirements, got %v", a.Requirements)
+	}
+	if a.Requirements[0] != sdk.DockerRequirement {
+		t.Errorf("Expected docker requirement, got %v", a.Requirements[0])
+	}
+}
@@ -1,17 +1,18 @@
 package action
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/sdk"
 )
 
-func insertEdge(db database.QueryExecuter, parentID, childID int64, execOrder int, final, enabled bool) (int64, error) {
-	query := `INSERT INTO action_edge (parent_id, child_id, exec_order, final, enabled) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+func insertEdge(db database.QueryExecuter, parentID, childID int64, execOrder int, final, enabled bool, condition string) (int64, error) {
+	query := `INSERT INTO action_edge (parent_id, child_id, exec_order, final, enabled, condition) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 
 	var id int64
-	err := db.QueryRow(query, parentID, childID, execOrder, final, enabled).Scan(&id)
+	err := db.QueryRow(query, parentID, childID, execOrder, final, enabled, condition).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -24,7 +25,7 @@ func insertActionChild(db database.QueryExecuter, actionID int64, child sdk.Acti
 		return fmt.Errorf("insertActionChild: child action has no id")
 	}
 
-	id, err := insertEdge(db, actionID, child.ID, execOrder, child.Final, child.Enabled)
+	id, err := insertEdge(db, actionID, child.ID, execOrder, child.Final, child.Enabled, child.Condition)
 	if err != nil {
 		return err
 	}
@@ -62,7 +63,7 @@ func loadActionChildren(db database.Querier, actionID int64) ([]sdk.Action, erro
 	var children []sdk.Action
 	var edgeIDs []int64
 	var childrenIDs []int64
-	query := `SELECT id, child_id, exec_order, final, enabled FROM action_edge WHERE parent_id = $1 ORDER BY exec_order ASC`
+	query := `SELECT id, child_id, exec_order, final, enabled, condition FROM action_edge WHERE parent_id = $1 ORDER BY exec_order ASC`
 
 	rows, err := db.Query(query, actionID)
 	if err != nil {
@@ -73,11 +74,13 @@ func loadActionChildren(db database.Querier, actionID int64) ([]sdk.Action, erro
 	var edgeID, childID int64
 	var execOrder int
 	var final, enabled bool
+	var condition sql.NullString
 	var mapFinal = make(map[int64]bool)
 	var mapEnabled = make(map[int64]bool)
+	var mapCondition = make(map[int64]string)
 
 	for rows.Next() {
-		err = rows.Scan(&edgeID, &childID, &execOrder, &final, &enabled)
+		err = rows.Scan(&edgeID, &childID, &execOrder, &final, &enabled, &condition)
 		if err != nil {
 			return nil, err
 		}
@@ -85,6 +88,7 @@ func loadActionChildren(db database.Querier, actionID int64) ([]sdk.Action, erro
 		childrenIDs = append(childrenIDs, childID)
 		mapFinal[edgeID] = final
 		mapEnabled[edgeID] = enabled
+		mapCondition[edgeID] = condition.String
 	}
 	rows.Close()
 
@@ -110,6 +114,8 @@ func loadActionChildren(db database.Querier, actionID int64) ([]sdk.Action, erro
 		children[i].Final = mapFinal[edgeIDs[i]]
 		// Get enable flag
 		children[i].Enabled = mapEnabled[edgeIDs[i]]
+		// Get condition
+		children[i].Condition = mapCondition[edgeIDs[i]]
 	}
 
 	return children, nil
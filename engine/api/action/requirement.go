@@ -79,3 +79,28 @@ func DeleteActionRequirements(db database.Executer, actionID int64) error {
 
 	return nil
 }
+
+// AddChildrenRequirements adds requirements of children to their parent, as done when an action is inserted or loaded.
+// A script step run in a container requires docker.
+func AddChildrenRequirements(a *sdk.Action) {
+	for _, c := range a.Actions {
+		requirements := c.Requirements
+		if c.Name == sdk.ScriptAction && sdk.ParameterValue(c.Parameters, sdk.ScriptImageParameter) != "" {
+			requirements = append(requirements, sdk.DockerRequirement)
+		}
+
+		// Now for each requirement of child, check if it exists in parent
+		for _, cr := range requirements {
+			found := false
+			for _, pr := range a.Requirements {
+				if pr.Type == cr.Type && pr.Value == cr.Value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				a.Requirements = append(a.Requirements, cr)
+			}
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 	"github.com/ovh/cds/sdk/plugin"
@@ -189,5 +190,9 @@ func Delete(db *sql.DB, name string, userID int64) error {
 		log.Warning("plugin.Delete> Action: Cannot get action %s: %s\n", name, err)
 		return err
 	}
+	if err := build.DeleteActionBuildsOfAction(db, a.ID); err != nil {
+		log.Warning("plugin.Delete> Action: Cannot delete builds of action %s: %s\n", name, err)
+		return err
+	}
 	return action.DeleteAction(db, a.ID, userID)
 }
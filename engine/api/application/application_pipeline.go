@@ -389,7 +389,7 @@ func LoadCDTree(db *sql.DB, projectkey, appName string, user *sdk.User) (*[]sdk.
 			root.Environment = sdk.DefaultEnv
 		}
 
-		if permission.AccessToPipeline(root.Environment.ID, root.Pipeline.ID, user, permission.PermissionRead) {
+		if permission.AccessToPipeline(root.Environment.ID, root.Pipeline.ID, user, sdk.RoleActionRead) {
 			if rootTrigger {
 				err = getChild(db, &root, user)
 				if err != nil {
@@ -500,7 +500,7 @@ func getChild(db *sql.DB, parent *sdk.CDPipeline, user *sdk.User) error {
 			return err
 		}
 
-		if permission.AccessToPipeline(child.Trigger.DestEnvironment.ID, child.Trigger.DestPipeline.ID, user, permission.PermissionRead) {
+		if permission.AccessToPipeline(child.Trigger.DestEnvironment.ID, child.Trigger.DestPipeline.ID, user, sdk.RoleActionRead) {
 			child.Trigger.SrcPipeline.Type = sdk.PipelineTypeFromString(srcType)
 			child.Trigger.DestPipeline.Type = sdk.PipelineTypeFromString(destType)
 
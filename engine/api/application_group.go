@@ -13,6 +13,7 @@ import (
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -54,8 +55,14 @@ func updateGroupRoleOnApplicationHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if groupApplication.Permission != permission.PermissionReadWriteExecute {
-		permissions, err := group.LoadAllApplicationGroupByRole(db, app.ID, permission.PermissionReadWriteExecute)
+	if err := permission.CanGiveRole(db, c.User, groupApplication.Permission, permission.ApplicationRoles(app.ID, c.User)); err != nil {
+		log.Warning("updateGroupRoleOnApplicationHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupApplication.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if !role.Can(groupApplication.Permission, sdk.RoleActionManageGroups) {
+		permissions, err := group.LoadAllApplicationGroupByAction(db, app.ID, sdk.RoleActionManageGroups)
 		if err != nil {
 			log.Warning("updateGroupRoleOnApplicationHandler: Cannot load group for application %s:  %s\n", appName, err)
 			WriteError(w, r, err)
@@ -110,13 +117,13 @@ func updateGroupsInApplicationHandler(w http.ResponseWriter, r *http.Request, db
 
 	found := false
 	for _, gp := range groupsPermission {
-		if gp.Permission == permission.PermissionReadWriteExecute {
+		if role.Can(gp.Permission, sdk.RoleActionManageGroups) {
 			found = true
 			break
 		}
 	}
 	if !found {
-		log.Warning("updateGroupsInApplicationHandler: Need one group with write permission.")
+		log.Warning("updateGroupsInApplicationHandler: Need one group able to manage groups.")
 		WriteError(w, r, sdk.ErrGroupNeedWrite)
 		return
 	}
@@ -128,6 +135,18 @@ func updateGroupsInApplicationHandler(w http.ResponseWriter, r *http.Request, db
 		return
 	}
 
+	current, err := group.LoadAllApplicationGroupByAction(db, app.ID, sdk.RoleActionRead)
+	if err != nil {
+		log.Warning("updateGroupsInApplicationHandler: Cannot load groups: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkGivenRoles(db, c.User, groupsPermission, current, permission.ApplicationRoles(app.ID, c.User)); err != nil {
+		log.Warning("updateGroupsInApplicationHandler: %s cannot give roles: %s\n", c.User.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		log.Warning("updateGroupsInApplicationHandler: Cannot start transaction: %s\n", err)
@@ -207,6 +226,12 @@ func addGroupInApplicationHandler(w http.ResponseWriter, r *http.Request, db *sq
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupPermission.Permission, permission.ApplicationRoles(app.ID, c.User)); err != nil {
+		log.Warning("addGroupInApplicationHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupPermission.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	err = group.InsertGroupInApplication(db, app.ID, g.ID, groupPermission.Permission)
 	if err != nil {
 		log.Warning("addGroupInApplicationHandler: Cannot add group %s in application %s:  %s\n", g.Name, app.Name, err)
@@ -271,7 +271,7 @@ func getUserNotificationApplicationPipelineHandler(w http.ResponseWriter, r *htt
 	}
 
 	if env.ID != sdk.DefaultEnv.ID {
-		if !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+		if !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 			log.Warning("getUserNotificationApplicationPipelineHandler> Cannot access to this environment")
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -337,7 +337,7 @@ func deleteUserNotificationApplicationPipelineHandler(w http.ResponseWriter, r *
 	}
 
 	if env.ID != sdk.DefaultEnv.ID {
-		if !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadWriteExecute) {
+		if !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionEdit) {
 			log.Warning("deleteUserNotificationApplicationPipelineHandler> Cannot access to this environment")
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -418,7 +418,7 @@ func updateUserNotificationApplicationPipelineHandler(w http.ResponseWriter, r *
 	}
 
 	if notifs.Environment.ID != sdk.DefaultEnv.ID {
-		if !permission.AccessToEnvironment(notifs.Environment.ID, c.User, permission.PermissionReadWriteExecute) {
+		if !permission.AccessToEnvironment(notifs.Environment.ID, c.User, sdk.RoleActionEdit) {
 			log.Warning("updateUserNotificationApplicationPipelineHandler> Cannot access to this environment")
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// waitingApprovalStage returns the first enabled stage with an approval policy which has not been scheduled yet
+func waitingApprovalStage(pip *sdk.Pipeline, actionBuilds []sdk.ActionBuild) *sdk.Stage {
+	scheduled := map[int64]bool{}
+	for _, ab := range actionBuilds {
+		scheduled[ab.PipelineStageID] = true
+	}
+
+	for i := range pip.Stages {
+		s := &pip.Stages[i]
+		if s.Enabled && s.Approval != nil && !scheduled[s.ID] {
+			return s
+		}
+	}
+	return nil
+}
+
+func approvePipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	appName := vars["permApplicationName"]
+	pipName := vars["permPipelineKey"]
+	buildNumberS := vars["build"]
+
+	if err := r.ParseForm(); err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot parse form: %s\n", err)
+		WriteError(w, r, sdk.ErrUnknownError)
+		return
+	}
+	envName := r.Form.Get("envName")
+
+	buildNumber, err := strconv.ParseInt(buildNumberS, 10, 64)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> buildNumber is not a int: %s\n", err)
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var req sdk.ApprovalRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Load pipeline
+	pip, err := pipeline.LoadPipeline(db, projectKey, pipName, true)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot load pipeline: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// Load application
+	app, err := application.LoadApplicationByName(db, projectKey, appName)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot load application: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// Load environment
+	if pip.Type != sdk.BuildPipeline && (envName == "" || envName == sdk.DefaultEnv.Name) {
+		WriteError(w, r, sdk.ErrNoEnvironmentProvided)
+		return
+	}
+	env := &sdk.DefaultEnv
+
+	if pip.Type != sdk.BuildPipeline {
+		env, err = environment.LoadEnvironmentByName(db, projectKey, envName)
+		if err != nil {
+			log.Warning("approvePipelineBuildHandler> Cannot load environment %s: %s\n", envName, err)
+			WriteError(w, r, err)
+			return
+		}
+
+		if !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionApproveDeployment) {
+			log.Warning("approvePipelineBuildHandler> %s cannot approve deployments on environment %s\n", c.User.Username, env.Name)
+			WriteError(w, r, sdk.ErrForbidden)
+			return
+		}
+	}
+
+	pb, err := pipeline.LoadPipelineBuild(db, pip.ID, app.ID, buildNumber, env.ID)
+	if err != nil {
+		errFinal := err
+		if err == sdk.ErrNoPipelineBuild {
+			errFinal = sdk.ErrBuildArchived
+		}
+		log.Warning("approvePipelineBuildHandler> Cannot load pipeline Build: %s\n", errFinal)
+		WriteError(w, r, errFinal)
+		return
+	}
+
+	if pb.Status != sdk.StatusWaitingApproval {
+		WriteError(w, r, sdk.ErrNotWaitingApproval)
+		return
+	}
+
+	// Users who triggered the build or committed its changes cannot approve it
+	authors, err := pipeline.LoadAuthorIDs(db, &pb)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot load authors of pipeline build %d: %s\n", pb.ID, err)
+		WriteError(w, r, err)
+		return
+	}
+	for _, id := range authors {
+		if id == c.User.ID {
+			WriteError(w, r, sdk.ErrApprovalBySelf)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot start tx: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Concurrent approvals wait for each other, so that each one is decided with all previous ones
+	if err := pipeline.SelectWaitingApprovalBuildForUpdate(tx, pb.ID); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, r, sdk.ErrNotWaitingApproval)
+			return
+		}
+		log.Warning("approvePipelineBuildHandler> Cannot lock pipeline build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	actionBuilds, err := build.LoadBuildByPipelineBuildID(db, pb.ID)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot load action builds: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	stage := waitingApprovalStage(pip, actionBuilds)
+	if stage == nil {
+		WriteError(w, r, sdk.ErrNotWaitingApproval)
+		return
+	}
+
+	approvals, err := build.LoadApprovals(tx, pb.ID)
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot load approvals: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	for _, a := range approvals {
+		if a.StageID == stage.ID && a.UserID == c.User.ID {
+			WriteError(w, r, sdk.ErrConflict)
+			return
+		}
+	}
+
+	a := sdk.Approval{
+		PipelineBuildID: pb.ID,
+		StageID:         stage.ID,
+		UserID:          c.User.ID,
+		Username:        c.User.Username,
+		Approved:        req.Approved,
+		Comment:         req.Comment,
+	}
+	if err := build.InsertApproval(tx, &a); err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot insert approval: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// Approved stage is scheduled again, rejected one fails the build
+	switch stage.Approval.Decision(append(approvals, a), stage.ID) {
+	case sdk.StatusSuccess:
+		err = pipeline.UpdatePipelineBuildStatus(tx, pb, sdk.StatusBuilding)
+	case sdk.StatusFail:
+		err = pipeline.UpdatePipelineBuildStatus(tx, pb, sdk.StatusFail)
+	}
+	if err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot update pipeline build status: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Warning("approvePipelineBuildHandler> Cannot commit tx: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	k := cache.Key("application", projectKey, "builds", "*")
+	cache.DeleteAll(k)
+
+	WriteJSON(w, r, a, http.StatusOK)
+}
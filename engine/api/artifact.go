@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
@@ -41,7 +43,7 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	m := r.MultipartForm
 	envName := m.Value["env"][0]
 
-	var sizeStr, permStr, md5sum string
+	var sizeStr, permStr, md5sum, sha string
 	if len(m.Value["size"]) > 0 {
 		sizeStr = m.Value["size"][0]
 	}
@@ -51,6 +53,9 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	if len(m.Value["md5sum"]) > 0 {
 		md5sum = m.Value["md5sum"][0]
 	}
+	if len(m.Value["sha256"]) > 0 {
+		sha = m.Value["sha256"][0]
+	}
 
 	if fileName == "" {
 		log.Warning("uploadArtifactHandler> %s header is not set", sdk.ArtifactFileName)
@@ -84,7 +89,7 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("uploadArtifactHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -127,6 +132,7 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		Size:         size,
 		Perm:         uint32(perm),
 		MD5sum:       md5sum,
+		SHA256:       sha,
 	}
 
 	files := m.File[fileName]
@@ -148,6 +154,90 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	}
 }
 
+// linkArtifactHandler saves an artifact whose content is already stored, without uploading it again.
+// It returns 404 if no content with given digest is stored.
+func linkArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	project := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	appName := vars["permApplicationName"]
+	tag := vars["tag"]
+	buildNumberString := vars["buildNumber"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var art sdk.Artifact
+	if err := json.Unmarshal(data, &art); err != nil || art.Name == "" || art.SHA256 == "" {
+		log.Warning("linkArtifactHandler> Invalid artifact: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p, err := pipeline.LoadPipeline(db, project, pipelineName, false)
+	if err != nil {
+		log.Warning("linkArtifactHandler> cannot load pipeline %s-%s: %s\n", project, pipelineName, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	a, err := application.LoadApplicationByName(db, project, appName)
+	if err != nil {
+		log.Warning("linkArtifactHandler> cannot load application %s-%s: %s\n", project, appName, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	env := &sdk.DefaultEnv
+	if art.Environment != "" && art.Environment != sdk.DefaultEnv.Name {
+		env, err = environment.LoadEnvironmentByName(db, project, art.Environment)
+		if err != nil {
+			log.Warning("linkArtifactHandler> Cannot load environment %s: %s\n", art.Environment, err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
+		log.Warning("linkArtifactHandler> No enought right on this environment %s: \n", art.Environment)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	art.BuildNumber, err = strconv.Atoi(buildNumberString)
+	if err != nil {
+		log.Warning("linkArtifactHandler> BuildNumber must be an integer: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	art.DownloadHash, err = generateHash()
+	if err != nil {
+		log.Warning("linkArtifactHandler> Could not generate hash: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	art.Project = project
+	art.Pipeline = pipelineName
+	art.Application = a.Name
+	art.Environment = env.Name
+	art.Tag = tag
+
+	if err := artifact.LinkFile(db, p, a, art, env); err != nil {
+		if err != sdk.ErrNotFound {
+			log.Warning("linkArtifactHandler> cannot link artifact %s to %s: %s\n", art.Name, art.SHA256, err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func downloadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	vars := mux.Vars(r)
 	project := vars["key"]
@@ -186,6 +276,15 @@ func downloadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 		return
 	}
 
+	// Let client download content from objectstore if it allows direct access and client follows redirect.
+	// Client has to verify content digest itself then.
+	if u, err := artifact.DownloadURL(*art); err != nil {
+		log.Warning("downloadArtifactHandler> Cannot get download URL of artifact %d: %s\n", artifactID, err)
+	} else if u != "" && r.FormValue("redirect") == "true" {
+		http.Redirect(w, r, u, http.StatusTemporaryRedirect)
+		return
+	}
+
 	log.Info("downloadArtifactHandler: Serving %+v\n", art)
 	err = artifact.StreamFile(w, *art)
 	if err != nil {
@@ -234,7 +333,7 @@ func listArtifactsBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("listArtifactsBuildHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -294,7 +393,7 @@ func listArtifactsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("listArtifactsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -327,6 +426,14 @@ func downloadArtifactDirectHandler(w http.ResponseWriter, r *http.Request, db *s
 		return
 	}
 
+	// Let client download content from objectstore if it asks for it
+	if u, err := artifact.DownloadURL(*art); err != nil {
+		log.Warning("downloadArtifactDirectHandler> Cannot get download URL of artifact %d: %s\n", art.ID, err)
+	} else if u != "" && r.FormValue("redirect") == "true" {
+		http.Redirect(w, r, u, http.StatusTemporaryRedirect)
+		return
+	}
+
 	w.Header().Add("Content-Type", "application/octet-stream")
 	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", art.Name))
 
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/ovh/cds/engine/api/database"
@@ -17,7 +18,7 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 	art := &sdk.Artifact{}
 	query := `SELECT artifact.id, artifact.name, artifact.tag, 
 		  pipeline.name, project.projectKey, application.name, environment.name,
-		  artifact.size, artifact.perm, artifact.md5sum, artifact.object_path
+		  artifact.size, artifact.perm, artifact.md5sum, artifact.object_path, artifact.sha256
 		  FROM artifact
 		  JOIN pipeline ON artifact.pipeline_id = pipeline.id
 		  JOIN project ON pipeline.project_id = project.id
@@ -25,15 +26,16 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 		  JOIN environment ON environment.id = artifact.environment_id
 		  WHERE download_hash = $1`
 
-	var md5sum, objectpath sql.NullString
+	var md5sum, objectpath, sha sql.NullString
 	var size, perm sql.NullInt64
-	err := db.QueryRow(query, hash).Scan(&art.ID, &art.Name, &art.Tag, &art.Pipeline, &art.Project, &art.Application, &art.Environment, &size, &perm, &md5sum, &objectpath)
+	err := db.QueryRow(query, hash).Scan(&art.ID, &art.Name, &art.Tag, &art.Pipeline, &art.Project, &art.Application, &art.Environment, &size, &perm, &md5sum, &objectpath, &sha)
 	if err != nil {
 		return nil, err
 	}
 	if md5sum.Valid {
 		art.MD5sum = md5sum.String
 	}
+	art.SHA256 = sha.String
 	if objectpath.Valid {
 		art.ObjectPath = objectpath.String
 	}
@@ -48,7 +50,7 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 
 // LoadArtifactsByBuildNumber Load artifact by pipeline ID and buildNUmber
 func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int64, buildNumber int, environmentID int64) ([]sdk.Artifact, error) {
-	query := `SELECT id, name, tag, download_hash, size, perm, md5sum, object_path
+	query := `SELECT id, name, tag, download_hash, size, perm, md5sum, object_path, sha256
 	          FROM "artifact"
 	          WHERE build_number = $1 AND pipeline_id = $2 AND application_id = $3 AND environment_id = $4
 	          ORDER BY name`
@@ -62,15 +64,16 @@ func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int6
 	arts := []sdk.Artifact{}
 	for rows.Next() {
 		art := sdk.Artifact{}
-		var md5sum, objectpath sql.NullString
+		var md5sum, objectpath, sha sql.NullString
 		var size, perm sql.NullInt64
-		err = rows.Scan(&art.ID, &art.Name, &art.Tag, &art.DownloadHash, &size, &perm, &md5sum, &objectpath)
+		err = rows.Scan(&art.ID, &art.Name, &art.Tag, &art.DownloadHash, &size, &perm, &md5sum, &objectpath, &sha)
 		if err != nil {
 			return nil, err
 		}
 		if md5sum.Valid {
 			art.MD5sum = md5sum.String
 		}
+		art.SHA256 = sha.String
 		if objectpath.Valid {
 			art.ObjectPath = objectpath.String
 		}
@@ -87,7 +90,7 @@ func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int6
 
 // LoadArtifacts Load artifact by pipeline ID
 func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmentID int64, tag string) ([]sdk.Artifact, error) {
-	query := `SELECT id, name, download_hash, size, perm, md5sum, object_path
+	query := `SELECT id, name, download_hash, size, perm, md5sum, object_path, sha256
 		FROM "artifact" 
 		WHERE tag = $1 
 		AND pipeline_id = $2 
@@ -102,15 +105,16 @@ func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmen
 	var arts []sdk.Artifact
 	for rows.Next() {
 		art := sdk.Artifact{}
-		var md5sum, objectpath sql.NullString
+		var md5sum, objectpath, sha sql.NullString
 		var size, perm sql.NullInt64
-		err = rows.Scan(&art.ID, &art.Name, &art.DownloadHash, &size, &perm, &md5sum, &objectpath)
+		err = rows.Scan(&art.ID, &art.Name, &art.DownloadHash, &size, &perm, &md5sum, &objectpath, &sha)
 		if err != nil {
 			return nil, err
 		}
 		if md5sum.Valid {
 			art.MD5sum = md5sum.String
 		}
+		art.SHA256 = sha.String
 		if objectpath.Valid {
 			art.ObjectPath = objectpath.String
 		}
@@ -129,7 +133,7 @@ func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmen
 // LoadArtifact Load artifact by ID
 func LoadArtifact(db *sql.DB, id int64) (*sdk.Artifact, error) {
 	query := `SELECT 
-			artifact.name, artifact.tag, artifact.download_hash, artifact.size, artifact.perm, artifact.md5sum, artifact.object_path, 
+			artifact.name, artifact.tag, artifact.download_hash, artifact.size, artifact.perm, artifact.md5sum, artifact.object_path, artifact.sha256,
 			pipeline.name, project.projectKey, application.name, environment.name FROM artifact
 			JOIN pipeline ON artifact.pipeline_id = pipeline.id
 			JOIN project ON pipeline.project_id = project.id
@@ -138,13 +142,14 @@ func LoadArtifact(db *sql.DB, id int64) (*sdk.Artifact, error) {
 			WHERE artifact.id = $1`
 
 	s := &sdk.Artifact{}
-	var md5sum, objectpath sql.NullString
+	var md5sum, objectpath, sha sql.NullString
 	var size, perm sql.NullInt64
-	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &s.DownloadHash, &size, &perm, &md5sum, &objectpath,
+	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &s.DownloadHash, &size, &perm, &md5sum, &objectpath, &sha,
 		&s.Pipeline, &s.Project, &s.Application, &s.Environment)
 	if md5sum.Valid {
 		s.MD5sum = md5sum.String
 	}
+	s.SHA256 = sha.String
 	if objectpath.Valid {
 		s.ObjectPath = objectpath.String
 	}
@@ -158,11 +163,11 @@ func LoadArtifact(db *sql.DB, id int64) (*sdk.Artifact, error) {
 }
 
 // DeleteArtifact lock the artifact in database,
-// then remove the actual object using storage driver,
+// then release its blob or remove the actual object using storage driver,
 // finally remove artifact from database if actual delete is performed
 func DeleteArtifact(db database.QueryExecuter, id int64) error {
 
-	query := `SELECT artifact.name, artifact.tag, pipeline.name, project.projectKey, application.name, environment.name FROM artifact
+	query := `SELECT artifact.name, artifact.tag, artifact.sha256, pipeline.name, project.projectKey, application.name, environment.name FROM artifact
 						JOIN pipeline ON artifact.pipeline_id = pipeline.id
 						JOIN project ON pipeline.project_id = project.id
 						JOIN application ON application.id = artifact.application_id
@@ -170,14 +175,14 @@ func DeleteArtifact(db database.QueryExecuter, id int64) error {
 						WHERE artifact.id = $1 FOR UPDATE`
 
 	s := sdk.Artifact{}
-	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &s.Pipeline, &s.Project, &s.Application, &s.Environment)
+	var sha sql.NullString
+	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &sha, &s.Pipeline, &s.Project, &s.Application, &s.Environment)
 	if err != nil {
 		return err
 	}
+	s.SHA256 = sha.String
 
-	err = objectstore.DeleteArtifact(s)
-	// If it's 404, it's lost anyway...
-	if err != nil && !strings.Contains(err.Error(), "404") {
+	if err := deleteContent(db, s); err != nil {
 		return err
 	}
 
@@ -190,18 +195,48 @@ func DeleteArtifact(db database.QueryExecuter, id int64) error {
 	return nil
 }
 
-func insertArtifact(db database.Executer, pipelineID, applicationID int64, environmentID int64, art sdk.Artifact) error {
-	query := `DELETE FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5`
-	_, err := db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, environmentID)
+// deleteContent releases the blob of an artifact, or deletes the object of artifacts stored before deduplication
+func deleteContent(db database.QueryExecuter, art sdk.Artifact) error {
+	if art.SHA256 != "" {
+		return releaseBlob(db, art.SHA256)
+	}
+
+	err := objectstore.DeleteArtifact(art)
+	// If it's 404, it's lost anyway...
+	if err != nil && !strings.Contains(err.Error(), "404") && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func insertArtifact(db database.QueryExecuter, pipelineID, applicationID int64, environmentID int64, art sdk.Artifact) error {
+	// Release content of the artifact being replaced
+	query := `SELECT sha256 FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5`
+	var sha sql.NullString
+	err := db.QueryRow(query, art.Name, art.Tag, pipelineID, applicationID, environmentID).Scan(&sha)
+	switch err {
+	case sql.ErrNoRows:
+	case nil:
+		replaced := art
+		replaced.SHA256 = sha.String
+		if err := deleteContent(db, replaced); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	query = `DELETE FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5`
+	_, err = db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, environmentID)
 	if err != nil {
 		return err
 	}
 
 	query = `INSERT INTO "artifact" 
-			(name, tag, pipeline_id, application_id, build_number, environment_id, download_hash, size, perm, md5sum, object_path) 
+			(name, tag, pipeline_id, application_id, build_number, environment_id, download_hash, size, perm, md5sum, object_path, sha256) 
 			VALUES 
-			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
-	_, err = db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, art.BuildNumber, environmentID, art.DownloadHash, art.Size, art.Perm, art.MD5sum, art.ObjectPath)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err = db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, art.BuildNumber, environmentID, art.DownloadHash, art.Size, art.Perm, art.MD5sum, art.ObjectPath, art.SHA256)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -209,7 +244,8 @@ func insertArtifact(db database.Executer, pipelineID, applicationID int64, envir
 	return nil
 }
 
-// SaveFile Insert file in db and write it in data directory
+// SaveFile Insert file in db and store its content by digest, content is shared by identical artifacts.
+// If art.SHA256 is set, it must match the digest of content.
 func SaveFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact, content io.ReadCloser, e *sdk.Environment) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -217,22 +253,47 @@ func SaveFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact,
 	}
 	defer tx.Rollback()
 
-	objectPath, err := objectstore.StoreArtifact(art, content)
+	digest, objectPath, stored, err := storeBlob(tx, content)
 	if err != nil {
 		return err
 	}
-	log.Debug("objectpath=%s\n", objectPath)
-	art.ObjectPath = objectPath
-	if err = insertArtifact(tx, p.ID, a.ID, e.ID, art); err != nil {
+	if art.SHA256 != "" && art.SHA256 != digest {
+		err = fmt.Errorf("artifact digest mismatch: expected %s, got %s", art.SHA256, digest)
+	} else {
+		log.Debug("objectpath=%s\n", objectPath)
+		art.SHA256 = digest
+		art.ObjectPath = objectPath
+		err = insertArtifact(tx, p.ID, a.ID, e.ID, art)
+	}
+	if err != nil {
+		// Object stored for a new blob would leak on rollback
+		if stored {
+			discardBlob(digest)
+		}
 		return err
 	}
 
 	return tx.Commit()
 }
 
-// StreamFile Stream artifact
+// DownloadURL returns a temporary URL to download artifact content directly from objectstore,
+// or an empty string if objectstore driver cannot give one
+func DownloadURL(art sdk.Artifact) (string, error) {
+	if art.SHA256 != "" {
+		return objectstore.ObjectURL(blobContainer, art.SHA256)
+	}
+	return objectstore.ArtifactURL(art)
+}
+
+// StreamFile Stream artifact, content digest is verified before streaming
 func StreamFile(w io.Writer, art sdk.Artifact) error {
-	f, err := objectstore.FetchArtifact(art)
+	var f io.ReadCloser
+	var err error
+	if art.SHA256 != "" {
+		f, err = fetchBlob(art)
+	} else {
+		f, err = objectstore.FetchArtifact(art)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot fetch artifact: %s", err)
 	}
@@ -0,0 +1,222 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// blobContainer is the objectstore container of artifact contents, stored by their SHA-256 digest
+const blobContainer = "blob"
+
+// storeBlob computes the digest of content and stores it in objectstore unless a blob with
+// the same digest is already used. The blob reference count is incremented in both cases.
+// stored is true when the object has been written, the blob row is then locked by db until
+// commit or rollback.
+func storeBlob(db database.QueryExecuter, content io.Reader) (digest string, objectPath string, stored bool, err error) {
+	// Content is written in a temporary file to know its digest before storing it
+	tmp, err := ioutil.TempFile("", "cds-artifact-")
+	if err != nil {
+		return "", "", false, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), content)
+	if err != nil {
+		return "", "", false, err
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+
+	// Concurrent uploads of the same content wait for each other on the blob row
+	var refcount int
+	var path sql.NullString
+	query := `INSERT INTO artifact_blob (sha256, size, refcount) VALUES ($1, $2, 1)
+		ON CONFLICT (sha256) DO UPDATE SET refcount = artifact_blob.refcount + 1
+		RETURNING refcount, object_path`
+	if err := db.QueryRow(query, digest, size).Scan(&refcount, &path); err != nil {
+		return "", "", false, err
+	}
+	if refcount > 1 {
+		log.Debug("storeBlob> Blob %s already stored\n", digest)
+		return digest, path.String, false, nil
+	}
+
+	// New blob, or unused one whose object may have been purged
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return "", "", false, err
+	}
+	objectPath, err = objectstore.StoreObject(blobContainer, digest, ioutil.NopCloser(tmp))
+	if err != nil {
+		return "", "", false, err
+	}
+
+	query = `UPDATE artifact_blob SET size = $2, object_path = $3 WHERE sha256 = $1`
+	if _, err := db.Exec(query, digest, size, objectPath); err != nil {
+		discardBlob(digest)
+		return "", "", false, err
+	}
+	return digest, objectPath, true, nil
+}
+
+// discardBlob deletes the object of a blob stored by a transaction which is not committed.
+// It must be called before rollback, while the blob row is still locked.
+func discardBlob(digest string) {
+	if err := objectstore.DeleteObject(blobContainer, digest); err != nil && !os.IsNotExist(err) {
+		log.Warning("discardBlob> Cannot delete blob %s: %s\n", digest, err)
+	}
+}
+
+// referenceBlob increments the reference count of a blob if it is used
+func referenceBlob(db database.QueryExecuter, digest string) (bool, string, error) {
+	var objectPath sql.NullString
+	query := `UPDATE artifact_blob SET refcount = refcount + 1 WHERE sha256 = $1 AND refcount > 0 RETURNING object_path`
+	err := db.QueryRow(query, digest).Scan(&objectPath)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, objectPath.String, nil
+}
+
+// releaseBlob decrements the reference count of a blob. Unused blobs are deleted by PurgeBlobs,
+// so that no object is deleted by a transaction which may be rolled back.
+func releaseBlob(db database.QueryExecuter, digest string) error {
+	query := `UPDATE artifact_blob SET refcount = refcount - 1 WHERE sha256 = $1 AND refcount > 0`
+	_, err := db.Exec(query, digest)
+	return err
+}
+
+// PurgeBlobs deletes blobs which are not used by any artifact anymore
+func PurgeBlobs(db *sql.DB) error {
+	query := `SELECT sha256 FROM artifact_blob WHERE refcount <= 0`
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	var digests []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			rows.Close()
+			return err
+		}
+		digests = append(digests, d)
+	}
+	rows.Close()
+
+	for _, d := range digests {
+		if err := purgeBlob(db, d); err != nil {
+			log.Warning("PurgeBlobs> Cannot delete blob %s: %s\n", d, err)
+		}
+	}
+	return nil
+}
+
+// purgeBlob deletes an unused blob. Its object is deleted while the blob row is locked, so that no
+// upload can reference it meanwhile. If commit fails, the row stays unused and its object is stored
+// again by the next upload of the same content.
+func purgeBlob(db *sql.DB, digest string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM artifact_blob WHERE sha256 = $1 AND refcount <= 0`
+	res, err := tx.Exec(query, digest)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	if err := objectstore.DeleteObject(blobContainer, digest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LinkFile saves an artifact using the content of an already stored blob. It returns sdk.ErrNotFound
+// if there is no blob with artifact digest, then content must be uploaded with SaveFile.
+func LinkFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact, e *sdk.Environment) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	found, objectPath, err := referenceBlob(tx, art.SHA256)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return sdk.ErrNotFound
+	}
+
+	art.ObjectPath = objectPath
+	if err = insertArtifact(tx, p.ID, a.ID, e.ID, art); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fetchBlob returns the content of an artifact once its digest has been verified,
+// so that corrupted content is never sent to clients
+func fetchBlob(art sdk.Artifact) (io.ReadCloser, error) {
+	r, err := objectstore.FetchObject(blobContainer, art.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return verifyBlob(r, art.SHA256)
+}
+
+// verifyBlob copies content in a temporary file, removed once closed, and checks its digest
+func verifyBlob(r io.Reader, digest string) (io.ReadCloser, error) {
+	tmp, err := ioutil.TempFile("", "cds-artifact-")
+	if err != nil {
+		return nil, err
+	}
+	f := &tempFile{tmp}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != digest {
+		f.Close()
+		return nil, fmt.Errorf("artifact digest mismatch: expected %s, got %s", digest, sum)
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// tempFile is a temporary file removed once closed
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
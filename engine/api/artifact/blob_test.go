@@ -0,0 +1,38 @@
+package artifact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVerifyBlob(t *testing.T) {
+	content := []byte("artifact content")
+	digest := "4d6b3d9ac1a1f3d39ba4f3a4ea0a9e5ec7a1d15b3b0ee05ac39e0e35a5a3d7b8"
+
+	if _, err := verifyBlob(bytes.NewReader(content), digest); err == nil {
+		t.Fatalf("Digest mismatch should be reported before reading")
+	}
+
+	sum := sha256.Sum256(content)
+	r, err := verifyBlob(bytes.NewReader(content), hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("Unexpected content: %s", data)
+	}
+
+	name := r.(*tempFile).Name()
+	r.Close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("Temporary file should be removed once closed")
+	}
+}
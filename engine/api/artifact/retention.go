@@ -0,0 +1,290 @@
+package artifact
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// LoadProjectRetention loads artifact retention rules of a project
+func LoadProjectRetention(db database.Querier, projectID int64) (sdk.ArtifactRetention, error) {
+	query := `SELECT keep_last_builds, keep_days, keep_deployed FROM artifact_retention WHERE project_id = $1 AND application_id IS NULL`
+	r, _, err := loadRetention(db, query, projectID)
+	return r, err
+}
+
+// LoadApplicationRetention loads artifact retention rules of an application, falling back to project ones
+func LoadApplicationRetention(db database.Querier, projectID, applicationID int64) (sdk.ArtifactRetention, error) {
+	query := `SELECT keep_last_builds, keep_days, keep_deployed FROM artifact_retention WHERE application_id = $1`
+	r, found, err := loadRetention(db, query, applicationID)
+	if err != nil || found {
+		return r, err
+	}
+	return LoadProjectRetention(db, projectID)
+}
+
+func loadRetention(db database.Querier, query string, id int64) (sdk.ArtifactRetention, bool, error) {
+	var r sdk.ArtifactRetention
+	err := db.QueryRow(query, id).Scan(&r.KeepLastBuilds, &r.KeepDays, &r.KeepDeployed)
+	if err == sql.ErrNoRows {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, err
+	}
+	return r, true, nil
+}
+
+// UpdateProjectRetention sets artifact retention rules of a project, empty rules keep artifacts forever
+func UpdateProjectRetention(db database.Executer, projectID int64, r sdk.ArtifactRetention) error {
+	query := `DELETE FROM artifact_retention WHERE project_id = $1 AND application_id IS NULL`
+	if _, err := db.Exec(query, projectID); err != nil {
+		return err
+	}
+	if r.IsEmpty() {
+		return nil
+	}
+
+	query = `INSERT INTO artifact_retention (project_id, keep_last_builds, keep_days, keep_deployed) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(query, projectID, r.KeepLastBuilds, r.KeepDays, r.KeepDeployed)
+	return err
+}
+
+// UpdateApplicationRetention sets artifact retention rules of an application, empty rules fall back to project ones
+func UpdateApplicationRetention(db database.Executer, projectID, applicationID int64, r sdk.ArtifactRetention) error {
+	query := `DELETE FROM artifact_retention WHERE application_id = $1`
+	if _, err := db.Exec(query, applicationID); err != nil {
+		return err
+	}
+	if r.IsEmpty() {
+		return nil
+	}
+
+	query = `INSERT INTO artifact_retention (project_id, application_id, keep_last_builds, keep_days, keep_deployed) VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.Exec(query, projectID, applicationID, r.KeepLastBuilds, r.KeepDays, r.KeepDeployed)
+	return err
+}
+
+// Purge deletes expired artifacts according to retention rules every interval seconds
+func Purge(interval int) {
+	// If this goroutine exits, then it's a crash
+	defer log.Fatalf("Goroutine of artifact.Purge exited - Exit CDS Engine")
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+		db := database.DB()
+		if db == nil {
+			continue
+		}
+
+		arts, err := LoadExpiredArtifacts(db)
+		if err != nil {
+			log.Warning("Purge> Cannot load expired artifacts: %s\n", err)
+			continue
+		}
+		log.Notice("Purge> %d artifacts expired\n", len(arts))
+
+		for _, a := range arts {
+			if err := lockAndDeleteArtifact(db, a.ID); err != nil {
+				log.Warning("Purge> Cannot delete artifact %d (%s/%s/%s): %s\n", a.ID, a.Project, a.Application, a.Name, err)
+			}
+		}
+
+		if err := PurgeBlobs(db); err != nil {
+			log.Warning("Purge> Cannot delete unused blobs: %s\n", err)
+		}
+	}
+}
+
+func lockAndDeleteArtifact(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := DeleteArtifact(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadExpiredArtifacts returns artifacts of all applications which are not kept by retention rules
+func LoadExpiredArtifacts(db *sql.DB) ([]sdk.Artifact, error) {
+	// Application rules override project ones
+	query := `SELECT application.id, artifact_retention.application_id, artifact_retention.keep_last_builds,
+			artifact_retention.keep_days, artifact_retention.keep_deployed
+		FROM application
+		JOIN artifact_retention ON artifact_retention.project_id = application.project_id
+		WHERE artifact_retention.application_id = application.id OR artifact_retention.application_id IS NULL`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := map[int64]sdk.ArtifactRetention{}
+	for rows.Next() {
+		var appID int64
+		var ruleAppID sql.NullInt64
+		var r sdk.ArtifactRetention
+		if err := rows.Scan(&appID, &ruleAppID, &r.KeepLastBuilds, &r.KeepDays, &r.KeepDeployed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, exists := rules[appID]; exists && !ruleAppID.Valid {
+			continue
+		}
+		rules[appID] = r
+	}
+	rows.Close()
+
+	expired := []sdk.Artifact{}
+	now := time.Now()
+	for appID, r := range rules {
+		if r.IsEmpty() {
+			continue
+		}
+		arts, err := loadRetentionArtifacts(db, appID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range expiredArtifacts(arts, r, now) {
+			expired = append(expired, a.Artifact)
+		}
+	}
+
+	return expired, nil
+}
+
+// retentionArtifact is an artifact with the build information used by retention rules
+type retentionArtifact struct {
+	sdk.Artifact
+	pipelineID    int64
+	environmentID int64
+	branch        string
+	created       time.Time
+	deployed      bool
+}
+
+func loadRetentionArtifacts(db database.Querier, applicationID int64) ([]retentionArtifact, error) {
+	deployed := map[int64]bool{}
+	query := `SELECT version FROM pipeline_build WHERE application_id = $1 AND environment_id <> $2 AND status = $3
+		UNION SELECT version FROM pipeline_history WHERE application_id = $1 AND environment_id <> $2 AND status = $3`
+	rows, err := db.Query(query, applicationID, sdk.DefaultEnv.ID, string(sdk.StatusSuccess))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var version sql.NullInt64
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deployed[version.Int64] = true
+	}
+	rows.Close()
+
+	query = `SELECT artifact.id, artifact.name, artifact.tag, artifact.build_number, artifact.created,
+			artifact.pipeline_id, artifact.environment_id, project.projectKey, application.name, pipeline.name, environment.name,
+			COALESCE(pipeline_build.vcs_changes_branch, pipeline_history.vcs_changes_branch, ''),
+			COALESCE(pipeline_build.version, pipeline_history.version, artifact.build_number)
+		FROM artifact
+		JOIN application ON application.id = artifact.application_id
+		JOIN project ON project.id = application.project_id
+		JOIN pipeline ON pipeline.id = artifact.pipeline_id
+		JOIN environment ON environment.id = artifact.environment_id
+		LEFT JOIN pipeline_build ON pipeline_build.application_id = artifact.application_id
+			AND pipeline_build.pipeline_id = artifact.pipeline_id
+			AND pipeline_build.environment_id = artifact.environment_id
+			AND pipeline_build.build_number = artifact.build_number
+		LEFT JOIN pipeline_history ON pipeline_history.application_id = artifact.application_id
+			AND pipeline_history.pipeline_id = artifact.pipeline_id
+			AND pipeline_history.environment_id = artifact.environment_id
+			AND pipeline_history.build_number = artifact.build_number
+		WHERE artifact.application_id = $1`
+	rows, err = db.Query(query, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var arts []retentionArtifact
+	for rows.Next() {
+		var a retentionArtifact
+		var version int64
+		var created pq.NullTime
+		if err := rows.Scan(&a.ID, &a.Name, &a.Tag, &a.BuildNumber, &created,
+			&a.pipelineID, &a.environmentID, &a.Project, &a.Application, &a.Pipeline, &a.Environment,
+			&a.branch, &version); err != nil {
+			return nil, err
+		}
+		a.created = created.Time
+		a.deployed = deployed[version]
+		arts = append(arts, a)
+	}
+	return arts, nil
+}
+
+// expiredArtifacts returns artifacts not kept by any retention rule
+func expiredArtifacts(arts []retentionArtifact, r sdk.ArtifactRetention, now time.Time) []retentionArtifact {
+	if r.IsEmpty() {
+		return nil
+	}
+
+	// Build numbers of each pipeline, environment and branch, most recent first
+	type buildKey struct {
+		pipelineID, environmentID int64
+		branch                    string
+	}
+	builds := map[buildKey][]int{}
+	for _, a := range arts {
+		k := buildKey{a.pipelineID, a.environmentID, a.branch}
+		found := false
+		for _, n := range builds[k] {
+			if n == a.BuildNumber {
+				found = true
+				break
+			}
+		}
+		if !found {
+			builds[k] = append(builds[k], a.BuildNumber)
+		}
+	}
+	for k := range builds {
+		sort.Sort(sort.Reverse(sort.IntSlice(builds[k])))
+	}
+
+	var expired []retentionArtifact
+	for _, a := range arts {
+		if r.KeepDeployed && a.deployed {
+			continue
+		}
+		if r.KeepDays > 0 && a.created.After(now.AddDate(0, 0, -r.KeepDays)) {
+			continue
+		}
+		if r.KeepLastBuilds > 0 {
+			last := builds[buildKey{a.pipelineID, a.environmentID, a.branch}]
+			if len(last) > r.KeepLastBuilds {
+				last = last[:r.KeepLastBuilds]
+			}
+			kept := false
+			for _, n := range last {
+				if n == a.BuildNumber {
+					kept = true
+					break
+				}
+			}
+			if kept {
+				continue
+			}
+		}
+		expired = append(expired, a)
+	}
+	return expired
+}
@@ -0,0 +1,61 @@
+package artifact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestExpiredArtifacts(t *testing.T) {
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+
+	art := func(id int64, build int, branch string, created time.Time, deployed bool) retentionArtifact {
+		return retentionArtifact{
+			Artifact:      sdk.Artifact{ID: id, BuildNumber: build},
+			pipelineID:    1,
+			environmentID: sdk.DefaultEnv.ID,
+			branch:        branch,
+			created:       created,
+			deployed:      deployed,
+		}
+	}
+	arts := []retentionArtifact{
+		art(1, 1, "master", old, true),
+		art(2, 2, "master", old, false),
+		art(3, 3, "master", old, false),
+		art(4, 3, "master", old, false),
+		art(5, 1, "feat", old, false),
+		art(6, 2, "feat", now, false),
+	}
+
+	tests := []struct {
+		retention sdk.ArtifactRetention
+		expected  []int64
+	}{
+		{sdk.ArtifactRetention{}, nil},
+		{sdk.ArtifactRetention{KeepLastBuilds: 1}, []int64{1, 2, 5}},
+		{sdk.ArtifactRetention{KeepLastBuilds: 1, KeepDeployed: true}, []int64{2, 5}},
+		{sdk.ArtifactRetention{KeepDays: 7}, []int64{1, 2, 3, 4, 5}},
+		{sdk.ArtifactRetention{KeepDays: 7, KeepLastBuilds: 2}, []int64{1}},
+	}
+
+	for _, test := range tests {
+		expired := expiredArtifacts(arts, test.retention, now)
+		var ids []int64
+		for _, a := range expired {
+			ids = append(ids, a.ID)
+		}
+		if len(ids) != len(test.expected) {
+			t.Errorf("Expected %v expired with %+v, got %v", test.expected, test.retention, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Errorf("Expected %v expired with %+v, got %v", test.expected, test.retention, ids)
+				break
+			}
+		}
+	}
+}
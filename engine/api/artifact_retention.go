@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/artifact"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func getProjectArtifactRetentionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	key := mux.Vars(r)["permProjectKey"]
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("getProjectArtifactRetentionHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	retention, err := artifact.LoadProjectRetention(db, proj.ID)
+	if err != nil {
+		log.Warning("getProjectArtifactRetentionHandler> Cannot load retention of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, retention, http.StatusOK)
+}
+
+func updateProjectArtifactRetentionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	key := mux.Vars(r)["permProjectKey"]
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("updateProjectArtifactRetentionHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	retention, err := readArtifactRetention(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := artifact.UpdateProjectRetention(db, proj.ID, retention); err != nil {
+		log.Warning("updateProjectArtifactRetentionHandler> Cannot update retention of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, retention, http.StatusOK)
+}
+
+func getApplicationArtifactRetentionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("getApplicationArtifactRetentionHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("getApplicationArtifactRetentionHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	retention, err := artifact.LoadApplicationRetention(db, proj.ID, app.ID)
+	if err != nil {
+		log.Warning("getApplicationArtifactRetentionHandler> Cannot load retention of application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, retention, http.StatusOK)
+}
+
+func updateApplicationArtifactRetentionHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("updateApplicationArtifactRetentionHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("updateApplicationArtifactRetentionHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	retention, err := readArtifactRetention(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := artifact.UpdateApplicationRetention(db, proj.ID, app.ID, retention); err != nil {
+		log.Warning("updateApplicationArtifactRetentionHandler> Cannot update retention of application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, retention, http.StatusOK)
+}
+
+// getExpiredArtifactsHandler returns artifacts which will be deleted by next purge
+func getExpiredArtifactsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	arts, err := artifact.LoadExpiredArtifacts(db)
+	if err != nil {
+		log.Warning("getExpiredArtifactsHandler> Cannot load expired artifacts: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, arts, http.StatusOK)
+}
+
+func readArtifactRetention(r *http.Request) (sdk.ArtifactRetention, error) {
+	var retention sdk.ArtifactRetention
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return retention, err
+	}
+	err = json.Unmarshal(data, &retention)
+	return retention, err
+}
@@ -19,7 +19,7 @@ import (
 	"github.com/ovh/cds/sdk"
 )
 
-//Driver is an interface to all auth method (local, ldap and beyond...)
+//Driver is an interface to all auth method (local, ldap, oidc and beyond...)
 type Driver interface {
 	Open(options interface{}, store sessionstore.Store) error
 	Store() sessionstore.Store
@@ -40,6 +40,8 @@ func GetDriver(mode string, options interface{}, storeOptions sessionstore.Optio
 	switch mode {
 	case "ldap":
 		d = &LDAPClient{}
+	case "oidc":
+		d = &OIDCClient{}
 	default:
 		d = &LocalClient{}
 	}
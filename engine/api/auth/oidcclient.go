@@ -0,0 +1,521 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/sessionstore"
+	"github.com/ovh/cds/engine/api/user"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+//OIDCConfig handles all config to connect to the OpenID Connect issuer
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	GroupsClaim  string
+}
+
+//OIDCClient authentifies users against an OpenID Connect issuer
+type OIDCClient struct {
+	store    sessionstore.Store
+	conf     OIDCConfig
+	provider oidcProvider
+	local    *LocalClient
+	client   *http.Client
+
+	mutex sync.Mutex
+	keys  map[string]*rsa.PublicKey
+}
+
+//OIDCClaims are the ID token claims used by CDS
+type OIDCClaims struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Fullname string
+	Email    string
+	Groups   []string
+}
+
+var (
+	//ErrOIDCAuthorizationPending is returned while the user has not completed a device login
+	ErrOIDCAuthorizationPending = errors.New("oidc: authorization pending")
+)
+
+const oidcScopes = "openid profile email"
+
+//oidcProvider is the issuer discovery document
+type oidcProvider struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	JWKSURI                     string `json:"jwks_uri"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type oidcJWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+//Open discovers the issuer endpoints
+func (c *OIDCClient) Open(options interface{}, store sessionstore.Store) error {
+	log.Notice("Auth> Connecting to session store")
+	c.store = store
+	//OIDC Client needs a local client to check local users
+	c.local = &LocalClient{}
+	c.local.Open(options, store)
+
+	conf, ok := options.(OIDCConfig)
+	if !ok {
+		return sdk.ErrOIDCConn
+	}
+	c.conf = conf
+	if c.conf.GroupsClaim == "" {
+		c.conf.GroupsClaim = "groups"
+	}
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	log.Notice("Auth> Discovering OpenID Connect issuer %s", c.conf.Issuer)
+	resp, err := c.client.Get(strings.TrimSuffix(c.conf.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		log.Critical("Auth> Cannot reach issuer %s: %s", c.conf.Issuer, err)
+		return sdk.ErrOIDCConn
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Critical("Auth> Cannot discover issuer %s: HTTP %d", c.conf.Issuer, resp.StatusCode)
+		return sdk.ErrOIDCConn
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&c.provider); err != nil {
+		log.Critical("Auth> Invalid discovery document for %s: %s", c.conf.Issuer, err)
+		return sdk.ErrOIDCConn
+	}
+	if c.provider.Issuer != c.conf.Issuer {
+		log.Critical("Auth> Issuer mismatch: %s announces %s", c.conf.Issuer, c.provider.Issuer)
+		return sdk.ErrOIDCConn
+	}
+	return nil
+}
+
+//Store returns store
+func (c *OIDCClient) Store() sessionstore.Store {
+	return c.store
+}
+
+//Authentify check username and password of local users, others log in through the issuer
+func (c *OIDCClient) Authentify(username, password string) (bool, error) {
+	return c.local.Authentify(username, password)
+}
+
+//AuthentifyUser check password in database
+func (c *OIDCClient) AuthentifyUser(u *sdk.User, password string) (bool, error) {
+	return c.local.AuthentifyUser(u, password)
+}
+
+//GetCheckAuthHeaderFunc returns the func to heck http headers.
+//Users log in through the issuer, then are authentified by session like in LDAP mode
+func (c *OIDCClient) GetCheckAuthHeaderFunc(options interface{}) func(db *sql.DB, headers http.Header, ctx *context.Context) error {
+	return func(db *sql.DB, headers http.Header, ctx *context.Context) error {
+		//Check if its a worker
+		if h := headers.Get(sdk.AuthHeader); h != "" {
+			return checkWorkerAuth(db, h, ctx)
+		}
+		//Check if its comming from CLI
+		if CheckPersistentSession(db, c.Store(), headers, ctx) {
+			return nil
+		}
+		return c.local.checkUserSessionAuth(db, headers, ctx)
+	}
+}
+
+//NewPKCE returns a random PKCE code verifier, also suitable as state or nonce
+func NewPKCE() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+//AuthorizeURL returns the issuer URL to redirect the user to for an authorization code login
+func (c *OIDCClient) AuthorizeURL(state, nonce, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.conf.ClientID)
+	v.Set("redirect_uri", c.conf.RedirectURL)
+	v.Set("scope", oidcScopes)
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", pkceChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(c.provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return c.provider.AuthorizationEndpoint + sep + v.Encode()
+}
+
+//Exchange trades an authorization code for the claims of the user ID token
+func (c *OIDCClient) Exchange(code, verifier, nonce string) (*OIDCClaims, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", c.conf.RedirectURL)
+	v.Set("code_verifier", verifier)
+
+	res, err := c.token(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.verifyIDToken(res.IDToken, nonce)
+}
+
+//DeviceAuthorization starts a device login for headless terminals
+func (c *OIDCClient) DeviceAuthorization() (*sdk.OIDCDeviceAuthorization, error) {
+	if c.provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("oidc: issuer does not support device login")
+	}
+
+	v := url.Values{}
+	v.Set("client_id", c.conf.ClientID)
+	v.Set("client_secret", c.conf.ClientSecret)
+	v.Set("scope", oidcScopes)
+	data, code, err := c.post(c.provider.DeviceAuthorizationEndpoint, v)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("oidc: device authorization failed: HTTP %d: %s", code, data)
+	}
+
+	var d sdk.OIDCDeviceAuthorization
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+//DeviceToken returns the claims of the user who completed the device login,
+//ErrOIDCAuthorizationPending if the user did not yet
+func (c *OIDCClient) DeviceToken(deviceCode string) (*OIDCClaims, error) {
+	v := url.Values{}
+	v.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+	v.Set("device_code", deviceCode)
+
+	res, err := c.token(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.verifyIDToken(res.IDToken, "")
+}
+
+func (c *OIDCClient) token(v url.Values) (*oidcTokenResponse, error) {
+	v.Set("client_id", c.conf.ClientID)
+	v.Set("client_secret", c.conf.ClientSecret)
+
+	data, code, err := c.post(c.provider.TokenEndpoint, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var res oidcTokenResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("oidc: invalid token response: HTTP %d", code)
+	}
+	switch res.Error {
+	case "":
+	case "authorization_pending", "slow_down":
+		return nil, ErrOIDCAuthorizationPending
+	default:
+		return nil, fmt.Errorf("oidc: %s: %s", res.Error, res.ErrorDescription)
+	}
+	if code != http.StatusOK || res.IDToken == "" {
+		return nil, fmt.Errorf("oidc: no id token: HTTP %d", code)
+	}
+	return &res, nil
+}
+
+func (c *OIDCClient) post(endpoint string, v url.Values) ([]byte, int, error) {
+	resp, err := c.client.PostForm(endpoint, v)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return data, resp.StatusCode, err
+}
+
+//verifyIDToken checks RS256 signature, issuer, audience, expiry and nonce of an ID token
+func (c *OIDCClient) verifyIDToken(raw, nonce string) (*OIDCClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("oidc: malformed id token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("oidc: unsupported id token algorithm %s", header.Alg)
+	}
+
+	key, err := c.publicKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed id token signature")
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return nil, fmt.Errorf("oidc: invalid id token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if stringClaim(claims, "iss") != c.provider.Issuer {
+		return nil, fmt.Errorf("oidc: id token issued by %s", stringClaim(claims, "iss"))
+	}
+	if !hasAudience(claims["aud"], c.conf.ClientID) {
+		return nil, fmt.Errorf("oidc: id token not issued for %s", c.conf.ClientID)
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Unix() >= int64(exp) {
+		return nil, fmt.Errorf("oidc: id token expired")
+	}
+	if stringClaim(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("oidc: invalid id token nonce")
+	}
+
+	cl := &OIDCClaims{
+		Issuer:   stringClaim(claims, "iss"),
+		Subject:  stringClaim(claims, "sub"),
+		Username: stringClaim(claims, "preferred_username"),
+		Fullname: stringClaim(claims, "name"),
+		Email:    stringClaim(claims, "email"),
+	}
+	if cl.Username == "" {
+		cl.Username = cl.Subject
+	}
+	if groups, ok := claims[c.conf.GroupsClaim].([]interface{}); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				cl.Groups = append(cl.Groups, s)
+			}
+		}
+	}
+	return cl, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("oidc: malformed id token")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("oidc: malformed id token")
+	}
+	return nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, s := range a {
+			if s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//publicKey returns the issuer key with given id, refreshing keys when it is unknown
+func (c *OIDCClient) publicKey(kid string) (*rsa.PublicKey, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if k, ok := c.keys[kid]; ok {
+		return k, nil
+	}
+
+	resp, err := c.client.Get(c.provider.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var jwks struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("oidc: invalid issuer keys: %s", err)
+	}
+
+	c.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			log.Warning("Auth> Invalid issuer key %s", k.Kid)
+			continue
+		}
+		c.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	k, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown issuer key %s", kid)
+	}
+	return k, nil
+}
+
+//ExternalID identifies the user on the issuer. Unlike the username, it cannot be changed by the user.
+func (cl *OIDCClaims) ExternalID() string {
+	return cl.Issuer + "#" + cl.Subject
+}
+
+//ProvisionUser creates or refreshes the CDS user bound to the issuer identity in given claims
+//and adds it to the existing CDS groups named in the groups claim. Memberships granted by a previous
+//groups claim are revoked once the group is no longer in the claim.
+func (c *OIDCClient) ProvisionUser(db *sql.DB, cl *OIDCClaims) (*sdk.User, error) {
+	if cl.Subject == "" {
+		return nil, fmt.Errorf("oidc: id token without subject")
+	}
+
+	u, err := user.LoadUserAndAuthByExternalID(db, cl.ExternalID())
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err == sql.ErrNoRows {
+		//Never hand an existing account over to an issuer identity, local or bound to another one
+		if _, err := user.LoadUserAndAuth(db, cl.Username); err == nil {
+			log.Warning("OIDC> User %s already exists, cannot bind it to %s", cl.Username, cl.ExternalID())
+			return nil, sdk.ErrUserConflict
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		u = &sdk.User{
+			Admin:    false,
+			Username: cl.Username,
+			Fullname: cl.Fullname,
+			Email:    cl.Email,
+			Origin:   "oidc",
+		}
+		a := &sdk.Auth{
+			EmailVerified: true,
+		}
+		if err := user.InsertUser(db, u, a); err != nil {
+			log.Critical("OIDC> Error inserting user %s: %s", u.Username, err)
+			return nil, err
+		}
+		if err := user.UpdateExternalID(db, u.ID, cl.ExternalID()); err != nil {
+			log.Critical("OIDC> Error binding user %s to %s: %s", u.Username, cl.ExternalID(), err)
+			return nil, err
+		}
+		u.Auth = *a
+	} else {
+		u.Fullname = cl.Fullname
+		u.Email = cl.Email
+		if err := user.UpdateUser(db, *u); err != nil {
+			log.Critical("OIDC> Unable to update user %s : %s", u.Username, err)
+			return nil, err
+		}
+	}
+
+	claimed := make(map[string]bool, len(cl.Groups))
+	for _, name := range cl.Groups {
+		claimed[name] = true
+		g, err := group.LoadGroup(db, name)
+		if err != nil {
+			log.Debug("OIDC> Ignoring group %s of %s: %s", name, u.Username, err)
+			continue
+		}
+		in, err := group.CheckUserInGroup(db, g.ID, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		if in {
+			continue
+		}
+		if err := group.InsertOIDCUserInGroup(db, g.ID, u.ID); err != nil {
+			log.Warning("OIDC> Cannot add %s in group %s: %s", u.Username, name, err)
+			return nil, err
+		}
+	}
+
+	//Revoke memberships granted by a previous groups claim, memberships granted in CDS are left untouched
+	granted, err := group.LoadOIDCGroupByUser(db, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range granted {
+		if claimed[g.Name] {
+			continue
+		}
+		if err := group.DeleteUserFromGroup(db, g.ID, u.ID); err != nil {
+			log.Warning("OIDC> Cannot remove %s from group %s: %s", u.Username, g.Name, err)
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/sdk"
+)
+
+//mockIssuer is a minimal OpenID Connect issuer
+type mockIssuer struct {
+	*httptest.Server
+	t         *testing.T
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    map[string]interface{}
+	pending   int
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcProvider{
+			Issuer:                      m.URL,
+			AuthorizationEndpoint:       m.URL + "/authorize",
+			TokenEndpoint:               m.URL + "/token",
+			JWKSURI:                     m.URL + "/keys",
+			DeviceAuthorizationEndpoint: m.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]oidcJWK{"keys": {{
+			Kid: "k1",
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"device_code":"dc","user_code":"ABCD-EFGH","verification_uri":"` + m.URL + `/activate","expires_in":600,"interval":1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "cds", r.FormValue("client_id"))
+		assert.Equal(t, "secret", r.FormValue("client_secret"))
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			assert.Equal(t, "code", r.FormValue("code"))
+			if pkceChallenge(r.FormValue("code_verifier")) != m.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"bad code verifier"}`))
+				return
+			}
+			m.writeIDToken(w, m.nonce)
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			assert.Equal(t, "dc", r.FormValue("device_code"))
+			if m.pending > 0 {
+				m.pending--
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			m.writeIDToken(w, "")
+		}
+	})
+	m.Server = httptest.NewServer(mux)
+
+	m.claims = map[string]interface{}{
+		"sub":                "42",
+		"aud":                []string{"cds"},
+		"preferred_username": "john",
+		"name":               "John Doe",
+		"email":              "john@example.com",
+		"groups":             []string{"dev", "ops"},
+	}
+	return m
+}
+
+func (m *mockIssuer) writeIDToken(w http.ResponseWriter, nonce string) {
+	claims := map[string]interface{}{
+		"iss": m.URL,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(claims)})
+}
+
+func (m *mockIssuer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	s := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(s))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, sum[:])
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return s + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func openMockIssuer(t *testing.T, m *mockIssuer) *OIDCClient {
+	c := &OIDCClient{}
+	err := c.Open(OIDCConfig{
+		Issuer:       m.URL,
+		ClientID:     "cds",
+		ClientSecret: "secret",
+		RedirectURL:  "http://cds/auth/oidc/callback",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestOIDCAuthorizationCode(t *testing.T) {
+	m := newMockIssuer(t)
+	defer m.Close()
+	c := openMockIssuer(t, m)
+
+	verifier, err := NewPKCE()
+	assert.NoError(t, err)
+	u, err := url.Parse(c.AuthorizeURL("state", "nonce", verifier))
+	assert.NoError(t, err)
+	assert.Equal(t, m.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	q := u.Query()
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "state", q.Get("state"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+
+	claims, err := c.Exchange("code", verifier, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &OIDCClaims{
+		Issuer:   m.URL,
+		Subject:  "42",
+		Username: "john",
+		Fullname: "John Doe",
+		Email:    "john@example.com",
+		Groups:   []string{"dev", "ops"},
+	}, claims)
+
+	// Wrong PKCE verifier
+	_, err = c.Exchange("code", "other", "nonce")
+	assert.Error(t, err)
+
+	// Replayed ID token for another login
+	_, err = c.Exchange("code", verifier, "other")
+	assert.Error(t, err)
+}
+
+func TestOIDCDeviceCode(t *testing.T) {
+	m := newMockIssuer(t)
+	defer m.Close()
+	c := openMockIssuer(t, m)
+	c.conf.GroupsClaim = "roles"
+	m.claims["roles"] = []string{"admin"}
+	m.pending = 1
+
+	d, err := c.DeviceAuthorization()
+	assert.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", d.UserCode)
+
+	_, err = c.DeviceToken(d.DeviceCode)
+	assert.Equal(t, ErrOIDCAuthorizationPending, err)
+
+	claims, err := c.DeviceToken(d.DeviceCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "john", claims.Username)
+	assert.Equal(t, []string{"admin"}, claims.Groups)
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	m := newMockIssuer(t)
+	defer m.Close()
+	c := openMockIssuer(t, m)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"iss": m.URL, "aud": "cds", "sub": "42", "exp": time.Now().Add(time.Hour).Unix()}
+	}
+
+	claims, err := c.verifyIDToken(m.sign(valid()), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", claims.Username)
+	assert.Equal(t, m.URL+"#42", claims.ExternalID())
+
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = c.verifyIDToken(m.sign(expired), "")
+	assert.Error(t, err)
+
+	otherAudience := valid()
+	otherAudience["aud"] = "other"
+	_, err = c.verifyIDToken(m.sign(otherAudience), "")
+	assert.Error(t, err)
+
+	otherIssuer := valid()
+	otherIssuer["iss"] = "http://evil"
+	_, err = c.verifyIDToken(m.sign(otherIssuer), "")
+	assert.Error(t, err)
+
+	// Tampered payload
+	parts := strings.Split(m.sign(valid()), ".")
+	forged := valid()
+	forged["sub"] = "1"
+	payload, _ := json.Marshal(forged)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	_, err = c.verifyIDToken(strings.Join(parts, "."), "")
+	assert.Error(t, err)
+}
+
+func TestOIDCProvisionUserGroups(t *testing.T) {
+	db := test.Setup("TestOIDCProvisionUserGroups", t)
+	c := &OIDCClient{}
+
+	ids := map[string]int64{}
+	for _, name := range []string{"dev", "ops", "qa"} {
+		g := &sdk.Group{Name: name}
+		if err := group.InsertGroup(db, g); err != nil {
+			t.Fatalf("cannot insert group %s: %s", name, err)
+		}
+		ids[name] = g.ID
+	}
+
+	names := func(userID int64) []string {
+		groups, err := group.LoadGroupByUser(db, userID)
+		if err != nil {
+			t.Fatalf("cannot load groups: %s", err)
+		}
+		var res []string
+		for _, g := range groups {
+			res = append(res, g.Name)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	cl := &OIDCClaims{Issuer: "https://idp", Subject: "42", Username: "john", Groups: []string{"dev", "ops", "unknown"}}
+	u, err := c.ProvisionUser(db, cl)
+	if err != nil {
+		t.Fatalf("cannot provision user: %s", err)
+	}
+	assert.Equal(t, []string{"dev", "ops"}, names(u.ID))
+
+	// Membership granted in CDS
+	if err := group.InsertUserInGroup(db, ids["qa"], u.ID, false); err != nil {
+		t.Fatalf("cannot insert user in group: %s", err)
+	}
+
+	// Removed from ops on the issuer
+	cl.Groups = []string{"dev", "qa"}
+	_, err = c.ProvisionUser(db, cl)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev", "qa"}, names(u.ID))
+
+	// Removed from every group on the issuer, qa was not granted by the claim
+	cl.Groups = nil
+	_, err = c.ProvisionUser(db, cl)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"qa"}, names(u.ID))
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/user"
+	"github.com/ovh/cds/engine/log"
+)
+
+//tokenLastUsedPrecision avoids writing the last use of a token on every request
+const tokenLastUsedPrecision = time.Minute
+
+//CheckTokenAuth authentifies a request with an API token, whatever the auth driver
+func CheckTokenAuth(db *sql.DB, token string, ctx *context.Context) error {
+	t, userID, err := user.LoadTokenByHash(db, user.HashToken(token))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unknown token")
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if now.After(t.Expiry) {
+		return fmt.Errorf("token %s expired", t.Name)
+	}
+
+	u, err := user.LoadUserWithoutAuthByID(db, userID)
+	if err != nil {
+		return fmt.Errorf("cannot load token owner %d: %s", userID, err)
+	}
+	if err := user.LoadUserPermissions(db, u); err != nil {
+		return fmt.Errorf("cannot load user %d permissions: %s", userID, err)
+	}
+
+	if t.LastUsed == nil || now.Sub(*t.LastUsed) > tokenLastUsedPrecision {
+		if err := user.UpdateTokenLastUsed(db, t.ID, now); err != nil {
+			log.Warning("CheckTokenAuth> Cannot update last use of token %d: %s", t.ID, err)
+		}
+		t.LastUsed = &now
+	}
+
+	ctx.User = u
+	ctx.Token = t
+	return nil
+}
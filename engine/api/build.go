@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -114,7 +115,7 @@ func deleteBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("deleteBuildHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -221,7 +222,7 @@ func getBuildStateHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getBuildStateHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -316,6 +317,17 @@ func getBuildStateHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 			}
 		}
 
+		// load approvals recorded on stages waiting for approval
+		approvals, err := build.LoadApprovals(db, pb.ID)
+		if err != nil {
+			log.Warning("getBuildStateHandler> Cannot load pipeline build approvals: %s\n", err)
+			WriteError(w, r, err)
+			return
+		}
+		if len(approvals) > 0 {
+			result.Approvals = approvals
+		}
+
 		result.Stages = stages
 		result.Status = pb.Status
 		result.Version = pb.Version
@@ -392,9 +404,18 @@ func addQueueResultHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		// We want to update ActionBuild status anyway
 	}
 
+	if err := build.UpdateActionBuildSteps(tx, b.ID, res.Steps); err != nil {
+		log.Warning("addQueueResultHandler> Cannot store steps of %s: %s\n", id, err)
+		// We want to update ActionBuild status anyway
+	}
+
 	// Update action status
 	log.Debug("Updating %s to %s in queue\n", id, res.Status)
-	err = build.UpdateActionBuildStatus(tx, &b, res.Status)
+	if res.Status == sdk.StatusFail {
+		err = build.FailActionBuild(tx, &b, sdk.FailureResult, res.ExitCode)
+	} else {
+		err = build.UpdateActionBuildStatus(tx, &b, res.Status)
+	}
 	if err != nil {
 		log.Warning("addQueueResultHandler> Cannot update %s status: %s\n", id, err)
 		WriteError(w, r, err)
@@ -410,6 +431,39 @@ func addQueueResultHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 
 }
 
+// getActionBuildStatusHandler lets building worker know if its action build has been stopped
+func getActionBuildStatusHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	id := mux.Vars(r)["id"]
+
+	actionBuildID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	// Only the worker building the action build is allowed to follow it
+	caller, err := worker.LoadWorker(db, c.WorkerID)
+	if err != nil {
+		log.Warning("getActionBuildStatusHandler> cannot load calling worker: %s\n", err)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+	if caller.ActionBuildID != actionBuildID {
+		log.Warning("getActionBuildStatusHandler> worker %s is not building action build %s\n", caller.ID, id)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	b, err := build.LoadActionBuild(db, id)
+	if err != nil {
+		log.Warning("getActionBuildStatusHandler> Cannot load action build %s: %s\n", id, err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	WriteJSON(w, r, sdk.ActionBuildStatus{Status: b.Status}, http.StatusOK)
+}
+
 func takeActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 
 	// Get action name in URL
@@ -431,6 +485,11 @@ func takeActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 
 	// update database
 	ab, err := build.TakeActionBuild(db, id, caller)
+	if err == build.ErrNotBooked {
+		log.Info("takeActionBuildHandler> ActionBuild %s is not booked for worker %s\n", id, caller.Name)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
 	if err != nil {
 		if err != build.ErrAlreadyTaken {
 			log.Warning("takeActionBuildHandler> Cannot give ActionBuild %s: %s\n", id, err)
@@ -571,6 +630,107 @@ func getQueueHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *cont
 	WriteJSON(w, r, queue, http.StatusOK)
 }
 
+// nextActionBuildHandler waits until an action build matching calling worker capabilities is leased to it
+func nextActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if c.WorkerID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	caller, err := worker.LoadWorker(db, c.WorkerID)
+	if err != nil {
+		log.Warning("nextActionBuildHandler> cannot load calling worker: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if caller.Status != sdk.StatusWaiting {
+		log.Debug("nextActionBuildHandler> worker %s is not available to build (status = %s)\n", caller.ID, caller.Status)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warning("nextActionBuildHandler> Cannot read body: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var caps sdk.WorkerCapabilities
+	if err := json.Unmarshal(data, &caps); err != nil {
+		log.Warning("nextActionBuildHandler> Cannot unmarshal capabilities: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	timeout := 30 * time.Second
+	if t, err := strconv.Atoi(r.FormValue("timeout")); err == nil && t > 0 && t < 300 {
+		timeout = time.Duration(t) * time.Second
+	}
+
+	models, err := worker.LoadWorkerModels(db)
+	if err != nil {
+		log.Warning("nextActionBuildHandler> Cannot load worker models: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	modelIDs := make(map[string]int64, len(models))
+	var singleUse bool
+	for _, m := range models {
+		modelIDs[m.Name] = m.ID
+		if m.ID == caller.Model {
+			singleUse = m.SingleUse
+		}
+	}
+
+	// A worker bound to an action build, or of a single use model, can only run the action build it has been spawned for
+	bookedID, err := build.LoadBookedActionBuildID(db, caller.ID)
+	if err != nil {
+		log.Warning("nextActionBuildHandler> Cannot load booked action build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	match := func(b sdk.ActionBuild) bool {
+		if (bookedID != 0 || singleUse) && b.ID != bookedID {
+			return false
+		}
+		return permission.AccessToPipeline(sdk.DefaultEnv.ID, b.PipelineID, c.User, sdk.RoleActionRead) &&
+			build.MatchCapabilities(b, caps, caller.Model, modelIDs)
+	}
+
+	ab, err := build.NextActionBuild(db, caller.ID, match, timeout)
+	if err != nil {
+		log.Warning("nextActionBuildHandler> Cannot lease action build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if ab == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	WriteJSON(w, r, ab, http.StatusOK)
+}
+
+// releaseActionBuildHandler gives back an action build leased by calling worker which cannot run it
+func releaseActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	idS := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idS, 10, 64)
+	if err != nil {
+		log.Warning("releaseActionBuildHandler> Cannot convert '%s' into int: %s\n", idS, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := build.ReleaseActionBuild(db, id, c.WorkerID); err != nil {
+		log.Warning("releaseActionBuildHandler> Cannot release action build %d: %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func requirementsErrorHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -615,7 +775,7 @@ func addBuildVariableHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("addBuildVariableHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -703,7 +863,7 @@ func addBuildTestResultsHandler(w http.ResponseWriter, r *http.Request, db *sql.
 
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("addBuildTestResultsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -846,7 +1006,7 @@ func getBuildTestResultsHandler(w http.ResponseWriter, r *http.Request, db *sql.
 
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getBuildTestResultsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
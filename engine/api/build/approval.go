@@ -0,0 +1,47 @@
+package build
+
+import (
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// InsertApproval records the decision of a user on a stage of a pipeline build
+func InsertApproval(db database.QueryExecuter, a *sdk.Approval) error {
+	a.Created = time.Now()
+	query := `INSERT INTO pipeline_build_approval (pipeline_build_id, stage_id, user_id, username, approved, comment, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	return db.QueryRow(query, a.PipelineBuildID, a.StageID, a.UserID, a.Username, a.Approved, a.Comment, a.Created).Scan(&a.ID)
+}
+
+// LoadApprovals retrieves all decisions recorded on a pipeline build, oldest first
+func LoadApprovals(db database.Querier, pbID int64) ([]sdk.Approval, error) {
+	query := `SELECT id, pipeline_build_id, stage_id, user_id, username, approved, comment, created
+		FROM pipeline_build_approval WHERE pipeline_build_id = $1 ORDER BY created, id`
+	rows, err := db.Query(query, pbID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []sdk.Approval{}
+	for rows.Next() {
+		var a sdk.Approval
+		if err := rows.Scan(&a.ID, &a.PipelineBuildID, &a.StageID, &a.UserID, &a.Username, &a.Approved, &a.Comment, &a.Created); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// DeleteApprovals removes decisions recorded on a pipeline build, only rejections if rejectionsOnly is set
+func DeleteApprovals(db database.Executer, pbID int64, rejectionsOnly bool) error {
+	query := `DELETE FROM pipeline_build_approval WHERE pipeline_build_id = $1`
+	if rejectionsOnly {
+		query += ` AND approved = false`
+	}
+	_, err := db.Exec(query, pbID)
+	return err
+}
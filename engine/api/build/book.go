@@ -0,0 +1,94 @@
+package build
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// BookingDuration is how long an action build booked by an hatchery waits for
+// the worker spawned for it to register. Then any hatchery can book it again.
+const BookingDuration = 10 * time.Minute
+
+var (
+	// ErrNotBooked Action build is not booked for the worker
+	ErrNotBooked = fmt.Errorf("cds: action build not booked for this worker")
+)
+
+// availableToWorker is true when the action build is not booked for another worker than $2 at $3.
+// A booking expires if the spawned worker did not register in time or if it has gone.
+const availableToWorker = `(action_build.booked_hatchery_id IS NULL
+	OR action_build.booked_worker_id = $2
+	OR (action_build.booked_worker_id IS NULL AND action_build.booked_until < $3)
+	OR (action_build.booked_worker_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM worker WHERE worker.id = action_build.booked_worker_id)))`
+
+// BookActionBuild reserves for an hatchery the first action build of given queue matching filter,
+// so that the worker it spawns is the only one able to take it
+func BookActionBuild(db *sql.DB, hatcheryID int64, queue []sdk.ActionBuild, match func(sdk.ActionBuild) bool) (*sdk.ActionBuild, error) {
+	for i := range queue {
+		if !match(queue[i]) {
+			continue
+		}
+
+		booked, err := bookActionBuild(db, queue[i].ID, hatcheryID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if booked {
+			return &queue[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func bookActionBuild(db database.Executer, id int64, hatcheryID int64, now time.Time) (bool, error) {
+	query := `UPDATE action_build SET booked_hatchery_id = $4, booked_until = $5, booked_worker_id = NULL
+		WHERE id = $1 AND status = $6 AND ` + availableToWorker
+	res, err := db.Exec(query, id, "", now, hatcheryID, now.Add(BookingDuration), sdk.StatusWaiting.String())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// BindActionBuild binds a registering worker to the action build its hatchery booked for it.
+// A worker registering again after it has been disconnected is bound again.
+// The hatchery has to belong to the user whose key the worker registers with.
+func BindActionBuild(db database.Executer, id int64, hatcheryID int64, userID int64, workerID string) error {
+	query := `UPDATE action_build SET booked_worker_id = $3
+		WHERE id = $1 AND booked_hatchery_id = $2
+		AND EXISTS (SELECT 1 FROM hatchery WHERE hatchery.id = $2 AND hatchery.owner_id = $5)
+		AND ((booked_worker_id IS NULL AND booked_until > $4)
+		OR (booked_worker_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM worker WHERE worker.id = action_build.booked_worker_id)))`
+	res, err := db.Exec(query, id, hatcheryID, workerID, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrNotBooked
+	}
+	return nil
+}
+
+// LoadBookedActionBuildID returns the waiting action build a worker is bound to, 0 if none
+func LoadBookedActionBuildID(db database.Querier, workerID string) (int64, error) {
+	query := `SELECT id FROM action_build WHERE booked_worker_id = $1 AND status = $2`
+	var id int64
+	err := db.QueryRow(query, workerID, sdk.StatusWaiting.String()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/ovh/cds/engine/api/action"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/notification"
@@ -31,6 +33,8 @@ func LoadBuildByPipelineBuildID(db *sql.DB, pipelineBuildID int64) ([]sdk.Action
 			action_build.queued,
 			action_build.start,
 			action_build.done ,
+			action_build.attempt,
+			action_build.steps,
 			pipeline_action.pipeline_stage_id,
 			action.name, action.id
 		   FROM action_build
@@ -50,8 +54,9 @@ func LoadBuildByPipelineBuildID(db *sql.DB, pipelineBuildID int64) ([]sdk.Action
 		var argsJSON string
 		var done interface{}
 		var sStatus string
+		var steps sql.NullString
 		var actionID int64
-		err = rows.Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.Queued, &b.Start, &done, &b.PipelineStageID, &b.ActionName, &actionID)
+		err = rows.Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.Queued, &b.Start, &done, &b.Attempt, &steps, &b.PipelineStageID, &b.ActionName, &actionID)
 		b.Status = sdk.StatusFromString(sStatus)
 		if err != nil {
 			return nil, err
@@ -73,6 +78,12 @@ func LoadBuildByPipelineBuildID(db *sql.DB, pipelineBuildID int64) ([]sdk.Action
 			return nil, err
 		}
 
+		if steps.Valid {
+			if err := json.Unmarshal([]byte(steps.String), &b.Steps); err != nil {
+				return nil, err
+			}
+		}
+
 		builds = append(builds, b)
 	}
 	return builds, nil
@@ -104,9 +115,9 @@ func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Stat
 	var err error
 	log.Debug("UpdateActionBuildStatus> Updating action_build %d to %s\n", build.ID, status)
 
-	query = `SELECT status FROM action_build WHERE id = $1 FOR UPDATE`
+	query = `SELECT status, pipeline_build_id, pipeline_action_id FROM action_build WHERE id = $1 FOR UPDATE`
 	var currentStatus string
-	err = db.QueryRow(query, build.ID).Scan(&currentStatus)
+	err = db.QueryRow(query, build.ID).Scan(&currentStatus, &build.PipelineBuildID, &build.PipelineActionID)
 	if err != nil {
 		return err
 	}
@@ -129,6 +140,15 @@ func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Stat
 			return nil
 		}
 
+		query = `UPDATE action_build SET status = $1, done = $2 WHERE id = $3`
+		_, err = db.Exec(query, status.String(), time.Now(), build.ID)
+
+	case sdk.StatusTimeout:
+		if currentStatus != string(sdk.StatusBuilding) && currentStatus != string(sdk.StatusWaiting) {
+			log.Info("Status is %s, cannot update %d to %s", currentStatus, build.ID, status)
+			return nil
+		}
+
 		query = `UPDATE action_build SET status = $1, done = $2 WHERE id = $3`
 		_, err = db.Exec(query, status.String(), time.Now(), build.ID)
 	default:
@@ -142,11 +162,18 @@ func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Stat
 	build.Status = status
 
 	notification.SendActionBuild(db, build, sdk.UpdateNotifEvent, status)
-
-	if status == sdk.StatusFail || status == sdk.StatusDisabled || status == sdk.StatusSkipped {
+	PublishBuildEvent(sdk.BuildEvent{
+		Type:             sdk.BuildEventActionBuild,
+		PipelineBuildID:  build.PipelineBuildID,
+		ActionBuildID:    build.ID,
+		PipelineActionID: build.PipelineActionID,
+		Status:           status,
+	})
+
+	if status == sdk.StatusFail || status == sdk.StatusTimeout || status == sdk.StatusDisabled || status == sdk.StatusSkipped {
 		var log string
 		switch status {
-		case sdk.StatusFail:
+		case sdk.StatusFail, sdk.StatusTimeout:
 			log = fmt.Sprintf("Action finished with status: %s\n", status)
 		case sdk.StatusDisabled:
 			log = fmt.Sprintf("Action disabled\n")
@@ -159,6 +186,29 @@ func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Stat
 	return nil
 }
 
+// FailActionBuild set an action_build Fail, recording why it failed so a retry policy can decide
+// whether it should be run again. exitCode is the one of the failed script, if any.
+func FailActionBuild(db *sql.Tx, build *sdk.ActionBuild, cause string, exitCode int) error {
+	query := `UPDATE action_build SET fail_cause = $1, exit_code = $2 WHERE id = $3 AND status = $4`
+	if _, err := db.Exec(query, cause, exitCode, build.ID, sdk.StatusBuilding.String()); err != nil {
+		return err
+	}
+	return UpdateActionBuildStatus(db, build, sdk.StatusFail)
+}
+
+// UpdateActionBuildSteps stores results of steps sent by the worker
+func UpdateActionBuildSteps(db database.Executer, id int64, steps []sdk.StepResult) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE action_build SET steps = $1 WHERE id = $2`, string(data), id)
+	return err
+}
+
 // LoadWaitingQueue Load Waiting action_build
 func LoadWaitingQueue(db *sql.DB) ([]sdk.ActionBuild, error) {
 	query := `SELECT action_build.id,
@@ -173,7 +223,7 @@ func LoadWaitingQueue(db *sql.DB) ([]sdk.ActionBuild, error) {
 		  JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 		  JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
 		  JOIN action ON action.id = pipeline_action.action_id
-		  WHERE action_build.status = $1
+		  WHERE action_build.status = $1 AND (action_build.not_before IS NULL OR action_build.not_before <= NOW())
 		  ORDER BY pipeline_build.id,action.name,action_build.pipeline_action_id
 			LIMIT 100`
 	var queue []sdk.ActionBuild
@@ -226,7 +276,7 @@ func LoadUserWaitingQueue(db *sql.DB, u *sdk.User) ([]sdk.ActionBuild, error) {
 		  JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
 			JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 			JOIN group_user ON group_user.group_id = pipeline_group.group_id
-			WHERE action_build.status = $1 AND group_user.user_id = $2
+			WHERE action_build.status = $1 AND (action_build.not_before IS NULL OR action_build.not_before <= NOW()) AND group_user.user_id = $2
 		  ORDER BY pipeline_build.id,action.name,action_build.pipeline_action_id
 			LIMIT 100
 			`
@@ -301,13 +351,17 @@ func TakeActionBuild(db *sql.DB, buildID string, worker *sdk.Worker) (sdk.Action
 			 action_build.args,
 			 action_build.status,
 			 action_build.pipeline_build_id,
-			 pipeline_build.build_number
+			 pipeline_build.build_number,
+			 action_build.lease_worker_id,
+			 action_build.lease_until
 	     FROM action_build
 	     JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 			 WHERE action_build.id = $1 FOR UPDATE`
 
 	var sStatus string
-	err = tx.QueryRow(query, buildID).Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.BuildNumber)
+	var leaseWorkerID sql.NullString
+	var leaseUntil pq.NullTime
+	err = tx.QueryRow(query, buildID).Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.BuildNumber, &leaseWorkerID, &leaseUntil)
 	b.Status = sdk.StatusFromString(sStatus)
 	if err != nil {
 		return b, err
@@ -322,6 +376,41 @@ func TakeActionBuild(db *sql.DB, buildID string, worker *sdk.Worker) (sdk.Action
 		return b, ErrAlreadyTaken
 	}
 
+	// Action build offered to another worker is reserved for it until lease expires
+	if leaseWorkerID.Valid && leaseWorkerID.String != worker.ID && leaseUntil.Valid && leaseUntil.Time.After(time.Now()) {
+		return b, ErrAlreadyTaken
+	}
+
+	// Action build booked for another worker can only be taken by it,
+	// and a worker bound to an action build cannot take another one
+	query = `SELECT COUNT(1) FROM action_build WHERE id = $1 AND ` + availableToWorker
+	var available int
+	if err := tx.QueryRow(query, b.ID, worker.ID, time.Now()).Scan(&available); err != nil {
+		return b, err
+	}
+	if available == 0 {
+		return b, ErrNotBooked
+	}
+	bookedID, err := LoadBookedActionBuildID(tx, worker.ID)
+	if err != nil {
+		return b, err
+	}
+	if bookedID != 0 && bookedID != b.ID {
+		return b, ErrNotBooked
+	}
+
+	// Workers of a single use model only run the action build they have been spawned for
+	if bookedID == 0 && worker.Model != 0 {
+		var singleUse bool
+		query = `SELECT single_use FROM worker_model WHERE id = $1`
+		if err := tx.QueryRow(query, worker.Model).Scan(&singleUse); err != nil && err != sql.ErrNoRows {
+			return b, err
+		}
+		if singleUse {
+			return b, ErrNotBooked
+		}
+	}
+
 	query = ` update action_build set worker_model_name = worker_model.name from worker_model where worker_model.id=$2 and action_build.id = $1`
 	if _, err := tx.Exec(query, b.ID, worker.Model); err != nil {
 		log.Warning("Cannot update model on action_build : %s", err)
@@ -360,6 +449,28 @@ func DeleteActionBuild(db database.QueryExecuter, pipelineActionIDs []int64) err
 	return nil
 }
 
+// DeleteActionBuildsOfAction deletes builds, and their logs, of all pipeline actions using an action.
+// It must be called before action.DeleteAction, which cannot reach the log store.
+func DeleteActionBuildsOfAction(db database.QueryExecuter, actionID int64) error {
+	query := `SELECT id FROM pipeline_action WHERE action_id = $1`
+	rows, err := db.Query(query, actionID)
+	if err != nil {
+		return err
+	}
+	var pipelineActionIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		pipelineActionIDs = append(pipelineActionIDs, id)
+	}
+	rows.Close()
+
+	return DeleteActionBuild(db, pipelineActionIDs)
+}
+
 func selectAllActionBuildIDsByPipelineAction(db database.QueryExecuter, pipelineActionID int64) ([]int64, error) {
 	var actionBuildIDs []int64
 	query := `SELECT id FROM "action_build"
@@ -381,17 +492,43 @@ func selectAllActionBuildIDsByPipelineAction(db database.QueryExecuter, pipeline
 	return actionBuildIDs, nil
 }
 
+func selectAllActionBuildIDsByPipelineBuild(db database.QueryExecuter, pipelineBuildID int64) ([]int64, error) {
+	var actionBuildIDs []int64
+	query := `SELECT id FROM "action_build"
+	 		  WHERE pipeline_build_id = $1`
+	rows, err := db.Query(query, pipelineBuildID)
+	if err != nil {
+		return actionBuildIDs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var abID int64
+		err = rows.Scan(&abID)
+		if err != nil {
+			return actionBuildIDs, err
+		}
+		actionBuildIDs = append(actionBuildIDs, abID)
+	}
+	return actionBuildIDs, nil
+}
+
 // DeleteBuild Delete a build
 func DeleteBuild(db database.QueryExecuter, buildID int64) error {
 
-	queryDeleteBuildLog := `DELETE FROM build_log WHERE action_build_id IN
-		(SELECT id from action_build WHERE pipeline_build_id = $1)`
-	_, err := db.Exec(queryDeleteBuildLog, buildID)
+	actionBuildIDs, err := selectAllActionBuildIDsByPipelineBuild(db, buildID)
 	if err != nil {
-		log.Warning("DeleteBuild> Cannot delete build log: %s\n", err)
+		log.Warning("DeleteBuild> Cannot select action builds: %s\n", err)
 		return err
 	}
 
+	for _, abID := range actionBuildIDs {
+		if err := DeleteBuildLogs(db, abID); err != nil {
+			log.Warning("DeleteBuild> Cannot delete build log: %s\n", err)
+			return err
+		}
+	}
+
 	// if we are deleting a building pipeline, delete workers as well
 	query := `UPDATE worker SET status = $1, action_build_id = NULL WHERE action_build_id IN
 		(SELECT id FROM action_build WHERE pipeline_build_id = $2)`
@@ -414,6 +551,12 @@ func DeleteBuild(db database.QueryExecuter, buildID int64) error {
 		return err
 	}
 
+	// delete approvals
+	if err := DeleteApprovals(db, buildID, false); err != nil {
+		log.Warning("DeleteBuild> Cannot delete approvals: %s", err)
+		return err
+	}
+
 	// delete pipeline build
 	queryDeletePipelineBuild := `DELETE FROM pipeline_build WHERE id=$1`
 	_, err = db.Exec(queryDeletePipelineBuild, buildID)
@@ -10,45 +10,23 @@ import (
 	"github.com/ovh/cds/sdk"
 )
 
-// InsertLog insert build log into database
-func InsertLog(db database.Executer, actionBuildID int64, step string, value string) error {
-	query := `INSERT INTO build_log (action_build_id, timestamp, step, value) VALUES ($1, $2, $3, $4)`
-
-	_, err := db.Exec(query, actionBuildID, time.Now(), step, value)
-	return err
+// InsertLog insert build log with the configured log store
+func InsertLog(db database.QueryExecuter, actionBuildID int64, step string, value string) error {
+	return InsertBuildLog(db, sdk.NewLog(actionBuildID, step, value))
 }
 
-// LoadLogs retrieves build logs from databse given an offset and a size
-func LoadLogs(db *sql.DB, actionBuildID int64, tail int64, start int64) ([]sdk.Log, error) {
-	query := `SELECT * FROM build_log WHERE action_build_id = $1`
-	var logs []sdk.Log
-
-	if start > 0 {
-		query = fmt.Sprintf("%s AND id > %d", query, start)
-	}
+// InsertBuildLog insert build log with the configured log store, setting its ID and timestamp
+func InsertBuildLog(db database.QueryExecuter, l *sdk.Log) error {
+	l.Timestamp = time.Now()
+	return logStore.Insert(db, l)
+}
 
-	query = fmt.Sprintf("%s ORDER BY id", query)
+// LoadLogs retrieves build logs from the configured log store given an offset and a size
+func LoadLogs(db database.Querier, actionBuildID int64, tail int64, start int64) ([]sdk.Log, error) {
 	if tail == 0 {
 		tail = 5000
 	}
-	query = fmt.Sprintf("%s LIMIT %d", query, tail)
-
-	rows, err := db.Query(query, actionBuildID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var l sdk.Log
-		err = rows.Scan(&l.ID, &l.ActionBuildID, &l.Timestamp, &l.Step, &l.Value)
-		if err != nil {
-			return nil, err
-		}
-		logs = append(logs, l)
-	}
-
-	return logs, nil
+	return logStore.Load(db, actionBuildID, tail, start)
 }
 
 // LoadPipelineActionBuildLogs Load log for the given pipeline action
@@ -93,11 +71,9 @@ func LoadPipelineActionBuildLogs(db *sql.DB, pipelineBuildID, pipelineActionID i
 	return buildLogResult, nil
 }
 
-// DeleteBuildLogs delete build log
-func DeleteBuildLogs(db database.Executer, actionBuildID int64) error {
-	query := `DELETE FROM build_log WHERE action_build_id = $1`
-	_, err := db.Exec(query, actionBuildID)
-	return err
+// DeleteBuildLogs delete build log from the configured log store
+func DeleteBuildLogs(db database.QueryExecuter, actionBuildID int64) error {
+	return logStore.Delete(db, actionBuildID)
 }
 
 // LoadPipelineBuildLogs Load pipeline build logs by pipeline ID
@@ -134,3 +110,46 @@ func LoadPipelineBuildLogs(db *sql.DB, pipelineBuildID int64, offset int64) ([]s
 
 	return pipelinelogs, nil
 }
+
+// sqlLogStore stores every log line as a row of build_log table
+type sqlLogStore struct{}
+
+func (s *sqlLogStore) Insert(db database.QueryExecuter, l *sdk.Log) error {
+	query := `INSERT INTO build_log (action_build_id, timestamp, step, value) VALUES ($1, $2, $3, $4) RETURNING id`
+
+	return db.QueryRow(query, l.ActionBuildID, l.Timestamp, l.Step, l.Value).Scan(&l.ID)
+}
+
+func (s *sqlLogStore) Load(db database.Querier, actionBuildID int64, tail int64, start int64) ([]sdk.Log, error) {
+	query := `SELECT * FROM build_log WHERE action_build_id = $1`
+	var logs []sdk.Log
+
+	if start > 0 {
+		query = fmt.Sprintf("%s AND id > %d", query, start)
+	}
+
+	query = fmt.Sprintf("%s ORDER BY id LIMIT %d", query, tail)
+
+	rows, err := db.Query(query, actionBuildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l sdk.Log
+		err = rows.Scan(&l.ID, &l.ActionBuildID, &l.Timestamp, &l.Step, &l.Value)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+
+	return logs, nil
+}
+
+func (s *sqlLogStore) Delete(db database.QueryExecuter, actionBuildID int64) error {
+	query := `DELETE FROM build_log WHERE action_build_id = $1`
+	_, err := db.Exec(query, actionBuildID)
+	return err
+}
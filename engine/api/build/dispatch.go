@@ -0,0 +1,210 @@
+package build
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// LeaseDuration is how long an action build offered to a worker is reserved for it.
+// The worker has to take it or release it before the lease expires.
+const LeaseDuration = 30 * time.Second
+
+// refusalDuration is how long an action build released by a worker is not offered to it again
+const refusalDuration = 5 * time.Minute
+
+// dispatcher keeps the waiting queue in memory, so workers waiting for an action build do not query database
+type dispatcher struct {
+	sync.Mutex
+	queue []sdk.ActionBuild
+	// changed is closed and replaced each time queue is refreshed
+	changed chan struct{}
+	// leased lists action builds leased by this API, until their lease expires
+	leased map[int64]time.Time
+	// refused lists workers which released an action build, until they can be offered it again
+	refused map[int64]map[string]time.Time
+}
+
+var queueDispatcher = &dispatcher{
+	changed: make(chan struct{}),
+	leased:  map[int64]time.Time{},
+	refused: map[int64]map[string]time.Time{},
+}
+
+// DispatchRoutine refreshes the waiting queue every interval and wakes up workers waiting for an action build
+func DispatchRoutine(interval time.Duration) {
+	// If this goroutine exits, then it's a crash
+	defer log.Fatalf("Goroutine of build.DispatchRoutine exited - Exit CDS Engine")
+
+	for {
+		time.Sleep(interval)
+		db := database.DB()
+		if db == nil {
+			continue
+		}
+
+		queue, err := LoadWaitingQueue(db)
+		if err != nil {
+			log.Warning("DispatchRoutine> Cannot load waiting queue: %s\n", err)
+			continue
+		}
+
+		queueDispatcher.refresh(queue, time.Now())
+	}
+}
+
+func (d *dispatcher) refresh(queue []sdk.ActionBuild, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	waiting := map[int64]bool{}
+	for _, b := range queue {
+		waiting[b.ID] = true
+	}
+	for id, until := range d.leased {
+		if until.Before(now) || !waiting[id] {
+			delete(d.leased, id)
+		}
+	}
+	for id, workers := range d.refused {
+		for w, until := range workers {
+			if until.Before(now) {
+				delete(workers, w)
+			}
+		}
+		if len(workers) == 0 || !waiting[id] {
+			delete(d.refused, id)
+		}
+	}
+
+	d.queue = queue
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// candidates returns action builds of the queue not refused by worker and matching filter
+func (d *dispatcher) candidates(workerID string, match func(sdk.ActionBuild) bool, now time.Time) ([]sdk.ActionBuild, chan struct{}) {
+	d.Lock()
+	defer d.Unlock()
+
+	var candidates []sdk.ActionBuild
+	for _, b := range d.queue {
+		if until, leased := d.leased[b.ID]; leased && until.After(now) {
+			continue
+		}
+		if until, refused := d.refused[b.ID][workerID]; refused && until.After(now) {
+			continue
+		}
+		if match(b) {
+			candidates = append(candidates, b)
+		}
+	}
+	return candidates, d.changed
+}
+
+func (d *dispatcher) lease(id int64, until time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	d.leased[id] = until
+}
+
+func (d *dispatcher) refuse(id int64, workerID string, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	delete(d.leased, id)
+
+	if d.refused[id] == nil {
+		d.refused[id] = map[string]time.Time{}
+	}
+	d.refused[id][workerID] = now.Add(refusalDuration)
+}
+
+// NextActionBuild waits until a waiting action build matching filter can be leased to worker.
+// It returns nil if none has been found before timeout.
+func NextActionBuild(db *sql.DB, workerID string, match func(sdk.ActionBuild) bool, timeout time.Duration) (*sdk.ActionBuild, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		candidates, changed := queueDispatcher.candidates(workerID, match, time.Now())
+		for i := range candidates {
+			now := time.Now()
+			leased, err := leaseActionBuild(db, candidates[i].ID, workerID, now)
+			if err != nil {
+				return nil, err
+			}
+			// Leased by this worker or another one, it should not be offered until lease expires
+			queueDispatcher.lease(candidates[i].ID, now.Add(LeaseDuration))
+			if leased {
+				return &candidates[i], nil
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// leaseActionBuild reserves a waiting action build for a worker if it's not already leased
+// to another one, nor booked for another one
+func leaseActionBuild(db database.Executer, id int64, workerID string, now time.Time) (bool, error) {
+	query := `UPDATE action_build SET lease_worker_id = $2, lease_until = $4
+		WHERE id = $1 AND status = $5 AND (lease_worker_id IS NULL OR lease_worker_id = $2 OR lease_until < $3) AND ` + availableToWorker
+	res, err := db.Exec(query, id, workerID, now, now.Add(LeaseDuration), sdk.StatusWaiting.String())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ReleaseActionBuild gives back an action build leased by a worker which cannot run it.
+// It will not be offered again to this worker for a while.
+func ReleaseActionBuild(db database.Executer, id int64, workerID string) error {
+	query := `UPDATE action_build SET lease_worker_id = NULL, lease_until = NULL WHERE id = $1 AND lease_worker_id = $2`
+	if _, err := db.Exec(query, id, workerID); err != nil {
+		return err
+	}
+	queueDispatcher.refuse(id, workerID, time.Now())
+	return nil
+}
+
+// MatchCapabilities returns false if an action build has requirements that worker capabilities
+// do not fulfill. Requirements which can only be checked by worker itself are considered fulfilled.
+func MatchCapabilities(b sdk.ActionBuild, caps sdk.WorkerCapabilities, modelID int64, models map[string]int64) bool {
+	for _, r := range b.Requirements {
+		switch r.Type {
+		case sdk.BinaryRequirement:
+			found := false
+			for _, bin := range caps.Binaries {
+				if bin == r.Value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case sdk.HostnameRequirement:
+			if caps.Hostname != r.Value {
+				return false
+			}
+		case sdk.ModelRequirement:
+			if id, ok := models[r.Value]; !ok || id != modelID {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestMatchCapabilities(t *testing.T) {
+	caps := sdk.WorkerCapabilities{Binaries: []string{"git", "go"}, Hostname: "host1"}
+	models := map[string]int64{"docker": 1, "openstack": 2}
+
+	tests := []struct {
+		requirements []sdk.Requirement
+		expected     bool
+	}{
+		{nil, true},
+		{[]sdk.Requirement{{Type: sdk.BinaryRequirement, Value: "git"}, {Type: sdk.BinaryRequirement, Value: "go"}}, true},
+		{[]sdk.Requirement{{Type: sdk.BinaryRequirement, Value: "npm"}}, false},
+		{[]sdk.Requirement{{Type: sdk.HostnameRequirement, Value: "host1"}}, true},
+		{[]sdk.Requirement{{Type: sdk.HostnameRequirement, Value: "host2"}}, false},
+		{[]sdk.Requirement{{Type: sdk.ModelRequirement, Value: "docker"}}, true},
+		{[]sdk.Requirement{{Type: sdk.ModelRequirement, Value: "openstack"}}, false},
+		{[]sdk.Requirement{{Type: sdk.ModelRequirement, Value: "unknown"}}, false},
+		// Checked by worker itself
+		{[]sdk.Requirement{{Type: sdk.NetworkAccessRequirement, Value: "example.com:80"}}, true},
+	}
+
+	for i, test := range tests {
+		b := sdk.ActionBuild{Requirements: test.requirements}
+		if got := MatchCapabilities(b, caps, 1, models); got != test.expected {
+			t.Errorf("#%d: expected %t, got %t", i, test.expected, got)
+		}
+	}
+}
+
+func TestDispatcherCandidates(t *testing.T) {
+	d := &dispatcher{
+		changed: make(chan struct{}),
+		leased:  map[int64]time.Time{},
+		refused: map[int64]map[string]time.Time{},
+	}
+	now := time.Now()
+	all := func(sdk.ActionBuild) bool { return true }
+
+	changed := d.changed
+	d.refresh([]sdk.ActionBuild{{ID: 1}, {ID: 2}, {ID: 3}}, now)
+	select {
+	case <-changed:
+	default:
+		t.Fatalf("Waiting workers should be woken up on refresh")
+	}
+
+	d.lease(1, now.Add(LeaseDuration))
+	d.refuse(2, "w1", now)
+
+	candidates, _ := d.candidates("w1", all, now)
+	if len(candidates) != 1 || candidates[0].ID != 3 {
+		t.Fatalf("Expected only action build 3, got %+v", candidates)
+	}
+	candidates, _ = d.candidates("w2", func(b sdk.ActionBuild) bool { return b.ID != 3 }, now)
+	if len(candidates) != 1 || candidates[0].ID != 2 {
+		t.Fatalf("Expected only action build 2, got %+v", candidates)
+	}
+
+	// Expired leases and refusals are forgotten
+	later := now.Add(refusalDuration + time.Second)
+	d.refresh([]sdk.ActionBuild{{ID: 1}, {ID: 2}}, later)
+	candidates, _ = d.candidates("w1", all, later)
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 action builds, got %+v", candidates)
+	}
+	if len(d.leased) != 0 || len(d.refused) != 0 {
+		t.Fatalf("Expected no lease nor refusal, got %v %v", d.leased, d.refused)
+	}
+}
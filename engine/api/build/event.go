@@ -0,0 +1,122 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+const buildEventsChannel = "events:builds"
+
+//subscriberBufferSize is the number of events a subscriber can be late before being dropped
+const subscriberBufferSize = 1000
+
+var streams = struct {
+	sync.Mutex
+	subscribers map[int64][]chan sdk.BuildEvent
+}{subscribers: map[int64][]chan sdk.BuildEvent{}}
+
+//PublishBuildEvent sends the event to every API instance, through cache pub/sub
+func PublishBuildEvent(e sdk.BuildEvent) {
+	cache.Publish(buildEventsChannel, e)
+}
+
+//PublishBuildLogs sends log lines of an action build as events. A step event is sent when a line
+//belongs to another step than the previous one
+func PublishBuildLogs(ab sdk.ActionBuild, logs []sdk.Log) {
+	k := cache.Key("build", "log", "step", fmt.Sprintf("%d", ab.ID))
+	var step string
+	cache.Get(k, &step)
+
+	for i := range logs {
+		l := logs[i]
+		if l.Step != step {
+			step = l.Step
+			PublishBuildEvent(sdk.BuildEvent{
+				Type:             sdk.BuildEventStep,
+				PipelineBuildID:  ab.PipelineBuildID,
+				ActionBuildID:    ab.ID,
+				PipelineActionID: ab.PipelineActionID,
+				Step:             step,
+				Status:           sdk.StatusBuilding,
+			})
+		}
+		PublishBuildEvent(sdk.BuildEvent{
+			Type:             sdk.BuildEventLog,
+			PipelineBuildID:  ab.PipelineBuildID,
+			ActionBuildID:    ab.ID,
+			PipelineActionID: ab.PipelineActionID,
+			Step:             l.Step,
+			Log:              &l,
+		})
+	}
+
+	cache.SetWithTTL(k, step, 3600)
+}
+
+//SubscribeBuildEvents returns a channel receiving events of the pipeline build.
+//The channel is closed if the subscriber does not consume events fast enough
+func SubscribeBuildEvents(pipelineBuildID int64) chan sdk.BuildEvent {
+	c := make(chan sdk.BuildEvent, subscriberBufferSize)
+	streams.Lock()
+	streams.subscribers[pipelineBuildID] = append(streams.subscribers[pipelineBuildID], c)
+	streams.Unlock()
+	return c
+}
+
+//UnsubscribeBuildEvents stops sending events to the channel
+func UnsubscribeBuildEvents(pipelineBuildID int64, c chan sdk.BuildEvent) {
+	streams.Lock()
+	defer streams.Unlock()
+	removeSubscriber(pipelineBuildID, c)
+}
+
+//removeSubscriber must be called with streams locked
+func removeSubscriber(pipelineBuildID int64, c chan sdk.BuildEvent) {
+	subs := streams.subscribers[pipelineBuildID]
+	for i := range subs {
+		if subs[i] == c {
+			close(c)
+			streams.subscribers[pipelineBuildID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(streams.subscribers[pipelineBuildID]) == 0 {
+		delete(streams.subscribers, pipelineBuildID)
+	}
+}
+
+//BuildEventsRoutine dispatches events published by all API instances to subscribers of this instance
+func BuildEventsRoutine() {
+	c := make(chan []byte, subscriberBufferSize)
+	go cache.Subscribe(buildEventsChannel, c)
+
+	for b := range c {
+		var e sdk.BuildEvent
+		if err := json.Unmarshal(b, &e); err != nil {
+			log.Warning("BuildEventsRoutine> Cannot unmarshal event: %s\n", err)
+			continue
+		}
+		dispatchBuildEvent(e)
+	}
+}
+
+func dispatchBuildEvent(e sdk.BuildEvent) {
+	streams.Lock()
+	defer streams.Unlock()
+
+	subs := make([]chan sdk.BuildEvent, len(streams.subscribers[e.PipelineBuildID]))
+	copy(subs, streams.subscribers[e.PipelineBuildID])
+	for _, s := range subs {
+		select {
+		case s <- e:
+		default:
+			log.Warning("dispatchBuildEvent> Subscriber of pipeline build %d is too slow, closing it\n", e.PipelineBuildID)
+			removeSubscriber(e.PipelineBuildID, s)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestDispatchBuildEvent(t *testing.T) {
+	c := SubscribeBuildEvents(42)
+	other := SubscribeBuildEvents(43)
+	defer UnsubscribeBuildEvents(43, other)
+
+	dispatchBuildEvent(sdk.BuildEvent{Type: sdk.BuildEventActionBuild, PipelineBuildID: 42, ActionBuildID: 1, Status: sdk.StatusBuilding})
+
+	e := <-c
+	if e.ActionBuildID != 1 || e.Status != sdk.StatusBuilding {
+		t.Fatalf("Unexpected event: %+v", e)
+	}
+	if len(other) != 0 {
+		t.Fatalf("Event should not be sent to other pipeline builds subscribers")
+	}
+
+	// A subscriber not consuming its events is closed
+	for i := 0; i <= subscriberBufferSize; i++ {
+		dispatchBuildEvent(sdk.BuildEvent{Type: sdk.BuildEventLog, PipelineBuildID: 42})
+	}
+	for range c {
+	}
+	if _, ok := streams.subscribers[42]; ok {
+		t.Fatalf("Slow subscriber should have been removed")
+	}
+
+	// Unsubscribing a closed subscriber is safe
+	UnsubscribeBuildEvents(42, c)
+}
@@ -0,0 +1,299 @@
+package build
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+//LogStore is the storage backend of action build logs
+type LogStore interface {
+	Insert(db database.QueryExecuter, l *sdk.Log) error
+	Load(db database.Querier, actionBuildID int64, tail int64, start int64) ([]sdk.Log, error)
+	Delete(db database.QueryExecuter, actionBuildID int64) error
+}
+
+//Log store modes
+const (
+	LogStoreDatabase    = "database"
+	LogStoreObjectstore = "objectstore"
+)
+
+const logContainer = "logs"
+
+var logStore LogStore = &sqlLogStore{}
+
+//InitializeLogStore setup the log store used by InsertLog, LoadLogs and DeleteBuildLogs.
+//With objectstore mode, log lines are inserted in database then moved every flushInterval
+//to the objectstore, as compressed chunks of at most flushLines lines.
+func InitializeLogStore(mode string, flushLines int, flushInterval time.Duration) error {
+	switch mode {
+	case LogStoreDatabase, "":
+		logStore = &sqlLogStore{}
+	case LogStoreObjectstore:
+		if flushLines <= 0 {
+			return fmt.Errorf("invalid build log flush lines: %d", flushLines)
+		}
+		if flushInterval <= 0 {
+			return fmt.Errorf("invalid build log flush interval: %s", flushInterval)
+		}
+		s := &objectstoreLogStore{maxLines: flushLines}
+		logStore = s
+		go s.flushRoutine(flushInterval)
+	default:
+		return fmt.Errorf("unsupported build log mode: %s", mode)
+	}
+	return nil
+}
+
+//objectstoreLogStore inserts log lines in build_log table, with the transaction of the caller, and moves them
+//in the objectstore as gzipped json chunks once committed. Chunks are referenced in build_log_chunk table.
+//Lines are deleted from build_log in the transaction referencing their chunk, so they are always readable
+//by every API instance.
+type objectstoreLogStore struct {
+	sqlLogStore
+	maxLines int
+}
+
+func (s *objectstoreLogStore) Load(db database.Querier, actionBuildID int64, tail int64, start int64) ([]sdk.Log, error) {
+	// Lines not flushed yet are loaded first: if they are flushed meanwhile, they are found in their chunk
+	logs, err := s.sqlLogStore.Load(db, actionBuildID, tail, start)
+	if err != nil {
+		return nil, err
+	}
+	loaded := map[int64]bool{}
+	for _, l := range logs {
+		loaded[l.ID] = true
+	}
+
+	query := `SELECT object_name FROM build_log_chunk WHERE action_build_id = $1 AND last_log_id > $2 ORDER BY first_log_id`
+	rows, err := db.Query(query, actionBuildID, start)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		chunk, err := fetchLogChunk(name)
+		if err != nil {
+			log.Warning("objectstoreLogStore.Load> Cannot fetch log chunk %s: %s\n", name, err)
+			return nil, err
+		}
+		for _, l := range chunk {
+			if l.ID > start && !loaded[l.ID] {
+				logs = append(logs, l)
+			}
+		}
+	}
+
+	sort.Sort(logsByID(logs))
+	if int64(len(logs)) > tail {
+		logs = logs[:tail]
+	}
+	return logs, nil
+}
+
+func (s *objectstoreLogStore) Delete(db database.QueryExecuter, actionBuildID int64) error {
+	query := `SELECT object_name FROM build_log_chunk WHERE action_build_id = $1`
+	rows, err := db.Query(query, actionBuildID)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	for _, name := range names {
+		if err := objectstore.DeleteObject(logContainer, name); err != nil {
+			log.Warning("objectstoreLogStore.Delete> Cannot delete log chunk %s: %s\n", name, err)
+		}
+	}
+
+	query = `DELETE FROM build_log_chunk WHERE action_build_id = $1`
+	if _, err := db.Exec(query, actionBuildID); err != nil {
+		return err
+	}
+
+	return s.sqlLogStore.Delete(db, actionBuildID)
+}
+
+//flush moves committed lines of an action build to a new chunk, it returns the number of lines moved
+func (s *objectstoreLogStore) flush(db *sql.DB, actionBuildID int64) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lines are locked so that concurrent API instances do not flush them twice
+	query := fmt.Sprintf(`SELECT id, action_build_id, "timestamp", step, value FROM build_log
+		WHERE action_build_id = $1 ORDER BY id LIMIT %d FOR UPDATE`, s.maxLines)
+	rows, err := tx.Query(query, actionBuildID)
+	if err != nil {
+		return 0, err
+	}
+	var logs []sdk.Log
+	for rows.Next() {
+		var l sdk.Log
+		if err := rows.Scan(&l.ID, &l.ActionBuildID, &l.Timestamp, &l.Step, &l.Value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	name, err := storeLogChunk(tx, actionBuildID, logs)
+	if err != nil {
+		return 0, err
+	}
+
+	// Lines are deleted one by one, lines committed meanwhile between them are not in the chunk
+	query = `DELETE FROM build_log WHERE id = $1`
+	for _, l := range logs {
+		if _, err := tx.Exec(query, l.ID); err != nil {
+			deleteLogChunk(name)
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		deleteLogChunk(name)
+		return 0, err
+	}
+	return len(logs), nil
+}
+
+func (s *objectstoreLogStore) flushRoutine(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		db := database.DB()
+		if db == nil {
+			continue
+		}
+
+		ids, err := loadActionBuildIDsWithLogs(db)
+		if err != nil {
+			log.Warning("objectstoreLogStore.flushRoutine> Cannot load action builds to flush: %s\n", err)
+			continue
+		}
+
+		for _, id := range ids {
+			for {
+				n, err := s.flush(db, id)
+				if err != nil {
+					log.Warning("objectstoreLogStore.flushRoutine> Cannot flush logs of action build %d: %s\n", id, err)
+				}
+				if err != nil || n < s.maxLines {
+					break
+				}
+			}
+		}
+	}
+}
+
+func loadActionBuildIDsWithLogs(db *sql.DB) ([]int64, error) {
+	query := `SELECT DISTINCT action_build_id FROM build_log`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+//storeLogChunk stores logs in the objectstore and references them in build_log_chunk, it returns the object name
+func storeLogChunk(db database.Executer, actionBuildID int64, logs []sdk.Log) (string, error) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	if err := json.NewEncoder(gz).Encode(logs); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	first, last := logs[0], logs[len(logs)-1]
+	name := fmt.Sprintf("%d/%d.json.gz", actionBuildID, first.ID)
+	if _, err := objectstore.StoreObject(logContainer, name, ioutil.NopCloser(buf)); err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO build_log_chunk (action_build_id, first_log_id, last_log_id, last_log, object_name) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := db.Exec(query, actionBuildID, first.ID, last.ID, last.Timestamp, name); err != nil {
+		deleteLogChunk(name)
+		return "", err
+	}
+	return name, nil
+}
+
+//deleteLogChunk deletes a chunk whose reference is not committed
+func deleteLogChunk(name string) {
+	if err := objectstore.DeleteObject(logContainer, name); err != nil {
+		log.Warning("deleteLogChunk> Cannot delete log chunk %s: %s\n", name, err)
+	}
+}
+
+func fetchLogChunk(name string) ([]sdk.Log, error) {
+	r, err := objectstore.FetchObject(logContainer, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var logs []sdk.Log
+	if err := json.NewDecoder(gz).Decode(&logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+type logsByID []sdk.Log
+
+func (l logsByID) Len() int           { return len(l) }
+func (l logsByID) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l logsByID) Less(i, j int) bool { return l[i].ID < l[j].ID }
@@ -0,0 +1,96 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/api/test"
+)
+
+func TestObjectstoreLogStore(t *testing.T) {
+	db := test.Setup("ObjectstoreLogStore", t)
+
+	dir, err := ioutil.TempDir("", "cds-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := objectstore.Initialize("filesystem", "", "", "", dir); err != nil {
+		t.Fatalf("Cannot initialize objectstore: %s", err)
+	}
+
+	s := &objectstoreLogStore{maxLines: 3}
+	logStore = s
+	defer func() { logStore = &sqlLogStore{} }()
+
+	for _, v := range []string{"foo 1", "foo 2", "foo 3", "foo 4"} {
+		if err := InsertLog(db, 3, "1", v); err != nil {
+			t.Fatalf("Cannot insert log: %s", err)
+		}
+	}
+	InsertLog(db, 2, "1", "bar 1")
+
+	// Lines are readable before being flushed
+	logs, err := LoadLogs(db, 3, 0, 0)
+	if err != nil {
+		t.Fatalf("Cannot load logs: %s", err)
+	}
+	if len(logs) != 4 {
+		t.Fatalf("Expected 4 log lines before flush, got %d", len(logs))
+	}
+
+	n, err := s.flush(db, 3)
+	if err != nil {
+		t.Fatalf("Cannot flush logs: %s", err)
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 lines flushed, got %d", n)
+	}
+
+	logs, err = LoadLogs(db, 3, 0, 0)
+	if err != nil {
+		t.Fatalf("Cannot load logs: %s", err)
+	}
+	if len(logs) != 4 {
+		t.Fatalf("Expected 4 log lines, got %d", len(logs))
+	}
+	for i, l := range logs {
+		if l.ActionBuildID != 3 {
+			t.Fatalf("Got build id != 3 -> %d", l.ActionBuildID)
+		}
+		if i > 0 && l.ID <= logs[i-1].ID {
+			t.Fatalf("Logs are not ordered: %d <= %d", l.ID, logs[i-1].ID)
+		}
+	}
+
+	logs, err = LoadLogs(db, 3, 0, logs[1].ID)
+	if err != nil {
+		t.Fatalf("Cannot load logs: %s", err)
+	}
+	if len(logs) != 2 || logs[0].Value != "foo 3" {
+		t.Fatalf("Expected 2 log lines from offset, got %v", logs)
+	}
+
+	if err := DeleteBuildLogs(db, 3); err != nil {
+		t.Fatalf("Cannot delete logs: %s", err)
+	}
+	logs, err = LoadLogs(db, 3, 0, 0)
+	if err != nil {
+		t.Fatalf("Cannot load logs: %s", err)
+	}
+	if len(logs) != 0 {
+		t.Fatalf("Expected no log lines after delete, got %d", len(logs))
+	}
+
+	// Lines of other builds are not altered
+	logs, err = LoadLogs(db, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("Cannot load logs: %s", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 line for build 2, got %d", len(logs))
+	}
+}
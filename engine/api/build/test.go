@@ -9,7 +9,7 @@ import (
 )
 
 // LoadTestResults retrieves tests on a specific build in database
-func LoadTestResults(db *sql.DB, pbID int64) (sdk.Tests, error) {
+func LoadTestResults(db database.Querier, pbID int64) (sdk.Tests, error) {
 	query := `SELECT tests FROM pipeline_build_test WHERE pipeline_build_id = $1`
 	t := sdk.Tests{}
 	var data string
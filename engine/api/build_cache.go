@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/buildcache"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func getBuildCachesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("getBuildCachesHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	caches, err := buildcache.LoadCaches(db, app.ID)
+	if err != nil {
+		log.Warning("getBuildCachesHandler> Cannot load caches of application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, caches, http.StatusOK)
+}
+
+func matchBuildCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("matchBuildCacheHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	cache, err := buildcache.Match(db, app.ID, query.Get("branch"), query["key"])
+	if err != nil {
+		if err != sdk.ErrNotFound {
+			log.Warning("matchBuildCacheHandler> Cannot match cache of application %s: %s\n", appName, err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, cache, http.StatusOK)
+}
+
+func uploadBuildCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+	branch := r.URL.Query().Get("branch")
+	cacheKey := r.URL.Query().Get("key")
+
+	if branch == "" || cacheKey == "" {
+		log.Warning("uploadBuildCacheHandler> Branch and key are mandatory\n")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("uploadBuildCacheHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("uploadBuildCacheHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	cache, err := buildcache.Save(db, proj.ID, app.ID, branch, cacheKey, r.Body)
+	if err != nil {
+		log.Warning("uploadBuildCacheHandler> Cannot save cache %s of application %s: %s\n", cacheKey, appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, cache, http.StatusOK)
+}
+
+func downloadBuildCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, cache, ok := loadBuildCache(w, r, db, key, appName, vars["id"])
+	if !ok {
+		return
+	}
+
+	f, err := buildcache.Fetch(db, *cache, app.ID)
+	if err != nil {
+		log.Warning("downloadBuildCacheHandler> Cannot fetch cache %d: %s\n", cache.ID, err)
+		WriteError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Add("Content-Type", "application/octet-stream")
+	if err := objectstore.StreamFile(w, f); err != nil {
+		log.Warning("downloadBuildCacheHandler> Cannot stream cache %d: %s\n", cache.ID, err)
+	}
+}
+
+func deleteBuildCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, cache, ok := loadBuildCache(w, r, db, key, appName, vars["id"])
+	if !ok {
+		return
+	}
+
+	if err := buildcache.Delete(db, *cache, app.ID); err != nil {
+		log.Warning("deleteBuildCacheHandler> Cannot delete cache %d: %s\n", cache.ID, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func loadBuildCache(w http.ResponseWriter, r *http.Request, db *sql.DB, key, appName, idS string) (*sdk.Application, *sdk.Cache, bool) {
+	id, err := strconv.ParseInt(idS, 10, 64)
+	if err != nil {
+		log.Warning("loadBuildCache> Cannot convert '%s' into int: %s\n", idS, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("loadBuildCache> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return nil, nil, false
+	}
+
+	cache, err := buildcache.LoadCache(db, app.ID, id)
+	if err != nil {
+		log.Warning("loadBuildCache> Cannot load cache %d: %s\n", id, err)
+		WriteError(w, r, err)
+		return nil, nil, false
+	}
+
+	return app, cache, true
+}
@@ -4,9 +4,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -61,7 +64,7 @@ func getBuildLogsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *
 
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getBuildLogsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -202,7 +205,7 @@ func getActionBuildLogsHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		env, err = environment.LoadEnvironmentByName(db, projectKey, envName)
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getActionBuildLogsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -262,6 +265,85 @@ func getActionBuildLogsHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 	WriteJSON(w, r, pipelinelogs, http.StatusOK)
 }
 
+// getActionBuildAttemptsHandler returns previous failed attempts of a retried action, with their test results.
+// Logs of all attempts are returned by getActionBuildLogsHandler.
+func getActionBuildAttemptsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	buildNumberS := vars["build"]
+	actionIDString := vars["actionID"]
+	appName := vars["permApplicationName"]
+
+	actionID, err := strconv.ParseInt(actionIDString, 10, 64)
+	if err != nil {
+		log.Warning("getActionBuildAttemptsHandler> actionID should be an integer : %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	p, err := pipeline.LoadPipeline(db, projectKey, pipelineName, false)
+	if err != nil {
+		log.Warning("getActionBuildAttemptsHandler> Cannot load pipeline %s: %s\n", pipelineName, err)
+		WriteError(w, r, sdk.ErrPipelineNotFound)
+		return
+	}
+
+	a, err := application.LoadApplicationByName(db, projectKey, appName)
+	if err != nil {
+		log.Warning("getActionBuildAttemptsHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, sdk.ErrApplicationNotFound)
+		return
+	}
+
+	var env *sdk.Environment
+	envName := r.FormValue("envName")
+	if envName == "" || envName == sdk.DefaultEnv.Name {
+		env = &sdk.DefaultEnv
+	} else {
+		env, err = environment.LoadEnvironmentByName(db, projectKey, envName)
+		if err != nil {
+			log.Warning("getActionBuildAttemptsHandler> Cannot load environment %s: %s\n", envName, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
+		log.Warning("getActionBuildAttemptsHandler> No enought right on this environment %s: \n", envName)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	buildNumber, err := strconv.ParseInt(buildNumberS, 10, 64)
+	if err != nil {
+		log.Warning("getActionBuildAttemptsHandler> Cannot parse build number %s: %s\n", buildNumberS, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	pb, err := pipeline.LoadPipelineBuild(db, p.ID, a.ID, buildNumber, env.ID)
+	if err != nil {
+		if err == sdk.ErrNoPipelineBuild {
+			// Attempts are not kept in history
+			WriteJSON(w, r, []sdk.ActionBuildAttempt{}, http.StatusOK)
+			return
+		}
+		log.Warning("getActionBuildAttemptsHandler> Cannot load pipeline build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	attempts, err := pipeline.LoadActionBuildAttempts(db, pb.ID, actionID)
+	if err != nil {
+		log.Warning("getActionBuildAttemptsHandler> Cannot load attempts of action %d: %s\n", actionID, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, attempts, http.StatusOK)
+}
+
 func addBuildLogHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 
 	// Get action name in URL
@@ -269,7 +351,7 @@ func addBuildLogHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 	id := vars["id"]
 
 	// Load Queue
-	_, err := build.LoadActionBuild(db, id)
+	ab, err := build.LoadActionBuild(db, id)
 	if err != nil {
 		log.Warning("addBuildLogHandler> Cannot load build %s from db: %s\n", id, err)
 		WriteError(w, r, err)
@@ -294,14 +376,248 @@ func addBuildLogHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		return
 	}
 
+	// Worker masks secrets, but plugins send their logs by themselves
+	masker, err := actionBuildLogMasker(db, ab.ID)
+	if err != nil {
+		log.Warning("addBuildLogHandler> Cannot load secrets of build %d: %s\n", ab.ID, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	for i := range logs {
-		err = build.InsertLog(db, logs[i].ActionBuildID, logs[i].Step, logs[i].Value)
+		logs[i].Value = masker.Mask(logs[i].Value)
+		err = build.InsertBuildLog(db, &logs[i])
 		if err != nil {
 			log.Warning("addBuildLogHandler> Cannot insert log line:  %s\n", err)
 			WriteError(w, r, err)
 			return
 		}
 	}
+
+	build.PublishBuildLogs(ab, logs)
+}
+
+// logMaskersTTL is how long secrets of an action build are kept in memory to mask its logs,
+// instead of loading them each time a worker sends logs
+const logMaskersTTL = time.Minute
+
+var logMaskers = struct {
+	sync.Mutex
+	m map[int64]logMaskerEntry
+}{m: map[int64]logMaskerEntry{}}
+
+type logMaskerEntry struct {
+	masker  *sdk.SecretMasker
+	expires time.Time
+}
+
+// actionBuildLogMasker returns the masker of secrets an action build has access to
+func actionBuildLogMasker(db *sql.DB, abID int64) (*sdk.SecretMasker, error) {
+	now := time.Now()
+	logMaskers.Lock()
+	e, ok := logMaskers.m[abID]
+	for id, e := range logMaskers.m {
+		if e.expires.Before(now) {
+			delete(logMaskers.m, id)
+		}
+	}
+	logMaskers.Unlock()
+	if ok && e.expires.After(now) {
+		return e.masker, nil
+	}
+
+	secrets, err := loadActionBuildSecrets(db, abID)
+	if err != nil {
+		return nil, err
+	}
+	masker := sdk.NewSecretMasker(secrets)
+
+	logMaskers.Lock()
+	logMaskers.m[abID] = logMaskerEntry{masker: masker, expires: now.Add(logMaskersTTL)}
+	logMaskers.Unlock()
+	return masker, nil
+}
+
+func getBuildLogsStreamHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+
+	// Get pipeline and action name in URL
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	buildNumberS := vars["build"]
+	appName := vars["permApplicationName"]
+
+	// Get offset, from Last-Event-ID header when the client reconnects
+	err := r.ParseForm()
+	if err != nil {
+		log.Warning("getBuildLogsStreamHandler> cannot parse form: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	offsetS := r.FormValue("offset")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		offsetS = lastEventID
+	}
+	var offset int64
+	if offsetS != "" {
+		offset, err = strconv.ParseInt(offsetS, 10, 64)
+		if err != nil {
+			log.Warning("getBuildLogsStreamHandler> Cannot parse offset %s: %s\n", offsetS, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	var env *sdk.Environment
+	envName := r.FormValue("envName")
+	if envName == "" || envName == sdk.DefaultEnv.Name {
+		env = &sdk.DefaultEnv
+	} else {
+		env, err = environment.LoadEnvironmentByName(db, projectKey, envName)
+		if err != nil {
+			log.Warning("getBuildLogsStreamHandler> Cannot load environment %s: %s\n", envName, err)
+			WriteError(w, r, sdk.ErrUnknownEnv)
+			return
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
+		log.Warning("getBuildLogsStreamHandler> No enought right on this environment %s: \n", envName)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	p, err := pipeline.LoadPipeline(db, projectKey, pipelineName, false)
+	if err != nil {
+		log.Warning("getBuildLogsStreamHandler> Cannot load pipeline %s: %s\n", pipelineName, err)
+		WriteError(w, r, sdk.ErrPipelineNotFound)
+		return
+	}
+
+	a, err := application.LoadApplicationByName(db, projectKey, appName)
+	if err != nil {
+		log.Warning("getBuildLogsStreamHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, sdk.ErrApplicationNotFound)
+		return
+	}
+
+	var buildNumber int64
+	if buildNumberS == "last" {
+		bn, _, err := pipeline.GetProbableLastBuildNumber(db, p.ID, a.ID, env.ID)
+		if err != nil {
+			log.Warning("getBuildLogsStreamHandler> Cannot load last build number for %s: %s\n", pipelineName, err)
+			WriteError(w, r, err)
+			return
+		}
+		buildNumber = bn
+	} else {
+		buildNumber, err = strconv.ParseInt(buildNumberS, 10, 64)
+		if err != nil {
+			log.Warning("getBuildLogsStreamHandler> Cannot parse build number %s: %s\n", buildNumberS, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	// Archived builds are not streamed, they are fetched with getBuildLogsHandler
+	pb, err := pipeline.LoadPipelineBuild(db, p.ID, a.ID, buildNumber, env.ID)
+	if err != nil {
+		if err != sdk.ErrNoPipelineBuild {
+			log.Warning("getBuildLogsStreamHandler> Cannot load pipeline build: %s\n", err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		log.Warning("getBuildLogsStreamHandler> Streaming unsupported\n")
+		WriteError(w, r, sdk.ErrUnknownError)
+		return
+	}
+
+	// Subscribe before loading logs, so no line is lost in between
+	events := build.SubscribeBuildEvents(pb.ID)
+	defer build.UnsubscribeBuildEvents(pb.ID, events)
+
+	logs, err := build.LoadPipelineBuildLogs(db, pb.ID, offset)
+	if err != nil {
+		log.Warning("getBuildLogsStreamHandler> Cannot load pipeline build logs: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// Reload status, the build may have ended before we subscribed
+	pb, err = pipeline.LoadPipelineBuild(db, p.ID, a.ID, buildNumber, env.ID)
+	if err != nil {
+		log.Warning("getBuildLogsStreamHandler> Cannot reload pipeline build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	sent := map[int64]bool{}
+	for i := range logs {
+		sent[logs[i].ID] = true
+		writeBuildEvent(w, sdk.BuildEvent{
+			Type:            sdk.BuildEventLog,
+			PipelineBuildID: pb.ID,
+			ActionBuildID:   logs[i].ActionBuildID,
+			Step:            logs[i].Step,
+			Log:             &logs[i],
+		})
+	}
+
+	if pb.Status.IsFinal() {
+		writeBuildEvent(w, sdk.BuildEvent{Type: sdk.BuildEventPipelineBuild, PipelineBuildID: pb.ID, Status: pb.Status})
+		f.Flush()
+		return
+	}
+	f.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			f.Flush()
+		case e, open := <-events:
+			// Subscriber has been dropped for being too slow, client will reconnect with Last-Event-ID
+			if !open {
+				return
+			}
+			if e.Log != nil && sent[e.Log.ID] {
+				continue
+			}
+			writeBuildEvent(w, e)
+			f.Flush()
+			if e.Type == sdk.BuildEventPipelineBuild && e.Status.IsFinal() {
+				return
+			}
+		}
+	}
+}
+
+// writeBuildEvent writes a build event in text/event-stream format. Log events carry the log ID
+// as event ID so that clients resume from it when reconnecting
+func writeBuildEvent(w io.Writer, e sdk.BuildEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Warning("writeBuildEvent> Cannot marshal event: %s\n", err)
+		return
+	}
+	if e.Log != nil {
+		fmt.Fprintf(w, "id: %d\n", e.Log.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
 }
 
 func setEngineLogLevel(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
@@ -0,0 +1,296 @@
+package buildcache
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// container is the objectstore container of build cache archives
+const container = "cache"
+
+// defaultBranch is the branch whose caches are restored when none matches on build branch
+const defaultBranch = "master"
+
+var (
+	maxSize int64
+	quota   int64
+	ttl     time.Duration
+)
+
+// Initialize sets build caches limits: maximum size of a cache, total size of caches of a
+// project and how long an unused cache is kept. Zero values disable the corresponding limit.
+func Initialize(cacheMaxSize, projectQuota int64, unusedTTL time.Duration) {
+	maxSize = cacheMaxSize
+	quota = projectQuota
+	ttl = unusedTTL
+}
+
+const cacheFields = `build_cache.id, project.projectkey, application.name, build_cache.branch, build_cache.cache_key,
+		build_cache.size, build_cache.created, build_cache.last_used`
+
+const cacheJoins = `FROM build_cache
+		JOIN application ON application.id = build_cache.application_id
+		JOIN project ON project.id = build_cache.project_id`
+
+func scanCache(s database.Scanner) (sdk.Cache, error) {
+	var c sdk.Cache
+	err := s.Scan(&c.ID, &c.Project, &c.Application, &c.Branch, &c.Key, &c.Size, &c.Created, &c.LastUsed)
+	return c, err
+}
+
+// LoadCaches returns all build caches of an application, most recently used first
+func LoadCaches(db database.Querier, applicationID int64) ([]sdk.Cache, error) {
+	query := `SELECT ` + cacheFields + ` ` + cacheJoins + `
+		WHERE build_cache.application_id = $1 ORDER BY build_cache.last_used DESC`
+	rows, err := db.Query(query, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	caches := []sdk.Cache{}
+	for rows.Next() {
+		c, err := scanCache(rows)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, c)
+	}
+	return caches, nil
+}
+
+// LoadCache loads a build cache of an application
+func LoadCache(db database.Querier, applicationID, id int64) (*sdk.Cache, error) {
+	query := `SELECT ` + cacheFields + ` ` + cacheJoins + `
+		WHERE build_cache.application_id = $1 AND build_cache.id = $2`
+	c, err := scanCache(db.QueryRow(query, applicationID, id))
+	if err == sql.ErrNoRows {
+		return nil, sdk.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Match returns the cache to restore for a build on branch. Each key is tried in order, first as exact
+// key then as prefix of the most recently used cache key. Caches of build branch are looked up first,
+// then caches of default branch. It returns sdk.ErrNotFound if no cache matches.
+func Match(db database.Querier, applicationID int64, branch string, keys []string) (*sdk.Cache, error) {
+	branches := []string{branch}
+	if branch != defaultBranch {
+		branches = append(branches, defaultBranch)
+	}
+
+	exact := `SELECT ` + cacheFields + ` ` + cacheJoins + `
+		WHERE build_cache.application_id = $1 AND build_cache.branch = $2 AND build_cache.cache_key = $3`
+	prefix := `SELECT ` + cacheFields + ` ` + cacheJoins + `
+		WHERE build_cache.application_id = $1 AND build_cache.branch = $2 AND build_cache.cache_key LIKE $3
+		ORDER BY build_cache.last_used DESC LIMIT 1`
+
+	for _, b := range branches {
+		for _, k := range keys {
+			if k == "" {
+				continue
+			}
+			for _, q := range []struct {
+				query string
+				arg   string
+			}{{exact, k}, {prefix, escapeLike(k) + "%"}} {
+				c, err := scanCache(db.QueryRow(q.query, applicationID, b, q.arg))
+				if err == sql.ErrNoRows {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				return &c, nil
+			}
+		}
+	}
+	return nil, sdk.ErrNotFound
+}
+
+func escapeLike(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `%`, `\%`, -1)
+	return strings.Replace(s, `_`, `\_`, -1)
+}
+
+// Save stores content as build cache with given key, replacing the previous cache with the same key,
+// then evicts caches of the project beyond quota. It returns sdk.ErrCacheTooLarge if content exceeds
+// maximum cache size.
+func Save(db *sql.DB, projectID, applicationID int64, branch, key string, content io.Reader) (*sdk.Cache, error) {
+	// Content is written in a temporary file to check its size before storing it
+	tmp, err := ioutil.TempFile("", "cds-cache-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if maxSize > 0 {
+		content = io.LimitReader(content, maxSize+1)
+	}
+	size, err := io.Copy(tmp, content)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, sdk.ErrCacheTooLarge
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	objectPath, err := objectstore.StoreObject(container, objectName(applicationID, branch, key), ioutil.NopCloser(tmp))
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	query := `UPDATE build_cache SET size = $4, object_path = $5, created = LOCALTIMESTAMP, last_used = LOCALTIMESTAMP
+		WHERE application_id = $1 AND branch = $2 AND cache_key = $3 RETURNING id`
+	err = db.QueryRow(query, applicationID, branch, key, size, objectPath).Scan(&id)
+	if err == sql.ErrNoRows {
+		query = `INSERT INTO build_cache (project_id, application_id, branch, cache_key, size, object_path)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+		err = db.QueryRow(query, projectID, applicationID, branch, key, size, objectPath).Scan(&id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Evict(db, projectID); err != nil {
+		log.Warning("Save> Cannot evict caches of project %d: %s\n", projectID, err)
+	}
+
+	return LoadCache(db, applicationID, id)
+}
+
+// Fetch returns build cache archive and marks the cache as used
+func Fetch(db database.Executer, c sdk.Cache, applicationID int64) (io.ReadCloser, error) {
+	if _, err := db.Exec(`UPDATE build_cache SET last_used = LOCALTIMESTAMP WHERE id = $1`, c.ID); err != nil {
+		return nil, err
+	}
+	return objectstore.FetchObject(container, objectName(applicationID, c.Branch, c.Key))
+}
+
+// Delete removes a build cache
+func Delete(db database.Executer, c sdk.Cache, applicationID int64) error {
+	if _, err := db.Exec(`DELETE FROM build_cache WHERE id = $1`, c.ID); err != nil {
+		return err
+	}
+	if err := objectstore.DeleteObject(container, objectName(applicationID, c.Branch, c.Key)); err != nil && !os.IsNotExist(err) {
+		log.Warning("Delete> Cannot delete cache %d: %s\n", c.ID, err)
+	}
+	return nil
+}
+
+// objectName returns the objectstore name of a cache, branch and key are hashed since they are free text
+func objectName(applicationID int64, branch, key string) string {
+	sum := sha256.Sum256([]byte(branch + "\n" + key))
+	return fmt.Sprintf("%d-%s", applicationID, hex.EncodeToString(sum[:]))
+}
+
+type evictableCache struct {
+	id, applicationID int64
+	branch, key       string
+	size              int64
+	lastUsed          time.Time
+}
+
+// Evict deletes caches of a project unused for too long, then least recently used caches
+// while total size of the project caches exceeds quota
+func Evict(db *sql.DB, projectID int64) error {
+	query := `SELECT id, application_id, branch, cache_key, size, last_used FROM build_cache
+		WHERE project_id = $1 ORDER BY last_used DESC`
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return err
+	}
+	var caches []evictableCache
+	for rows.Next() {
+		var c evictableCache
+		if err := rows.Scan(&c.id, &c.applicationID, &c.branch, &c.key, &c.size, &c.lastUsed); err != nil {
+			rows.Close()
+			return err
+		}
+		caches = append(caches, c)
+	}
+	rows.Close()
+
+	for _, c := range evictedCaches(caches, quota, ttl, time.Now()) {
+		log.Info("Evict> Deleting cache %s of branch %s (application %d)\n", c.key, c.branch, c.applicationID)
+		if err := Delete(db, sdk.Cache{ID: c.id, Branch: c.branch, Key: c.key}, c.applicationID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evictedCaches returns caches to delete, caches must be sorted by most recent use first
+func evictedCaches(caches []evictableCache, quota int64, ttl time.Duration, now time.Time) []evictableCache {
+	var evicted []evictableCache
+	var total int64
+	for _, c := range caches {
+		if ttl > 0 && c.lastUsed.Before(now.Add(-ttl)) {
+			evicted = append(evicted, c)
+			continue
+		}
+		total += c.size
+		if quota > 0 && total > quota {
+			evicted = append(evicted, c)
+			total -= c.size
+		}
+	}
+	return evicted
+}
+
+// EvictRoutine applies eviction rules to caches of all projects every interval seconds
+func EvictRoutine(interval int) {
+	// If this goroutine exits, then it's a crash
+	defer log.Fatalf("Goroutine of buildcache.EvictRoutine exited - Exit CDS Engine")
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+		db := database.DB()
+		if db == nil {
+			continue
+		}
+
+		rows, err := db.Query(`SELECT DISTINCT project_id FROM build_cache`)
+		if err != nil {
+			log.Warning("EvictRoutine> Cannot load projects: %s\n", err)
+			continue
+		}
+		var projects []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				log.Warning("EvictRoutine> Cannot scan project: %s\n", err)
+				break
+			}
+			projects = append(projects, id)
+		}
+		rows.Close()
+
+		for _, id := range projects {
+			if err := Evict(db, id); err != nil {
+				log.Warning("EvictRoutine> Cannot evict caches of project %d: %s\n", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package buildcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictedCaches(t *testing.T) {
+	now := time.Now()
+	caches := []evictableCache{
+		{id: 1, size: 40, lastUsed: now},
+		{id: 2, size: 40, lastUsed: now.Add(-time.Hour)},
+		{id: 3, size: 10, lastUsed: now.Add(-2 * time.Hour)},
+		{id: 4, size: 10, lastUsed: now.Add(-48 * time.Hour)},
+	}
+
+	tests := []struct {
+		quota    int64
+		ttl      time.Duration
+		expected []int64
+	}{
+		{0, 0, nil},
+		{100, 0, nil},
+		{60, 0, []int64{2}},
+		{50, 0, []int64{2, 4}},
+		{0, 24 * time.Hour, []int64{4}},
+		{85, 24 * time.Hour, []int64{3, 4}},
+	}
+
+	for _, test := range tests {
+		var ids []int64
+		for _, c := range evictedCaches(caches, test.quota, test.ttl, now) {
+			ids = append(ids, c.id)
+		}
+		if len(ids) != len(test.expected) {
+			t.Errorf("Expected %v evicted with quota %d and ttl %s, got %v", test.expected, test.quota, test.ttl, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Errorf("Expected %v evicted with quota %d and ttl %s, got %v", test.expected, test.quota, test.ttl, ids)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package buildcache
+
+import (
+	"database/sql"
+
+	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// CreateBuiltinCacheActions creates CacheSave and CacheRestore builtin actions if needed
+func CreateBuiltinCacheActions(db *sql.DB) error {
+	var name string
+	query := `SELECT action.name FROM action where action.name = $1`
+
+	for _, a := range []sdk.Action{cacheSaveAction(), cacheRestoreAction()} {
+		err := db.QueryRow(query, a.Name).Scan(&name)
+		if err != sql.ErrNoRows {
+			continue
+		}
+		if err := insertBuiltinAction(db, a); err != nil {
+			log.Warning("CreateBuiltinCacheActions> Cannot create action %s: %s\n", a.Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheSaveAction() sdk.Action {
+	save := sdk.NewAction(sdk.CacheSave)
+	save.Type = sdk.BuiltinAction
+	save.Description = "Save files in a cache restored by next builds of the application"
+	save.Parameter(sdk.Parameter{
+		Name:        "key",
+		Type:        sdk.StringParameter,
+		Description: "Cache key, {{checksum \"path\"}} is replaced by the checksum of files matching path, example: npm-{{checksum \"package-lock.json\"}}"})
+	save.Parameter(sdk.Parameter{
+		Name:        "path",
+		Type:        sdk.TextParameter,
+		Description: "Files or directories to save, relative to build directory, one per line, example: ./node_modules"})
+	return *save
+}
+
+func cacheRestoreAction() sdk.Action {
+	restore := sdk.NewAction(sdk.CacheRestore)
+	restore.Type = sdk.BuiltinAction
+	restore.Description = "Restore files saved by CacheSave in a previous build of the application"
+	restore.Parameter(sdk.Parameter{
+		Name:        "key",
+		Type:        sdk.TextParameter,
+		Description: "Cache keys tried in order, one per line. A key matches a cache with the same key, then the most recently used cache key starting with it. Caches of current branch are looked up first, then of master branch"})
+	return *restore
+}
+
+func insertBuiltinAction(db *sql.DB, a sdk.Action) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := action.InsertAction(tx, &a, true); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
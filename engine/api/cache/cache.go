@@ -25,6 +25,8 @@ type Store interface {
 	DeleteAll(key string)
 	Enqueue(queueName string, value interface{})
 	Dequeue(queueName string, value interface{})
+	Publish(channel string, value interface{})
+	Subscribe(channel string, c chan<- []byte)
 }
 
 //Initialize the global cache in memory, or redis
@@ -109,3 +111,19 @@ func Dequeue(queueName string, value interface{}) {
 	}
 	s.Dequeue(queueName, value)
 }
+
+//Publish sends a message to all subscribers of the channel
+func Publish(channel string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.Publish(channel, value)
+}
+
+//Subscribe pushes json messages published on the channel into c. This is blocking
+func Subscribe(channel string, c chan<- []byte) {
+	if s == nil {
+		return
+	}
+	s.Subscribe(channel, c)
+}
@@ -63,6 +63,7 @@ type redisClient interface {
 	PubSubChannels(pattern string) *redis.StringSliceCmd
 	PubSubNumPat() *redis.IntCmd
 	Publish(channel, message string) *redis.IntCmd
+	Subscribe(channels ...string) (*redis.PubSub, error)
 	RPop(key string) *redis.StringCmd
 	RPopLPush(source, destination string) *redis.StringCmd
 	RPush(key string, values ...interface{}) *redis.IntCmd
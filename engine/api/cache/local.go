@@ -17,6 +17,8 @@ type LocalStore struct {
 	Data   map[string][]byte
 	Queues map[string]*list.List
 	TTL    int
+
+	subscribers map[string][]chan<- []byte
 }
 
 //Get a key from local store
@@ -109,3 +111,29 @@ func (s *LocalStore) Dequeue(queueName string, value interface{}) {
 	json.Unmarshal(b, value)
 	return
 }
+
+//Publish sends a message to local subscribers of the channel
+func (s *LocalStore) Publish(channel string, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		log.Warning("Cache> Cannot publish on %s: %s", channel, err)
+		return
+	}
+	s.Mutex.Lock()
+	subscribers := s.subscribers[channel]
+	s.Mutex.Unlock()
+	for _, c := range subscribers {
+		c <- b
+	}
+}
+
+//Subscribe registers c as a subscriber of the channel. This is blocking
+func (s *LocalStore) Subscribe(channel string, c chan<- []byte) {
+	s.Mutex.Lock()
+	if s.subscribers == nil {
+		s.subscribers = map[string][]chan<- []byte{}
+	}
+	s.subscribers[channel] = append(s.subscribers[channel], c)
+	s.Mutex.Unlock()
+	select {}
+}
@@ -144,3 +144,37 @@ func (s *RedisStore) Dequeue(queueName string, value interface{}) {
 		log.Warning("redis> Cannot unmarshal %s :%s", queueName, err)
 	}
 }
+
+//Publish a message on a redis channel
+func (s *RedisStore) Publish(channel string, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		log.Warning("redis> Error publishing on %s: %s", channel, err)
+		return
+	}
+	if err := s.Client.Publish(channel, string(b)).Err(); err != nil {
+		log.Warning("redis> Error while PUBLISH to %s: %s", channel, err)
+	}
+}
+
+//Subscribe to a redis channel and push received messages into c. This is blocking
+func (s *RedisStore) Subscribe(channel string, c chan<- []byte) {
+	for {
+		pubsub, err := s.Client.Subscribe(channel)
+		if err != nil {
+			log.Warning("redis> Error while SUBSCRIBE to %s: %s", channel, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				log.Warning("redis> Error receiving message from %s: %s", channel, err)
+				break
+			}
+			c <- []byte(msg.Payload)
+		}
+		pubsub.Close()
+		time.Sleep(time.Second)
+	}
+}
@@ -8,4 +8,6 @@ import (
 type Context struct {
 	User     *sdk.User
 	WorkerID string
+	// Token authentifying the request, its scopes restrict user permissions
+	Token *sdk.APIToken
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/ovh/cds/engine/api/keys"
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/api/sanity"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
@@ -39,16 +40,22 @@ func getEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	projectKey := vars["key"]
 	environmentName := vars["permEnvironmentName"]
 
-	environment, err := environment.LoadEnvironmentByName(db, projectKey, environmentName)
+	env, err := environment.LoadEnvironmentByName(db, projectKey, environmentName)
 	if err != nil {
 		log.Warning("getEnvironmentHandler: Cannot load environment %s for project %s from db: %s\n", environmentName, projectKey, err)
 		WriteError(w, r, err)
 		return
 	}
 
-	environment.Permission = permission.EnvironmentPermission(environment.ID, c.User)
+	env.Permission = permission.EnvironmentPermission(env.ID, c.User)
 
-	WriteJSON(w, r, environment, http.StatusOK)
+	if err := environment.LoadLocks(db, env); err != nil {
+		log.Warning("getEnvironmentHandler: Cannot load locks of environment %s: %s\n", environmentName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, env, http.StatusOK)
 }
 
 func updateEnvironmentsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
@@ -120,16 +127,28 @@ func updateEnvironmentsHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		}
 		found := false
 		for _, eg := range env.EnvironmentGroups {
-			if eg.Permission == permission.PermissionReadWriteExecute {
+			if role.Can(eg.Permission, sdk.RoleActionManageGroups) {
 				found = true
 			}
 		}
 		if !found {
-			log.Warning("updateEnvironmentsHandler> Cannot have an environment (%s) without group with write permission\n", env.Name)
+			log.Warning("updateEnvironmentsHandler> Cannot have an environment (%s) without group able to manage groups\n", env.Name)
 			WriteError(w, r, sdk.ErrGroupNeedWrite)
 			return
 		}
 
+		current, err := group.LoadAllEnvironmentGroupByAction(tx, env.ID, sdk.RoleActionRead)
+		if err != nil {
+			log.Warning("updateEnvironmentsHandler> Cannot load groups of environment %s: %s\n", env.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+		if err := checkGivenRoles(tx, c.User, env.EnvironmentGroups, current, permission.EnvironmentRoles(env.ID, c.User)); err != nil {
+			log.Warning("updateEnvironmentsHandler> %s cannot give roles on environment %s: %s\n", c.User.Username, env.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+
 		err = group.DeleteAllGroupFromEnvironment(tx, env.ID)
 		if err != nil {
 			log.Warning("updateEnvironmentsHandler> Cannot delete groups from environment %s for update: %s\n", env.Name, err)
@@ -8,6 +8,7 @@ import (
 	"github.com/lib/pq"
 
 	"github.com/ovh/cds/engine/api/artifact"
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
@@ -20,14 +21,14 @@ func LoadEnvironments(db database.Querier, projectKey string, loadDeps bool, use
 	var rows *sql.Rows
 	var err error
 	if user.Admin {
-		query := `SELECT environment.id, environment.name, environment.last_modified
+		query := `SELECT environment.id, environment.name, environment.last_modified, environment.lock_policy
 		  FROM environment
 		  JOIN project ON project.id = environment.project_id
 		  WHERE project.projectKey = $1
 		  ORDER by environment.name`
 		rows, err = db.Query(query, projectKey)
 	} else {
-		query := `SELECT distinct(environment.id), environment.name, environment.last_modified
+		query := `SELECT distinct(environment.id), environment.name, environment.last_modified, environment.lock_policy
 			  FROM environment
 			  JOIN environment_group ON environment.id = environment_group.environment_id
 			  JOIN group_user ON environment_group.group_id = group_user.group_id
@@ -49,11 +50,16 @@ func LoadEnvironments(db database.Querier, projectKey string, loadDeps bool, use
 	for rows.Next() {
 		var env sdk.Environment
 		var lastModified time.Time
-		err = rows.Scan(&env.ID, &env.Name, &lastModified)
+		var lockPolicy sql.NullString
+		err = rows.Scan(&env.ID, &env.Name, &lastModified, &lockPolicy)
 		env.LastModified = lastModified.Unix()
 		if err != nil {
 			return envs, err
 		}
+		env.LockPolicy, err = lockPolicyFromDB(lockPolicy)
+		if err != nil {
+			return envs, err
+		}
 		envs = append(envs, env)
 	}
 	rows.Close()
@@ -72,17 +78,22 @@ func LoadEnvironments(db database.Querier, projectKey string, loadDeps bool, use
 // LoadEnvironmentByName load the given environment
 func LoadEnvironmentByName(db database.Querier, projectKey, envName string) (*sdk.Environment, error) {
 	var env sdk.Environment
-	query := `SELECT environment.id, environment.name
+	var lockPolicy sql.NullString
+	query := `SELECT environment.id, environment.name, environment.lock_policy
 		  FROM environment
 		  JOIN project ON project.id = environment.project_id
 		  WHERE project.projectKey = $1 AND environment.name = $2`
-	err := db.QueryRow(query, projectKey, envName).Scan(&env.ID, &env.Name)
+	err := db.QueryRow(query, projectKey, envName).Scan(&env.ID, &env.Name, &lockPolicy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &env, sdk.ErrNoEnvironment
 		}
 		return &env, err
 	}
+	env.LockPolicy, err = lockPolicyFromDB(lockPolicy)
+	if err != nil {
+		return &env, err
+	}
 	err = loadDependencies(db, &env)
 	return &env, err
 }
@@ -179,15 +190,31 @@ func DeleteEnvironment(db *sql.Tx, environmentID int64) error {
 	}
 
 	// Delete builds
-	query = `DELETE FROM build_log where action_build_id IN (
-			SELECT id FROM action_build WHERE pipeline_build_id IN (
-					SELECT id FROM pipeline_build WHERE environment_id = $1
-			))`
-	_, err = db.Exec(query, environmentID)
+	query = `SELECT id FROM action_build WHERE pipeline_build_id IN (
+			SELECT id FROM pipeline_build WHERE environment_id = $1
+	)`
+	rows, err := db.Query(query, environmentID)
 	if err != nil {
-		log.Warning("DeleteEnvironment> Cannot delete environment related builds: %s\n", err)
+		log.Warning("DeleteEnvironment> Cannot load environment related builds: %s\n", err)
 		return err
 	}
+	var actionBuildIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		actionBuildIDs = append(actionBuildIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range actionBuildIDs {
+		if err := build.DeleteBuildLogs(db, id); err != nil {
+			log.Warning("DeleteEnvironment> Cannot delete environment related build logs: %s\n", err)
+			return err
+		}
+	}
 
 	query = `DELETE FROM action_build WHERE pipeline_build_id
 			IN (SELECT id FROM pipeline_build WHERE environment_id = $1)`
@@ -206,7 +233,7 @@ func DeleteEnvironment(db *sql.Tx, environmentID int64) error {
 
 	// Delete artifacts related to this environments
 	query = `SELECT id FROM artifact where environment_id = $1`
-	rows, err := db.Query(query, environmentID)
+	rows, err = db.Query(query, environmentID)
 	if err != nil {
 		return fmt.Errorf("DeleteEnvironment> Cannot load related artifacts: %s", err)
 	}
@@ -0,0 +1,219 @@
+package environment
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// QueuedBuild is a pipeline build waiting for the lock of its environment
+type QueuedBuild struct {
+	PipelineBuildID int64
+	EnvironmentID   int64
+	ApplicationID   int64 // application part of the lock, 0 if the whole environment is locked
+	Locked          bool  // false if the environment lock policy has been removed since the build was queued
+}
+
+// UpdateLockPolicy set how concurrent deployments are handled on an environment, nil removes the lock
+func UpdateLockPolicy(db database.Executer, envID int64, policy *sdk.EnvironmentLockPolicy) error {
+	lockPolicy, err := lockPolicyToDB(policy)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE environment SET lock_policy = $1, last_modified = current_timestamp WHERE id = $2`
+	_, err = db.Exec(query, lockPolicy, envID)
+	return err
+}
+
+// LockHolder returns the id of the running pipeline build holding the lock, 0 if the lock is free.
+// Environment row is selected for update first, so concurrent builds wait for the transaction
+// to decide even when there is no lock row yet.
+func LockHolder(db database.Querier, envID, appID int64) (int64, error) {
+	var id int64
+	query := `SELECT id FROM environment WHERE id = $1 FOR UPDATE`
+	if err := db.QueryRow(query, envID).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	query = `SELECT environment_lock.pipeline_build_id
+		FROM environment_lock
+		JOIN pipeline_build ON pipeline_build.id = environment_lock.pipeline_build_id
+		WHERE environment_lock.environment_id = $1 AND environment_lock.application_id = $2
+		AND pipeline_build.status IN ($3, $4)`
+
+	var pbID int64
+	err := db.QueryRow(query, envID, appID, sdk.StatusBuilding.String(), sdk.StatusWaitingApproval.String()).Scan(&pbID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return pbID, err
+}
+
+// Lock gives the lock of the environment, or of the application on the environment, to a pipeline build
+func Lock(db database.Executer, envID, appID, pbID int64) error {
+	if err := Unlock(db, envID, appID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO environment_lock (environment_id, application_id, pipeline_build_id, locked) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(query, envID, appID, pbID, time.Now())
+	return err
+}
+
+// Unlock releases the lock of the environment, or of the application on the environment
+func Unlock(db database.Executer, envID, appID int64) error {
+	query := `DELETE FROM environment_lock WHERE environment_id = $1 AND application_id = $2`
+	_, err := db.Exec(query, envID, appID)
+	return err
+}
+
+// ReleaseLocks releases locks held by pipeline builds which are not running anymore
+func ReleaseLocks(db database.Executer) error {
+	query := `DELETE FROM environment_lock WHERE NOT EXISTS (
+		SELECT 1 FROM pipeline_build
+		WHERE pipeline_build.id = environment_lock.pipeline_build_id AND pipeline_build.status IN ($1, $2)
+	)`
+	_, err := db.Exec(query, sdk.StatusBuilding.String(), sdk.StatusWaitingApproval.String())
+	return err
+}
+
+// LoadQueuedBuilds returns pipeline builds waiting for an environment lock, oldest first
+func LoadQueuedBuilds(db database.Querier) ([]QueuedBuild, error) {
+	query := `SELECT pb.id, pb.environment_id, pb.application_id, environment.lock_policy
+		FROM pipeline_build pb
+		JOIN environment ON environment.id = pb.environment_id
+		WHERE pb.status = $1
+		ORDER BY pb.id`
+	rows, err := db.Query(query, sdk.StatusWaiting.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queued []QueuedBuild
+	for rows.Next() {
+		var q QueuedBuild
+		var lockPolicy sql.NullString
+		if err := rows.Scan(&q.PipelineBuildID, &q.EnvironmentID, &q.ApplicationID, &lockPolicy); err != nil {
+			return nil, err
+		}
+		policy, err := lockPolicyFromDB(lockPolicy)
+		if err != nil {
+			return nil, err
+		}
+		q.Locked = policy != nil
+		q.ApplicationID = policy.LockApplicationID(q.ApplicationID)
+		queued = append(queued, q)
+	}
+	return queued, rows.Err()
+}
+
+// LoadLocks fills environment locks with the pipeline builds holding them and the ones queued behind
+func LoadLocks(db database.Querier, env *sdk.Environment) error {
+	env.Locks = nil
+	locks := map[int64]*sdk.EnvironmentLock{}
+	var keys []int64
+	lockOf := func(appID int64, appName string) *sdk.EnvironmentLock {
+		key := env.LockPolicy.LockApplicationID(appID)
+		l, ok := locks[key]
+		if !ok {
+			l = &sdk.EnvironmentLock{}
+			if key != 0 {
+				l.Application = appName
+			}
+			locks[key] = l
+			keys = append(keys, key)
+		}
+		return l
+	}
+
+	query := `SELECT pb.application_id, pb.id, application.name, pipeline.name, pb.build_number, pb.status, environment_lock.locked
+		FROM environment_lock
+		JOIN pipeline_build pb ON pb.id = environment_lock.pipeline_build_id
+		JOIN application ON application.id = pb.application_id
+		JOIN pipeline ON pipeline.id = pb.pipeline_id
+		WHERE environment_lock.environment_id = $1 AND pb.status IN ($2, $3)
+		ORDER BY environment_lock.locked`
+	rows, err := db.Query(query, env.ID, sdk.StatusBuilding.String(), sdk.StatusWaitingApproval.String())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var appID int64
+		b, err := scanLockBuild(rows, &appID)
+		if err != nil {
+			return err
+		}
+		lockOf(appID, b.Application).Holder = b
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	query = `SELECT pb.application_id, pb.id, application.name, pipeline.name, pb.build_number, pb.status, pb.start
+		FROM pipeline_build pb
+		JOIN application ON application.id = pb.application_id
+		JOIN pipeline ON pipeline.id = pb.pipeline_id
+		WHERE pb.environment_id = $1 AND pb.status = $2
+		ORDER BY pb.id`
+	rows, err = db.Query(query, env.ID, sdk.StatusWaiting.String())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var appID int64
+		b, err := scanLockBuild(rows, &appID)
+		if err != nil {
+			return err
+		}
+		l := lockOf(appID, b.Application)
+		l.Queue = append(l.Queue, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		env.Locks = append(env.Locks, *locks[k])
+	}
+	return nil
+}
+
+func scanLockBuild(s database.Scanner, appID *int64) (*sdk.EnvironmentLockBuild, error) {
+	var b sdk.EnvironmentLockBuild
+	var status string
+	if err := s.Scan(appID, &b.PipelineBuildID, &b.Application, &b.Pipeline, &b.BuildNumber, &status, &b.Since); err != nil {
+		return nil, err
+	}
+	b.Status = sdk.StatusFromString(status)
+	return &b, nil
+}
+
+func lockPolicyToDB(p *sdk.EnvironmentLockPolicy) (sql.NullString, error) {
+	if p == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func lockPolicyFromDB(s sql.NullString) (*sdk.EnvironmentLockPolicy, error) {
+	if !s.Valid || s.String == "" {
+		return nil, nil
+	}
+	p := &sdk.EnvironmentLockPolicy{}
+	if err := json.Unmarshal([]byte(s.String), p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ovh/cds/engine/api/environment"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -53,8 +54,14 @@ func updateGroupRoleOnEnvironmentHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if groupEnvironment.Permission != permission.PermissionReadWriteExecute {
-		permissions, err := group.LoadAllEnvironmentGroupByRole(db, env.ID, permission.PermissionReadWriteExecute)
+	if err := permission.CanGiveRole(db, c.User, groupEnvironment.Permission, permission.EnvironmentRoles(env.ID, c.User)); err != nil {
+		log.Warning("updateGroupRoleOnEnvironmentHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupEnvironment.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if !role.Can(groupEnvironment.Permission, sdk.RoleActionManageGroups) {
+		permissions, err := group.LoadAllEnvironmentGroupByAction(db, env.ID, sdk.RoleActionManageGroups)
 		if err != nil {
 			log.Warning("updateGroupRoleOnEnvironmentHandler: Cannot load group for environment %s :%s", envName, err)
 			WriteError(w, r, err)
@@ -114,6 +121,12 @@ func addGroupInEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *sq
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupPermission.Permission, permission.EnvironmentRoles(env.ID, c.User)); err != nil {
+		log.Warning("addGroupInEnvironmentHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupPermission.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	alreadyAdded, err := group.IsInEnvironment(db, env.ID, g.ID)
 	if err != nil {
 		log.Warning("addGroupInEnvironmentHandler> Cannot check if group is in env: %s\n", err)
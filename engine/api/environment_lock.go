@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func updateEnvironmentLockPolicyHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	environmentName := vars["permEnvironmentName"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var policy *sdk.EnvironmentLockPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !policy.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidLockPolicy)
+		return
+	}
+
+	env, err := environment.LoadEnvironmentByName(db, projectKey, environmentName)
+	if err != nil {
+		log.Warning("updateEnvironmentLockPolicyHandler> Cannot load environment %s: %s\n", environmentName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := environment.UpdateLockPolicy(db, env.ID, policy); err != nil {
+		log.Warning("updateEnvironmentLockPolicyHandler> Cannot update lock policy of environment %s: %s\n", environmentName, err)
+		WriteError(w, r, err)
+		return
+	}
+	env.LockPolicy = policy
+
+	WriteJSON(w, r, env, http.StatusOK)
+}
+
+func releaseEnvironmentLockHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	environmentName := vars["permEnvironmentName"]
+
+	if !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Warning("releaseEnvironmentLockHandler> Cannot parse form: %s\n", err)
+		WriteError(w, r, sdk.ErrUnknownError)
+		return
+	}
+	appName := r.Form.Get("application")
+
+	env, err := environment.LoadEnvironmentByName(db, projectKey, environmentName)
+	if err != nil {
+		log.Warning("releaseEnvironmentLockHandler> Cannot load environment %s: %s\n", environmentName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	var appID int64
+	if appName != "" {
+		app, err := application.LoadApplicationByName(db, projectKey, appName)
+		if err != nil {
+			log.Warning("releaseEnvironmentLockHandler> Cannot load application %s: %s\n", appName, err)
+			WriteError(w, r, err)
+			return
+		}
+		appID = app.ID
+	}
+
+	// queued builds get the lock on next scheduling, even if the previous holder is still running
+	if err := environment.Unlock(db, env.ID, env.LockPolicy.LockApplicationID(appID)); err != nil {
+		log.Warning("releaseEnvironmentLockHandler> Cannot release lock of environment %s: %s\n", environmentName, err)
+		WriteError(w, r, err)
+		return
+	}
+	log.Notice("releaseEnvironmentLockHandler> %s released lock of environment %s/%s (application: %s)\n", c.User.Username, projectKey, environmentName, appName)
+
+	w.WriteHeader(http.StatusOK)
+}
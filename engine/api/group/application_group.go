@@ -2,19 +2,20 @@ package group
 
 import (
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/sdk"
 )
 
-// LoadAllApplicationGroupByRole load all group for the given application and role
-func LoadAllApplicationGroupByRole(db database.Querier, applicationID int64, role int) ([]sdk.GroupPermission, error) {
+// LoadAllApplicationGroupByAction load all group whose role on the given application grants action
+func LoadAllApplicationGroupByAction(db database.Querier, applicationID int64, action string) ([]sdk.GroupPermission, error) {
 	groupsPermission := []sdk.GroupPermission{}
 	query := `
 		SELECT application_group.group_id, application_group.role
 		FROM application_group
 		JOIN application ON application_group.application_id = application.id
-		WHERE application.id = $1 AND role = $2;
+		WHERE application.id = $1;
 	`
-	rows, err := db.Query(query, applicationID, role)
+	rows, err := db.Query(query, applicationID)
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +23,9 @@ func LoadAllApplicationGroupByRole(db database.Querier, applicationID int64, rol
 	for rows.Next() {
 		var gPermission sdk.GroupPermission
 		rows.Scan(&gPermission.Group.ID, &gPermission.Permission)
-		groupsPermission = append(groupsPermission, gPermission)
+		if role.Can(gPermission.Permission, action) {
+			groupsPermission = append(groupsPermission, gPermission)
+		}
 	}
 	return groupsPermission, nil
 }
@@ -2,19 +2,20 @@ package group
 
 import (
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/sdk"
 )
 
-// LoadAllEnvironmentGroupByRole load all group for the given environment and role
-func LoadAllEnvironmentGroupByRole(db database.Querier, environmentID int64, role int) ([]sdk.GroupPermission, error) {
+// LoadAllEnvironmentGroupByAction load all group whose role on the given environment grants action
+func LoadAllEnvironmentGroupByAction(db database.Querier, environmentID int64, action string) ([]sdk.GroupPermission, error) {
 	groupsPermission := []sdk.GroupPermission{}
 	query := `
 		SELECT environment_group.group_id, environment_group.role
 		FROM environment_group
 		JOIN environment ON environment_group.environment_id = environment.id
-		WHERE environment.id = $1 AND role = $2;
+		WHERE environment.id = $1;
 	`
-	rows, err := db.Query(query, environmentID, role)
+	rows, err := db.Query(query, environmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +23,9 @@ func LoadAllEnvironmentGroupByRole(db database.Querier, environmentID int64, rol
 	for rows.Next() {
 		var gPermission sdk.GroupPermission
 		rows.Scan(&gPermission.Group.ID, &gPermission.Permission)
-		groupsPermission = append(groupsPermission, gPermission)
+		if role.Can(gPermission.Permission, action) {
+			groupsPermission = append(groupsPermission, gPermission)
+		}
 	}
 	return groupsPermission, nil
 }
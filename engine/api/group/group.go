@@ -237,6 +237,42 @@ func InsertUserInGroup(db database.Executer, groupID, userID int64, admin bool)
 	return err
 }
 
+// InsertOIDCUserInGroup insert user in group as a member granted by an OpenID Connect groups claim
+func InsertOIDCUserInGroup(db database.Executer, groupID, userID int64) error {
+	query := `INSERT INTO group_user (group_id,user_id,group_admin,from_oidc) VALUES($1,$2,$3,$4)`
+	_, err := db.Exec(query, groupID, userID, false, true)
+	return err
+}
+
+// LoadOIDCGroupByUser return groups user was added to by an OpenID Connect groups claim
+func LoadOIDCGroupByUser(db database.Querier, userID int64) ([]sdk.Group, error) {
+	groups := []sdk.Group{}
+
+	query := `
+		SELECT "group".id, "group".name
+		FROM "group"
+		JOIN "group_user" ON "group".id = "group_user".group_id
+		WHERE "group_user".user_id = $1 AND "group_user".from_oidc = true
+		`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		g := sdk.NewGroup(name)
+		g.ID = id
+		groups = append(groups, *g)
+	}
+	return groups, nil
+}
+
 // DeleteGroupUserByGroup Delete all user from a group
 func DeleteGroupUserByGroup(db database.Executer, group *sdk.Group) error {
 	query := `DELETE FROM group_user WHERE group_id=$1`
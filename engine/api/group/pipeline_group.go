@@ -2,19 +2,20 @@ package group
 
 import (
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/sdk"
 )
 
-// LoadAllPipelineGroupByRole load all group for the given pipeline and role
-func LoadAllPipelineGroupByRole(db database.Querier, pipelineID int64, role int) ([]sdk.GroupPermission, error) {
+// LoadAllPipelineGroupByAction load all group whose role on the given pipeline grants action
+func LoadAllPipelineGroupByAction(db database.Querier, pipelineID int64, action string) ([]sdk.GroupPermission, error) {
 	groupsPermission := []sdk.GroupPermission{}
 	query := `
 		SELECT pipeline_group.group_id, pipeline_group.role
 		FROM pipeline_group
 		JOIN pipeline ON pipeline_group.pipeline_id = pipeline.id
-		WHERE pipeline.id = $1 AND role = $2;
+		WHERE pipeline.id = $1;
 	`
-	rows, err := db.Query(query, pipelineID, role)
+	rows, err := db.Query(query, pipelineID)
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +23,9 @@ func LoadAllPipelineGroupByRole(db database.Querier, pipelineID int64, role int)
 	for rows.Next() {
 		var gPermission sdk.GroupPermission
 		rows.Scan(&gPermission.Group.ID, &gPermission.Permission)
-		groupsPermission = append(groupsPermission, gPermission)
+		if role.Can(gPermission.Permission, action) {
+			groupsPermission = append(groupsPermission, gPermission)
+		}
 	}
 	return groupsPermission, nil
 }
@@ -2,19 +2,20 @@ package group
 
 import (
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/sdk"
 )
 
-// LoadAllProjectGroupByRole load all group for the given project and role
-func LoadAllProjectGroupByRole(db database.Querier, projectID int64, role int) ([]sdk.GroupPermission, error) {
+// LoadAllProjectGroupByAction load all group whose role on the given project grants action
+func LoadAllProjectGroupByAction(db database.Querier, projectID int64, action string) ([]sdk.GroupPermission, error) {
 	groupsPermission := []sdk.GroupPermission{}
 	query := `
 		SELECT project_group.group_id, project_group.role
 		FROM project_group
 		JOIN project ON project_group.project_id = project.id
-		WHERE project.id = $1 AND role = $2;
+		WHERE project.id = $1;
 	`
-	rows, err := db.Query(query, projectID, role)
+	rows, err := db.Query(query, projectID)
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +23,9 @@ func LoadAllProjectGroupByRole(db database.Querier, projectID int64, role int) (
 	for rows.Next() {
 		var gPermission sdk.GroupPermission
 		rows.Scan(&gPermission.Group.ID, &gPermission.Permission)
-		groupsPermission = append(groupsPermission, gPermission)
+		if role.Can(gPermission.Permission, action) {
+			groupsPermission = append(groupsPermission, gPermission)
+		}
 	}
 	return groupsPermission, nil
 }
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/hatchery"
+	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -58,3 +61,60 @@ func refreshHatcheryHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 		return
 	}
 }
+
+// bookActionBuildHandler books for an hatchery a waiting action build the worker model can run
+func bookActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+
+	hatcheryID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		log.Warning("bookActionBuildHandler> hatchery id is no integer '%s': %s\n", vars["id"], err)
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+	modelID, err := strconv.ParseInt(vars["model"], 10, 64)
+	if err != nil {
+		log.Warning("bookActionBuildHandler> model id is no integer '%s': %s\n", vars["model"], err)
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	// Only the owner of the hatchery can book action builds for it
+	if err := hatchery.CheckOwner(db, hatcheryID, c.User.ID); err != nil {
+		log.Warning("bookActionBuildHandler> %s cannot book for hatchery %d: %s\n", c.User.Username, hatcheryID, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	m, err := worker.LoadWorkerModelByID(db, modelID)
+	if err != nil {
+		if err != sdk.ErrNoWorkerModel {
+			log.Warning("bookActionBuildHandler> cannot load worker model %d: %s\n", modelID, err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	queue, err := build.LoadUserWaitingQueue(db, c.User)
+	if err != nil {
+		log.Warning("bookActionBuildHandler> cannot load queue: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	match := func(b sdk.ActionBuild) bool {
+		return worker.ModelCanRun(m.Name, b.Requirements, m.Capabilities)
+	}
+	ab, err := build.BookActionBuild(db, hatcheryID, queue, match)
+	if err != nil {
+		log.Warning("bookActionBuildHandler> cannot book action build: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if ab == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	WriteJSON(w, r, ab, http.StatusOK)
+}
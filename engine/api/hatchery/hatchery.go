@@ -117,6 +117,22 @@ func Exists(db *sql.DB, id int64) error {
 	return db.QueryRow(query, id).Scan(&id)
 }
 
+// CheckOwner returns sdk.ErrForbidden if hatchery does not belong to given user
+func CheckOwner(db *sql.DB, id int64, userID int64) error {
+	query := `SELECT owner_id FROM hatchery WHERE id = $1`
+	var ownerID int64
+	if err := db.QueryRow(query, id).Scan(&ownerID); err != nil {
+		if err == sql.ErrNoRows {
+			return sdk.ErrNotFound
+		}
+		return err
+	}
+	if ownerID != userID {
+		return sdk.ErrForbidden
+	}
+	return nil
+}
+
 // LoadDeadHatcheries load hatchery with refresh last beat > timeout
 func LoadDeadHatcheries(db *sql.DB, timeout float64) ([]Hatchery, error) {
 	var hatcheries []Hatchery
@@ -18,6 +18,7 @@ import (
 	"github.com/ovh/cds/engine/api/hook"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/repositoriesmanager/repogitlab"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -47,6 +48,20 @@ func receiveHook(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.
 		UID:        r.FormValue("uid"),
 	}
 
+	//Gitlab does not expand variables in hook url, data are in the payload
+	if r.Header.Get("X-Gitlab-Event") == repogitlab.PushHookEvent {
+		var e repogitlab.PushEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			log.Warning("receiveHook> cannot parse gitlab push event: %s\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rh.Branch = e.Branch()
+		rh.Hash = e.After
+		rh.Author = e.UserUsername
+		rh.Message = e.Type()
+	}
+
 	if db == nil {
 		hook.Recovery(rh, fmt.Errorf("database not available"))
 		WriteError(w, r, err)
@@ -17,6 +17,8 @@ import (
 	"github.com/ovh/cds/engine/api/archivist"
 	"github.com/ovh/cds/engine/api/artifact"
 	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/buildcache"
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/hatchery"
@@ -32,6 +34,7 @@ import (
 	"github.com/ovh/cds/engine/api/stats"
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
 )
 
 var startup time.Time
@@ -55,6 +58,14 @@ var mainCmd = &cobra.Command{
 			log.Fatalf("SMTP configuration error: %s\n", err)
 		}
 
+		objectstore.SetS3Options(objectstore.S3Options{
+			Bucket:      viper.GetString("artifact_s3_bucket"),
+			Region:      viper.GetString("artifact_s3_region"),
+			PartSize:    int64(viper.GetInt("artifact_s3_part_size")) * 1024 * 1024,
+			SSE:         viper.GetString("artifact_s3_sse"),
+			SSEKMSKeyID: viper.GetString("artifact_s3_sse_kms_key_id"),
+			PresignTTL:  time.Duration(viper.GetInt("artifact_s3_presign_ttl")) * time.Second,
+		})
 		if err := objectstore.Initialize(
 			viper.GetString("artifact_mode"),
 			viper.GetString("artifact_address"),
@@ -64,6 +75,13 @@ var mainCmd = &cobra.Command{
 			log.Fatalf("Cannot initialize storage: %s\n", err)
 		}
 
+		if err := build.InitializeLogStore(
+			viper.GetString("build_log_mode"),
+			viper.GetInt("build_log_flush_lines"),
+			time.Duration(viper.GetInt("build_log_flush_interval"))*time.Second); err != nil {
+			log.Fatalf("Cannot initialize build log store: %s\n", err)
+		}
+
 		db, err := database.Init()
 		if err != nil {
 			log.Warning("Cannot connect to database: %s\n", err)
@@ -77,6 +95,10 @@ var mainCmd = &cobra.Command{
 				log.Critical("Cannot setup builtin Artifact actions: %s\n", err)
 			}
 
+			if err = buildcache.CreateBuiltinCacheActions(db); err != nil {
+				log.Critical("Cannot setup builtin Cache actions: %s\n", err)
+			}
+
 			if err = worker.CreateBuiltinActions(db); err != nil {
 				log.Critical("Cannot setup builtin actions: %s\n", err)
 			}
@@ -140,8 +162,8 @@ var mainCmd = &cobra.Command{
 		// Initialize the auth driver
 		var authMode string
 		var authOptions interface{}
-		switch viper.GetBool("ldap_enable") {
-		case true:
+		switch {
+		case viper.GetBool("ldap_enable"):
 			authMode = "ldap"
 			authOptions = auth.LDAPConfig{
 				Host:         viper.GetString("ldap_host"),
@@ -151,6 +173,15 @@ var mainCmd = &cobra.Command{
 				SSL:          viper.GetBool("ldap_ssl"),
 				UserFullname: viper.GetString("ldap_user_fullname"),
 			}
+		case viper.GetBool("oidc_enable"):
+			authMode = "oidc"
+			authOptions = auth.OIDCConfig{
+				Issuer:       viper.GetString("oidc_issuer"),
+				ClientID:     viper.GetString("oidc_client_id"),
+				ClientSecret: viper.GetString("oidc_client_secret"),
+				RedirectURL:  viper.GetString("oidc_redirect_url"),
+				GroupsClaim:  viper.GetString("oidc_groups_claim"),
+			}
 		default:
 			authMode = "local"
 		}
@@ -167,8 +198,17 @@ var mainCmd = &cobra.Command{
 		cache.Initialize(viper.GetString("cache"), viper.GetString("redis_host"), viper.GetString("redis_password"), viper.GetInt("cache_ttl"))
 
 		go archivist.Archive(viper.GetInt("interval_archive_seconds"), viper.GetInt("archived_build_hours"))
+		go artifact.Purge(viper.GetInt("interval_artifact_purge_seconds"))
+
+		buildcache.Initialize(int64(viper.GetInt("build_cache_max_size"))*1024*1024,
+			int64(viper.GetInt("build_cache_project_quota"))*1024*1024,
+			time.Duration(viper.GetInt("build_cache_ttl_days"))*24*time.Hour)
+		go build.DispatchRoutine(time.Second)
+		go buildcache.EvictRoutine(viper.GetInt("interval_build_cache_eviction_seconds"))
 		go scheduler.Schedule()
 		go pipeline.AWOLPipelineKiller()
+		go pipeline.TimeoutKiller()
+		go build.BuildEventsRoutine()
 		//go pipeline.HistoryCleaningRoutine(db)
 		go worker.Heartbeat()
 		go hatchery.Heartbeat()
@@ -225,9 +265,14 @@ func (router *Router) init() {
 	router.Handle("/group/{permGroupName}/user/{user}", DELETE(removeUserFromGroupHandler))
 	router.Handle("/group/{permGroupName}/user/{user}/admin", POST(setUserGroupAdminHandler), DELETE(removeUserGroupAdminHandler))
 
+	// Role
+	router.Handle("/role", GET(getRolesHandler), POST(addRoleHandler))
+	router.Handle("/role/{name}", NeedAdmin(true), PUT(updateRoleHandler), DELETE(deleteRoleHandler))
+
 	// Hatchery
 	router.Handle("/hatchery", POST(registerHatchery))
 	router.Handle("/hatchery/{id}", PUT(refreshHatcheryHandler))
+	router.Handle("/hatchery/{id}/book/{model}", POST(bookActionBuildHandler))
 
 	// Hooks
 	router.Handle("/hook", Auth(false) /* Public handler called by third parties */, POST(receiveHook))
@@ -246,6 +291,7 @@ func (router *Router) init() {
 	router.Handle("/mon/building/{hash}", GET(getPipelineBuildingCommit))
 	router.Handle("/mon/warning", GET(getUserWarnings))
 	router.Handle("/mon/lastupdates", GET(getUserLastUpdates))
+	router.Handle("/mon/artifact/expired", GET(getExpiredArtifactsHandler))
 
 	// Notif builtin from worker
 	router.Handle("/notif/{actionBuildId}", POST(notifHandler))
@@ -253,12 +299,12 @@ func (router *Router) init() {
 	// Project
 	router.Handle("/project", GET(getProjects), POST(addProject))
 	router.Handle("/project/{permProjectKey}", GET(getProject), PUT(updateProject), DELETE(deleteProject))
-	router.Handle("/project/{permProjectKey}/group", POST(addGroupInProject), PUT(updateGroupsInProject))
-	router.Handle("/project/{permProjectKey}/group/{group}", PUT(updateGroupRoleOnProjectHandler), DELETE(deleteGroupFromProjectHandler))
-	router.Handle("/project/{permProjectKey}/variable", GET(getVariablesInProjectHandler), PUT(updateVariablesInProjectHandler))
+	router.Handle("/project/{permProjectKey}/group", NeedAction(sdk.RoleActionManageGroups), POST(addGroupInProject), PUT(updateGroupsInProject))
+	router.Handle("/project/{permProjectKey}/group/{group}", NeedAction(sdk.RoleActionManageGroups), PUT(updateGroupRoleOnProjectHandler), DELETE(deleteGroupFromProjectHandler))
+	router.Handle("/project/{permProjectKey}/variable", NeedAction(sdk.RoleActionManageVariables), GET(getVariablesInProjectHandler), PUT(updateVariablesInProjectHandler))
 	router.Handle("/project/{key}/variable/audit", GET(getVariablesAuditInProjectnHandler))
-	router.Handle("/project/{key}/variable/audit/{auditID}", PUT(restoreProjectVariableAuditHandler))
-	router.Handle("/project/{permProjectKey}/variable/{name}", POST(addVariableInProjectHandler), PUT(updateVariableInProjectHandler), DELETE(deleteVariableFromProjectHandler))
+	router.Handle("/project/{key}/variable/audit/{auditID}", NeedAction(sdk.RoleActionManageVariables), PUT(restoreProjectVariableAuditHandler))
+	router.Handle("/project/{permProjectKey}/variable/{name}", NeedAction(sdk.RoleActionManageVariables), POST(addVariableInProjectHandler), PUT(updateVariableInProjectHandler), DELETE(deleteVariableFromProjectHandler))
 	router.Handle("/project/{permProjectKey}/applications", GET(getApplicationsHandler), POST(addApplicationHandler))
 
 	// Application
@@ -266,8 +312,8 @@ func (router *Router) init() {
 	router.Handle("/project/{key}/application/{permApplicationName}/branches", GET(getApplicationBranchHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/version", GET(getApplicationBranchVersionHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/clone", POST(cloneApplicationHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/group", POST(addGroupInApplicationHandler), PUT(updateGroupsInApplicationHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/group/{group}", PUT(updateGroupRoleOnApplicationHandler), DELETE(deleteGroupFromApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/group", NeedAction(sdk.RoleActionManageGroups), POST(addGroupInApplicationHandler), PUT(updateGroupsInApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/group/{group}", NeedAction(sdk.RoleActionManageGroups), PUT(updateGroupRoleOnApplicationHandler), DELETE(deleteGroupFromApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history", GET(getApplicationHistoryHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history/branch", GET(getPipelineBuildBranchHistoryHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history/env/deploy", GET(getApplicationDeployHistoryHandler))
@@ -275,29 +321,34 @@ func (router *Router) init() {
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}", POST(attachPipelineToApplicationHandler), PUT(updatePipelineToApplicationHandler), DELETE(removePipelineFromApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/notification", GET(getUserNotificationApplicationPipelineHandler), PUT(updateUserNotificationApplicationPipelineHandler), DELETE(deleteUserNotificationApplicationPipelineHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/tree", GET(getApplicationTreeHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable", GET(getVariablesInApplicationHandler), PUT(updateVariablesInApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable", NeedAction(sdk.RoleActionManageVariables), GET(getVariablesInApplicationHandler), PUT(updateVariablesInApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit", GET(getVariablesAuditInApplicationHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit/{auditID}", PUT(restoreAuditHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable/{name}", POST(addVariableInApplicationHandler), PUT(updateVariableInApplicationHandler), DELETE(deleteVariableFromApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit/{auditID}", NeedAction(sdk.RoleActionManageVariables), PUT(restoreAuditHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable/{name}", NeedAction(sdk.RoleActionManageVariables), POST(addVariableInApplicationHandler), PUT(updateVariableInApplicationHandler), DELETE(deleteVariableFromApplicationHandler))
 
 	// Pipeline
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/history", GET(getPipelineHistoryHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/log", GET(getBuildLogsHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/log/stream", GET(getBuildLogsStreamHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/test", POST(addBuildTestResultsHandler), GET(getBuildTestResultsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/variable", POST(addBuildVariableHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/action/{actionID}/log", GET(getActionBuildLogsHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/action/{actionID}/attempt", GET(getActionBuildAttemptsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}", GET(getBuildStateHandler), DELETE(deleteBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/triggered", GET(getPipelineBuildTriggeredHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/stop", POSTEXECUTE(stopPipelineBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/restart", POSTEXECUTE(restartPipelineBuildHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/approval", NeedAction(sdk.RoleActionApproveDeployment), POST(approvePipelineBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/commits", GET(getPipelineBuildCommitsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/commits", GET(getPipelineCommitsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/run", POSTEXECUTE(runPipelineHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/rollback", POSTEXECUTE(rollbackPipelineHandler))
 	router.Handle("/project/{permProjectKey}/pipeline", GET(getPipelinesHandler), POST(addPipeline))
+	router.Handle("/project/{permProjectKey}/import/pipeline", POST(importPipelineHandler))
+	router.Handle("/project/{key}/pipeline/{permPipelineKey}/export", GET(exportPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/application", GET(getApplicationUsingPipelineHandler))
-	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group", POST(addGroupInPipelineHandler), PUT(updateGroupsOnPipelineHandler))
-	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group/{group}", PUT(updateGroupRoleOnPipelineHandler), DELETE(deleteGroupFromPipelineHandler))
+	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group", NeedAction(sdk.RoleActionManageGroups), POST(addGroupInPipelineHandler), PUT(updateGroupsOnPipelineHandler))
+	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group/{group}", NeedAction(sdk.RoleActionManageGroups), PUT(updateGroupRoleOnPipelineHandler), DELETE(deleteGroupFromPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/parameter", GET(getParametersInPipelineHandler), PUT(updateParametersInPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/parameter/{name}", POST(addParameterInPipelineHandler), PUT(updateParameterInPipelineHandler), DELETE(deleteParameterFromPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}", GET(getPipelineHandler), PUT(updatePipelineHandler), DELETE(deletePipeline))
@@ -318,17 +369,25 @@ func (router *Router) init() {
 	router.Handle("/project/{permProjectKey}/environment", GET(getEnvironmentsHandler), POST(addEnvironmentHandler), PUT(updateEnvironmentsHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}", GET(getEnvironmentHandler), PUT(updateEnvironmentHandler), DELETE(deleteEnvironmentHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/audit", GET(getEnvironmentsAuditHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/lock", DELETE(releaseEnvironmentLockHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/lock/policy", PUT(updateEnvironmentLockPolicyHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/audit/{auditID}", PUT(restoreEnvironmentAuditHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}/group", POST(addGroupInEnvironmentHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}/group/{group}", PUT(updateGroupRoleOnEnvironmentHandler), DELETE(deleteGroupFromEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/group", NeedAction(sdk.RoleActionManageGroups), POST(addGroupInEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/group/{group}", NeedAction(sdk.RoleActionManageGroups), PUT(updateGroupRoleOnEnvironmentHandler), DELETE(deleteGroupFromEnvironmentHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable", GET(getVariablesInEnvironmentHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable/{name}", POST(addVariableInEnvironmentHandler), PUT(updateVariableInEnvironmentHandler), DELETE(deleteVariableFromEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable/{name}", NeedAction(sdk.RoleActionManageVariables), POST(addVariableInEnvironmentHandler), PUT(updateVariableInEnvironmentHandler), DELETE(deleteVariableFromEnvironmentHandler))
 
 	// Artifacts
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/{tag}", GET(listArtifactsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact", GET(listArtifactsBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact/{tag}", POST(uploadArtifactHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact/{tag}/link", POST(linkArtifactHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/download/{id}", GET(downloadArtifactHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/cache", GET(getBuildCachesHandler), POST(uploadBuildCacheHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/cache/match", GET(matchBuildCacheHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/cache/{id}", GET(downloadBuildCacheHandler), DELETE(deleteBuildCacheHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/artifact/retention", GET(getApplicationArtifactRetentionHandler), PUT(updateApplicationArtifactRetentionHandler))
+	router.Handle("/project/{permProjectKey}/artifact/retention", GET(getProjectArtifactRetentionHandler), PUT(updateProjectArtifactRetentionHandler))
 	router.Handle("/artifact/{hash}", Auth(false), GET(downloadArtifactDirectHandler))
 
 	// Hooks
@@ -343,8 +402,11 @@ func (router *Router) init() {
 	// Build queue
 	router.Handle("/queue", GET(getQueueHandler))
 	router.Handle("/queue/requirements/errors", POST(requirementsErrorHandler))
+	router.Handle("/queue/next", POST(nextActionBuildHandler))
 	router.Handle("/queue/{id}/take", POST(takeActionBuildHandler))
+	router.Handle("/queue/{id}/release", POST(releaseActionBuildHandler))
 	router.Handle("/queue/{id}/result", POST(addQueueResultHandler))
+	router.Handle("/queue/{id}/status", GET(getActionBuildStatusHandler))
 	router.Handle("/build/{id}/log", POST(addBuildLogHandler))
 
 	router.Handle("/variable/type", GET(getVariableTypeHandler))
@@ -388,8 +450,14 @@ func (router *Router) init() {
 	router.Handle("/user/{name}", NeedAdmin(true), GET(GetUserHandler), PUT(UpdateUserHandler), DELETE(DeleteUserHandler))
 	router.Handle("/user/{name}/confirm/{token}", Auth(false), GET(ConfirmUser))
 	router.Handle("/user/{name}/reset", Auth(false), POST(ResetUser))
+	router.Handle("/user/{name}/token", GET(getUserTokensHandler), POST(addUserTokenHandler))
+	router.Handle("/user/{name}/token/{id}", DELETE(deleteUserTokenHandler))
 	router.Handle("/user/worker/key/{expiry}", POST(generateUserKeyHandler))
 	router.Handle("/auth/mode", Auth(false), GET(AuthModeHandler))
+	router.Handle("/auth/oidc/login", Auth(false), GET(oidcLoginHandler))
+	router.Handle("/auth/oidc/callback", Auth(false), GET(oidcCallbackHandler))
+	router.Handle("/auth/oidc/device", Auth(false), POST(oidcDeviceHandler))
+	router.Handle("/auth/oidc/device/token", Auth(false), POST(oidcDeviceTokenHandler))
 
 	// Workers
 	router.Handle("/worker", Auth(false), GET(getWorkersHandler), POST(registerWorkerHandler))
@@ -401,6 +469,7 @@ func (router *Router) init() {
 	router.Handle("/worker/model/type", GET(getWorkerModelTypes))
 	router.Handle("/worker/model/{id}", PUT(updateWorkerModel), DELETE(deleteWorkerModel))
 	router.Handle("/worker/model/{id}/capability", POST(addWorkerModelCapa))
+	router.Handle("/worker/model/{id}/validation", POST(validateWorkerModelHandler))
 	router.Handle("/worker/model/capability/type", GET(getWorkerModelCapaTypes))
 	router.Handle("/worker/model/{id}/capability/{capa}", PUT(updateWorkerModelCapa), DELETE(deleteWorkerModelCapa))
 }
@@ -423,8 +492,8 @@ func init() {
 	flags.String("listen-port", "8081", "CDS Engine Listen Port")
 	viper.BindPFlag("listen_port", flags.Lookup("listen-port"))
 
-	flags.String("artifact-mode", "filesystem", "Artifact Mode: openstack or filesystem")
-	flags.String("artifact-address", "", "Artifact Adress: used with --artifact-mode=openstask")
+	flags.String("artifact-mode", "filesystem", "Artifact Mode: openstack, s3 or filesystem")
+	flags.String("artifact-address", "", "Artifact Adress: used with --artifact-mode=openstask or s3")
 	flags.String("artifact-user", "", "Artifact User: used with --artifact-mode=openstask")
 	flags.String("artifact-password", "", "Artifact Password: used with --artifact-mode=openstask")
 	flags.String("artifact-basedir", "/tmp", "Artifact Basedir: used with --artifact-mode=filesystem")
@@ -433,6 +502,25 @@ func init() {
 	viper.BindPFlag("artifact_user", flags.Lookup("artifact-user"))
 	viper.BindPFlag("artifact_password", flags.Lookup("artifact-password"))
 	viper.BindPFlag("artifact_basedir", flags.Lookup("artifact-basedir"))
+	flags.String("artifact-s3-bucket", "cds", "Artifact S3 bucket: used with --artifact-mode=s3")
+	flags.String("artifact-s3-region", "us-east-1", "Artifact S3 region: used with --artifact-mode=s3")
+	flags.Int("artifact-s3-part-size", 16, "Artifact S3 multipart upload part size in MB, minimum 5: used with --artifact-mode=s3")
+	flags.String("artifact-s3-sse", "", "Artifact S3 server side encryption: AES256 or aws:kms, used with --artifact-mode=s3")
+	flags.String("artifact-s3-sse-kms-key-id", "", "Artifact S3 KMS key: used with --artifact-s3-sse=aws:kms")
+	flags.Int("artifact-s3-presign-ttl", 0, "Artifact S3 presigned URLs lifetime in seconds, downloads are redirected to S3 if set: used with --artifact-mode=s3")
+	viper.BindPFlag("artifact_s3_bucket", flags.Lookup("artifact-s3-bucket"))
+	viper.BindPFlag("artifact_s3_region", flags.Lookup("artifact-s3-region"))
+	viper.BindPFlag("artifact_s3_part_size", flags.Lookup("artifact-s3-part-size"))
+	viper.BindPFlag("artifact_s3_sse", flags.Lookup("artifact-s3-sse"))
+	viper.BindPFlag("artifact_s3_sse_kms_key_id", flags.Lookup("artifact-s3-sse-kms-key-id"))
+	viper.BindPFlag("artifact_s3_presign_ttl", flags.Lookup("artifact-s3-presign-ttl"))
+
+	flags.String("build-log-mode", "database", "Build log storage: database or objectstore (uses --artifact-mode storage)")
+	flags.Int("build-log-flush-lines", 500, "Build log flush size: used with --build-log-mode=objectstore")
+	flags.Int("build-log-flush-interval", 5, "Build log flush interval in seconds: used with --build-log-mode=objectstore")
+	viper.BindPFlag("build_log_mode", flags.Lookup("build-log-mode"))
+	viper.BindPFlag("build_log_flush_lines", flags.Lookup("build-log-flush-lines"))
+	viper.BindPFlag("build_log_flush_interval", flags.Lookup("build-log-flush-interval"))
 
 	flags.String("db-user", "cds", "DB User")
 	flags.String("db-password", "", "DB Password")
@@ -472,6 +560,18 @@ func init() {
 	flags.Int("archived-build-hours", 24, "After n hours, build is archived")
 	viper.BindPFlag("archived_build_hours", flags.Lookup("archived-build-hours"))
 
+	flags.Int("interval-artifact-purge-seconds", 3600, "Interval of routine deleting artifacts expired according to retention rules, in seconds")
+	viper.BindPFlag("interval_artifact_purge_seconds", flags.Lookup("interval-artifact-purge-seconds"))
+
+	flags.Int("build-cache-max-size", 1024, "Maximum size of a build cache in MB, 0 for unlimited")
+	flags.Int("build-cache-project-quota", 10240, "Maximum size of build caches of a project in MB, least recently used caches are evicted beyond, 0 for unlimited")
+	flags.Int("build-cache-ttl-days", 7, "Build caches unused for this number of days are evicted, 0 to keep them")
+	flags.Int("interval-build-cache-eviction-seconds", 3600, "Interval of build cache eviction routine, in seconds")
+	viper.BindPFlag("build_cache_max_size", flags.Lookup("build-cache-max-size"))
+	viper.BindPFlag("build_cache_project_quota", flags.Lookup("build-cache-project-quota"))
+	viper.BindPFlag("build_cache_ttl_days", flags.Lookup("build-cache-ttl-days"))
+	viper.BindPFlag("interval_build_cache_eviction_seconds", flags.Lookup("interval-build-cache-eviction-seconds"))
+
 	flags.String("download-directory", "/app", "Directory prefix for cds binaries")
 	viper.BindPFlag("download_directory", flags.Lookup("download-directory"))
 
@@ -505,6 +605,24 @@ func init() {
 	flags.String("ldap-user-fullname", "{{.givenName}} {{.sn}}", "LDAP User fullname")
 	viper.BindPFlag("ldap_user_fullname", flags.Lookup("ldap-user-fullname"))
 
+	flags.Bool("oidc-enable", false, "Enable OpenID Connect Auth mode : true|false")
+	viper.BindPFlag("oidc_enable", flags.Lookup("oidc-enable"))
+
+	flags.String("oidc-issuer", "", "OpenID Connect issuer URL")
+	viper.BindPFlag("oidc_issuer", flags.Lookup("oidc-issuer"))
+
+	flags.String("oidc-client-id", "", "OpenID Connect client ID")
+	viper.BindPFlag("oidc_client_id", flags.Lookup("oidc-client-id"))
+
+	flags.String("oidc-client-secret", "", "OpenID Connect client secret")
+	viper.BindPFlag("oidc_client_secret", flags.Lookup("oidc-client-secret"))
+
+	flags.String("oidc-redirect-url", "", "OpenID Connect redirect URL, forwarding code and state to /auth/oidc/callback")
+	viper.BindPFlag("oidc_redirect_url", flags.Lookup("oidc-redirect-url"))
+
+	flags.String("oidc-groups-claim", "groups", "OpenID Connect ID token claim listing CDS groups of the user")
+	viper.BindPFlag("oidc_groups_claim", flags.Lookup("oidc-groups-claim"))
+
 	flags.String("vault-token-header", "X-Vault-Token", "Vault application header")
 	viper.BindPFlag("vault_token_header", flags.Lookup("vault-token-header"))
 
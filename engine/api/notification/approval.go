@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// SendApprovalRequest notifies users allowed to approve deployments on the pipeline build
+// environment that a stage is waiting for their approval. Authors of the build, given by
+// their ids, are not notified since they cannot approve it.
+func SendApprovalRequest(db database.QueryExecuter, pb *sdk.PipelineBuild, s *sdk.Stage, authors []int64) {
+	users, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, sdk.RoleActionApproveDeployment)
+	if err != nil {
+		log.Critical("notification.SendApprovalRequest> error while loading approvers: %s", err)
+		return
+	}
+
+	isAuthor := map[int64]bool{}
+	for _, id := range authors {
+		isAuthor[id] = true
+	}
+
+	var usernames, emails []string
+	for _, u := range users {
+		if isAuthor[u.ID] {
+			continue
+		}
+		usernames = append(usernames, u.Username)
+		if u.Email != "" {
+			emails = append(emails, u.Email)
+		}
+	}
+	if len(usernames) == 0 {
+		log.Warning("notification.SendApprovalRequest> nobody can approve stage %s of %s/%s/%s #%d\n", s.Name, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, pb.BuildNumber)
+		return
+	}
+
+	title := fmt.Sprintf("[CDS] %s/%s/%s #%d is waiting for approval", pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, pb.BuildNumber)
+	message := fmt.Sprintf("Stage %s of pipeline %s on environment %s needs %d approval(s) before running.\n\n%s/#/project/%s/application/%s/pipeline/%s/build/%d?env=%s&tab=detail\n\ncds pipeline approve %s %s %s %s %d",
+		s.Name, pb.Pipeline.Name, pb.Environment.Name, s.Approval.Approvals,
+		baseURL, pb.Pipeline.ProjectKey, pb.Application.Name, pb.Pipeline.Name, pb.BuildNumber, pb.Environment.Name,
+		pb.Pipeline.ProjectKey, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, pb.BuildNumber)
+
+	n := &sdk.Notif{
+		DateNotif:   time.Now().Unix(),
+		Status:      sdk.StatusWaitingApproval,
+		NotifType:   sdk.UserNotif,
+		Destination: "jabber",
+		Title:       title,
+		Message:     message,
+		Recipients:  usernames,
+	}
+	log.Notice("Notification[Jabber]> Send jabber notif '%s'", n.Title)
+	go post(n)
+
+	if len(emails) > 0 {
+		m := *n
+		m.Destination = "email"
+		m.Recipients = emails
+		log.Notice("Notification[Email]> Send mail notif '%s'", m.Title)
+		go SendMailNotif(&m)
+	}
+}
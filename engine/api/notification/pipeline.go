@@ -65,7 +65,7 @@ func SendPipeline(db database.QueryExecuter, pb *sdk.PipelineBuild, event sdk.No
 				}
 				//Get recipents from groups
 				if jn.SendToGroups {
-					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, permission.PermissionRead)
+					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, sdk.RoleActionRead)
 					if err != nil {
 						log.Critical("notification[Jabber].SendPipelineBuild> error while loading permission :%s", err.Error())
 						return
@@ -76,7 +76,7 @@ func SendPipeline(db database.QueryExecuter, pb *sdk.PipelineBuild, event sdk.No
 				}
 				//Get recipents from groups
 				if jn.SendToGroups {
-					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, permission.PermissionRead)
+					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, sdk.RoleActionRead)
 					if err != nil {
 						log.Critical("notification[Jabber].SendPipelineBuild> error while loading permission :%s", err.Error())
 						return
@@ -111,7 +111,7 @@ func SendPipeline(db database.QueryExecuter, pb *sdk.PipelineBuild, event sdk.No
 				}
 				//Get recipents from groups
 				if jn.SendToGroups {
-					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, permission.PermissionRead)
+					u, err := permission.ApplicationPipelineEnvironmentUsers(db, pb.Application.ID, pb.Pipeline.ID, pb.Environment.ID, sdk.RoleActionRead)
 					if err != nil {
 						log.Critical("notification[Email].SendPipelineBuild> error while loading permission :%s", err.Error())
 						return
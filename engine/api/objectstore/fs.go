@@ -113,6 +113,39 @@ func (fss *FilesystemStore) DeletePlugin(art sdk.ActionPlugin) error {
 	return os.RemoveAll(dst)
 }
 
+// StoreObject create a new file on disk in the container directory
+func (fss *FilesystemStore) StoreObject(container, name string, data io.ReadCloser) (string, error) {
+	p := path.Join(fss.basedir, container, name)
+	log.Debug("FilesystemStore.StoreObject> New object in %s\n", p)
+
+	dir, _ := filepath.Split(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, data); err != nil {
+		return "", err
+	}
+
+	return p, nil
+}
+
+// FetchObject lookup on disk for object data
+func (fss *FilesystemStore) FetchObject(container, name string) (io.ReadCloser, error) {
+	return os.Open(path.Join(fss.basedir, container, name))
+}
+
+// DeleteObject remove object data from disk
+func (fss *FilesystemStore) DeleteObject(container, name string) error {
+	return os.Remove(path.Join(fss.basedir, container, name))
+}
+
 func (fss *FilesystemStore) path(art sdk.Artifact) string {
 	dir := fmt.Sprintf("%s/%s/%s/%s", art.Project, art.Application, art.Environment, art.Pipeline)
 	return path.Join(fss.basedir, dir, art.Tag, art.Name)
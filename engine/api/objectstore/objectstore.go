@@ -66,9 +66,52 @@ func DeletePlugin(art sdk.ActionPlugin) error {
 	return fmt.Errorf("store not initialized")
 }
 
+//StoreObject stores data under the given container and name with default objectstore driver
+func StoreObject(container, name string, data io.ReadCloser) (string, error) {
+	if storage != nil {
+		return storage.StoreObject(container, name, data)
+	}
+	return "", fmt.Errorf("store not initialized")
+}
+
+//FetchObject fetches data stored under the given container and name with default objectstore driver
+func FetchObject(container, name string) (io.ReadCloser, error) {
+	if storage != nil {
+		return storage.FetchObject(container, name)
+	}
+	return nil, fmt.Errorf("store not initialized")
+}
+
+//DeleteObject deletes data stored under the given container and name with default objectstore driver
+func DeleteObject(container, name string) error {
+	if storage != nil {
+		return storage.DeleteObject(container, name)
+	}
+	return fmt.Errorf("store not initialized")
+}
+
+//ArtifactURL returns a temporary URL to download an artifact directly from the storage backend,
+//or an empty string if default objectstore driver cannot give one
+func ArtifactURL(art sdk.Artifact) (string, error) {
+	if s, ok := storage.(URLSigner); ok {
+		return s.ArtifactURL(art)
+	}
+	return "", nil
+}
+
+//ObjectURL returns a temporary URL to download an object directly from the storage backend,
+//or an empty string if default objectstore driver cannot give one
+func ObjectURL(container, name string) (string, error) {
+	if s, ok := storage.(URLSigner); ok {
+		return s.ObjectURL(container, name)
+	}
+	return "", nil
+}
+
 // Driver allows artifact to be stored and retrieve the same way to any backend
 // - Openstack ObjectStore
 // - Filesystem
+// - S3
 type Driver interface {
 	Status() string
 	StoreArtifact(art sdk.Artifact, data io.ReadCloser) (string, error)
@@ -77,6 +120,16 @@ type Driver interface {
 	StorePlugin(art sdk.ActionPlugin, data io.ReadCloser) (string, error)
 	FetchPlugin(art sdk.ActionPlugin) (io.ReadCloser, error)
 	DeletePlugin(art sdk.ActionPlugin) error
+	StoreObject(container, name string, data io.ReadCloser) (string, error)
+	FetchObject(container, name string) (io.ReadCloser, error)
+	DeleteObject(container, name string) error
+}
+
+// URLSigner is implemented by drivers able to give temporary download URLs,
+// so clients can fetch data without going through the API
+type URLSigner interface {
+	ArtifactURL(art sdk.Artifact) (string, error)
+	ObjectURL(container, name string) (string, error)
 }
 
 // Initialize setup wanted ObjectStore driver
@@ -97,6 +150,8 @@ func New(mode, address, user, password, basedir string) (Driver, error) {
 		return NewOpenstackStore(address, user, password)
 	case "filesystem":
 		return NewFilesystemStore(basedir)
+	case "s3":
+		return NewS3Store(address, user, password, s3Options)
 	default:
 		return nil, fmt.Errorf("Invalid flag --artifact-mode")
 	}
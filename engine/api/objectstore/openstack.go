@@ -162,6 +162,41 @@ func (ops *OpenstackStore) DeletePlugin(art sdk.ActionPlugin) error {
 	return nil
 }
 
+// StoreObject creates a new object in openstack
+func (ops *OpenstackStore) StoreObject(c, name string, data io.ReadCloser) (string, error) {
+	container, object := ops.format(name, c)
+	log.Info("OpenstackStore> Storing /%s/%s\n", container, object)
+
+	// Create container if it doesn't exist
+	if err := createContainer(ops.token.ID, ops.endpoint, container); err != nil {
+		log.Warning("OpenstackStore.StoreObject> Cannot create container: %s\n", err)
+		return "", err
+	}
+
+	if err := createObject(ops.token.ID, ops.endpoint, container, object, data); err != nil {
+		log.Warning("OpenstackStore.StoreObject> Cannot create object: %s\n", err)
+		return "", err
+	}
+
+	return container + "/" + object, nil
+}
+
+// FetchObject retrieves object data from openstack
+func (ops *OpenstackStore) FetchObject(c, name string) (io.ReadCloser, error) {
+	container, object := ops.format(name, c)
+	log.Info("OpenstackStore> Fetching /%s/%s\n", container, object)
+
+	return fetchObject(ops.token.ID, ops.endpoint, container, object)
+}
+
+// DeleteObject removes object data from openstack
+func (ops *OpenstackStore) DeleteObject(c, name string) error {
+	container, object := ops.format(name, c)
+	log.Info("OpenstackStore> Deleting /%s/%s\n", container, object)
+
+	return deleteObject(ops.token.ID, ops.endpoint, container, object)
+}
+
 func (ops *OpenstackStore) format(x string, y ...string) (container string, object string) {
 	container = strings.Join(y, "-")
 
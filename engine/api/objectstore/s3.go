@@ -0,0 +1,523 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3MinPartSize is the minimum size of all multipart upload parts but the last one
+	s3MinPartSize = 5 * 1024 * 1024
+)
+
+// S3Options configures the S3 driver in addition to address and credentials
+type S3Options struct {
+	Bucket string
+	Region string
+	// PartSize is the size of multipart upload parts, smaller artifacts are uploaded at once
+	PartSize int64
+	// SSE is the server side encryption, AES256 or aws:kms
+	SSE string
+	// SSEKMSKeyID is the KMS key used with aws:kms server side encryption
+	SSEKMSKeyID string
+	// PresignTTL is the lifetime of presigned download URLs, no URL is given if zero
+	PresignTTL time.Duration
+}
+
+var s3Options = S3Options{
+	Bucket:   "cds",
+	Region:   "us-east-1",
+	PartSize: 16 * 1024 * 1024,
+}
+
+// SetS3Options configures the S3 driver, it must be called before Initialize
+func SetS3Options(o S3Options) {
+	s3Options = o
+}
+
+// S3Store implements ObjectStore interface with S3 API, buckets are addressed by path
+// to support S3 compatible services like MinIO or Ceph RGW
+type S3Store struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	options   S3Options
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Store create a new ObjectStore with S3 driver, check configuration and create bucket if needed
+func NewS3Store(address, accessKey, secretKey string, o S3Options) (*S3Store, error) {
+	if address == "" {
+		return nil, fmt.Errorf("artifact storage is s3, but flag --artifact-address is not provided")
+	}
+
+	if accessKey == "" {
+		return nil, fmt.Errorf("artifact storage is s3, but flag --artifact-user is not provided")
+	}
+
+	if secretKey == "" {
+		return nil, fmt.Errorf("artifact storage is s3, but flag --artifact-password is not provided")
+	}
+
+	if o.Bucket == "" {
+		return nil, fmt.Errorf("artifact storage is s3, but flag --artifact-s3-bucket is not provided")
+	}
+
+	switch o.SSE {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("invalid flag --artifact-s3-sse %s: must be AES256 or aws:kms", o.SSE)
+	}
+
+	if o.Region == "" {
+		o.Region = "us-east-1"
+	}
+	if o.PartSize < s3MinPartSize {
+		o.PartSize = s3MinPartSize
+	}
+
+	s3 := &S3Store{
+		endpoint:  strings.TrimSuffix(address, "/"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+		options:   o,
+		client:    http.DefaultClient,
+		now:       time.Now,
+	}
+
+	if err := s3.createBucket(); err != nil {
+		return nil, err
+	}
+
+	log.Notice("NewS3Store> Using bucket %s at %s\n", o.Bucket, s3.endpoint)
+	return s3, nil
+}
+
+//Status return S3 storage status
+func (s3 *S3Store) Status() string {
+	resp, err := s3.do("HEAD", "", nil, nil, nil)
+	if err != nil {
+		return "S3 KO (" + err.Error() + ")"
+	}
+	resp.Body.Close()
+	return "S3 OK"
+}
+
+// StoreArtifact creates a new object in bucket with artifact data
+func (s3 *S3Store) StoreArtifact(art sdk.Artifact, data io.ReadCloser) (string, error) {
+	return s3.store(s3.artifactKey(art), data)
+}
+
+// FetchArtifact retrieves artifact data from bucket
+func (s3 *S3Store) FetchArtifact(art sdk.Artifact) (io.ReadCloser, error) {
+	return s3.fetch(s3.artifactKey(art))
+}
+
+// DeleteArtifact removes artifact data from bucket
+func (s3 *S3Store) DeleteArtifact(art sdk.Artifact) error {
+	return s3.delete(s3.artifactKey(art))
+}
+
+// StorePlugin store a plugin in bucket
+func (s3 *S3Store) StorePlugin(art sdk.ActionPlugin, data io.ReadCloser) (string, error) {
+	return s3.store(s3.key("plugins", art.Name), data)
+}
+
+// FetchPlugin retrieves plugin data from bucket
+func (s3 *S3Store) FetchPlugin(art sdk.ActionPlugin) (io.ReadCloser, error) {
+	return s3.fetch(s3.key("plugins", art.Name))
+}
+
+// DeletePlugin removes plugin data from bucket
+func (s3 *S3Store) DeletePlugin(art sdk.ActionPlugin) error {
+	return s3.delete(s3.key("plugins", art.Name))
+}
+
+// StoreObject creates a new object in bucket
+func (s3 *S3Store) StoreObject(container, name string, data io.ReadCloser) (string, error) {
+	return s3.store(s3.key(container, name), data)
+}
+
+// FetchObject retrieves object data from bucket
+func (s3 *S3Store) FetchObject(container, name string) (io.ReadCloser, error) {
+	return s3.fetch(s3.key(container, name))
+}
+
+// DeleteObject removes object data from bucket
+func (s3 *S3Store) DeleteObject(container, name string) error {
+	return s3.delete(s3.key(container, name))
+}
+
+// ArtifactURL returns a presigned URL to download artifact data directly from bucket
+func (s3 *S3Store) ArtifactURL(art sdk.Artifact) (string, error) {
+	return s3.presign(s3.artifactKey(art))
+}
+
+// ObjectURL returns a presigned URL to download object data directly from bucket
+func (s3 *S3Store) ObjectURL(container, name string) (string, error) {
+	return s3.presign(s3.key(container, name))
+}
+
+func (s3 *S3Store) artifactKey(art sdk.Artifact) string {
+	return s3.key(art.Project, art.Application, art.Environment, art.Pipeline, art.Tag, art.Name)
+}
+
+func (s3 *S3Store) key(elems ...string) string {
+	for i := range elems {
+		elems[i] = strings.Replace(elems[i], "/", "-", -1)
+	}
+	return strings.Join(elems, "/")
+}
+
+func (s3 *S3Store) store(key string, data io.ReadCloser) (string, error) {
+	defer data.Close()
+	log.Info("S3Store> Storing /%s/%s\n", s3.options.Bucket, key)
+
+	// Read the first part to know whether multipart upload is needed
+	first := make([]byte, s3.options.PartSize)
+	n, err := io.ReadFull(data, first)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	first = first[:n]
+
+	if int64(n) < s3.options.PartSize {
+		resp, err := s3.do("PUT", key, nil, s3.sseHeaders(), first)
+		if err != nil {
+			log.Warning("S3Store.store> Cannot create object %s: %s\n", key, err)
+			return "", err
+		}
+		resp.Body.Close()
+		return s3.options.Bucket + "/" + key, nil
+	}
+
+	if err := s3.multipartUpload(key, first, data); err != nil {
+		log.Warning("S3Store.store> Cannot upload object %s: %s\n", key, err)
+		return "", err
+	}
+	return s3.options.Bucket + "/" + key, nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletePart `xml:"Part"`
+}
+
+// multipartUpload uploads data by parts of PartSize, first part being already read
+func (s3 *S3Store) multipartUpload(key string, first []byte, data io.Reader) error {
+	resp, err := s3.do("POST", key, url.Values{"uploads": {""}}, s3.sseHeaders(), nil)
+	if err != nil {
+		return err
+	}
+	var initiate s3InitiateMultipartUploadResult
+	err = xml.NewDecoder(resp.Body).Decode(&initiate)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("cannot decode multipart upload: %s", err)
+	}
+
+	var complete s3CompleteMultipartUpload
+	part := first
+	buf := make([]byte, s3.options.PartSize)
+	for number := 1; len(part) > 0; number++ {
+		query := url.Values{"partNumber": {fmt.Sprintf("%d", number)}, "uploadId": {initiate.UploadID}}
+		resp, err := s3.do("PUT", key, query, nil, part)
+		if err != nil {
+			s3.abortMultipartUpload(key, initiate.UploadID)
+			return err
+		}
+		resp.Body.Close()
+		complete.Parts = append(complete.Parts, s3CompletePart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(data, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			s3.abortMultipartUpload(key, initiate.UploadID)
+			return err
+		}
+		part = buf[:n]
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		s3.abortMultipartUpload(key, initiate.UploadID)
+		return err
+	}
+	resp, err = s3.do("POST", key, url.Values{"uploadId": {initiate.UploadID}}, nil, body)
+	if err != nil {
+		s3.abortMultipartUpload(key, initiate.UploadID)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Complete multipart upload may fail after a 200 OK response
+	rbody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(rbody, []byte("<Error>")) {
+		return unmarshalS3Error(rbody, resp.Status)
+	}
+	return nil
+}
+
+func (s3 *S3Store) abortMultipartUpload(key, uploadID string) {
+	resp, err := s3.do("DELETE", key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		log.Warning("S3Store.abortMultipartUpload> Cannot abort upload of %s: %s\n", key, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s3 *S3Store) fetch(key string) (io.ReadCloser, error) {
+	log.Info("S3Store> Fetching /%s/%s\n", s3.options.Bucket, key)
+	resp, err := s3.do("GET", key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s3 *S3Store) delete(key string) error {
+	log.Info("S3Store> Deleting /%s/%s\n", s3.options.Bucket, key)
+	resp, err := s3.do("DELETE", key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s3 *S3Store) createBucket() error {
+	resp, err := s3.do("HEAD", "", nil, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+		return nil
+	}
+
+	var body []byte
+	if s3.options.Region != "us-east-1" {
+		body = []byte(fmt.Sprintf(`<CreateBucketConfiguration><LocationConstraint>%s</LocationConstraint></CreateBucketConfiguration>`, s3.options.Region))
+	}
+	resp, err = s3.do("PUT", "", nil, nil, body)
+	if err != nil {
+		return fmt.Errorf("cannot create bucket %s: %s", s3.options.Bucket, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s3 *S3Store) sseHeaders() http.Header {
+	h := http.Header{}
+	if s3.options.SSE != "" {
+		h.Set("X-Amz-Server-Side-Encryption", s3.options.SSE)
+	}
+	if s3.options.SSE == "aws:kms" && s3.options.SSEKMSKeyID != "" {
+		h.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s3.options.SSEKMSKeyID)
+	}
+	return h
+}
+
+// presign returns a GET URL on key authenticated by its query string
+func (s3 *S3Store) presign(key string) (string, error) {
+	if s3.options.PresignTTL <= 0 {
+		return "", nil
+	}
+
+	u, err := url.Parse(s3.endpoint + s3.path(key))
+	if err != nil {
+		return "", err
+	}
+
+	now := s3.now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s3.accessKey+"/"+s3.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(s3.options.PresignTTL/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	header := http.Header{"Host": {u.Host}}
+	signature := s3.signature(now, "GET", u.Path, query, header, s3UnsignedPayload)
+	u.RawQuery = s3CanonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// do sends a signed request on the bucket or one of its keys
+func (s3 *S3Store) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	uri := s3.endpoint + s3.path(key)
+	if len(query) > 0 {
+		uri += "?" + s3CanonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	now := s3.now().UTC()
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signature := s3.signature(now, method, s3.path(key), query, req.Header, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s3.accessKey, s3.scope(now), strings.Join(s3SignedHeaders(req.Header), ";"), signature))
+	req.Header.Del("Host")
+
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		rbody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read body")
+		}
+		return nil, unmarshalS3Error(rbody, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// path returns the escaped path of key in bucket
+func (s3 *S3Store) path(key string) string {
+	p := "/" + s3URIEscape(s3.options.Bucket, false)
+	if key != "" {
+		p += "/" + s3URIEscape(key, true)
+	}
+	return p
+}
+
+func (s3 *S3Store) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s3.options.Region + "/s3/aws4_request"
+}
+
+// signature computes AWS signature version 4 of a request
+func (s3 *S3Store) signature(t time.Time, method, path string, query url.Values, header http.Header, payloadHash string) string {
+	signed := s3SignedHeaders(header)
+	var headers bytes.Buffer
+	for _, k := range signed {
+		values := header[http.CanonicalHeaderKey(k)]
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", k, strings.Join(values, ","))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		s3CanonicalQuery(query),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		s3.scope(t),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := s3HMAC([]byte("AWS4"+s3.secretKey), t.Format(s3DateFormat))
+	key = s3HMAC(key, s3.options.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+// s3SignedHeaders returns sorted lower case names of headers included in signature
+func s3SignedHeaders(header http.Header) []string {
+	var signed []string
+	for k := range header {
+		k = strings.ToLower(k)
+		if k == "host" || k == "content-md5" || k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			signed = append(signed, k)
+		}
+	}
+	sort.Strings(signed)
+	return signed
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	var params []string
+	for k, values := range query {
+		for _, v := range values {
+			params = append(params, s3URIEscape(k, false)+"="+s3URIEscape(v, false))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// s3URIEscape escapes all characters but unreserved ones as required by AWS signature
+func s3URIEscape(s string, keepSlash bool) string {
+	var buf bytes.Buffer
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func unmarshalS3Error(data []byte, status string) error {
+	var e s3Error
+	if err := xml.Unmarshal(data, &e); err != nil || e.Code == "" {
+		return fmt.Errorf("%s", status)
+	}
+	return fmt.Errorf("%s: %s (%s)", status, e.Code, e.Message)
+}
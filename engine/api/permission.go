@@ -5,11 +5,13 @@ import (
 
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
 )
 
 // PermCheckFunc defines func call to check permission
-type PermCheckFunc func(key string, c *context.Context, permission int, routeVar map[string]string) bool
+type PermCheckFunc func(key string, c *context.Context, action string, routeVar map[string]string) bool
 
 var permissionMapFunction = initPermissionFunc()
 
@@ -25,28 +27,29 @@ func initPermissionFunc() map[string]PermCheckFunc {
 	}
 }
 
-func getPermissionByMethod(method string, isExecution bool) int {
+func getActionByMethod(method string, rc *routerConfig) string {
 	switch method {
 	case "POST":
-		if isExecution {
-			return permission.PermissionReadExecute
+		if rc.isExecution {
+			return sdk.RoleActionRun
 		}
-		return permission.PermissionReadWriteExecute
-	case "PUT":
-		return permission.PermissionReadWriteExecute
-	case "DELETE":
-		return permission.PermissionReadWriteExecute
+		fallthrough
+	case "PUT", "DELETE":
+		if rc.action != "" {
+			return rc.action
+		}
+		return sdk.RoleActionEdit
 	default:
-		return permission.PermissionRead
+		return sdk.RoleActionRead
 	}
 }
 
-func checkPermission(routeVar map[string]string, c *context.Context, permission int) bool {
+func checkPermission(routeVar map[string]string, c *context.Context, action string) bool {
 	permissionOk := true
 	for key, value := range routeVar {
 		if permFunc, ok := permissionMapFunction[key]; ok {
 			log.Info("Check permission for %s", key)
-			permissionOk = permFunc(value, c, permission, routeVar)
+			permissionOk = permFunc(value, c, action, routeVar)
 			if !permissionOk {
 				return permissionOk
 			}
@@ -55,11 +58,39 @@ func checkPermission(routeVar map[string]string, c *context.Context, permission
 	return permissionOk
 }
 
-func checkProjectPermissions(projectKey string, c *context.Context, permission int, routeVar map[string]string) bool {
+// checkTokenScopes restricts requests authentified by an API token to its scopes,
+// group permissions of its user are then checked as usual
+func checkTokenScopes(routeVar map[string]string, c *context.Context, action string) bool {
+	projectKey := routeVar["permProjectKey"]
+	if projectKey == "" {
+		projectKey = routeVar["key"]
+	}
+
+	var scope string
+	switch action {
+	case sdk.RoleActionRead:
+		scope = sdk.TokenScopeRead
+	case sdk.RoleActionRun:
+		scope = sdk.TokenScopeRun
+	case sdk.RoleActionManageVariables:
+		scope = sdk.TokenScopeVariables
+	default:
+		log.Warning("Access denied. token %s of user %s cannot %s", c.Token.Name, c.User.Username, action)
+		return false
+	}
+
+	if !permission.TokenAllows(c.Token, scope, projectKey) {
+		log.Warning("Access denied. token %s of user %s has no scope %s on project %s", c.Token.Name, c.User.Username, scope, projectKey)
+		return false
+	}
+	return true
+}
+
+func checkProjectPermissions(projectKey string, c *context.Context, action string, routeVar map[string]string) bool {
 	if c.User.Groups != nil {
 		for _, g := range c.User.Groups {
 			for _, p := range g.ProjectGroups {
-				if projectKey == p.Project.Key && p.Permission >= permission {
+				if projectKey == p.Project.Key && role.Can(p.Permission, action) {
 					return true
 				}
 			}
@@ -69,13 +100,13 @@ func checkProjectPermissions(projectKey string, c *context.Context, permission i
 	return false
 }
 
-func checkPipelinePermissions(pipelineName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkPipelinePermissions(pipelineName string, c *context.Context, action string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		if c.User.Groups != nil {
 			for _, g := range c.User.Groups {
 				for _, p := range g.PipelineGroups {
-					if pipelineName == p.Pipeline.Name && p.Permission >= permission && projectKey == p.Pipeline.ProjectKey {
+					if pipelineName == p.Pipeline.Name && role.Can(p.Permission, action) && projectKey == p.Pipeline.ProjectKey {
 						return true
 					}
 				}
@@ -88,13 +119,13 @@ func checkPipelinePermissions(pipelineName string, c *context.Context, permissio
 	return false
 }
 
-func checkEnvironmentPermissions(envName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkEnvironmentPermissions(envName string, c *context.Context, action string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		if c.User.Groups != nil {
 			for _, g := range c.User.Groups {
 				for _, p := range g.EnvironmentGroups {
-					if envName == p.Environment.Name && p.Permission >= permission && projectKey == p.Environment.ProjectKey {
+					if envName == p.Environment.Name && role.Can(p.Permission, action) && projectKey == p.Environment.ProjectKey {
 						return true
 					}
 				}
@@ -107,13 +138,13 @@ func checkEnvironmentPermissions(envName string, c *context.Context, permission
 	return false
 }
 
-func checkApplicationPermissions(applicationName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkApplicationPermissions(applicationName string, c *context.Context, action string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		if c.User.Groups != nil {
 			for _, g := range c.User.Groups {
 				for _, a := range g.ApplicationGroups {
-					if applicationName == a.Application.Name && a.Permission >= permission && projectKey == a.Application.ProjectKey {
+					if applicationName == a.Application.Name && role.Can(a.Permission, action) && projectKey == a.Application.ProjectKey {
 						return true
 					}
 				}
@@ -126,7 +157,7 @@ func checkApplicationPermissions(applicationName string, c *context.Context, per
 	return false
 }
 
-func checkApplicationIDPermissions(appIDS string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkApplicationIDPermissions(appIDS string, c *context.Context, action string, routeVar map[string]string) bool {
 
 	appID, err := strconv.ParseInt(appIDS, 10, 64)
 	if err != nil {
@@ -137,7 +168,7 @@ func checkApplicationIDPermissions(appIDS string, c *context.Context, permission
 	if c.User.Groups != nil {
 		for _, g := range c.User.Groups {
 			for _, a := range g.ApplicationGroups {
-				if appID == a.Application.ID && a.Permission >= permission {
+				if appID == a.Application.ID && role.Can(a.Permission, action) {
 					return true
 				}
 			}
@@ -148,11 +179,11 @@ func checkApplicationIDPermissions(appIDS string, c *context.Context, permission
 	return false
 }
 
-func checkGroupPermissions(groupName string, c *context.Context, permissionValue int, routeVar map[string]string) bool {
+func checkGroupPermissions(groupName string, c *context.Context, action string, routeVar map[string]string) bool {
 	for _, g := range c.User.Groups {
 		if g.Name == groupName {
 
-			if permissionValue == permission.PermissionRead {
+			if action == sdk.RoleActionRead {
 				return true
 			}
 
@@ -167,12 +198,12 @@ func checkGroupPermissions(groupName string, c *context.Context, permissionValue
 	return false
 }
 
-func checkActionPermissions(groupName string, c *context.Context, permissionValue int, routeVar map[string]string) bool {
-	if permissionValue == permission.PermissionRead {
+func checkActionPermissions(groupName string, c *context.Context, action string, routeVar map[string]string) bool {
+	if action == sdk.RoleActionRead {
 		return true
 	}
 
-	if permissionValue != permission.PermissionRead && c.User.Admin {
+	if action != sdk.RoleActionRead && c.User.Admin {
 		return true
 	}
 
@@ -0,0 +1,85 @@
+package permission
+
+import (
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
+	"github.com/ovh/cds/sdk"
+)
+
+// CanGiveRole checks the role exists and, unless user is admin, that userRoles, the roles of the user
+// on the resource, grant every action of it, so that nobody gives a group more rights than they have
+func CanGiveRole(db database.Querier, user *sdk.User, roleID int, userRoles []int) error {
+	r, err := role.LoadByID(db, roleID)
+	if err != nil {
+		return err
+	}
+	if user.Admin {
+		return nil
+	}
+
+	for _, a := range r.Actions {
+		granted := false
+		for _, id := range userRoles {
+			if role.Can(id, a) {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return sdk.ErrForbidden
+		}
+	}
+	return nil
+}
+
+// ProjectRoles returns the roles given to the user groups on the project
+func ProjectRoles(projectKey string, user *sdk.User) []int {
+	var roles []int
+	for _, g := range user.Groups {
+		for _, pg := range g.ProjectGroups {
+			if pg.Project.Key == projectKey {
+				roles = append(roles, pg.Permission)
+			}
+		}
+	}
+	return roles
+}
+
+// ApplicationRoles returns the roles given to the user groups on the application
+func ApplicationRoles(applicationID int64, user *sdk.User) []int {
+	var roles []int
+	for _, g := range user.Groups {
+		for _, ag := range g.ApplicationGroups {
+			if ag.Application.ID == applicationID {
+				roles = append(roles, ag.Permission)
+			}
+		}
+	}
+	return roles
+}
+
+// PipelineRoles returns the roles given to the user groups on the pipeline
+func PipelineRoles(pipelineID int64, user *sdk.User) []int {
+	var roles []int
+	for _, g := range user.Groups {
+		for _, pg := range g.PipelineGroups {
+			if pg.Pipeline.ID == pipelineID {
+				roles = append(roles, pg.Permission)
+			}
+		}
+	}
+	return roles
+}
+
+// EnvironmentRoles returns the roles given to the user groups on the environment
+func EnvironmentRoles(envID int64, user *sdk.User) []int {
+	var roles []int
+	for _, g := range user.Groups {
+		for _, eg := range g.EnvironmentGroups {
+			if eg.Environment.ID == envID {
+				roles = append(roles, eg.Permission)
+			}
+		}
+	}
+	return roles
+}
@@ -0,0 +1,31 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestCanGiveRole(t *testing.T) {
+	user := &sdk.User{}
+	admin := &sdk.User{Admin: true}
+
+	tests := []struct {
+		user      *sdk.User
+		roleID    int
+		userRoles []int
+		err       error
+	}{
+		{user, sdk.RoleReadID, []int{sdk.RoleReadID}, nil},
+		{user, sdk.RoleReadExecuteID, []int{sdk.RoleReadID, sdk.RoleReadExecuteID}, nil},
+		{user, sdk.RoleReadWriteExecuteID, []int{sdk.RoleReadExecuteID}, sdk.ErrForbidden},
+		{user, sdk.RoleReadExecuteID, nil, sdk.ErrForbidden},
+		{admin, sdk.RoleReadWriteExecuteID, nil, nil},
+	}
+
+	for _, tt := range tests {
+		if err := CanGiveRole(nil, tt.user, tt.roleID, tt.userRoles); err != tt.err {
+			t.Errorf("CanGiveRole(%d, %v) = %v, want %v", tt.roleID, tt.userRoles, err, tt.err)
+		}
+	}
+}
@@ -4,20 +4,21 @@ import (
 	"database/sql"
 
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
-// PermissionRead  read permission on the resource
-const PermissionRead = 4
+// PermissionRead  read permission on the resource, id of the built-in read role
+const PermissionRead = sdk.RoleReadID
 
-// PermissionReadExecute  read & execute permission on the resource
-const PermissionReadExecute = 5
+// PermissionReadExecute  read & execute permission on the resource, id of the built-in read-execute role
+const PermissionReadExecute = sdk.RoleReadExecuteID
 
-// PermissionReadWriteExecute read/execute/write permission on the resource
-const PermissionReadWriteExecute = 7
+// PermissionReadWriteExecute read/execute/write permission on the resource, id of the built-in read-write-execute role
+const PermissionReadWriteExecute = sdk.RoleReadWriteExecuteID
 
-// ApplicationPermission  Get the permission for the given application
+// ApplicationPermission  Get the permission level given by the user roles on the given application
 func ApplicationPermission(applicationID int64, user *sdk.User) int {
 	if user.Admin {
 		return PermissionReadWriteExecute
@@ -25,15 +26,15 @@ func ApplicationPermission(applicationID int64, user *sdk.User) int {
 	max := 0
 	for _, g := range user.Groups {
 		for _, ag := range g.ApplicationGroups {
-			if ag.Application.ID == applicationID && ag.Permission > max {
-				max = ag.Permission
+			if ag.Application.ID == applicationID && role.Level(ag.Permission) > max {
+				max = role.Level(ag.Permission)
 			}
 		}
 	}
 	return max
 }
 
-// ProjectPermission  Get the permission for the given project
+// ProjectPermission  Get the permission level given by the user roles on the given project
 func ProjectPermission(projectKey string, user *sdk.User) int {
 	if user.Admin {
 		return PermissionReadWriteExecute
@@ -41,15 +42,15 @@ func ProjectPermission(projectKey string, user *sdk.User) int {
 	max := 0
 	for _, g := range user.Groups {
 		for _, pg := range g.ProjectGroups {
-			if pg.Project.Key == projectKey && pg.Permission > max {
-				max = pg.Permission
+			if pg.Project.Key == projectKey && role.Level(pg.Permission) > max {
+				max = role.Level(pg.Permission)
 			}
 		}
 	}
 	return max
 }
 
-// PipelinePermission  Get the permission for the given pipeline
+// PipelinePermission  Get the permission level given by the user roles on the given pipeline
 func PipelinePermission(pipelineID int64, user *sdk.User) int {
 	if user.Admin {
 		return PermissionReadWriteExecute
@@ -57,15 +58,15 @@ func PipelinePermission(pipelineID int64, user *sdk.User) int {
 	max := 0
 	for _, g := range user.Groups {
 		for _, pg := range g.PipelineGroups {
-			if pg.Pipeline.ID == pipelineID && pg.Permission > max {
-				max = pg.Permission
+			if pg.Pipeline.ID == pipelineID && role.Level(pg.Permission) > max {
+				max = role.Level(pg.Permission)
 			}
 		}
 	}
 	return max
 }
 
-// EnvironmentPermission  Get the permission for the given environment
+// EnvironmentPermission  Get the permission level given by the user roles on the given environment
 func EnvironmentPermission(envID int64, user *sdk.User) int {
 	if user.Admin {
 		return PermissionReadWriteExecute
@@ -73,23 +74,23 @@ func EnvironmentPermission(envID int64, user *sdk.User) int {
 	max := 0
 	for _, g := range user.Groups {
 		for _, eg := range g.EnvironmentGroups {
-			if eg.Environment.ID == envID && eg.Permission > max {
-				max = eg.Permission
+			if eg.Environment.ID == envID && role.Level(eg.Permission) > max {
+				max = role.Level(eg.Permission)
 			}
 		}
 	}
 	return max
 }
 
-// AccessToApplication check if we can modify the given application
-func AccessToApplication(applicationID int64, user *sdk.User, access int) bool {
+// AccessToApplication check if a role of the user on the given application grants action
+func AccessToApplication(applicationID int64, user *sdk.User, action string) bool {
 	if user.Admin {
 		return true
 	}
 
 	for _, g := range user.Groups {
 		for _, ag := range g.ApplicationGroups {
-			if ag.Application.ID == applicationID && ag.Permission >= access {
+			if ag.Application.ID == applicationID && role.Can(ag.Permission, action) {
 				return true
 			}
 		}
@@ -97,17 +98,17 @@ func AccessToApplication(applicationID int64, user *sdk.User, access int) bool {
 	return false
 }
 
-// AccessToPipeline check if we can modify the given pipeline
-func AccessToPipeline(environmentID, pipelineID int64, user *sdk.User, access int) bool {
+// AccessToPipeline check if roles of the user on the given pipeline, and environment if any, grant action
+func AccessToPipeline(environmentID, pipelineID int64, user *sdk.User, action string) bool {
 	if user.Admin {
 		return true
 	}
 
 	for _, g := range user.Groups {
 		for _, pg := range g.PipelineGroups {
-			if pg.Pipeline.ID == pipelineID && pg.Permission >= access {
+			if pg.Pipeline.ID == pipelineID && role.Can(pg.Permission, action) {
 				if environmentID != sdk.DefaultEnv.ID {
-					return AccessToEnvironment(environmentID, user, access)
+					return AccessToEnvironment(environmentID, user, action)
 				}
 				return true
 			}
@@ -116,15 +117,15 @@ func AccessToPipeline(environmentID, pipelineID int64, user *sdk.User, access in
 	return false
 }
 
-// AccessToEnvironment check if we can modify the given environment
-func AccessToEnvironment(envID int64, user *sdk.User, access int) bool {
+// AccessToEnvironment check if a role of the user on the given environment grants action
+func AccessToEnvironment(envID int64, user *sdk.User, action string) bool {
 	if user.Admin {
 		return true
 	}
 
 	for _, g := range user.Groups {
 		for _, eg := range g.EnvironmentGroups {
-			if eg.Environment.ID == envID && eg.Permission >= access {
+			if eg.Environment.ID == envID && role.Can(eg.Permission, action) {
 				return true
 			}
 		}
@@ -132,14 +133,14 @@ func AccessToEnvironment(envID int64, user *sdk.User, access int) bool {
 	return false
 }
 
-// ApplicationPipelineEnvironmentUsers returns users list with expected access to application/pipeline/environment
-func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envID int64, access int) ([]sdk.User, error) {
+// ApplicationPipelineEnvironmentUsers returns users whose roles on application/pipeline/environment grant action
+func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envID int64, action string) ([]sdk.User, error) {
 	var query string
 	var args []interface{}
 
 	if envID == sdk.DefaultEnv.ID {
 		query = `
-			SELECT 	DISTINCT "user".id, "user".username, "user".data
+			SELECT 	"user".id, "user".username, "user".data, application_group.role, pipeline_group.role
 			FROM 	"group"
 			JOIN 	application_group ON "group".id = application_group.group_id
 			JOIN 	pipeline_group ON "group".id = pipeline_group.group_id
@@ -147,13 +148,11 @@ func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envI
 			JOIN 	"user" ON group_user.user_id = "user".id
 			WHERE	application_group.application_id = $1
 			AND	pipeline_group.pipeline_id = $2
-			AND  	application_group.role >= $3
-			AND  	pipeline_group.role >= $3
 		`
-		args = []interface{}{appID, pipID, access}
+		args = []interface{}{appID, pipID}
 	} else {
 		query = `
-			SELECT 	DISTINCT "user".id, "user".username, "user".data
+			SELECT 	"user".id, "user".username, "user".data, application_group.role, pipeline_group.role, environment_group.role
 			FROM 	"group"
 			JOIN 	application_group ON "group".id = application_group.group_id
 			JOIN 	pipeline_group ON "group".id = pipeline_group.group_id
@@ -163,11 +162,8 @@ func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envI
 			WHERE	application_group.application_id = $1
 			AND	pipeline_group.pipeline_id = $2
 			AND 	environment_group.environment_id = $3
-			AND  	application_group.role >= $4
-			AND  	pipeline_group.role >= $4
-			AND 	environment_group.role >= $4
 		`
-		args = []interface{}{appID, pipID, envID, access}
+		args = []interface{}{appID, pipID, envID}
 	}
 
 	rows, err := db.Query(query, args...)
@@ -180,18 +176,33 @@ func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envI
 	defer rows.Close()
 
 	users := []sdk.User{}
+	found := map[int64]bool{}
 	for rows.Next() {
 		u := sdk.User{}
 		var data string
-		if err := rows.Scan(&u.ID, &u.Username, &data); err != nil {
+		var appRole, pipRole, envRole int
+		dest := []interface{}{&u.ID, &u.Username, &data, &appRole, &pipRole}
+		if envID != sdk.DefaultEnv.ID {
+			dest = append(dest, &envRole)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			log.Warning("permission.ApplicationPipelineEnvironmentGroups> error while scanning user : %s", err)
 			continue
 		}
+		if found[u.ID] || !role.Can(appRole, action) || !role.Can(pipRole, action) {
+			continue
+		}
+		if envID != sdk.DefaultEnv.ID && !role.Can(envRole, action) {
+			continue
+		}
+		id := u.ID
 		uTemp, err := u.FromJSON([]byte(data))
 		if err != nil {
 			log.Warning("permission.ApplicationPipelineEnvironmentGroups> error while parsing user : %s", err)
 			continue
 		}
+		uTemp.ID = id
+		found[id] = true
 		users = append(users, *uTemp)
 	}
 	return users, nil
@@ -0,0 +1,19 @@
+package permission
+
+import (
+	"github.com/ovh/cds/sdk"
+)
+
+// TokenAllows returns true if a scope of given token grants scope on given project.
+// When projectKey is empty only scopes not restricted to a project grant it.
+func TokenAllows(t *sdk.APIToken, scope, projectKey string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+		if projectKey != "" && s == scope+":"+projectKey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestTokenAllows(t *testing.T) {
+	token := &sdk.APIToken{Scopes: []string{sdk.TokenScopeRead, "run:PROJ"}}
+
+	tests := []struct {
+		scope      string
+		projectKey string
+		allowed    bool
+	}{
+		{sdk.TokenScopeRead, "", true},
+		{sdk.TokenScopeRead, "OTHER", true},
+		{sdk.TokenScopeRun, "PROJ", true},
+		{sdk.TokenScopeRun, "OTHER", false},
+		{sdk.TokenScopeRun, "", false},
+		{sdk.TokenScopeVariables, "PROJ", false},
+	}
+
+	for _, tt := range tests {
+		if got := TokenAllows(token, tt.scope, tt.projectKey); got != tt.allowed {
+			t.Errorf("TokenAllows(%s, %s) = %v, want %v", tt.scope, tt.projectKey, got, tt.allowed)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/ovh/cds/engine/api/action"
 	"github.com/ovh/cds/engine/api/application"
 	"github.com/ovh/cds/engine/api/archivist"
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/environment"
@@ -79,7 +80,7 @@ func rollbackPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 			return
 		}
 
-		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 			log.Warning("rollbackPipelineHandler> No enought right on this environment %s: \n", request.Env.Name)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -89,7 +90,7 @@ func rollbackPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 		env = &sdk.DefaultEnv
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("rollbackPipelineHandler> You do not have Execution Right on this environment %s\n", env.Name)
 		WriteError(w, r, sdk.ErrNoEnvExecution)
 		return
@@ -247,13 +248,13 @@ func runPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 			return
 		}
 
-		if envDest.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(envDest.ID, c.User, permission.PermissionReadExecute) {
+		if envDest.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(envDest.ID, c.User, sdk.RoleActionRun) {
 			log.Warning("runPipelineHandler> No enought right on this environment %s: \n", request.Env.Name)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
 		}
 	}
-	if envDest.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(envDest.ID, c.User, permission.PermissionReadExecute) {
+	if envDest.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(envDest.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("runPipelineHandler> You do not have Execution Right on this environment\n")
 		WriteError(w, r, sdk.ErrNoEnvExecution)
 		return
@@ -339,6 +340,26 @@ func updatePipelineActionHandler(w http.ResponseWriter, r *http.Request, db *sql
 		return
 	}
 
+	if !pipelineAction.Matrix.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidMatrix)
+		return
+	}
+
+	if pipelineAction.Timeout < 0 {
+		WriteError(w, r, sdk.ErrInvalidTimeout)
+		return
+	}
+
+	if !pipelineAction.Retry.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidRetryPolicy)
+		return
+	}
+
+	if !sdk.IsValidCondition(pipelineAction.Condition) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
 	args, err := json.Marshal(pipelineAction.Parameters)
 	if err != nil {
 		log.Warning("updatePipelineActionHandler>Cannot marshal parameters: %s\n", err)
@@ -823,7 +844,7 @@ func getPipelineHistoryHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getPipelineHistoryHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -930,6 +951,26 @@ func addJoinedActionToPipelineHandler(w http.ResponseWriter, r *http.Request, db
 		return
 	}
 
+	if !a.Matrix.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidMatrix)
+		return
+	}
+
+	if a.Timeout < 0 {
+		WriteError(w, r, sdk.ErrInvalidTimeout)
+		return
+	}
+
+	if !a.Retry.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidRetryPolicy)
+		return
+	}
+
+	if !sdk.IsValidCondition(a.Condition) || !stepConditionsValid(a) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
 	proj, err := project.LoadProject(db, projectKey, c.User)
 	if err != nil {
 		log.Warning("addJoinedActionToPipelineHandler> Cannot load project %s: %s\n", projectKey, err)
@@ -992,6 +1033,38 @@ func addJoinedActionToPipelineHandler(w http.ResponseWriter, r *http.Request, db
 	}
 	a.PipelineActionID = pipelineActionID
 
+	if a.Matrix != nil {
+		if err := pipeline.UpdatePipelineActionMatrix(tx, pipelineActionID, a.ID, a.Matrix); err != nil {
+			log.Warning("addJoinedActionToPipelineHandler> Cannot set matrix of pipeline action %d: %s\n", pipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	if a.Timeout > 0 {
+		if err := pipeline.UpdatePipelineActionTimeout(tx, pipelineActionID, a.ID, a.Timeout); err != nil {
+			log.Warning("addJoinedActionToPipelineHandler> Cannot set timeout of pipeline action %d: %s\n", pipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	if a.Retry != nil {
+		if err := pipeline.UpdatePipelineActionRetry(tx, pipelineActionID, a.ID, a.Retry); err != nil {
+			log.Warning("addJoinedActionToPipelineHandler> Cannot set retry policy of pipeline action %d: %s\n", pipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	if a.Condition != "" {
+		if err := pipeline.UpdatePipelineActionCondition(tx, pipelineActionID, a.ID, a.Condition); err != nil {
+			log.Warning("addJoinedActionToPipelineHandler> Cannot set condition of pipeline action %d: %s\n", pipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	//warnings, err := sanity.CheckActionRequirements(tx, proj.Key, pip.Name, a.ID)
 	warnings, err := sanity.CheckAction(tx, proj, pip, a.ID)
 	if err != nil {
@@ -1065,6 +1138,26 @@ func updateJoinedAction(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 	}
 	a.ID = actionID
 
+	if !a.Matrix.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidMatrix)
+		return
+	}
+
+	if a.Timeout < 0 {
+		WriteError(w, r, sdk.ErrInvalidTimeout)
+		return
+	}
+
+	if !a.Retry.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidRetryPolicy)
+		return
+	}
+
+	if !sdk.IsValidCondition(a.Condition) || !stepConditionsValid(a) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
 	//clearJoinedAction, err := action.LoadActionByID(db, actionID, action.WithClearPasswords())
 	clearJoinedAction, err := action.LoadActionByID(db, actionID)
 	if err != nil {
@@ -1138,6 +1231,29 @@ func updateJoinedAction(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		return
 	}
 
+	if a.PipelineActionID != 0 {
+		if err := pipeline.UpdatePipelineActionMatrix(tx, a.PipelineActionID, a.ID, a.Matrix); err != nil {
+			log.Warning("updateJoinedAction> cannot update matrix of pipeline action %d: %s\n", a.PipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+		if err := pipeline.UpdatePipelineActionTimeout(tx, a.PipelineActionID, a.ID, a.Timeout); err != nil {
+			log.Warning("updateJoinedAction> cannot update timeout of pipeline action %d: %s\n", a.PipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+		if err := pipeline.UpdatePipelineActionRetry(tx, a.PipelineActionID, a.ID, a.Retry); err != nil {
+			log.Warning("updateJoinedAction> cannot update retry policy of pipeline action %d: %s\n", a.PipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+		if err := pipeline.UpdatePipelineActionCondition(tx, a.PipelineActionID, a.ID, a.Condition); err != nil {
+			log.Warning("updateJoinedAction> cannot update condition of pipeline action %d: %s\n", a.PipelineActionID, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	err = pipeline.UpdatePipelineLastModified(tx, pip.ID)
 	if err != nil {
 		log.Warning("updateJoinedAction> cannot update pipeline last_modified date: %s\n", err)
@@ -1202,6 +1318,12 @@ func deleteJoinedAction(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 	}
 	defer tx.Rollback()
 
+	if err := build.DeleteActionBuildsOfAction(db, actionID); err != nil {
+		log.Warning("deleteJoinedAction> Cannot delete builds of joined action: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
 	err = action.DeleteAction(db, actionID, c.User.ID)
 	if err != nil {
 		log.Warning("deleteJoinedAction> Cannot delete joined action: %s\n", err)
@@ -1368,14 +1490,14 @@ func stopPipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB
 			return
 		}
 
-		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 			log.Warning("stopPipelineBuildHandler> No enought right on this environment %s: \n", env.Name)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("stopPipelineBuildHandler> You do not have Execution Right on this environment %s\n", env.Name)
 		WriteError(w, r, sdk.ErrNoEnvExecution)
 		return
@@ -1399,6 +1521,15 @@ func stopPipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB
 		return
 	}
 
+	// no action is running on a build waiting for approval or for the environment lock, scheduler will not end it
+	if pb.Status == sdk.StatusWaitingApproval || pb.Status == sdk.StatusWaiting {
+		if err := pipeline.UpdatePipelineBuildStatus(db, pb, sdk.StatusFail); err != nil {
+			log.Warning("stopPipelineBuildHandler> Cannot stop pb waiting for approval or lock: %s\n", err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	k := cache.Key("application", projectKey, "builds", "*")
 	cache.DeleteAll(k)
 }
@@ -1456,7 +1587,7 @@ func restartPipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql
 			return
 		}
 
-		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+		if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 			log.Warning("restartPipelineBuildHandler> No enought right on this environment %s: \n", envName)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -1474,12 +1605,27 @@ func restartPipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql
 		return
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRun) {
 		log.Warning("restartPipelineBuildHandler> You do not have Execution Right on this environment %s\n", env.Name)
 		WriteError(w, r, sdk.ErrNoEnvExecution)
 		return
 	}
 
+	// a restarted build deploys again, it cannot run beside the build holding the environment lock
+	lockAppID := env.LockPolicy.LockApplicationID(app.ID)
+	if env.LockPolicy != nil {
+		holder, err := environment.LockHolder(db, env.ID, lockAppID)
+		if err != nil {
+			log.Warning("restartPipelineBuildHandler> Cannot load lock of environment %s: %s\n", env.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+		if holder != 0 && holder != pb.ID {
+			WriteError(w, r, sdk.ErrEnvironmentLocked)
+			return
+		}
+	}
+
 	err = pipeline.RestartPipelineBuild(db, pb)
 	if err != nil {
 		log.Warning("restartPipelineBuildHandler> cannot restart pb: %s\n", err)
@@ -1487,6 +1633,14 @@ func restartPipelineBuildHandler(w http.ResponseWriter, r *http.Request, db *sql
 		return
 	}
 
+	if env.LockPolicy != nil {
+		if err := environment.Lock(db, env.ID, lockAppID, pb.ID); err != nil {
+			log.Warning("restartPipelineBuildHandler> Cannot lock environment %s: %s\n", env.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	k := cache.Key("application", projectKey, "builds", "*")
 	cache.DeleteAll(k)
 
@@ -1528,7 +1682,7 @@ func getPipelineCommitsHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getPipelineCommitsHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -1642,7 +1796,7 @@ func getPipelineBuildCommitsHandler(w http.ResponseWriter, r *http.Request, db *
 		}
 	}
 
-	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionRead) {
 		log.Warning("getPipelineHistoryHandler> No enought right on this environment %s: \n", envName)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/lib/pq"
 
@@ -35,14 +36,69 @@ func loadActionBuildsByStagePosition(db database.Querier, pipelineBuildID int64,
 	return actionBuilds, nil
 }
 
-// LoadActionStatus  Load status of action_build for the given pipeline_action
-func LoadActionStatus(db database.Querier, pipelineActionID int64, pipelineBuildID int64) (sdk.Status, error) {
-	query := `SELECT status FROM action_build WHERE pipeline_action_id = $1 AND pipeline_build_id = $2`
-	var status string
+// LoadActionStatus  Load status of action_build for the given pipeline_action.
+// With a build matrix, there is one action_build for each combination and the status is:
+// Fail as soon as a combination not allowed to fail has failed, Building until all combinations are done,
+// then Success, or Disabled/Skipped if all combinations are.
+func LoadActionStatus(db database.Querier, pipelineActionID int64, pipelineBuildID int64, matrix *sdk.Matrix) (sdk.Status, error) {
+	query := `SELECT status, args FROM action_build WHERE pipeline_action_id = $1 AND pipeline_build_id = $2`
+	rows, err := db.Query(query, pipelineActionID, pipelineBuildID)
+	if err != nil {
+		return sdk.StatusUnknown, err
+	}
+	defer rows.Close()
+
+	var statuses []sdk.Status
+	var cells []map[string]string
+	for rows.Next() {
+		var status, args string
+		if err := rows.Scan(&status, &args); err != nil {
+			return sdk.StatusUnknown, err
+		}
+		var params []sdk.Parameter
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return sdk.StatusUnknown, err
+		}
+		statuses = append(statuses, sdk.StatusFromString(status))
+		cells = append(cells, sdk.MatrixCell(params))
+	}
+	if len(statuses) == 0 {
+		return sdk.StatusUnknown, sql.ErrNoRows
+	}
+
+	return matrixStatus(statuses, cells, matrix), nil
+}
+
+func matrixStatus(statuses []sdk.Status, cells []map[string]string, matrix *sdk.Matrix) sdk.Status {
+	if len(statuses) == 1 && cells[0] == nil {
+		return statuses[0]
+	}
+
+	var waiting, building, same = true, false, true
+	for i, s := range statuses {
+		if (s == sdk.StatusFail || s == sdk.StatusTimeout) && !matrix.AllowFailure(cells[i]) {
+			return s
+		}
+		if s != sdk.StatusWaiting {
+			waiting = false
+		}
+		if s == sdk.StatusWaiting || s == sdk.StatusBuilding {
+			building = true
+		}
+		if s != statuses[0] {
+			same = false
+		}
+	}
 
-	err := db.QueryRow(query, pipelineActionID, pipelineBuildID).Scan(&status)
-	statusAction := sdk.StatusFromString(status)
-	return statusAction, err
+	switch {
+	case waiting:
+		return sdk.StatusWaiting
+	case building:
+		return sdk.StatusBuilding
+	case same && (statuses[0] == sdk.StatusDisabled || statuses[0] == sdk.StatusSkipped):
+		return statuses[0]
+	}
+	return sdk.StatusSuccess
 }
 
 func loadStageAndActionBuilds(db database.Querier, pb *sdk.PipelineBuild) error {
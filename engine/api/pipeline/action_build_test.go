@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestMatrixStatus(t *testing.T) {
+	m := &sdk.Matrix{
+		Axes: []sdk.MatrixAxis{
+			{Name: "go", Values: []string{"1.6", "1.7"}},
+			{Name: "os", Values: []string{"linux", "windows"}},
+		},
+		AllowFailures: []map[string]string{{"os": "windows"}},
+	}
+
+	cells := m.Combinations()
+	if len(cells) != 4 {
+		t.Fatalf("Expected 4 combinations, got %d", len(cells))
+	}
+
+	tests := []struct {
+		statuses []sdk.Status
+		expected sdk.Status
+	}{
+		{[]sdk.Status{sdk.StatusWaiting, sdk.StatusWaiting, sdk.StatusWaiting, sdk.StatusWaiting}, sdk.StatusWaiting},
+		{[]sdk.Status{sdk.StatusSuccess, sdk.StatusBuilding, sdk.StatusWaiting, sdk.StatusSuccess}, sdk.StatusBuilding},
+		{[]sdk.Status{sdk.StatusSuccess, sdk.StatusFail, sdk.StatusSuccess, sdk.StatusSuccess}, sdk.StatusSuccess},
+		{[]sdk.Status{sdk.StatusFail, sdk.StatusBuilding, sdk.StatusSuccess, sdk.StatusSuccess}, sdk.StatusFail},
+		{[]sdk.Status{sdk.StatusSuccess, sdk.StatusSuccess, sdk.StatusTimeout, sdk.StatusSuccess}, sdk.StatusTimeout},
+		{[]sdk.Status{sdk.StatusSkipped, sdk.StatusSkipped, sdk.StatusSkipped, sdk.StatusSkipped}, sdk.StatusSkipped},
+	}
+
+	for _, test := range tests {
+		// cells are ordered go=1.6 os=linux, go=1.6 os=windows, go=1.7 os=linux, go=1.7 os=windows
+		if s := matrixStatus(test.statuses, cells, m); s != test.expected {
+			t.Errorf("Expected %s for %v, got %s", test.expected, test.statuses, s)
+		}
+	}
+
+	// Build without matrix keeps its own status
+	if s := matrixStatus([]sdk.Status{sdk.StatusUnknown}, []map[string]string{nil}, nil); s != sdk.StatusUnknown {
+		t.Errorf("Expected single build status, got %s", s)
+	}
+
+	params := sdk.MatrixParameters(cells[1])
+	if len(params) != 2 || params[0].Name != "cds.matrix.go" || params[1].Value != "windows" {
+		t.Fatalf("Unexpected matrix parameters: %v", params)
+	}
+	if !m.AllowFailure(sdk.MatrixCell(params)) {
+		t.Fatalf("Failure of %v should be allowed", params)
+	}
+}
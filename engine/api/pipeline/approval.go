@@ -0,0 +1,30 @@
+package pipeline
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func approvalToDB(a *sdk.ApprovalPolicy) (sql.NullString, error) {
+	if a == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func approvalFromDB(s sql.NullString) (*sdk.ApprovalPolicy, error) {
+	if !s.Valid || s.String == "" {
+		return nil, nil
+	}
+	a := &sdk.ApprovalPolicy{}
+	if err := json.Unmarshal([]byte(s.String), a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
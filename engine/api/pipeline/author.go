@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/sdk"
+)
+
+// LoadAuthorIDs returns the ids of the CDS users who triggered the pipeline build or authored the commits it builds.
+// Committers are resolved to CDS users by the email address their repositories manager knows for them.
+func LoadAuthorIDs(db database.Querier, pb *sdk.PipelineBuild) ([]int64, error) {
+	var ids []int64
+	if t := pb.Trigger.TriggeredBy; t != nil {
+		id := t.ID
+		if id == 0 && t.Username != "" {
+			query := `SELECT id FROM "user" WHERE username = $1`
+			if err := db.QueryRow(query, t.Username).Scan(&id); err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+		}
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	emails, err := committerEmails(db, pb)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id FROM "user" WHERE lower(data::json->>'email') = $1`
+	for _, e := range emails {
+		rows, err := db.Query(query, e)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+	}
+	return ids, nil
+}
+
+// committerEmails returns the lowercased email addresses of the authors of the commits built
+// since the previous pipeline build on the same branch
+func committerEmails(db database.Querier, pb *sdk.PipelineBuild) ([]string, error) {
+	if pb.Trigger.VCSChangesHash == "" {
+		return nil, nil
+	}
+
+	var rmName, repo string
+	query := `SELECT repositories_manager.name, application.repo_fullname
+		FROM application
+		JOIN repositories_manager ON repositories_manager.id = application.repositories_manager_id
+		WHERE application.id = $1`
+	if err := db.QueryRow(query, pb.Application.ID).Scan(&rmName, &repo); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	client, err := repositoriesmanager.AuthorizedClient(db, pb.Pipeline.ProjectKey, rmName)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []sdk.VCSCommit
+	_, prev, err := CurrentAndPreviousPipelineBuildNumberAndHash(db, pb.BuildNumber, pb.Pipeline.ID, pb.Application.ID, pb.Environment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.Hash != "" && prev.Hash != pb.Trigger.VCSChangesHash {
+		commits, err = client.Commits(repo, prev.Hash, pb.Trigger.VCSChangesHash)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c, err := client.Commit(repo, pb.Trigger.VCSChangesHash)
+		if err != nil {
+			return nil, err
+		}
+		commits = []sdk.VCSCommit{c}
+	}
+
+	var emails []string
+	seen := map[string]bool{}
+	for _, c := range commits {
+		e := strings.ToLower(c.Author.Email)
+		if e != "" && !seen[e] {
+			seen[e] = true
+			emails = append(emails, e)
+		}
+	}
+	return emails, nil
+}
@@ -60,7 +60,7 @@ func killAWOLAction(db *sql.DB, actionBuildID int64) error {
 	defer tx.Rollback()
 
 	build.InsertLog(tx, actionBuildID, "SYSTEM", "Killed (Reason: Timeout)\n")
-	err = build.UpdateActionBuildStatus(tx, &sdk.ActionBuild{ID: actionBuildID}, sdk.StatusFail)
+	err = build.FailActionBuild(tx, &sdk.ActionBuild{ID: actionBuildID}, sdk.FailureWorkerLost, 0)
 	if err != nil {
 		return err
 	}
@@ -75,18 +75,18 @@ func killAWOLAction(db *sql.DB, actionBuildID int64) error {
 }
 
 // SELECT action_build.id
-// JOIN WITH build_log even if there is no log !
 // WHERE action_build status is building, obviously
 // WHERE action_build was started at least 15 minutes ago
-// WHERE LAAAAAAAAAAAAAAST logs are older than 15 minutes OR no logs at all
+// WHERE LAAAAAAAAAAAAAAST logs, in build_log rows or in flushed build_log_chunk, are older than 15 minutes OR no logs at all
 func loadAWOLActionBuild(db *sql.DB) ([]int64, error) {
 	query := `
 		SELECT action_build.id FROM action_build
-		LEFT OUTER JOIN build_log ON build_log.action_build_id = action_build.id
 		WHERE status = 'Building'
 		AND action_build.start < NOW() - INTERVAL '15 minutes'
-		GROUP BY action_build.id
-		HAVING MAX(build_log.timestamp) < NOW() - INTERVAL '15 minutes' OR MAX(build_log.timestamp) IS NULL
+		AND COALESCE(GREATEST(
+			(SELECT MAX(build_log.timestamp) FROM build_log WHERE build_log.action_build_id = action_build.id),
+			(SELECT MAX(build_log_chunk.last_log) FROM build_log_chunk WHERE build_log_chunk.action_build_id = action_build.id)
+		), action_build.start) < NOW() - INTERVAL '15 minutes'
 		`
 	var ids []int64
 	var tmp int64
@@ -0,0 +1,12 @@
+package pipeline
+
+import (
+	"github.com/ovh/cds/engine/api/database"
+)
+
+// UpdatePipelineActionCondition set the condition of a pipeline action, empty removes it
+func UpdatePipelineActionCondition(db database.Executer, pipelineActionID, actionID int64, condition string) error {
+	query := `UPDATE pipeline_action SET condition = $1 WHERE id = $2 AND action_id = $3`
+	_, err := db.Exec(query, condition, pipelineActionID, actionID)
+	return err
+}
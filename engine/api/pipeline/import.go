@@ -0,0 +1,366 @@
+package pipeline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// ImportPipeline applies a pipeline definition on a pipeline loaded with its stages and parameters.
+// Parameters, stages and jobs are matched by name: missing ones are created, changed ones updated
+// and the ones absent from definition are deleted. It returns the list of applied changes.
+func ImportPipeline(tx *sql.Tx, p *sdk.Pipeline, d *sdk.PipelineDefinition, userID int64) ([]string, error) {
+	changes := []string{}
+
+	if d.Type != "" && d.Type != p.Type {
+		p.Type = d.Type
+		if err := UpdatePipeline(tx, p); err != nil {
+			return nil, fmt.Errorf("cannot update pipeline type> %s", err)
+		}
+		changes = append(changes, fmt.Sprintf("pipeline type set to %s", p.Type))
+	}
+
+	paramChanges, err := importPipelineParameters(tx, p, sdk.Parameters(d.Parameters))
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, paramChanges...)
+
+	// Stages removed from definition are deleted first, build orders are then set from definition
+	stages := map[string]*sdk.Stage{}
+	for i := range p.Stages {
+		s := &p.Stages[i]
+		if !stageInDefinition(d, s.Name) {
+			if err := DeletePipelineActionByStage(tx, s.ID, userID); err != nil {
+				return nil, fmt.Errorf("cannot delete actions of stage %s> %s", s.Name, err)
+			}
+			if err := deleteStageByID(tx, s); err != nil {
+				return nil, fmt.Errorf("cannot delete stage %s> %s", s.Name, err)
+			}
+			changes = append(changes, fmt.Sprintf("stage %s deleted", s.Name))
+			continue
+		}
+		stages[s.Name] = s
+	}
+
+	for i, sd := range d.Stages {
+		s, exists := stages[sd.Name]
+		if !exists {
+			s = &sdk.Stage{
+				Name:          sd.Name,
+				PipelineID:    p.ID,
+				BuildOrder:    i + 1,
+				Prerequisites: sd.Prerequisites,
+				Matrix:        sd.Matrix,
+				Timeout:       sd.Timeout,
+				Condition:     sd.Condition,
+				Approval:      sd.Approval,
+			}
+			if err := InsertStage(tx, s); err != nil {
+				return nil, fmt.Errorf("cannot insert stage %s> %s", sd.Name, err)
+			}
+			changes = append(changes, fmt.Sprintf("stage %s added", sd.Name))
+		}
+
+		if s.BuildOrder != i+1 || s.Enabled == sd.Disabled || !reflect.DeepEqual(s.Matrix, sd.Matrix) || s.Timeout != sd.Timeout || s.Condition != sd.Condition || !reflect.DeepEqual(s.Approval, sd.Approval) || !samePrerequisites(s.Prerequisites, sd.Prerequisites) {
+			s.BuildOrder = i + 1
+			s.Enabled = !sd.Disabled
+			s.Matrix = sd.Matrix
+			s.Timeout = sd.Timeout
+			s.Condition = sd.Condition
+			s.Approval = sd.Approval
+			s.Prerequisites = sd.Prerequisites
+			if err := UpdateStage(tx, s); err != nil {
+				return nil, fmt.Errorf("cannot update stage %s> %s", sd.Name, err)
+			}
+			if exists {
+				changes = append(changes, fmt.Sprintf("stage %s updated", sd.Name))
+			}
+		}
+
+		jobChanges, err := importStageJobs(tx, p, s, sd.Jobs, userID)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, jobChanges...)
+	}
+
+	if err := UpdatePipelineLastModified(tx, p.ID); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func importPipelineParameters(tx *sql.Tx, p *sdk.Pipeline, params []sdk.Parameter) ([]string, error) {
+	changes := []string{}
+
+	current := map[string]sdk.Parameter{}
+	for _, param := range p.Parameter {
+		current[param.Name] = param
+	}
+
+	for i := range params {
+		param := params[i]
+		old, exists := current[param.Name]
+		delete(current, param.Name)
+
+		if !exists {
+			if err := InsertParameterInPipeline(tx, p.ID, &param); err != nil {
+				return nil, fmt.Errorf("cannot insert parameter %s> %s", param.Name, err)
+			}
+			changes = append(changes, fmt.Sprintf("parameter %s added", param.Name))
+			continue
+		}
+
+		if old.Type == param.Type && old.Value == param.Value && old.Description == param.Description {
+			continue
+		}
+		param.ID = old.ID
+		if err := UpdateParameterInPipeline(tx, p.ID, param); err != nil {
+			return nil, fmt.Errorf("cannot update parameter %s> %s", param.Name, err)
+		}
+		changes = append(changes, fmt.Sprintf("parameter %s updated", param.Name))
+	}
+
+	for name := range current {
+		if err := DeleteParameterFromPipeline(tx, p.ID, name); err != nil {
+			return nil, fmt.Errorf("cannot delete parameter %s> %s", name, err)
+		}
+		changes = append(changes, fmt.Sprintf("parameter %s deleted", name))
+	}
+
+	return changes, nil
+}
+
+func importStageJobs(tx *sql.Tx, p *sdk.Pipeline, s *sdk.Stage, jobs []sdk.JobDefinition, userID int64) ([]string, error) {
+	changes := []string{}
+
+	current := map[string]sdk.Action{}
+	for _, a := range s.Actions {
+		current[a.Name] = a
+	}
+
+	for i := range jobs {
+		j := &jobs[i]
+		old, exists := current[j.Name]
+		delete(current, j.Name)
+
+		// Job has changed from joined action to public action or the other way
+		if exists && (old.Type == sdk.JoinedAction) != j.IsJoined() {
+			if err := deleteStageJob(tx, old, userID); err != nil {
+				return nil, err
+			}
+			exists = false
+		}
+
+		if !exists {
+			if err := insertStageJob(tx, p, s, j); err != nil {
+				return nil, err
+			}
+			changes = append(changes, fmt.Sprintf("job %s added in stage %s", j.Name, s.Name))
+			continue
+		}
+
+		var joined *sdk.Action
+		if j.IsJoined() {
+			var err error
+			if joined, err = joinedActionDefinition(tx, j); err != nil {
+				return nil, err
+			}
+		}
+		if !jobChanged(old, j, joined) {
+			continue
+		}
+		if err := updateStageJob(tx, old, j, userID); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("job %s updated in stage %s", j.Name, s.Name))
+	}
+
+	for name, a := range current {
+		if err := deleteStageJob(tx, a, userID); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("job %s deleted from stage %s", name, s.Name))
+	}
+
+	return changes, nil
+}
+
+func insertStageJob(tx *sql.Tx, p *sdk.Pipeline, s *sdk.Stage, j *sdk.JobDefinition) error {
+	var a *sdk.Action
+	args := "[]"
+	if j.IsJoined() {
+		a = j.JoinedAction()
+		if err := action.InsertAction(tx, a, false); err != nil {
+			return fmt.Errorf("cannot insert action %s> %s", j.Name, err)
+		}
+	} else {
+		var err error
+		a, err = action.LoadPublicAction(tx, j.Action)
+		if err != nil {
+			return fmt.Errorf("cannot load action %s> %s", j.Action, err)
+		}
+		if args, err = jobArgs(j); err != nil {
+			return err
+		}
+	}
+
+	pipelineActionID, err := InsertPipelineAction(tx, p.ProjectKey, p.Name, a.ID, args, s.ID)
+	if err != nil {
+		return fmt.Errorf("cannot insert pipeline action %s> %s", j.Name, err)
+	}
+
+	a.PipelineActionID = pipelineActionID
+	a.PipelineStageID = s.ID
+	a.Enabled = !j.Disabled
+	a.Matrix = j.Matrix
+	a.Timeout = j.Timeout
+	a.Retry = j.Retry
+	a.Condition = j.Condition
+	return UpdatePipelineAction(tx, *a, args)
+}
+
+func updateStageJob(tx *sql.Tx, old sdk.Action, j *sdk.JobDefinition, userID int64) error {
+	a := &old
+	args := "[]"
+	if j.IsJoined() {
+		a = j.JoinedAction()
+		a.ID = old.ID
+		a.PipelineActionID = old.PipelineActionID
+		a.PipelineStageID = old.PipelineStageID
+		if err := action.UpdateActionDB(tx, a, userID); err != nil {
+			return fmt.Errorf("cannot update action %s> %s", j.Name, err)
+		}
+	} else {
+		var err error
+		if args, err = jobArgs(j); err != nil {
+			return err
+		}
+	}
+
+	a.Enabled = !j.Disabled
+	a.Matrix = j.Matrix
+	a.Timeout = j.Timeout
+	a.Retry = j.Retry
+	a.Condition = j.Condition
+	return UpdatePipelineAction(tx, *a, args)
+}
+
+func deleteStageJob(tx *sql.Tx, a sdk.Action, userID int64) error {
+	if err := build.DeleteActionBuild(tx, []int64{a.PipelineActionID}); err != nil {
+		return fmt.Errorf("cannot delete builds of action %s> %s", a.Name, err)
+	}
+	if err := DeletePipelineAction(tx, a.PipelineActionID); err != nil {
+		return fmt.Errorf("cannot delete pipeline action %s> %s", a.Name, err)
+	}
+	if a.Type != sdk.JoinedAction {
+		return nil
+	}
+	return action.DeleteAction(tx, a.ID, userID)
+}
+
+// jobArgs returns pipeline action arguments of a public action job
+func jobArgs(j *sdk.JobDefinition) (string, error) {
+	params := sdk.Parameters(j.Parameters)
+	if params == nil {
+		params = []sdk.Parameter{}
+	}
+	args, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return string(args), nil
+}
+
+// joinedActionDefinition returns the action described by a joined job, with requirements of its steps
+// added the same way as on an action loaded from database, so that both can be compared
+func joinedActionDefinition(db database.Querier, j *sdk.JobDefinition) (*sdk.Action, error) {
+	a := j.JoinedAction()
+	for i := range a.Actions {
+		child, err := action.LoadPublicAction(db, a.Actions[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load action %s> %s", a.Actions[i].Name, err)
+		}
+		a.Actions[i].Requirements = child.Requirements
+	}
+	action.AddChildrenRequirements(a)
+	return a, nil
+}
+
+// jobChanged compares an action attached to a stage with its definition. A joined action is compared
+// with joined, given by joinedActionDefinition. Parameters of a public action default to the action ones,
+// so only parameters set in definition are compared
+func jobChanged(a sdk.Action, j *sdk.JobDefinition, joined *sdk.Action) bool {
+	if j.IsJoined() {
+		return !reflect.DeepEqual(comparableJob(a), comparableJob(*joined))
+	}
+
+	if a.Enabled == j.Disabled || !reflect.DeepEqual(a.Matrix, j.Matrix) || a.Timeout != j.Timeout || !reflect.DeepEqual(a.Retry, j.Retry) || a.Condition != j.Condition {
+		return true
+	}
+	for _, p := range sdk.Parameters(j.Parameters) {
+		found := false
+		for _, ap := range a.Parameters {
+			if ap.Name == p.Name && ap.Value == p.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+	return false
+}
+
+// comparableJob returns the definition of an action with requirements sorted, their order depending
+// on the one children were added in
+func comparableJob(a sdk.Action) sdk.JobDefinition {
+	j := sdk.NewJobDefinition(a)
+	sort.Sort(requirementDefinitions(j.Requirements))
+	return j
+}
+
+type requirementDefinitions []sdk.RequirementDefinition
+
+func (r requirementDefinitions) Len() int      { return len(r) }
+func (r requirementDefinitions) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r requirementDefinitions) Less(i, j int) bool {
+	if r[i].Type != r[j].Type {
+		return r[i].Type < r[j].Type
+	}
+	if r[i].Name != r[j].Name {
+		return r[i].Name < r[j].Name
+	}
+	return r[i].Value < r[j].Value
+}
+
+func stageInDefinition(d *sdk.PipelineDefinition, name string) bool {
+	for _, s := range d.Stages {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func samePrerequisites(a, b []sdk.Prerequisite) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestJobChanged(t *testing.T) {
+	data := []byte(`
+name: build
+stages:
+- name: Compile
+  jobs:
+  - name: make
+    requirements:
+    - name: make
+      type: binary
+      value: make
+    steps:
+    - action: Script
+      parameters:
+      - name: script
+        value: make all
+  - action: GitClone
+    parameters:
+    - name: branch
+      value: master
+`)
+
+	d, err := sdk.ParsePipelineDefinition(data, sdk.FormatYAML)
+	if err != nil {
+		t.Fatalf("Cannot parse definition: %s", err)
+	}
+	jobs := d.Stages[0].Jobs
+	if jobs[1].Name != "GitClone" {
+		t.Fatalf("Job using a public action should be named after it, got %s", jobs[1].Name)
+	}
+
+	// Joined action as loaded from database
+	def := jobs[0].JoinedAction()
+	joined := *jobs[0].JoinedAction()
+	if jobChanged(joined, &jobs[0], def) {
+		t.Errorf("Joined action should not have changed")
+	}
+	joined.Actions[0].Parameters[0].Value = "make clean all"
+	if !jobChanged(joined, &jobs[0], def) {
+		t.Errorf("Joined action step parameter has changed")
+	}
+
+	// Public action parameters are merged with action default ones
+	public := sdk.Action{
+		Name:    "GitClone",
+		Type:    sdk.DefaultAction,
+		Enabled: true,
+		Parameters: []sdk.Parameter{
+			{Name: "branch", Type: sdk.StringParameter, Value: "master"},
+			{Name: "directory", Type: sdk.StringParameter, Value: "."},
+		},
+	}
+	if jobChanged(public, &jobs[1], nil) {
+		t.Errorf("Public action should not have changed")
+	}
+	public.Enabled = false
+	if !jobChanged(public, &jobs[1], nil) {
+		t.Errorf("Public action has been enabled")
+	}
+
+	if _, err := sdk.ParsePipelineDefinition([]byte("name: build\nstages:\n- name: a\n- name: a\n"), sdk.FormatYAML); err != sdk.ErrInvalidPipelineDefinition {
+		t.Errorf("Duplicated stage names should be rejected, got %v", err)
+	}
+}
+
+func TestJobChangedDerivedRequirements(t *testing.T) {
+	data := []byte(`
+name: build
+stages:
+- name: Test
+  jobs:
+  - name: test
+    requirements:
+    - name: make
+      type: binary
+      value: make
+    steps:
+    - action: Script
+      parameters:
+      - name: script
+        value: npm test
+      - name: image
+        value: node:6
+    - action: Upload
+`)
+
+	d, err := sdk.ParsePipelineDefinition(data, sdk.FormatYAML)
+	if err != nil {
+		t.Fatalf("Cannot parse definition: %s", err)
+	}
+	j := &d.Stages[0].Jobs[0]
+	upload := []sdk.Requirement{{Name: "upload.example.com:443", Type: sdk.NetworkAccessRequirement, Value: "upload.example.com:443"}}
+
+	// First import stores docker requirement with the job ones, loading it adds requirements of steps
+	stored := j.JoinedAction()
+	action.AddChildrenRequirements(stored)
+	stored.Actions[1].Requirements = upload
+	action.AddChildrenRequirements(stored)
+
+	// Second import of the same definition
+	def := j.JoinedAction()
+	def.Actions[1].Requirements = upload
+	action.AddChildrenRequirements(def)
+	if jobChanged(*stored, j, def) {
+		t.Errorf("Joined action imported twice should not have changed: %v != %v", stored.Requirements, def.Requirements)
+	}
+
+	j.Requirements = j.Requirements[:0]
+	def = j.JoinedAction()
+	def.Actions[1].Requirements = upload
+	action.AddChildrenRequirements(def)
+	if !jobChanged(*stored, j, def) {
+		t.Errorf("Joined action requirement has been removed")
+	}
+}
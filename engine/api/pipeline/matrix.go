@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// UpdatePipelineActionMatrix set the build matrix of a pipeline action, nil removes it
+func UpdatePipelineActionMatrix(db database.Executer, pipelineActionID, actionID int64, m *sdk.Matrix) error {
+	matrix, err := matrixToDB(m)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE pipeline_action SET matrix = $1 WHERE id = $2 AND action_id = $3`
+	_, err = db.Exec(query, matrix, pipelineActionID, actionID)
+	return err
+}
+
+func matrixToDB(m *sdk.Matrix) (sql.NullString, error) {
+	if m == nil || len(m.Axes) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func matrixFromDB(s sql.NullString) (*sdk.Matrix, error) {
+	if !s.Valid || s.String == "" {
+		return nil, nil
+	}
+	m := &sdk.Matrix{}
+	if err := json.Unmarshal([]byte(s.String), m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -176,9 +176,19 @@ func InsertPipelineAction(db database.QueryExecuter, projectKey, pipelineName st
 
 // UpdatePipelineAction Update an action in a pipeline
 func UpdatePipelineAction(db database.Executer, action sdk.Action, args string) error {
-	query := `UPDATE pipeline_action set action_id=$1, args=$2, pipeline_stage_id=$3, enabled=$5  WHERE id=$4`
+	query := `UPDATE pipeline_action set action_id=$1, args=$2, pipeline_stage_id=$3, enabled=$5, matrix=$6, timeout=$7, retry=$8, condition=$9  WHERE id=$4`
 
-	_, err := db.Exec(query, action.ID, args, action.PipelineStageID, action.PipelineActionID, action.Enabled)
+	matrix, err := matrixToDB(action.Matrix)
+	if err != nil {
+		return err
+	}
+
+	retry, err := retryToDB(action.Retry)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(query, action.ID, args, action.PipelineStageID, action.PipelineActionID, action.Enabled, matrix, action.Timeout, retry, action.Condition)
 	if err != nil {
 		return err
 	}
@@ -1,9 +1,11 @@
 package pipeline
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -538,6 +540,11 @@ func UpdatePipelineBuildStatus(db database.QueryExecuter, pb sdk.PipelineBuild,
 	cache.DeleteAll(k)
 
 	notification.SendPipeline(db, &pb, sdk.UpdateNotifEvent, status, previous)
+	build.PublishBuildEvent(sdk.BuildEvent{
+		Type:            sdk.BuildEventPipelineBuild,
+		PipelineBuildID: pb.ID,
+		Status:          status,
+	})
 
 	return nil
 }
@@ -1301,6 +1308,20 @@ func LoadPipelineBuild(db *sql.DB, pipelineID int64, applicationID int64, buildN
 	return pb, sdk.ErrNoPipelineBuild
 }
 
+// LoadPipelineBuildByID retrieves informations about a build from its id
+func LoadPipelineBuildByID(db database.Querier, pbID int64) (sdk.PipelineBuild, error) {
+	var pb sdk.PipelineBuild
+
+	query := fmt.Sprintf(LoadPipelineBuildRequest, "", "pb.id = $1", "")
+	if err := scanPbShort(&pb, db.QueryRow(query, pbID)); err != nil {
+		if err == sql.ErrNoRows {
+			return pb, sdk.ErrNoPipelineBuild
+		}
+		return pb, err
+	}
+	return pb, nil
+}
+
 // LoadPipelineBuildChildren load triggered pipeline from given build
 func LoadPipelineBuildChildren(db *sql.DB, pipelineID int64, applicationID int64, buildNumber int64, environmentID int64) ([]sdk.PipelineBuild, error) {
 	pbs := []sdk.PipelineBuild{}
@@ -1324,7 +1345,7 @@ func LoadPipelineBuildChildren(db *sql.DB, pipelineID int64, applicationID int64
 }
 
 // StopPipelineBuild fails all currently building actions
-func StopPipelineBuild(db *sql.DB, pbID int64) error {
+func StopPipelineBuild(db database.Executer, pbID int64) error {
 	query := `UPDATE action_build SET status = $1, done = now() WHERE pipeline_build_id = $2 AND status IN ( $3, $4 )`
 	_, err := db.Exec(query, string(sdk.StatusFail), pbID, string(sdk.StatusBuilding), string(sdk.StatusWaiting))
 	if err != nil {
@@ -1356,7 +1377,7 @@ func RestartPipelineBuild(db *sql.DB, pb sdk.PipelineBuild) error {
 	defer tx.Rollback()
 
 	for _, ab := range actionBuilds {
-		if ab.Status != sdk.StatusDisabled && ab.Status != sdk.StatusSkipped && (ab.Status == sdk.StatusFail || pb.Status == sdk.StatusSuccess) {
+		if ab.Status != sdk.StatusDisabled && ab.Status != sdk.StatusSkipped && (ab.Status == sdk.StatusFail || ab.Status == sdk.StatusTimeout || pb.Status == sdk.StatusSuccess) {
 			log.Notice("RestartPipelineBuild: Action %s: restarting\n", ab.ActionName)
 			err = RestartActionBuild(tx, ab.ID)
 			if err != nil {
@@ -1416,6 +1437,11 @@ func RestartPipelineBuild(db *sql.DB, pb sdk.PipelineBuild) error {
 
 	}
 
+	// Stages waiting for approval are approved again, rejections only if the build failed
+	if err := build.DeleteApprovals(tx, pb.ID, pb.Status != sdk.StatusSuccess); err != nil {
+		return fmt.Errorf("RestartPipelineBuild> Cannot delete approvals: %s", err)
+	}
+
 	err = UpdatePipelineBuildStatus(tx, pb, sdk.StatusBuilding)
 	if err != nil {
 		return fmt.Errorf("RestartPipelineBuild> UpdatePipelineBuildStatus> %s", err)
@@ -1441,14 +1467,21 @@ func RestartActionBuild(db *sql.Tx, actionBuildID int64) error {
 	}
 
 	// Delete previous build logs
-	query = `DELETE FROM build_log WHERE action_build_id = $1`
-	_, err = db.Exec(query, actionBuildID)
+	err = build.DeleteBuildLogs(db, actionBuildID)
 	if err != nil {
 		return err
 	}
 
+	// Logs are gone, so are previous attempts of a retry policy
+	query = `DELETE FROM action_build_attempt WHERE action_build_id = $1`
+	if _, err = db.Exec(query, actionBuildID); err != nil {
+		return err
+	}
+
 	// Update status to Waiting
-	query = `UPDATE action_build SET status = $1 WHERE id = $2`
+	// Timeouts run again from now
+	query = `UPDATE action_build SET status = $1, queued = NOW(), not_before = NULL, attempt = 1, exit_code = NULL, fail_cause = NULL, steps = NULL,
+		booked_hatchery_id = NULL, booked_until = NULL, booked_worker_id = NULL WHERE id = $2`
 	res, err := db.Exec(query, sdk.StatusWaiting.String(), actionBuildID)
 	if err != nil {
 		return err
@@ -1489,6 +1522,7 @@ func LoadCompletePipelineBuildToArchive(db database.Querier, pipelineBuildID int
 			 action_build.start,
 			 action_build.done,
 			 action_build.worker_model_name,
+			 action_build.steps,
 			 action.name,
 			 pipeline_stage.id,
 			 pipeline_stage.name,
@@ -1538,7 +1572,7 @@ func LoadCompletePipelineBuildToArchive(db database.Querier, pipelineBuildID int
 		var stage sdk.Stage
 		var manual sql.NullBool
 		var stageBuildOrder, stageID, actionBuildID, actionBuildPipelineActionID, trigBy, parentID sql.NullInt64
-		var stageName, actionBuildStatusTmp, actionBuildArgs, actionBuildActionName, branch, hash, author, username, trigPipname, actionBuildWorkerModelName sql.NullString
+		var stageName, actionBuildStatusTmp, actionBuildArgs, actionBuildActionName, branch, hash, author, username, trigPipname, actionBuildWorkerModelName, actionBuildSteps sql.NullString
 		var version sql.NullInt64
 
 		err = rows.Scan(
@@ -1560,6 +1594,7 @@ func LoadCompletePipelineBuildToArchive(db database.Querier, pipelineBuildID int
 			&actionStart,
 			&actionDone,
 			&actionBuildWorkerModelName,
+			&actionBuildSteps,
 			&actionBuildActionName,
 			&stageID,
 			&stageName,
@@ -1617,6 +1652,12 @@ func LoadCompletePipelineBuildToArchive(db database.Querier, pipelineBuildID int
 			actionBuild.Model = actionBuildWorkerModelName.String
 		}
 
+		if actionBuildSteps.Valid {
+			if err := json.Unmarshal([]byte(actionBuildSteps.String), &actionBuild.Steps); err != nil {
+				log.Warning("LoadCompletePipelineBuildToArchive> Cannot unmarshal steps of action build %d: %s", actionBuild.ID, err)
+			}
+		}
+
 		pb.Trigger = sdk.PipelineBuildTrigger{}
 		loadPbTrigger(&pb, manual, parentID, branch, hash, author, username, trigPipname, version)
 
@@ -1677,27 +1718,29 @@ func LoadCompletePipelineBuildToArchive(db database.Querier, pipelineBuildID int
 		for i := range stage.ActionBuilds {
 			actionBuild := &stage.ActionBuilds[i]
 			// add logs
-			var logs sql.NullString
-			queryLog := `
-			SELECT string_agg(log,'')
-			FROM (
-				SELECT '[' || timestamp || ']' || ' ' || value as log
-				FROM build_log
-				WHERE action_build_id = $1
-				ORDER BY build_log.id ASC
-			     ) sub
-			`
-			if err := db.QueryRow(queryLog, actionBuild.ID).Scan(&logs); err != nil {
-				if err != sql.ErrNoRows {
-					log.Warning("LoadCompletePipelineBuildToArchive> Error querying build_log : %s", err)
-					return pb, err
-				}
-			} else if logs.Valid {
-				actionBuild.Logs = logs.String
+			logs, err := build.LoadLogs(db, actionBuild.ID, math.MaxInt32, 0)
+			if err != nil {
+				log.Warning("LoadCompletePipelineBuildToArchive> Error loading build logs : %s", err)
+				return pb, err
+			}
+			var buffer bytes.Buffer
+			for _, l := range logs {
+				buffer.WriteString(fmt.Sprintf("[%s] %s", l.Timestamp.Format("2006-01-02 15:04:05.999999-07"), l.Value))
 			}
+			actionBuild.Logs = buffer.String()
 		}
 	}
 
+	// add approvals
+	approvals, err := build.LoadApprovals(db, pb.ID)
+	if err != nil {
+		log.Warning("LoadCompletePipelineBuildToArchive> Error loading approvals : %s", err)
+		return pb, err
+	}
+	if len(approvals) > 0 {
+		pb.Approvals = approvals
+	}
+
 	return pb, nil
 }
 
@@ -1711,6 +1754,14 @@ func SelectBuildForUpdate(db database.Querier, buildID int64) error {
 	return db.QueryRow(query, buildID, sdk.StatusBuilding.String()).Scan(&id)
 }
 
+// SelectWaitingApprovalBuildForUpdate locks a pipeline build waiting for approval, so that concurrent
+// approvals are decided one after the other. It returns sql.ErrNoRows if the build is not waiting anymore.
+func SelectWaitingApprovalBuildForUpdate(db database.Querier, buildID int64) error {
+	var id int64
+	query := `SELECT id FROM pipeline_build WHERE id = $1 AND status = $2 FOR UPDATE`
+	return db.QueryRow(query, buildID, sdk.StatusWaitingApproval.String()).Scan(&id)
+}
+
 // LoadBuildIDsToArchive Load build to archive
 func LoadBuildIDsToArchive(db *sql.DB, hours int) ([]int64, error) {
 	var buildIDs []int64
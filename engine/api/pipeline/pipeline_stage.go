@@ -25,7 +25,7 @@ var (
 // LoadStage Get a stage from its ID and pipeline ID
 func LoadStage(db database.Querier, pipelineID int64, stageID int64) (*sdk.Stage, error) {
 	query := `
-		SELECT pipeline_stage.id, pipeline_stage.pipeline_id, pipeline_stage.name, pipeline_stage.build_order, pipeline_stage.enabled, pipeline_stage_prerequisite.parameter, pipeline_stage_prerequisite.expected_value
+		SELECT pipeline_stage.id, pipeline_stage.pipeline_id, pipeline_stage.name, pipeline_stage.build_order, pipeline_stage.enabled, pipeline_stage.matrix, pipeline_stage.timeout, pipeline_stage.condition, pipeline_stage.approval, pipeline_stage_prerequisite.parameter, pipeline_stage_prerequisite.expected_value
 		FROM pipeline_stage
 		LEFT OUTER JOIN pipeline_stage_prerequisite ON pipeline_stage_prerequisite.pipeline_stage_id = pipeline_stage.id
 		WHERE pipeline_stage.pipeline_id = $1 
@@ -45,8 +45,17 @@ func LoadStage(db database.Querier, pipelineID int64, stageID int64) (*sdk.Stage
 	defer rows.Close()
 
 	for rows.Next() {
-		var parameter, expectedValue sql.NullString
-		rows.Scan(&stage.ID, &stage.PipelineID, &stage.Name, &stage.BuildOrder, &stage.Enabled, &parameter, &expectedValue)
+		var parameter, expectedValue, matrix, condition, approval sql.NullString
+		rows.Scan(&stage.ID, &stage.PipelineID, &stage.Name, &stage.BuildOrder, &stage.Enabled, &matrix, &stage.Timeout, &condition, &approval, &parameter, &expectedValue)
+		stage.Condition = condition.String
+		stage.Matrix, err = matrixFromDB(matrix)
+		if err != nil {
+			return nil, err
+		}
+		stage.Approval, err = approvalFromDB(approval)
+		if err != nil {
+			return nil, err
+		}
 		if parameter.Valid && expectedValue.Valid {
 			p := sdk.Prerequisite{
 				Parameter:     parameter.String,
@@ -62,9 +71,18 @@ func LoadStage(db database.Querier, pipelineID int64, stageID int64) (*sdk.Stage
 // InsertStage insert given stage into given database
 func InsertStage(db database.QueryExecuter, s *sdk.Stage) error {
 	s.Enabled = true
-	query := `INSERT INTO "pipeline_stage" (pipeline_id, name, build_order, enabled) VALUES($1,$2,$3,$4) RETURNING id`
+	query := `INSERT INTO "pipeline_stage" (pipeline_id, name, build_order, enabled, matrix, timeout, condition, approval) VALUES($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`
 
-	if err := db.QueryRow(query, s.PipelineID, s.Name, s.BuildOrder, true).Scan(&s.ID); err != nil {
+	matrix, err := matrixToDB(s.Matrix)
+	if err != nil {
+		return err
+	}
+	approval, err := approvalToDB(s.Approval)
+	if err != nil {
+		return err
+	}
+
+	if err := db.QueryRow(query, s.PipelineID, s.Name, s.BuildOrder, true, matrix, s.Timeout, s.Condition, approval).Scan(&s.ID); err != nil {
 		return err
 	}
 	return InsertStagePrequisites(db, s)
@@ -153,13 +171,14 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 
 	query := `
 	SELECT  pipeline_stage_R.id as stage_id, pipeline_stage_R.pipeline_id, pipeline_stage_R.name, pipeline_stage_R.last_modified, 
-			pipeline_stage_R.build_order, pipeline_stage_R.enabled, pipeline_stage_R.parameter, 
+			pipeline_stage_R.build_order, pipeline_stage_R.enabled, pipeline_stage_R.matrix, pipeline_stage_R.timeout, pipeline_stage_R.condition, pipeline_stage_R.approval, pipeline_stage_R.parameter, 
 			pipeline_stage_R.expected_value, pipeline_action_R.id as pipeline_action_id, pipeline_action_R.action_id, pipeline_action_R.action_last_modified,
-			pipeline_action_R.action_args, pipeline_action_R.action_enabled
+			pipeline_action_R.action_args, pipeline_action_R.action_enabled, pipeline_action_R.action_matrix, pipeline_action_R.action_timeout, pipeline_action_R.action_retry,
+			pipeline_action_R.action_condition
 	FROM (
 		SELECT  pipeline_stage.id, pipeline_stage.pipeline_id, 
 				pipeline_stage.name, pipeline_stage.last_modified ,pipeline_stage.build_order, 
-				pipeline_stage.enabled, 
+				pipeline_stage.enabled, pipeline_stage.matrix, pipeline_stage.timeout, pipeline_stage.condition, pipeline_stage.approval,
 				pipeline_stage_prerequisite.parameter, pipeline_stage_prerequisite.expected_value
 		FROM pipeline_stage
 		LEFT OUTER JOIN pipeline_stage_prerequisite ON pipeline_stage.id = pipeline_stage_prerequisite.pipeline_stage_id
@@ -168,7 +187,7 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 	LEFT OUTER JOIN (
 		SELECT  pipeline_action.id, action.id as action_id, action.name as action_name, action.last_modified as action_last_modified, 
 				pipeline_action.args as action_args, pipeline_action.enabled as action_enabled, 
-				pipeline_action.pipeline_stage_id
+				pipeline_action.matrix as action_matrix, pipeline_action.timeout as action_timeout, pipeline_action.retry as action_retry, pipeline_action.condition as action_condition, pipeline_action.pipeline_stage_id
 		FROM action
 		JOIN pipeline_action ON pipeline_action.action_id = action.id
 	) as pipeline_action_R ON pipeline_action_R.pipeline_stage_id = pipeline_stage_R.id
@@ -184,22 +203,24 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 	mapAllActions := map[int64]*sdk.Action{}
 	mapActionsStages := map[int64][]sdk.Action{}
 	mapArgs := map[int64][]string{}
+	mapMatrix := map[int64][]*sdk.Matrix{}
 	stagesPtr := []*sdk.Stage{}
 
 	for rows.Next() {
 		var stageID, pipelineID int64
 		var stageBuildOrder int
-		var pipelineActionID, actionID sql.NullInt64
+		var pipelineActionID, actionID, stageTimeout, actionTimeout sql.NullInt64
 		var stageName string
-		var stagePrerequisiteParameter, stagePrerequisiteExpectedValue, actionArgs sql.NullString
+		var stagePrerequisiteParameter, stagePrerequisiteExpectedValue, actionArgs, stageMatrix, actionMatrix, actionRetry sql.NullString
+		var stageCondition, stageApproval, actionCondition sql.NullString
 		var stageEnabled, actionEnabled sql.NullBool
 		var stageLastModified, actionLastModified pq.NullTime
 
 		err = rows.Scan(
 			&stageID, &pipelineID, &stageName, &stageLastModified,
-			&stageBuildOrder, &stageEnabled, &stagePrerequisiteParameter,
+			&stageBuildOrder, &stageEnabled, &stageMatrix, &stageTimeout, &stageCondition, &stageApproval, &stagePrerequisiteParameter,
 			&stagePrerequisiteExpectedValue, &pipelineActionID, &actionID, &actionLastModified,
-			&actionArgs, &actionEnabled)
+			&actionArgs, &actionEnabled, &actionMatrix, &actionTimeout, &actionRetry, &actionCondition)
 		if err != nil {
 			return err
 		}
@@ -214,6 +235,16 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 				Enabled:      stageEnabled.Bool,
 				BuildOrder:   stageBuildOrder,
 				LastModified: stageLastModified.Time.Unix(),
+				Timeout:      stageTimeout.Int64,
+				Condition:    stageCondition.String,
+			}
+			stageData.Matrix, err = matrixFromDB(stageMatrix)
+			if err != nil {
+				return err
+			}
+			stageData.Approval, err = approvalFromDB(stageApproval)
+			if err != nil {
+				return err
 			}
 			mapStages[stageID] = stageData
 			stagesPtr = append(stagesPtr, stageData)
@@ -249,10 +280,21 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 					ID:               actionID.Int64,
 					Enabled:          actionEnabled.Bool,
 					LastModified:     actionLastModified.Time.Unix(),
+					Timeout:          actionTimeout.Int64,
+					Condition:        actionCondition.String,
+				}
+				a.Retry, err = retryFromDB(actionRetry)
+				if err != nil {
+					return err
 				}
 				mapAllActions[pipelineActionID.Int64] = a
 				mapActionsStages[stageID] = append(mapActionsStages[stageID], *a)
 				mapArgs[stageID] = append(mapArgs[stageID], actionArgs.String)
+				m, err := matrixFromDB(actionMatrix)
+				if err != nil {
+					return err
+				}
+				mapMatrix[stageID] = append(mapMatrix[stageID], m)
 			}
 		}
 	}
@@ -276,6 +318,10 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 			a.Enabled = mapActionsStages[id][index].Enabled
 			a.PipelineStageID = id
 			a.PipelineActionID = mapActionsStages[id][index].PipelineActionID
+			a.Matrix = mapMatrix[id][index]
+			a.Timeout = mapActionsStages[id][index].Timeout
+			a.Retry = mapActionsStages[id][index].Retry
+			a.Condition = mapActionsStages[id][index].Condition
 
 			var pipelineActionParameter []sdk.Parameter
 			var isUpdated bool
@@ -303,8 +349,18 @@ func loadPipelineStage(db database.Querier, p *sdk.Pipeline, args ...FuncArg) er
 
 // UpdateStage update Stage and all its prequisites
 func UpdateStage(db database.QueryExecuter, s *sdk.Stage) error {
-	query := `UPDATE pipeline_stage SET name=$1, build_order=$2, enabled=$3 WHERE id=$4`
-	_, err := db.Exec(query, s.Name, s.BuildOrder, s.Enabled, s.ID)
+	matrix, err := matrixToDB(s.Matrix)
+	if err != nil {
+		return err
+	}
+
+	approval, err := approvalToDB(s.Approval)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE pipeline_stage SET name=$1, build_order=$2, enabled=$3, matrix=$5, timeout=$6, condition=$7, approval=$8 WHERE id=$4`
+	_, err = db.Exec(query, s.Name, s.BuildOrder, s.Enabled, s.ID, matrix, s.Timeout, s.Condition, approval)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// UpdatePipelineActionRetry set the retry policy of a pipeline action, nil removes it
+func UpdatePipelineActionRetry(db database.Executer, pipelineActionID, actionID int64, r *sdk.RetryPolicy) error {
+	retry, err := retryToDB(r)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE pipeline_action SET retry = $1 WHERE id = $2 AND action_id = $3`
+	_, err = db.Exec(query, retry, pipelineActionID, actionID)
+	return err
+}
+
+func retryToDB(r *sdk.RetryPolicy) (sql.NullString, error) {
+	if r == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func retryFromDB(s sql.NullString) (*sdk.RetryPolicy, error) {
+	if !s.Valid || s.String == "" {
+		return nil, nil
+	}
+	r := &sdk.RetryPolicy{}
+	if err := json.Unmarshal([]byte(s.String), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RetryActionBuilds puts back in queue failed action builds of a pipeline action which can be retried
+// according to the policy. Failed attempt is archived with test results of the pipeline build at that time,
+// logs are kept and the next attempt appends its own.
+func RetryActionBuilds(tx *sql.Tx, pipelineActionID, pipelineBuildID int64, policy *sdk.RetryPolicy) error {
+	query := `SELECT id, attempt, fail_cause, exit_code, start, done FROM action_build
+		WHERE pipeline_action_id = $1 AND pipeline_build_id = $2 AND status = $3 FOR UPDATE`
+	rows, err := tx.Query(query, pipelineActionID, pipelineBuildID, sdk.StatusFail.String())
+	if err != nil {
+		return err
+	}
+
+	var attempts []sdk.ActionBuildAttempt
+	for rows.Next() {
+		var cause sql.NullString
+		var exitCode sql.NullInt64
+		var start, done pq.NullTime
+		a := sdk.ActionBuildAttempt{Status: sdk.StatusFail}
+		if err := rows.Scan(&a.ActionBuildID, &a.Attempt, &cause, &exitCode, &start, &done); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Cause = cause.String
+		a.ExitCode = int(exitCode.Int64)
+		a.Start = start.Time
+		a.Done = done.Time
+		if policy.ShouldRetry(a.Attempt, a.Cause, a.ExitCode) {
+			attempts = append(attempts, a)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	tests, err := build.LoadTestResults(tx, pipelineBuildID)
+	if err != nil {
+		return err
+	}
+
+	for i := range attempts {
+		attempts[i].Tests = &tests
+		if err := retryActionBuild(tx, &attempts[i], policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func retryActionBuild(tx *sql.Tx, a *sdk.ActionBuildAttempt, policy *sdk.RetryPolicy) error {
+	if err := insertActionBuildAttempt(tx, a); err != nil {
+		return err
+	}
+
+	delay := policy.Delay(a.Attempt)
+	reason := a.Cause
+	if a.Cause == sdk.FailureResult && a.ExitCode != 0 {
+		reason = fmt.Sprintf("exit code %d", a.ExitCode)
+	}
+	log.Notice("retryActionBuild> Retrying action_build %d in %s (attempt %d/%d failed: %s)\n", a.ActionBuildID, delay, a.Attempt, policy.MaxAttempts, reason)
+	msg := fmt.Sprintf("Attempt %d/%d failed (%s), retrying in %s\n", a.Attempt, policy.MaxAttempts, reason, delay)
+	if err := build.InsertLog(tx, a.ActionBuildID, "SYSTEM", msg); err != nil {
+		return err
+	}
+
+	// Stage timeout still runs from the first time the action build was queued
+	query := `UPDATE action_build SET status = $1, attempt = attempt + 1, not_before = $2, start = $2, done = NULL,
+		exit_code = NULL, fail_cause = NULL, steps = NULL, lease_worker_id = NULL, lease_until = NULL,
+		booked_hatchery_id = NULL, booked_until = NULL, booked_worker_id = NULL
+		WHERE id = $3 RETURNING pipeline_build_id, pipeline_action_id`
+	var pbID, paID int64
+	if err := tx.QueryRow(query, sdk.StatusWaiting.String(), time.Now().Add(delay), a.ActionBuildID).Scan(&pbID, &paID); err != nil {
+		return err
+	}
+
+	build.PublishBuildEvent(sdk.BuildEvent{
+		Type:             sdk.BuildEventActionBuild,
+		PipelineBuildID:  pbID,
+		ActionBuildID:    a.ActionBuildID,
+		PipelineActionID: paID,
+		Status:           sdk.StatusWaiting,
+	})
+	return nil
+}
+
+func insertActionBuildAttempt(db database.Executer, a *sdk.ActionBuildAttempt) error {
+	var tests sql.NullString
+	if a.Tests != nil {
+		b, err := json.Marshal(a.Tests)
+		if err != nil {
+			return err
+		}
+		tests = sql.NullString{String: string(b), Valid: true}
+	}
+
+	query := `INSERT INTO action_build_attempt (action_build_id, attempt, status, fail_cause, exit_code, start, done, tests)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := db.Exec(query, a.ActionBuildID, a.Attempt, a.Status.String(), a.Cause, a.ExitCode, a.Start, a.Done, tests)
+	return err
+}
+
+// LoadActionBuildAttempts loads previous attempts of action builds of a pipeline action, oldest first
+func LoadActionBuildAttempts(db database.Querier, pipelineBuildID, pipelineActionID int64) ([]sdk.ActionBuildAttempt, error) {
+	query := `SELECT action_build_attempt.action_build_id, action_build_attempt.attempt, action_build_attempt.status,
+		action_build_attempt.fail_cause, action_build_attempt.exit_code, action_build_attempt.start,
+		action_build_attempt.done, action_build_attempt.tests
+		FROM action_build_attempt
+		JOIN action_build ON action_build.id = action_build_attempt.action_build_id
+		WHERE action_build.pipeline_build_id = $1 AND action_build.pipeline_action_id = $2
+		ORDER BY action_build_attempt.action_build_id, action_build_attempt.attempt`
+	rows, err := db.Query(query, pipelineBuildID, pipelineActionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []sdk.ActionBuildAttempt{}
+	for rows.Next() {
+		var a sdk.ActionBuildAttempt
+		var status string
+		var cause, tests sql.NullString
+		var exitCode sql.NullInt64
+		var start, done pq.NullTime
+		if err := rows.Scan(&a.ActionBuildID, &a.Attempt, &status, &cause, &exitCode, &start, &done, &tests); err != nil {
+			return nil, err
+		}
+		a.Status = sdk.StatusFromString(status)
+		a.Cause = cause.String
+		a.ExitCode = int(exitCode.Int64)
+		a.Start = start.Time
+		a.Done = done.Time
+		if tests.Valid {
+			a.Tests = &sdk.Tests{}
+			if err := json.Unmarshal([]byte(tests.String), a.Tests); err != nil {
+				return nil, err
+			}
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	data := []byte(`
+name: deploy
+stages:
+- name: Deploy
+  jobs:
+  - name: upload
+    retry:
+      max_attempts: 3
+      backoff: 30
+      exit_codes: [7, 28]
+      worker_lost: true
+    steps:
+    - action: Script
+      parameters:
+      - name: script
+        value: curl -T package.tar.gz http://repository
+`)
+
+	d, err := sdk.ParsePipelineDefinition(data, sdk.FormatYAML)
+	if err != nil {
+		t.Fatalf("Cannot parse definition: %s", err)
+	}
+	policy := d.Stages[0].Jobs[0].JoinedAction().Retry
+	if policy == nil || policy.MaxAttempts != 3 {
+		t.Fatalf("Job retry policy should be set on joined action, got %+v", policy)
+	}
+
+	// Stored as is in pipeline_action
+	s, err := retryToDB(policy)
+	if err != nil {
+		t.Fatalf("Cannot marshal retry policy: %s", err)
+	}
+	loaded, err := retryFromDB(s)
+	if err != nil {
+		t.Fatalf("Cannot unmarshal retry policy: %s", err)
+	}
+	if !reflect.DeepEqual(policy, loaded) {
+		t.Errorf("Retry policy should be loaded as stored, got %+v", loaded)
+	}
+	if s, _ := retryToDB(nil); s.Valid {
+		t.Errorf("No retry policy should be stored as NULL")
+	}
+
+	tests := []struct {
+		attempt  int
+		cause    string
+		exitCode int
+		retry    bool
+	}{
+		{1, sdk.FailureResult, 7, true},
+		{2, sdk.FailureResult, 28, true},
+		{3, sdk.FailureResult, 7, false},
+		{1, sdk.FailureResult, 1, false},
+		{1, sdk.FailureWorkerLost, 0, true},
+		{1, "", 0, false},
+	}
+	for _, tt := range tests {
+		if got := policy.ShouldRetry(tt.attempt, tt.cause, tt.exitCode); got != tt.retry {
+			t.Errorf("ShouldRetry(%d, %s, %d) = %v, want %v", tt.attempt, tt.cause, tt.exitCode, got, tt.retry)
+		}
+	}
+
+	if d := policy.Delay(1); d != 30*time.Second {
+		t.Errorf("First retry should wait backoff, got %s", d)
+	}
+	if d := policy.Delay(2); d != time.Minute {
+		t.Errorf("Delay should double for each attempt, got %s", d)
+	}
+	if d := policy.Delay(20); d != time.Hour {
+		t.Errorf("Delay should be capped, got %s", d)
+	}
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// UpdatePipelineActionTimeout set the timeout of a pipeline action in seconds, 0 removes it
+func UpdatePipelineActionTimeout(db database.Executer, pipelineActionID, actionID, timeout int64) error {
+	query := `UPDATE pipeline_action SET timeout = $1 WHERE id = $2 AND action_id = $3`
+	_, err := db.Exec(query, timeout, pipelineActionID, actionID)
+	return err
+}
+
+// TimeoutKiller stops action builds:
+// - building for longer than their action timeout
+// - waiting or building while their stage has been running for longer than the stage timeout
+// Building workers are notified that their action build has been stopped and cancel it.
+func TimeoutKiller() {
+	// If this goroutine exits, then it's a crash
+	defer log.Fatalf("Goroutine of pipeline.TimeoutKiller exited - Exit CDS Engine")
+
+	for {
+		time.Sleep(10 * time.Second)
+		db := database.DB()
+
+		if db != nil {
+			timeouts, err := loadTimedOutActionBuilds(db)
+			if err != nil {
+				log.Warning("TimeoutKiller> Cannot load timed out actions: %s\n", err)
+				continue
+			}
+
+			for id, reason := range timeouts {
+				if err := killTimedOutAction(db, id, reason); err != nil {
+					log.Warning("TimeoutKiller> Cannot kill action build %d: %s\n", id, err)
+					time.Sleep(1 * time.Second) // Do not spam an unavailable database
+				}
+			}
+		}
+	}
+}
+
+func killTimedOutAction(db *sql.DB, actionBuildID int64, reason string) error {
+	log.Notice("killTimedOutAction> Stopping action_build %d: %s\n", actionBuildID, reason)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := build.InsertLog(tx, actionBuildID, "SYSTEM", fmt.Sprintf("Timeout: %s\n", reason)); err != nil {
+		return err
+	}
+	if err := build.UpdateActionBuildStatus(tx, &sdk.ActionBuild{ID: actionBuildID}, sdk.StatusTimeout); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadTimedOutActionBuilds returns action builds to stop, with the reason why
+func loadTimedOutActionBuilds(db *sql.DB) (map[int64]string, error) {
+	query := `
+		SELECT id, timeout, stage_timeout, status = $1 AND timeout > 0 AND start + timeout * INTERVAL '1 second' < NOW()
+		FROM action_build
+		WHERE (status = $1 AND timeout > 0 AND start + timeout * INTERVAL '1 second' < NOW())
+		OR (status IN ($1, $2) AND stage_timeout > 0 AND queued + stage_timeout * INTERVAL '1 second' < NOW())
+		`
+	rows, err := db.Query(query, string(sdk.StatusBuilding), string(sdk.StatusWaiting))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timeouts := map[int64]string{}
+	for rows.Next() {
+		var id, timeout, stageTimeout int64
+		var actionTimedOut bool
+		if err := rows.Scan(&id, &timeout, &stageTimeout, &actionTimedOut); err != nil {
+			return nil, err
+		}
+		if actionTimedOut {
+			timeouts[id] = fmt.Sprintf("action did not finish within %s", time.Duration(timeout)*time.Second)
+		} else {
+			timeouts[id] = fmt.Sprintf("stage did not finish within %s", time.Duration(stageTimeout)*time.Second)
+		}
+	}
+
+	return timeouts, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -56,6 +57,12 @@ func updateGroupRoleOnPipelineHandler(w http.ResponseWriter, r *http.Request, db
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupPipeline.Permission, permission.PipelineRoles(p.ID, c.User)); err != nil {
+		log.Warning("updateGroupRoleOnPipelineHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupPipeline.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	groupInPipeline, err := group.CheckGroupInPipeline(db, p.ID, g.ID)
 	if err != nil {
 		log.Warning("updateGroupRoleOnPipelineHandler: Cannot check if group %s is already in the pipeline %s: %s\n", g.Name, p.Name, err)
@@ -63,8 +70,8 @@ func updateGroupRoleOnPipelineHandler(w http.ResponseWriter, r *http.Request, db
 		return
 	}
 	if groupInPipeline {
-		if groupPipeline.Permission != permission.PermissionReadWriteExecute {
-			permissions, err := group.LoadAllPipelineGroupByRole(db, p.ID, permission.PermissionReadWriteExecute)
+		if !role.Can(groupPipeline.Permission, sdk.RoleActionManageGroups) {
+			permissions, err := group.LoadAllPipelineGroupByAction(db, p.ID, sdk.RoleActionManageGroups)
 			if err != nil {
 				log.Warning("updateGroupRoleOnPipelineHandler: Cannot load groups for pipeline %s: %s\n", p.Name, err)
 				WriteError(w, r, err)
@@ -138,13 +145,13 @@ func updateGroupsOnPipelineHandler(w http.ResponseWriter, r *http.Request, db *s
 
 	found := false
 	for _, gp := range groupsPermission {
-		if gp.Permission == permission.PermissionReadWriteExecute {
+		if role.Can(gp.Permission, sdk.RoleActionManageGroups) {
 			found = true
 			break
 		}
 	}
 	if !found {
-		log.Warning("updateGroupsOnPipelineHandler: Need one group with write permission.")
+		log.Warning("updateGroupsOnPipelineHandler: Need one group able to manage groups.")
 		WriteError(w, r, sdk.ErrGroupNeedWrite)
 		return
 	}
@@ -156,6 +163,18 @@ func updateGroupsOnPipelineHandler(w http.ResponseWriter, r *http.Request, db *s
 		return
 	}
 
+	current, err := group.LoadAllPipelineGroupByAction(db, p.ID, sdk.RoleActionRead)
+	if err != nil {
+		log.Warning("updateGroupsOnPipelineHandler: Cannot load groups: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkGivenRoles(db, c.User, groupsPermission, current, permission.PipelineRoles(p.ID, c.User)); err != nil {
+		log.Warning("updateGroupsOnPipelineHandler: %s cannot give roles: %s\n", c.User.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		log.Warning("updateGroupsOnPipelineHandler: Cannot start transaction: %s\n", err)
@@ -237,6 +256,12 @@ func addGroupInPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.D
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupPermission.Permission, permission.PipelineRoles(p.ID, c.User)); err != nil {
+		log.Warning("addGroupInPipeline: %s cannot give role %d to group %s: %s\n", c.User.Username, groupPermission.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	groupInPipeline, err := group.CheckGroupInPipeline(db, p.ID, g.ID)
 	if err != nil {
 		log.Warning("addGroupInPipeline: Cannot check if group %s is already in the pipeline %s: %s\n", g.Name, p.Name, err)
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func exportPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	name := vars["permPipelineKey"]
+	format := r.FormValue("format")
+
+	p, err := pipeline.LoadPipeline(db, key, name, true)
+	if err != nil {
+		log.Warning("exportPipelineHandler> Cannot load pipeline %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	data, err := sdk.NewPipelineDefinition(p).Marshal(format)
+	if err != nil {
+		log.Warning("exportPipelineHandler> Cannot export pipeline %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if format == sdk.FormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "application/x-yaml")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	dryRun := r.FormValue("dryRun") == "true"
+
+	proj, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("importPipelineHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	def, err := sdk.ParsePipelineDefinition(data, r.FormValue("format"))
+	if err != nil {
+		log.Warning("importPipelineHandler> Cannot parse pipeline definition: %s\n", err)
+		if _, ok := err.(*sdk.Error); ok {
+			WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	changes, err := importPipelineDefinition(db, proj, def, c.User, dryRun)
+	if err != nil {
+		log.Warning("importPipelineHandler> Cannot import pipeline %s: %s\n", def.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, changes, http.StatusOK)
+}
+
+// importPipelineDefinition creates or updates a pipeline from its definition in one transaction,
+// which is rolled back with dryRun
+func importPipelineDefinition(db *sql.DB, proj *sdk.Project, def *sdk.PipelineDefinition, user *sdk.User, dryRun bool) ([]string, error) {
+	regexp := regexp.MustCompile(sdk.NamePattern)
+	if !regexp.MatchString(def.Name) {
+		return nil, sdk.ErrInvalidPipelinePattern
+	}
+
+	exist, err := pipeline.ExistPipeline(db, proj.ID, def.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	changes := []string{}
+	if !exist {
+		p := &sdk.Pipeline{Name: def.Name, Type: def.Type, ProjectID: proj.ID}
+		if p.Type == "" {
+			p.Type = sdk.BuildPipeline
+		}
+		if err := pipeline.InsertPipeline(tx, p); err != nil {
+			return nil, err
+		}
+		if err := group.LoadGroupByProject(tx, proj); err != nil {
+			return nil, err
+		}
+		if err := group.InsertGroupsInPipeline(tx, proj.ProjectGroups, p.ID); err != nil {
+			return nil, err
+		}
+		changes = append(changes, "pipeline "+def.Name+" created")
+	}
+
+	p, err := pipeline.LoadPipeline(tx, proj.Key, def.Name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if exist && !permission.AccessToPipeline(sdk.DefaultEnv.ID, p.ID, user, sdk.RoleActionEdit) {
+		return nil, sdk.ErrForbidden
+	}
+
+	pipChanges, err := pipeline.ImportPipeline(tx, p, def, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, pipChanges...)
+
+	if dryRun {
+		return changes, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	cache.DeleteAll(cache.Key("application", proj.Key, "*"))
+	cache.Delete(cache.Key("pipeline", proj.Key, def.Name))
+
+	return changes, nil
+}
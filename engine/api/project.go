@@ -17,6 +17,7 @@ import (
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -240,6 +241,14 @@ func addProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.C
 		return
 	}
 
+	for _, gp := range p.ProjectGroups {
+		if _, err := role.LoadByID(db, gp.Permission); err != nil {
+			log.Warning("AddProject: Cannot give role %d to group %s: %s\n", gp.Permission, gp.Group.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	tx, err := db.Begin()
 	defer tx.Rollback()
 	if err != nil {
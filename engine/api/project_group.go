@@ -15,6 +15,7 @@ import (
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -88,6 +89,12 @@ func updateGroupRoleOnProjectHandler(w http.ResponseWriter, r *http.Request, db
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupProject.Permission, permission.ProjectRoles(key, c.User)); err != nil {
+		log.Warning("updateGroupRoleHandler: %s cannot give role %d to group %s: %s\n", c.User.Username, groupProject.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	groupInProject, err := group.CheckGroupInProject(db, p.ID, g.ID)
 	if err != nil {
 		log.Warning("updateGroupRoleHandler: Cannot check if group %s is already in the project %s: %s\n", g.Name, p.Name, err)
@@ -96,8 +103,8 @@ func updateGroupRoleOnProjectHandler(w http.ResponseWriter, r *http.Request, db
 	}
 	if groupInProject {
 
-		if groupProject.Permission != permission.PermissionReadWriteExecute {
-			permissions, err := group.LoadAllProjectGroupByRole(db, p.ID, permission.PermissionReadWriteExecute)
+		if !role.Can(groupProject.Permission, sdk.RoleActionManageGroups) {
+			permissions, err := group.LoadAllProjectGroupByAction(db, p.ID, sdk.RoleActionManageGroups)
 			if err != nil {
 				log.Warning("updateGroupRoleHandler: Cannot load group for the given project %s:  %s\n", p.Name, err)
 				WriteError(w, r, err)
@@ -148,13 +155,13 @@ func updateGroupsInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 
 	found := false
 	for _, gp := range groupProject {
-		if gp.Permission == permission.PermissionReadWriteExecute {
+		if role.Can(gp.Permission, sdk.RoleActionManageGroups) {
 			found = true
 			break
 		}
 	}
 	if !found {
-		log.Warning("updateGroupsInProject: Need one group with write permission.")
+		log.Warning("updateGroupsInProject: Need one group able to manage groups.")
 		WriteError(w, r, sdk.ErrGroupNeedWrite)
 		return
 	}
@@ -166,6 +173,18 @@ func updateGroupsInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		return
 	}
 
+	current, err := group.LoadAllProjectGroupByAction(db, p.ID, sdk.RoleActionRead)
+	if err != nil {
+		log.Warning("updateGroupsInProject: Cannot load groups: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkGivenRoles(db, c.User, groupProject, current, permission.ProjectRoles(key, c.User)); err != nil {
+		log.Warning("updateGroupsInProject: %s cannot give roles: %s\n", c.User.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		log.Warning("updateGroupsInProject: Cannot start transaction: %s\n", err)
@@ -241,6 +260,12 @@ func addGroupInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		return
 	}
 
+	if err := permission.CanGiveRole(db, c.User, groupProject.Permission, permission.ProjectRoles(key, c.User)); err != nil {
+		log.Warning("AddGroupInProject: %s cannot give role %d to group %s: %s\n", c.User.Username, groupProject.Permission, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
 	groupInProject, err := group.CheckGroupInProject(db, p.ID, g.ID)
 	if err != nil {
 		log.Warning("AddGroupInProject: Cannot check if group %s is already in the project %s: %s\n", g.Name, p.Name, err)
@@ -276,7 +301,7 @@ func addGroupInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 			}
 
 			for _, app := range applications {
-				if permission.AccessToApplication(app.ID, c.User, permission.PermissionReadWriteExecute) {
+				if permission.AccessToApplication(app.ID, c.User, sdk.RoleActionManageGroups) {
 					inApp, err := group.CheckGroupInApplication(tx, app.ID, g.ID)
 					if err != nil {
 						log.Warning("AddGroupInProject: Cannot check if group %s is already in the application %s: %s\n", g.Name, app.Name, err)
@@ -310,7 +335,7 @@ func addGroupInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 			}
 
 			for _, pip := range pipelines {
-				if permission.AccessToPipeline(sdk.DefaultEnv.ID, pip.ID, c.User, permission.PermissionReadWriteExecute) {
+				if permission.AccessToPipeline(sdk.DefaultEnv.ID, pip.ID, c.User, sdk.RoleActionManageGroups) {
 					inPip, err := group.CheckGroupInPipeline(tx, pip.ID, g.ID)
 					if err != nil {
 						log.Warning("AddGroupInProject: Cannot check if group %s is already in the pipeline %s: %s\n", g.Name, pip.Name, err)
@@ -344,7 +369,7 @@ func addGroupInProject(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 			}
 
 			for _, env := range envs {
-				if permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadWriteExecute) {
+				if permission.AccessToEnvironment(env.ID, c.User, sdk.RoleActionManageGroups) {
 					inEnv, err := group.IsInEnvironment(tx, env.ID, g.ID)
 					if err != nil {
 						log.Warning("AddGroupInProject: Cannot check if group %s is already in the environment %s: %s\n", g.Name, env.Name, err)
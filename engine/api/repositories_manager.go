@@ -546,7 +546,7 @@ func addHookOnRepositoriesManagerHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, pipeline.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, pipeline.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addHookOnRepositoriesManagerHandler> You don't have enought right on this pipeline %s", pipeline.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -631,7 +631,7 @@ func deleteHookOnRepositoriesManagerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, pipeline.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, pipeline.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("deleteHookOnRepositoriesManagerHandler> You don't have enought right on this pipeline %s", pipeline.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -0,0 +1,397 @@
+package repogitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// GitlabClient is a gitlab wrapper for CDS RepositoriesManagerClient interface
+type GitlabClient struct {
+	URL        string
+	OAuthToken string
+}
+
+func (g *GitlabClient) host() string {
+	u, err := url.Parse(g.URL)
+	if err != nil {
+		return g.URL
+	}
+	return u.Host
+}
+
+func toVCSRepo(p Project) sdk.VCSRepo {
+	return sdk.VCSRepo{
+		ID:           strconv.Itoa(p.ID),
+		Name:         p.Name,
+		Slug:         p.Path,
+		Fullname:     p.PathWithNamespace,
+		URL:          p.WebURL,
+		HTTPCloneURL: p.HTTPURLToRepo,
+		SSHCloneURL:  p.SSHURLToRepo,
+	}
+}
+
+func toVCSCommit(c Commit) sdk.VCSCommit {
+	commit := sdk.VCSCommit{
+		Hash:    c.ID,
+		Message: c.Message,
+		URL:     c.WebURL,
+		Author: sdk.VCSAuthor{
+			Name:        c.AuthorName,
+			DisplayName: c.AuthorName,
+			Email:       c.AuthorEmail,
+		},
+	}
+	if c.AuthoredDate != nil {
+		commit.Timestamp = c.AuthoredDate.Unix() * 1000
+	}
+	return commit
+}
+
+// Repos list projects the authenticated user is a member of
+// https://docs.gitlab.com/ce/api/projects.html#list-projects
+func (g *GitlabClient) Repos() ([]sdk.VCSRepo, error) {
+	var projects = []Project{}
+	err := g.getAll("/projects?membership=true&order_by=path&sort=asc", func(body []byte) error {
+		nextProjects := []Project{}
+		if err := json.Unmarshal(body, &nextProjects); err != nil {
+			log.Warning("GitlabClient.Repos> Unable to parse gitlab projects: %s", err)
+			return err
+		}
+		projects = append(projects, nextProjects...)
+		return nil
+	})
+	if err != nil {
+		log.Warning("GitlabClient.Repos> Error %s", err)
+		return nil, err
+	}
+
+	responseRepos := []sdk.VCSRepo{}
+	for _, p := range projects {
+		responseRepos = append(responseRepos, toVCSRepo(p))
+	}
+	return responseRepos, nil
+}
+
+// RepoByFullname Get only one repo
+// https://docs.gitlab.com/ce/api/projects.html#get-single-project
+func (g *GitlabClient) RepoByFullname(fullname string) (sdk.VCSRepo, error) {
+	p, err := g.project(fullname)
+	if err != nil {
+		return sdk.VCSRepo{}, err
+	}
+	return toVCSRepo(p), nil
+}
+
+func (g *GitlabClient) project(fullname string) (Project, error) {
+	status, body, _, err := g.get(projectPath(fullname))
+	if err != nil {
+		log.Warning("GitlabClient.project> Error %s", err)
+		return Project{}, err
+	}
+	if status >= 400 {
+		return Project{}, sdk.NewError(sdk.ErrRepoNotFound, ErrorAPI(body))
+	}
+	p := Project{}
+	if err := json.Unmarshal(body, &p); err != nil {
+		log.Warning("GitlabClient.project> Unable to parse gitlab project: %s", err)
+		return Project{}, err
+	}
+	return p, nil
+}
+
+// Branches returns list of branches for a repo
+// https://docs.gitlab.com/ce/api/branches.html#list-repository-branches
+func (g *GitlabClient) Branches(fullname string) ([]sdk.VCSBranch, error) {
+	p, err := g.project(fullname)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches = []Branch{}
+	err = g.getAll(projectPath(fullname)+"/repository/branches", func(body []byte) error {
+		nextBranches := []Branch{}
+		if err := json.Unmarshal(body, &nextBranches); err != nil {
+			log.Warning("GitlabClient.Branches> Unable to parse gitlab branches: %s", err)
+			return err
+		}
+		branches = append(branches, nextBranches...)
+		return nil
+	})
+	if err != nil {
+		log.Warning("GitlabClient.Branches> Error %s", err)
+		return nil, err
+	}
+
+	branchesResult := []sdk.VCSBranch{}
+	for _, b := range branches {
+		branchesResult = append(branchesResult, sdk.VCSBranch{
+			ID:           b.Name,
+			DisplayID:    b.Name,
+			LatestCommit: b.Commit.ID,
+			Default:      b.Name == p.DefaultBranch,
+		})
+	}
+	return branchesResult, nil
+}
+
+// Branch returns only detail of a branch
+// https://docs.gitlab.com/ce/api/branches.html#get-single-repository-branch
+func (g *GitlabClient) Branch(fullname, branchName string) (sdk.VCSBranch, error) {
+	p, err := g.project(fullname)
+	if err != nil {
+		return sdk.VCSBranch{}, err
+	}
+
+	status, body, _, err := g.get(projectPath(fullname) + "/repository/branches/" + url.PathEscape(branchName))
+	if err != nil {
+		log.Warning("GitlabClient.Branch> Error %s", err)
+		return sdk.VCSBranch{}, err
+	}
+	if status >= 400 {
+		return sdk.VCSBranch{}, sdk.NewError(sdk.ErrNoBranch, ErrorAPI(body))
+	}
+	b := Branch{}
+	if err := json.Unmarshal(body, &b); err != nil {
+		log.Warning("GitlabClient.Branch> Unable to parse gitlab branch: %s", err)
+		return sdk.VCSBranch{}, err
+	}
+
+	return sdk.VCSBranch{
+		ID:           b.Name,
+		DisplayID:    b.Name,
+		LatestCommit: b.Commit.ID,
+		Default:      b.Name == p.DefaultBranch,
+	}, nil
+}
+
+// Commits returns the commits between a commit SHA (since) and another ref (until).
+// If since is empty, it returns the commits of the ref until.
+// If until is empty, the default branch of the repository is used.
+// https://docs.gitlab.com/ce/api/repositories.html#compare-branches-tags-or-commits
+func (g *GitlabClient) Commits(repo, since, until string) ([]sdk.VCSCommit, error) {
+	if until == "" {
+		p, err := g.project(repo)
+		if err != nil {
+			return nil, err
+		}
+		until = p.DefaultBranch
+	}
+
+	var glCommitsKey = cache.Key("reposmanager", "gitlab", g.host(), repo, "commits", "since@"+since, "until@"+until)
+	var glCommits = []Commit{}
+	cache.Get(glCommitsKey, &glCommits)
+
+	if len(glCommits) == 0 {
+		if since == "" {
+			val := url.Values{}
+			val.Add("ref_name", until)
+			err := g.getAll(projectPath(repo)+"/repository/commits?"+val.Encode(), func(body []byte) error {
+				nextCommits := []Commit{}
+				if err := json.Unmarshal(body, &nextCommits); err != nil {
+					log.Warning("GitlabClient.Commits> Unable to parse gitlab commits: %s", err)
+					return err
+				}
+				glCommits = append(glCommits, nextCommits...)
+				return nil
+			})
+			if err != nil {
+				log.Warning("GitlabClient.Commits> Error %s", err)
+				return nil, err
+			}
+		} else {
+			val := url.Values{}
+			val.Add("from", since)
+			val.Add("to", until)
+			status, body, _, err := g.get(projectPath(repo) + "/repository/compare?" + val.Encode())
+			if err != nil {
+				log.Warning("GitlabClient.Commits> Error %s", err)
+				return nil, err
+			}
+			if status >= 400 {
+				return nil, sdk.NewError(sdk.ErrCommitsFetchFailed, ErrorAPI(body))
+			}
+			compare := Compare{}
+			if err := json.Unmarshal(body, &compare); err != nil {
+				log.Warning("GitlabClient.Commits> Unable to parse gitlab compare: %s", err)
+				return nil, err
+			}
+			glCommits = compare.Commits
+			//Commits between two SHA won't change, keep them for one hour
+			cache.SetWithTTL(glCommitsKey, glCommits, 60*60)
+		}
+	}
+
+	commits := []sdk.VCSCommit{}
+	for _, c := range glCommits {
+		commits = append(commits, toVCSCommit(c))
+	}
+	return commits, nil
+}
+
+// Commit Get a single commit
+// https://docs.gitlab.com/ce/api/commits.html#get-a-single-commit
+func (g *GitlabClient) Commit(repo, hash string) (sdk.VCSCommit, error) {
+	var glCommitKey = cache.Key("reposmanager", "gitlab", g.host(), repo, hash)
+	var c = Commit{}
+	cache.Get(glCommitKey, &c)
+
+	if c.ID == "" {
+		status, body, _, err := g.get(projectPath(repo) + "/repository/commits/" + url.PathEscape(hash))
+		if err != nil {
+			log.Warning("GitlabClient.Commit> Error %s", err)
+			return sdk.VCSCommit{}, err
+		}
+		if status >= 400 {
+			return sdk.VCSCommit{}, sdk.NewError(sdk.ErrRepoNotFound, ErrorAPI(body))
+		}
+		if err := json.Unmarshal(body, &c); err != nil {
+			log.Warning("GitlabClient.Commit> Unable to parse gitlab commit: %s", err)
+			return sdk.VCSCommit{}, err
+		}
+		cache.Set(glCommitKey, c)
+	}
+
+	return toVCSCommit(c), nil
+}
+
+func (g *GitlabClient) hooks(repo string) ([]ProjectHook, error) {
+	hooks := []ProjectHook{}
+	err := g.getAll(projectPath(repo)+"/hooks", func(body []byte) error {
+		nextHooks := []ProjectHook{}
+		if err := json.Unmarshal(body, &nextHooks); err != nil {
+			log.Warning("GitlabClient.hooks> Unable to parse gitlab hooks: %s", err)
+			return err
+		}
+		hooks = append(hooks, nextHooks...)
+		return nil
+	})
+	return hooks, err
+}
+
+// CreateHook adds a push hook on the project
+// https://docs.gitlab.com/ce/api/projects.html#add-project-hook
+func (g *GitlabClient) CreateHook(repo, url string) error {
+	hooks, err := g.hooks(repo)
+	if err != nil {
+		log.Warning("GitlabClient.CreateHook> Unable to list hooks on %s: %s", repo, err)
+		return err
+	}
+	for _, h := range hooks {
+		if h.URL == url {
+			log.Notice("GitlabClient.CreateHook> Hook already exists on %s : %s", repo, url)
+			return nil
+		}
+	}
+
+	log.Notice("GitlabClient.CreateHook> Ask Gitlab to create Hook on %s : %s", repo, url)
+	status, body, _, err := g.post(projectPath(repo)+"/hooks", ProjectHook{URL: url, PushEvents: true})
+	if err != nil {
+		if err == ErrorUnauthorized {
+			return sdk.ErrNoReposManagerClientAuth
+		}
+		return err
+	}
+	if status >= 400 {
+		return ErrorAPI(body)
+	}
+	log.Notice("GitlabClient.CreateHook> Hook created on %s", repo)
+	return nil
+}
+
+// DeleteHook removes the push hooks matching the url on the project
+// https://docs.gitlab.com/ce/api/projects.html#delete-project-hook
+func (g *GitlabClient) DeleteHook(repo, url string) error {
+	hooks, err := g.hooks(repo)
+	if err != nil {
+		log.Warning("GitlabClient.DeleteHook> Unable to list hooks on %s: %s", repo, err)
+		if err == ErrorUnauthorized {
+			return sdk.ErrNoReposManagerClientAuth
+		}
+		return err
+	}
+
+	log.Notice("GitlabClient.DeleteHook> Ask Gitlab to delete Hook on %s : %s", repo, url)
+	for _, h := range hooks {
+		if h.URL != url {
+			continue
+		}
+		status, body, _, err := g.delete(fmt.Sprintf("%s/hooks/%d", projectPath(repo), h.ID))
+		if err != nil {
+			return err
+		}
+		if status >= 400 {
+			return ErrorAPI(body)
+		}
+	}
+	log.Notice("GitlabClient.DeleteHook> Hook successfully deleted")
+	return nil
+}
+
+//PushEvents returns the last pushed commit of each branch after the reference date
+// https://docs.gitlab.com/ce/api/events.html#list-a-project-s-visible-events
+func (g *GitlabClient) PushEvents(fullname string, dateRef time.Time) ([]sdk.VCSPushEvent, time.Duration, error) {
+	log.Debug("GitlabClient.PushEvents> loading events for %s after %v", fullname, dateRef)
+	interval := time.Duration(60.0)
+
+	val := url.Values{}
+	val.Add("action", "pushed")
+	//after is a date without time and it is exclusive
+	val.Add("after", dateRef.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	events := []Event{}
+	err := g.getAll(projectPath(fullname)+"/events?"+val.Encode(), func(body []byte) error {
+		nextEvents := []Event{}
+		if err := json.Unmarshal(body, &nextEvents); err != nil {
+			log.Warning("GitlabClient.PushEvents> Unable to parse gitlab events: %s", err)
+			return err
+		}
+		events = append(events, nextEvents...)
+		return nil
+	})
+	if err != nil {
+		log.Warning("GitlabClient.PushEvents> Error %s", err)
+		return nil, interval, err
+	}
+
+	lastEventPerBranch := map[string]Event{}
+	for _, e := range events {
+		if e.PushData == nil || e.PushData.RefType != "branch" || e.PushData.Action == "removed" {
+			continue
+		}
+		if !e.CreatedAt.After(dateRef) {
+			continue
+		}
+		l, b := lastEventPerBranch[e.PushData.Ref]
+		if !b || l.CreatedAt.Before(e.CreatedAt) {
+			lastEventPerBranch[e.PushData.Ref] = e
+		}
+	}
+
+	res := []sdk.VCSPushEvent{}
+	for b, e := range lastEventPerBranch {
+		branch, err := g.Branch(fullname, b)
+		if err != nil {
+			return nil, interval, fmt.Errorf("Unable to find branch %s in %s : %s", b, fullname, err)
+		}
+		commit, err := g.Commit(fullname, e.PushData.CommitTo)
+		if err != nil {
+			return nil, interval, fmt.Errorf("Unable to find commit %s in %s : %s", e.PushData.CommitTo, fullname, err)
+		}
+		commit.Author.Name = e.AuthorUsername
+		commit.Author.Avatar = e.Author.AvatarURL
+		res = append(res, sdk.VCSPushEvent{
+			Branch: branch,
+			Commit: commit,
+		})
+	}
+
+	return res, interval, nil
+}
@@ -0,0 +1,198 @@
+package repogitlab
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//newGitlabStub starts a local http server acting as a gitlab instance with one project "foo/bar"
+func newGitlabStub(t *testing.T) (*httptest.Server, *[]ProjectHook) {
+	hooks := &[]ProjectHook{}
+	mux := http.NewServeMux()
+
+	write := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	date := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	commit := func(sha string) Commit {
+		return Commit{ID: sha, Message: "commit " + sha, AuthorName: "John Doe", AuthorEmail: "john@doe.com", AuthoredDate: &date, WebURL: "http://gitlab/foo/bar/commit/" + sha}
+	}
+
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.FormValue("code") != "the-code" || r.FormValue("client_secret") != "the-secret" || r.FormValue("grant_type") != "authorization_code" {
+			w.WriteHeader(http.StatusUnauthorized)
+			write(w, map[string]string{"error": "invalid_grant", "error_description": "bad code"})
+			return
+		}
+		write(w, map[string]string{"access_token": "the-token", "refresh_token": "the-refresh-token"})
+	})
+
+	mux.HandleFunc("/api/v4/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer the-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+		switch {
+		case path == "/projects":
+			if r.FormValue("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				write(w, []Project{{ID: 1, Name: "bar", Path: "bar", PathWithNamespace: "foo/bar", DefaultBranch: "master"}})
+				return
+			}
+			write(w, []Project{{ID: 2, Name: "baz", Path: "baz", PathWithNamespace: "foo/baz", DefaultBranch: "master"}})
+		case path == "/projects/foo%2Fbar":
+			write(w, Project{ID: 1, Name: "bar", Path: "bar", PathWithNamespace: "foo/bar", DefaultBranch: "master", WebURL: "http://gitlab/foo/bar"})
+		case path == "/projects/foo%2Fbar/repository/branches":
+			write(w, []Branch{{Name: "master", Commit: Commit{ID: "aaa"}}, {Name: "feat/x", Commit: Commit{ID: "bbb"}}})
+		case path == "/projects/foo%2Fbar/repository/branches/feat%2Fx":
+			write(w, Branch{Name: "feat/x", Commit: Commit{ID: "bbb"}})
+		case path == "/projects/foo%2Fbar/repository/compare":
+			if r.FormValue("from") != "aaa" || r.FormValue("to") != "bbb" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			write(w, Compare{Commits: []Commit{commit("ab1"), commit("bbb")}})
+		case strings.HasPrefix(path, "/projects/foo%2Fbar/repository/commits/"):
+			write(w, commit(strings.TrimPrefix(path, "/projects/foo%2Fbar/repository/commits/")))
+		case path == "/projects/foo%2Fbar/hooks" && r.Method == http.MethodGet:
+			write(w, *hooks)
+		case path == "/projects/foo%2Fbar/hooks" && r.Method == http.MethodPost:
+			h := ProjectHook{}
+			json.NewDecoder(r.Body).Decode(&h)
+			h.ID = len(*hooks) + 1
+			*hooks = append(*hooks, h)
+			w.WriteHeader(http.StatusCreated)
+			write(w, h)
+		case strings.HasPrefix(path, "/projects/foo%2Fbar/hooks/") && r.Method == http.MethodDelete:
+			*hooks = []ProjectHook{}
+			w.WriteHeader(http.StatusNoContent)
+		case path == "/projects/foo%2Fbar/events":
+			if r.FormValue("action") != "pushed" {
+				t.Errorf("events should be filtered on pushed action")
+			}
+			write(w, []Event{
+				{ActionName: "pushed to", CreatedAt: date.Add(-time.Hour), AuthorUsername: "old", PushData: &PushData{RefType: "branch", Action: "pushed", Ref: "feat/x", CommitTo: "old"}},
+				{ActionName: "pushed to", CreatedAt: date.Add(2 * time.Hour), AuthorUsername: "john", PushData: &PushData{RefType: "branch", Action: "pushed", Ref: "feat/x", CommitTo: "bbb"}},
+				{ActionName: "pushed to", CreatedAt: date.Add(time.Hour), AuthorUsername: "john", PushData: &PushData{RefType: "branch", Action: "pushed", Ref: "feat/x", CommitTo: "ab1"}},
+				{ActionName: "pushed new", CreatedAt: date.Add(time.Hour), AuthorUsername: "john", PushData: &PushData{RefType: "tag", Action: "created", Ref: "v1.0", CommitTo: "ab1"}},
+			})
+		default:
+			t.Logf("unexpected call on gitlab stub: %s %s", r.Method, path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	return httptest.NewServer(mux), hooks
+}
+
+func TestAuthorize(t *testing.T) {
+	s, _ := newGitlabStub(t)
+	defer s.Close()
+
+	f, err := ioutil.TempFile("", "gitlab-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("the-secret\n")
+	f.Close()
+
+	g := New(s.URL+"/", "the-client", f.Name(), "http://cds/repositories_manager/oauth2/callback")
+
+	state, u, err := g.AuthorizeRedirect()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, state)
+	assert.True(t, strings.HasPrefix(u, s.URL+"/oauth/authorize?"))
+	assert.Contains(t, u, "client_id=the-client")
+	assert.Contains(t, u, "response_type=code")
+
+	token, refresh, err := g.AuthorizeToken(state, "the-code")
+	assert.NoError(t, err)
+	assert.Equal(t, "the-token", token)
+	assert.Equal(t, "the-refresh-token", refresh)
+
+	_, _, err = g.AuthorizeToken(state, "bad-code")
+	assert.Error(t, err)
+}
+
+func TestGitlabClient(t *testing.T) {
+	s, hooks := newGitlabStub(t)
+	defer s.Close()
+
+	c, err := New(s.URL, "the-client", "", "").GetAuthorized("the-token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := c.Repos()
+	assert.NoError(t, err)
+	assert.Len(t, repos, 2)
+	assert.Equal(t, "foo/bar", repos[0].Fullname)
+	assert.Equal(t, "foo/baz", repos[1].Fullname)
+
+	repo, err := c.RepoByFullname("foo/bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://gitlab/foo/bar", repo.URL)
+
+	branches, err := c.Branches("foo/bar")
+	assert.NoError(t, err)
+	assert.Len(t, branches, 2)
+	assert.True(t, branches[0].Default)
+	assert.False(t, branches[1].Default)
+
+	branch, err := c.Branch("foo/bar", "feat/x")
+	assert.NoError(t, err)
+	assert.Equal(t, "bbb", branch.LatestCommit)
+
+	commits, err := c.Commits("foo/bar", "aaa", "bbb")
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "john@doe.com", commits[0].Author.Email)
+	assert.Equal(t, int64(1475323200000), commits[0].Timestamp)
+
+	commit, err := c.Commit("foo/bar", "aaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "commit aaa", commit.Message)
+
+	link := "http://cds/hook?uid=1&project=foo&name=bar"
+	assert.NoError(t, c.CreateHook("foo/bar", link))
+	assert.NoError(t, c.CreateHook("foo/bar", link))
+	assert.Len(t, *hooks, 1)
+	assert.True(t, (*hooks)[0].PushEvents)
+	assert.NoError(t, c.DeleteHook("foo/bar", link))
+	assert.Len(t, *hooks, 0)
+
+	events, _, err := c.PushEvents("foo/bar", time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "feat/x", events[0].Branch.DisplayID)
+	assert.Equal(t, "bbb", events[0].Commit.Hash)
+	assert.Equal(t, "john", events[0].Commit.Author.Name)
+
+	bad, _ := New(s.URL, "the-client", "", "").GetAuthorized("bad-token", "")
+	_, err = bad.Repos()
+	assert.Equal(t, ErrorUnauthorized, err)
+}
+
+func TestPushEvent(t *testing.T) {
+	var e PushEvent
+	data := `{"object_kind":"push","before":"95790bf891e76fee5e1747ab589903a6a1f80f22","after":"da1560886d4f094c3e6c9ef40349f7d38b5d27d7","ref":"refs/heads/feat/x","user_username":"jsmith"}`
+	assert.NoError(t, json.Unmarshal([]byte(data), &e))
+	assert.Equal(t, "feat/x", e.Branch())
+	assert.Equal(t, "UPDATE", e.Type())
+
+	e.After = nullSha
+	assert.Equal(t, "DELETE", e.Type())
+}
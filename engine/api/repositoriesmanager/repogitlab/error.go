@@ -0,0 +1,48 @@
+package repogitlab
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//Error wraps gitlab error format
+type Error struct {
+	ID   string `json:"error"`
+	Desc string `json:"error_description"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("(gl_%s) %s", e.ID, e.Desc)
+}
+
+func (e Error) String() string {
+	return e.Error()
+}
+
+//Gitlab errors
+var (
+	ErrorUnauthorized = &Error{
+		ID:   "unauthorized",
+		Desc: "401 Unauthorized",
+	}
+)
+
+//ErrorAPI creates a new error from a gitlab api response body
+func ErrorAPI(body []byte) Error {
+	res := map[string]interface{}{}
+	json.Unmarshal(body, &res)
+	var desc string
+	switch m := res["message"].(type) {
+	case string:
+		desc = m
+	case nil:
+		desc, _ = res["error"].(string)
+	default:
+		b, _ := json.Marshal(m)
+		desc = string(b)
+	}
+	return Error{
+		ID:   "api_error",
+		Desc: desc,
+	}
+}
@@ -0,0 +1,146 @@
+package repogitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/facebookgo/httpcontrol"
+
+	"github.com/ovh/cds/engine/log"
+)
+
+//APIPath is the path of the gitlab API on a gitlab instance
+const APIPath = "/api/v4"
+
+var (
+	httpClient = &http.Client{
+		Transport: &httpcontrol.Transport{
+			RequestTimeout: time.Second * 30,
+			MaxTries:       5,
+		},
+	}
+)
+
+func (g *GitlabConsumer) postForm(path string, data url.Values) (int, []byte, error) {
+	body := strings.NewReader(data.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, g.URL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, err
+	}
+
+	if res.StatusCode >= 400 {
+		glErr := &Error{}
+		if err := json.Unmarshal(resBody, glErr); err == nil && glErr.ID != "" {
+			return res.StatusCode, resBody, glErr
+		}
+	}
+
+	return res.StatusCode, resBody, nil
+}
+
+//projectPath returns the API path of a project identified by its fullname (namespace/project)
+func projectPath(fullname string) string {
+	return "/projects/" + url.PathEscape(fullname)
+}
+
+func (c *GitlabClient) do(method, path string, in interface{}) (int, []byte, http.Header, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		path = c.URL + APIPath + path
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	req.Header.Set("User-Agent", "CDS")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.OAuthToken))
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	log.Debug("Gitlab API>> Request %s %s", method, req.URL.String())
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return res.StatusCode, nil, nil, ErrorUnauthorized
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, nil, err
+	}
+
+	return res.StatusCode, resBody, res.Header, nil
+}
+
+func (c *GitlabClient) get(path string) (int, []byte, http.Header, error) {
+	return c.do(http.MethodGet, path, nil)
+}
+
+func (c *GitlabClient) post(path string, in interface{}) (int, []byte, http.Header, error) {
+	return c.do(http.MethodPost, path, in)
+}
+
+func (c *GitlabClient) delete(path string) (int, []byte, http.Header, error) {
+	return c.do(http.MethodDelete, path, nil)
+}
+
+//getAll follows gitlab pagination and unmarshals every page with the unmarshal func
+func (c *GitlabClient) getAll(path string, unmarshal func([]byte) error) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	page := "1"
+	for page != "" {
+		status, body, headers, err := c.get(path + sep + "per_page=100&page=" + page)
+		if err != nil {
+			return err
+		}
+		if status >= 400 {
+			return ErrorAPI(body)
+		}
+		if err := unmarshal(body); err != nil {
+			return err
+		}
+		page = headers.Get("X-Next-Page")
+	}
+	return nil
+}
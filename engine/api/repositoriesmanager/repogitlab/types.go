@@ -0,0 +1,117 @@
+package repogitlab
+
+import (
+	"strings"
+	"time"
+)
+
+// Project represents a GitLab project.
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	WebURL            string `json:"web_url"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+}
+
+// Branch represents a GitLab repository branch.
+type Branch struct {
+	Name   string `json:"name"`
+	Commit Commit `json:"commit"`
+}
+
+// Commit represents a GitLab commit.
+type Commit struct {
+	ID           string     `json:"id"`
+	ShortID      string     `json:"short_id"`
+	Title        string     `json:"title"`
+	Message      string     `json:"message"`
+	AuthorName   string     `json:"author_name"`
+	AuthorEmail  string     `json:"author_email"`
+	AuthoredDate *time.Time `json:"authored_date"`
+	WebURL       string     `json:"web_url"`
+}
+
+// Compare represents the result of a GitLab compare between two refs.
+type Compare struct {
+	Commits []Commit `json:"commits"`
+}
+
+// User represents a GitLab user.
+type User struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// ProjectHook represents a GitLab project hook.
+type ProjectHook struct {
+	ID         int    `json:"id,omitempty"`
+	URL        string `json:"url"`
+	PushEvents bool   `json:"push_events"`
+	TagEvents  bool   `json:"tag_push_events"`
+}
+
+// Event represents a GitLab project event.
+type Event struct {
+	ActionName     string    `json:"action_name"`
+	CreatedAt      time.Time `json:"created_at"`
+	Author         User      `json:"author"`
+	AuthorUsername string    `json:"author_username"`
+	PushData       *PushData `json:"push_data"`
+}
+
+// PushData represents the push details of a GitLab event
+type PushData struct {
+	CommitCount int    `json:"commit_count"`
+	Action      string `json:"action"`
+	RefType     string `json:"ref_type"`
+	CommitFrom  string `json:"commit_from"`
+	CommitTo    string `json:"commit_to"`
+	Ref         string `json:"ref"`
+	CommitTitle string `json:"commit_title"`
+}
+
+// PushEvent represents the payload sent by a GitLab push hook
+// doc: https://docs.gitlab.com/ce/user/project/integrations/webhooks.html#push-events
+type PushEvent struct {
+	ObjectKind   string `json:"object_kind"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+	Ref          string `json:"ref"`
+	UserName     string `json:"user_name"`
+	UserUsername string `json:"user_username"`
+	Project      struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	} `json:"commits"`
+}
+
+// PushHookEvent is the value of the X-Gitlab-Event header sent with push hooks
+const PushHookEvent = "Push Hook"
+
+const nullSha = "0000000000000000000000000000000000000000"
+
+// Branch returns the name of the pushed branch
+func (e PushEvent) Branch() string {
+	return strings.TrimPrefix(e.Ref, "refs/heads/")
+}
+
+// Type returns the type of ref change, as stash names them: ADD, UPDATE or DELETE
+func (e PushEvent) Type() string {
+	switch {
+	case e.After == nullSha:
+		return "DELETE"
+	case e.Before == nullSha:
+		return "ADD"
+	}
+	return "UPDATE"
+}
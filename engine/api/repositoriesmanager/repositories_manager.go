@@ -12,6 +12,7 @@ import (
 
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/repositoriesmanager/repogithub"
+	"github.com/ovh/cds/engine/api/repositoriesmanager/repogitlab"
 	"github.com/ovh/cds/engine/api/repositoriesmanager/repostash"
 	"github.com/ovh/cds/engine/api/vault"
 	"github.com/ovh/cds/engine/log"
@@ -183,6 +184,70 @@ func New(t sdk.RepositoriesManagerType, id int64, name, URL string, args map[str
 			PollingSupported: *withPolling && github.PollingSupported(),
 		}
 
+		return &rm, nil
+	case sdk.Gitlab:
+		var gitlab *repogitlab.GitlabConsumer
+		var withHook, withPolling *bool
+		//Check if it isn't comming from the DB
+		if id == 0 || consumerData == "" {
+			//Check args
+			if len(args) < 2 || args["client-id"] == "" || args["client-secret"] == "" {
+				return nil, fmt.Errorf("client-id args and client-secret are mandatory to connect to gitlab : %v", args)
+			}
+
+			gitlab = repogitlab.New(URL, args["client-id"], args["client-secret"], apiURL+"/repositories_manager/oauth2/callback")
+			if args["with-hooks"] != "" {
+				b, err := strconv.ParseBool(args["with-hooks"])
+				if err == nil {
+					withHook = &b
+				}
+			}
+
+			if args["with-polling"] != "" {
+				b, err := strconv.ParseBool(args["with-polling"])
+				if err == nil {
+					withPolling = &b
+				}
+			}
+		} else {
+			//It's coming from the database, we just have to unmarshal data from the DB to get consumerData
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(consumerData), &data); err != nil {
+				log.Warning("New> Error %s", err)
+				return nil, err
+			}
+
+			gitlab = repogitlab.New(URL, data["client-id"].(string), data["client-secret"].(string), apiURL+"/repositories_manager/oauth2/callback")
+			if b, ok := data["with-hooks"].(bool); ok {
+				withHook = &b
+			}
+			if b, ok := data["with-polling"].(bool); ok {
+				withPolling = &b
+			}
+		}
+
+		if withHook == nil {
+			b := gitlab.HooksSupported()
+			withHook = &b
+		}
+		gitlab.WithHooks = *withHook
+		//Hooks are prefered on gitlab, polling has to be explicitly asked
+		if withPolling == nil {
+			b := false
+			withPolling = &b
+		}
+		gitlab.WithPolling = *withPolling
+
+		rm := sdk.RepositoriesManager{
+			ID:               id,
+			Consumer:         gitlab,
+			Name:             name,
+			URL:              gitlab.URL,
+			Type:             sdk.Gitlab,
+			HooksSupported:   *withHook && gitlab.HooksSupported(),
+			PollingSupported: *withPolling && gitlab.PollingSupported(),
+		}
+
 		return &rm, nil
 	}
 	return nil, fmt.Errorf("Unknown type %s. Cannot instanciate repositories manager t=%s id=%d name=%s url=%s args=%s consumerData=%s", t, t, id, name, URL, args, consumerData)
@@ -227,5 +292,24 @@ func initRepositoriesManager(db *sql.DB, rm *sdk.RepositoriesManager, directory
 		}
 		return nil
 	}
+
+	if rm.Type == sdk.Gitlab {
+		clientSecret := secrets["client-secret"]
+		if clientSecret == "" {
+			return fmt.Errorf("Cannot init %s. Missing client secret", rm.Name)
+		}
+		path := filepath.Join(directory, fmt.Sprintf("%s.%s", rm.Name, "clientSecret"))
+		log.Notice("RepositoriesManager> Writing gitlab client secret %s", path)
+		if err := ioutil.WriteFile(path, []byte(clientSecret), 0600); err != nil {
+			log.Warning("RepositoriesManager> Unable to write gitlab client secret %s : %s", path, err)
+			return err
+		}
+		gl := rm.Consumer.(*repogitlab.GitlabConsumer)
+		gl.ClientSecret = path
+		if err := Update(db, rm); err != nil {
+			return err
+		}
+		return nil
+	}
 	return fmt.Errorf("Unsupported repositories manager : %s: %s", rm.Name, rm.Type)
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/role"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// unmarshalRole reads a custom role from request body and checks its actions
+func unmarshalRole(w http.ResponseWriter, r *http.Request) (*sdk.Role, bool) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	var ro sdk.Role
+	if err := json.Unmarshal(data, &ro); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(ro.Actions) == 0 {
+		WriteError(w, r, sdk.ErrInvalidRole)
+		return nil, false
+	}
+	for _, a := range ro.Actions {
+		if !sdk.IsValidRoleAction(a) {
+			WriteError(w, r, sdk.ErrInvalidRole)
+			return nil, false
+		}
+	}
+	return &ro, true
+}
+
+// loadCustomRole returns the role named in the route, built-in roles cannot be modified
+func loadCustomRole(w http.ResponseWriter, r *http.Request, db *sql.DB) (*sdk.Role, bool) {
+	name := mux.Vars(r)["name"]
+	ro, err := role.LoadByName(db, name)
+	if err != nil {
+		log.Warning("loadCustomRole> Cannot load role %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return nil, false
+	}
+	if ro.BuiltIn {
+		WriteError(w, r, sdk.ErrForbidden)
+		return nil, false
+	}
+	return ro, true
+}
+
+func getRolesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	roles, err := role.LoadAll(db)
+	if err != nil {
+		log.Warning("getRolesHandler> Cannot load roles: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, roles, http.StatusOK)
+}
+
+func addRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	ro, ok := unmarshalRole(w, r)
+	if !ok {
+		return
+	}
+	if !regexp.MustCompile(sdk.NamePattern).MatchString(ro.Name) {
+		WriteError(w, r, sdk.ErrInvalidRole)
+		return
+	}
+
+	if _, err := role.LoadByName(db, ro.Name); err != sdk.ErrRoleNotFound {
+		if err != nil {
+			log.Warning("addRoleHandler> Cannot check role %s: %s\n", ro.Name, err)
+			WriteError(w, r, err)
+			return
+		}
+		WriteError(w, r, sdk.ErrConflict)
+		return
+	}
+
+	ro.BuiltIn = false
+	if err := role.Insert(db, ro); err != nil {
+		log.Warning("addRoleHandler> Cannot insert role %s: %s\n", ro.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, ro, http.StatusCreated)
+}
+
+func updateRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	ro, ok := loadCustomRole(w, r, db)
+	if !ok {
+		return
+	}
+
+	update, ok := unmarshalRole(w, r)
+	if !ok {
+		return
+	}
+
+	ro.Actions = update.Actions
+	if err := role.Update(db, ro); err != nil {
+		log.Warning("updateRoleHandler> Cannot update role %s: %s\n", ro.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, ro, http.StatusOK)
+}
+
+func deleteRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	ro, ok := loadCustomRole(w, r, db)
+	if !ok {
+		return
+	}
+
+	used, err := role.IsUsed(db, ro.ID)
+	if err != nil {
+		log.Warning("deleteRoleHandler> Cannot check if role %s is used: %s\n", ro.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+	if used {
+		WriteError(w, r, sdk.ErrRoleUsed)
+		return
+	}
+
+	if err := role.Delete(db, ro); err != nil {
+		log.Warning("deleteRoleHandler> Cannot delete role %s: %s\n", ro.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkGivenRoles checks caller can give roles to the groups of a resource. Roles groups already have
+// on it are kept without check, current are the groups of the resource and userRoles the roles of the caller on it.
+func checkGivenRoles(db database.Querier, user *sdk.User, given, current []sdk.GroupPermission, userRoles []int) error {
+	currentRoles := map[int64]int{}
+	for _, gp := range current {
+		currentRoles[gp.Group.ID] = gp.Permission
+	}
+
+	for _, gp := range given {
+		g, err := group.LoadGroup(db, gp.Group.Name)
+		if err != nil {
+			return sdk.ErrGroupNotFound
+		}
+		if r, ok := currentRoles[g.ID]; ok && r == gp.Permission {
+			continue
+		}
+		if err := permission.CanGiveRole(db, user, gp.Permission, userRoles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
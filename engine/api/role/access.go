@@ -0,0 +1,62 @@
+package role
+
+import (
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// Get returns the role with given id, custom roles are kept in cache.
+// It returns nil when the role does not exist.
+func Get(id int) *sdk.Role {
+	if r := builtIn(func(r *sdk.Role) bool { return r.ID == id }); r != nil {
+		return r
+	}
+
+	k := cacheKey(id)
+	var r sdk.Role
+	cache.Get(k, &r)
+	if r.ID == id {
+		return &r
+	}
+
+	db := database.DB()
+	if db == nil {
+		return nil
+	}
+	loaded, err := LoadByID(db, id)
+	if err != nil {
+		if err != sdk.ErrRoleNotFound {
+			log.Warning("role.Get> Cannot load role %d: %s\n", id, err)
+		}
+		return nil
+	}
+	cache.Set(k, loaded)
+	return loaded
+}
+
+// Can returns true if role with given id grants action
+func Can(id int, action string) bool {
+	r := Get(id)
+	if r == nil {
+		return false
+	}
+	return r.Can(action)
+}
+
+// Level returns the former permission level matching the role, for clients still relying on it:
+// 7 if it allows to edit, 5 if it allows to run, 4 otherwise and 0 for an unknown role
+func Level(id int) int {
+	r := Get(id)
+	switch {
+	case r == nil:
+		return 0
+	case r.Can(sdk.RoleActionEdit):
+		return sdk.RoleReadWriteExecuteID
+	case r.Can(sdk.RoleActionRun):
+		return sdk.RoleReadExecuteID
+	default:
+		return sdk.RoleReadID
+	}
+}
@@ -0,0 +1,53 @@
+package role
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestBuiltInRoles(t *testing.T) {
+	tests := []struct {
+		id      int
+		action  string
+		allowed bool
+	}{
+		{sdk.RoleReadID, sdk.RoleActionRead, true},
+		{sdk.RoleReadID, sdk.RoleActionRun, false},
+		{sdk.RoleReadExecuteID, sdk.RoleActionRun, true},
+		{sdk.RoleReadExecuteID, sdk.RoleActionEdit, false},
+		{sdk.RoleReadExecuteID, sdk.RoleActionApproveDeployment, false},
+		{sdk.RoleReadWriteExecuteID, sdk.RoleActionEdit, true},
+		{sdk.RoleReadWriteExecuteID, sdk.RoleActionManageVariables, true},
+		{sdk.RoleReadWriteExecuteID, sdk.RoleActionManageGroups, true},
+		{sdk.RoleReadWriteExecuteID, sdk.RoleActionApproveDeployment, true},
+	}
+
+	for _, tt := range tests {
+		if got := Can(tt.id, tt.action); got != tt.allowed {
+			t.Errorf("Can(%d, %s) = %v, want %v", tt.id, tt.action, got, tt.allowed)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for _, id := range []int{sdk.RoleReadID, sdk.RoleReadExecuteID, sdk.RoleReadWriteExecuteID} {
+		if got := Level(id); got != id {
+			t.Errorf("Level(%d) = %d, built-in roles keep their former level", id, got)
+		}
+	}
+}
+
+func TestCustomRoleCan(t *testing.T) {
+	deployer := sdk.Role{Name: "deployer", Actions: []string{sdk.RoleActionRun, sdk.RoleActionApproveDeployment}}
+
+	if !deployer.Can(sdk.RoleActionRead) {
+		t.Errorf("every role allows to read")
+	}
+	if !deployer.Can(sdk.RoleActionApproveDeployment) {
+		t.Errorf("deployer should approve deployments")
+	}
+	if deployer.Can(sdk.RoleActionEdit) {
+		t.Errorf("deployer should not edit")
+	}
+}
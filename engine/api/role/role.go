@@ -0,0 +1,127 @@
+package role
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// LoadAll returns built-in roles followed by custom roles
+func LoadAll(db database.Querier) ([]sdk.Role, error) {
+	roles := append([]sdk.Role{}, sdk.BuiltInRoles...)
+
+	query := `SELECT id, name, actions FROM "role" ORDER BY name`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *r)
+	}
+	return roles, rows.Err()
+}
+
+// LoadByID returns the built-in or custom role with given id
+func LoadByID(db database.Querier, id int) (*sdk.Role, error) {
+	if r := builtIn(func(r *sdk.Role) bool { return r.ID == id }); r != nil {
+		return r, nil
+	}
+
+	query := `SELECT id, name, actions FROM "role" WHERE id = $1`
+	r, err := scanRole(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, sdk.ErrRoleNotFound
+	}
+	return r, err
+}
+
+// LoadByName returns the built-in or custom role with given name
+func LoadByName(db database.Querier, name string) (*sdk.Role, error) {
+	if r := builtIn(func(r *sdk.Role) bool { return r.Name == name }); r != nil {
+		return r, nil
+	}
+
+	query := `SELECT id, name, actions FROM "role" WHERE name = $1`
+	r, err := scanRole(db.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, sdk.ErrRoleNotFound
+	}
+	return r, err
+}
+
+func builtIn(match func(r *sdk.Role) bool) *sdk.Role {
+	for i := range sdk.BuiltInRoles {
+		if match(&sdk.BuiltInRoles[i]) {
+			r := sdk.BuiltInRoles[i]
+			return &r
+		}
+	}
+	return nil
+}
+
+func scanRole(s database.Scanner) (*sdk.Role, error) {
+	var r sdk.Role
+	var actions string
+	if err := s.Scan(&r.ID, &r.Name, &actions); err != nil {
+		return nil, err
+	}
+	if actions != "" {
+		r.Actions = strings.Split(actions, ",")
+	}
+	return &r, nil
+}
+
+// Insert creates a custom role
+func Insert(db database.QueryExecuter, r *sdk.Role) error {
+	query := `INSERT INTO "role" (name, actions) VALUES($1,$2) RETURNING id`
+	return db.QueryRow(query, r.Name, strings.Join(r.Actions, ",")).Scan(&r.ID)
+}
+
+// Update replaces actions of a custom role
+func Update(db database.Executer, r *sdk.Role) error {
+	query := `UPDATE "role" SET actions = $2 WHERE id = $1`
+	if _, err := db.Exec(query, r.ID, strings.Join(r.Actions, ",")); err != nil {
+		return err
+	}
+	cache.Delete(cacheKey(r.ID))
+	return nil
+}
+
+// Delete deletes a custom role
+func Delete(db database.Executer, r *sdk.Role) error {
+	query := `DELETE FROM "role" WHERE id = $1`
+	if _, err := db.Exec(query, r.ID); err != nil {
+		return err
+	}
+	cache.Delete(cacheKey(r.ID))
+	return nil
+}
+
+// IsUsed returns true if the role is given to a group on a project, application, pipeline or environment
+func IsUsed(db database.Querier, id int) (bool, error) {
+	for _, table := range []string{"project_group", "application_group", "pipeline_group", "environment_group"} {
+		var n int
+		query := `SELECT COUNT(*) FROM "` + table + `" WHERE role = $1`
+		if err := db.QueryRow(query, id).Scan(&n); err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func cacheKey(id int) string {
+	return cache.Key("role", strconv.Itoa(id))
+}
@@ -44,6 +44,7 @@ type routerConfig struct {
 	auth          bool
 	isExecution   bool
 	needAdmin     bool
+	action        string
 }
 
 // ServeAbsoluteFile Serve file to download
@@ -166,11 +167,17 @@ func (r *Router) Handle(uri string, handlers ...RouterConfigParam) {
 			}
 		}
 
+		action := getActionByMethod(req.Method, rc)
+		if rc.auth && c.Token != nil && !checkTokenScopes(mux.Vars(req), c, action) {
+			WriteError(w, req, sdk.ErrForbidden)
+			return
+		}
+
 		permissionOk := true
 		if rc.auth && rc.needAdmin && !c.User.Admin {
 			permissionOk = false
 		} else if rc.auth && !rc.needAdmin && !c.User.Admin {
-			permissionOk = checkPermission(mux.Vars(req), c, getPermissionByMethod(req.Method, rc.isExecution))
+			permissionOk = checkPermission(mux.Vars(req), c, action)
 		}
 		if permissionOk {
 			if req.Method == "GET" && rc.get != nil {
@@ -247,6 +254,15 @@ func NeedAdmin(admin bool) RouterConfigParam {
 	return f
 }
 
+// NeedAction set the role action needed to write on the route,
+// by default writing needs the edit action
+func NeedAction(action string) RouterConfigParam {
+	f := func(rc *routerConfig) {
+		rc.action = action
+	}
+	return f
+}
+
 // DELETE will set given handler only for DELETE request
 func DELETE(h Handler) RouterConfigParam {
 	f := func(rc *routerConfig) {
@@ -310,6 +326,9 @@ func Auth(v bool) RouterConfigParam {
 }
 
 func (r *Router) checkAuthHeader(db *sql.DB, headers http.Header, c *context.Context) error {
+	if h := headers.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return auth.CheckTokenAuth(db, strings.TrimPrefix(h, "Bearer "), c)
+	}
 	return r.authDriver.GetCheckAuthHeaderFunc(localCLientAuthMode)(db, headers, c)
 }
 
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -32,6 +33,8 @@ func Schedule() {
 
 		db := database.DB()
 		if db != nil {
+			scheduleQueuedBuilds(db)
+
 			pipelines, err := pipeline.LoadBuildingPipelines(db)
 			if err != nil {
 				log.Warning("Schedule> Cannot load building pipelines: %s\n", err)
@@ -97,8 +100,22 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 
 		// browse all actions in the current stage
 		for i, a := range s.Actions {
+			// action matrix overrides stage matrix
+			matrix := a.Matrix
+			if matrix == nil {
+				matrix = s.Matrix
+			}
+
+			// put back in queue failed action builds which can be retried
+			if a.Retry != nil {
+				if err := pipeline.RetryActionBuilds(tx, a.PipelineActionID, pb.ID, a.Retry); err != nil {
+					log.Warning("PipelineScheduler> Cannot retry action %s with pipelineBuildID %d: %s\n", a.Name, pb.ID, err)
+					return
+				}
+			}
+
 			// get action status
-			status, errActionStatus := pipeline.LoadActionStatus(tx, a.PipelineActionID, pb.ID)
+			status, errActionStatus := pipeline.LoadActionStatus(tx, a.PipelineActionID, pb.ID, matrix)
 			if errActionStatus != nil && errActionStatus != sql.ErrNoRows {
 				log.Warning("PipelineScheduler> Cannot load action %s with pipelineBuildID %d: %s\n", a.Name, pb.ID, errActionStatus)
 				return
@@ -124,8 +141,8 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 			if !s.Enabled || !prerequisitesOK {
 				//scheduleAction, and set it to disabled
 				if errActionStatus != nil && errActionStatus == sql.ErrNoRows && (runningStage == -1 || stageIndex == runningStage) {
-					var actionBuild *sdk.ActionBuild
-					actionBuild, err = scheduleAction(tx, a, pb, s.ID)
+					var actionBuilds []sdk.ActionBuild
+					actionBuilds, err = scheduleAction(tx, a, pb, s, matrix)
 					if err != nil {
 						log.Warning("PipelineScheduler> Cannot schedule action: %s\n", err)
 						return
@@ -138,9 +155,11 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 						status = sdk.StatusSkipped
 					}
 
-					log.Debug("PipelineScheduler> Disable action %d %s (status=%s)", actionBuild.ID, actionBuild.ActionName, status)
-					if err := build.UpdateActionBuildStatus(tx, actionBuild, status); err != nil {
-						log.Warning("PipelineScheduler> Cannot disable action %s with pipelineBuildID %d: %s\n", a.Name, pb.ID, err)
+					for j := range actionBuilds {
+						log.Debug("PipelineScheduler> Disable action %d %s (status=%s)", actionBuilds[j].ID, actionBuilds[j].ActionName, status)
+						if err := build.UpdateActionBuildStatus(tx, &actionBuilds[j], status); err != nil {
+							log.Warning("PipelineScheduler> Cannot disable action %s with pipelineBuildID %d: %s\n", a.Name, pb.ID, err)
+						}
 					}
 
 					continue
@@ -150,7 +169,11 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 				// If no row, action should be scheduled if current stage is running
 				if errActionStatus != nil && errActionStatus == sql.ErrNoRows {
 					if runningStage == -1 || stageIndex == runningStage {
-						_, err = scheduleAction(tx, a, pb, s.ID)
+						// stage waiting for approval is not scheduled yet
+						if runningStage == -1 && s.Approval != nil && !checkApproval(db, tx, pb, &s) {
+							return
+						}
+						_, err = scheduleAction(tx, a, pb, s, matrix)
 						if err != nil {
 							log.Warning("PipelineScheduler> Cannot schedule action: %s\n", err)
 							return
@@ -162,14 +185,14 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 
 				log.Debug("PipelineScheduler> %s.%s #%d has status %s\n", pb.Pipeline.Name, a.Name, pb.BuildNumber, status)
 
-				if status == sdk.StatusSuccess || status == sdk.StatusDisabled {
+				if status == sdk.StatusSuccess || status == sdk.StatusDisabled || status == sdk.StatusSkipped {
 					numberOfActionSuccess++
 				}
 
 				//condition de sortie
-				if status == sdk.StatusFail {
-					log.Info("PipelineScheduler> %s #%d: Action %s failed, stoping\n", pb.Pipeline.Name, pb.BuildNumber, a.Name)
-					if err := pipeline.UpdatePipelineBuildStatus(tx, pb, status); err != nil {
+				if status == sdk.StatusFail || status == sdk.StatusTimeout {
+					log.Info("PipelineScheduler> %s #%d: Action %s failed (%s), stoping\n", pb.Pipeline.Name, pb.BuildNumber, a.Name, status)
+					if err := pipeline.UpdatePipelineBuildStatus(tx, pb, sdk.StatusFail); err != nil {
 						log.Warning("PipelineScheduler> Cannot update pipeline status: %s\n", err)
 					} else {
 						err = tx.Commit()
@@ -210,6 +233,40 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 
 }
 
+// checkApproval returns true if the stage got enough approvals to be scheduled. Otherwise the pipeline build
+// is failed if the stage has been rejected, or paused until approvers decide, and the transaction is committed
+func checkApproval(db *sql.DB, tx *sql.Tx, pb sdk.PipelineBuild, s *sdk.Stage) bool {
+	approvals, err := build.LoadApprovals(tx, pb.ID)
+	if err != nil {
+		log.Warning("PipelineScheduler> Cannot load approvals of pipeline build %d: %s\n", pb.ID, err)
+		return false
+	}
+
+	status := s.Approval.Decision(approvals, s.ID)
+	if status == sdk.StatusSuccess {
+		return true
+	}
+
+	log.Info("PipelineScheduler> %s #%d: stage %s is %s\n", pb.Pipeline.Name, pb.BuildNumber, s.Name, status)
+	if err := pipeline.UpdatePipelineBuildStatus(tx, pb, status); err != nil {
+		log.Warning("PipelineScheduler> Cannot update pipeline status: %s\n", err)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		log.Warning("PipelineScheduler> Cannot commit tx on pb %d: %s\n", pb.ID, err)
+		return false
+	}
+
+	if status == sdk.StatusWaitingApproval {
+		authors, err := pipeline.LoadAuthorIDs(db, &pb)
+		if err != nil {
+			log.Warning("PipelineScheduler> Cannot load authors of pb %d: %s\n", pb.ID, err)
+		}
+		notification.SendApprovalRequest(db, &pb, s, authors)
+	}
+	return false
+}
+
 func scheduleEnd(tx *sql.Tx, pb sdk.PipelineBuild) {
 	log.Debug("buildScheduler> Updating pipeline build %d status to Success", pb.ID)
 
@@ -328,7 +385,8 @@ func ParentBuildInfos(pb sdk.PipelineBuild) ([]sdk.Parameter, error) {
 	return params, nil
 }
 
-func scheduleAction(db database.QueryExecuter, a sdk.Action, pb sdk.PipelineBuild, stageID int64) (*sdk.ActionBuild, error) {
+// scheduleAction pushes the action in build queue, once for each combination of the matrix if any
+func scheduleAction(db database.QueryExecuter, a sdk.Action, pb sdk.PipelineBuild, s sdk.Stage, matrix *sdk.Matrix) ([]sdk.ActionBuild, error) {
 	log.Info("scheduleAction> Starting action %s for pipeline %s #%d\n", a.Name,
 		pb.Pipeline.Name, pb.BuildNumber)
 
@@ -367,45 +425,118 @@ func scheduleAction(db database.QueryExecuter, a sdk.Action, pb sdk.PipelineBuil
 		return nil, err
 	}
 
-	/* Create and process the full set of build variables from
-	** - Project variables
-	** - Pipeline variables
-	** - Action definition in pipeline
-	** - ActionBuild variables (global ones + trigger parameters)
-	**
-	** -> Replaces all placeholder but PasswordParameter
-	 */
-	params, err := action.ProcessActionBuildVariables(
-		projectVariables,
-		appVariables,
-		envVariables,
-		pipelineParameters,
-		pipelineActionArgs,
-		pb.Parameters, a)
-	if err != nil {
-		return nil, err
-	}
+	// Without matrix, there is a single build without cds.matrix.* variables
+	cells := matrix.Combinations()
+	if len(cells) == 0 {
+		cells = []map[string]string{nil}
+	}
+
+	var builds []sdk.ActionBuild
+	for _, cell := range cells {
+		buildParameters := make([]sdk.Parameter, 0, len(pb.Parameters)+len(cell))
+		buildParameters = append(buildParameters, pb.Parameters...)
+		buildParameters = append(buildParameters, sdk.MatrixParameters(cell)...)
+
+		/* Create and process the full set of build variables from
+		** - Project variables
+		** - Pipeline variables
+		** - Action definition in pipeline
+		** - ActionBuild variables (global ones + trigger parameters + matrix values)
+		**
+		** -> Replaces all placeholder but PasswordParameter
+		 */
+		params, err := action.ProcessActionBuildVariables(
+			projectVariables,
+			appVariables,
+			envVariables,
+			pipelineParameters,
+			pipelineActionArgs,
+			buildParameters, a)
+		if err != nil {
+			return nil, err
+		}
 
-	b := sdk.ActionBuild{
-		PipelineBuildID:  pb.ID,
-		PipelineID:       pb.Pipeline.ID,
-		PipelineActionID: a.PipelineActionID,
-		Args:             params,
-		ActionName:       a.Name,
-		Status:           sdk.StatusWaiting,
-	}
+		b := sdk.ActionBuild{
+			PipelineBuildID:  pb.ID,
+			PipelineID:       pb.Pipeline.ID,
+			PipelineActionID: a.PipelineActionID,
+			Args:             params,
+			ActionName:       a.Name,
+			Status:           sdk.StatusWaiting,
+			Timeout:          timeoutParameter(params, sdk.TimeoutParameter, a.Timeout),
+			StageTimeout:     timeoutParameter(params, sdk.StageTimeoutParameter, s.Timeout),
+		}
 
-	if !a.Enabled {
-		b.Status = sdk.StatusDisabled
-		b.Done = time.Now()
+		// Stage and action conditions are evaluated with build variables, matrix values included
+		var conditionLogs []string
+		if a.Enabled {
+			var ok bool
+			conditionLogs, ok, err = checkConditions(s, a, params)
+			switch {
+			case err != nil:
+				b.Status = sdk.StatusFail
+				b.Done = time.Now()
+			case !ok:
+				b.Status = sdk.StatusSkipped
+				b.Done = time.Now()
+			}
+		} else {
+			b.Status = sdk.StatusDisabled
+			b.Done = time.Now()
+		}
+
+		if err := InsertBuild(db, &b); err != nil {
+			return nil, fmt.Errorf("Cannot push action %s for pipeline %s #%d in build queue: %s\n",
+				a.Name, pb.Pipeline.Name, b.PipelineBuildID, err)
+		}
+		for _, l := range conditionLogs {
+			if err := build.InsertLog(db, b.ID, "SYSTEM", l); err != nil {
+				return nil, err
+			}
+		}
+		builds = append(builds, b)
 	}
 
-	if err := InsertBuild(db, &b); err != nil {
-		return nil, fmt.Errorf("Cannot push action %s for pipeline %s #%d in build queue: %s\n",
-			a.Name, pb.Pipeline.Name, b.PipelineBuildID, err)
+	return builds, nil
+}
+
+// checkConditions evaluates stage then action conditions, and returns their results to log.
+// Evaluation stops at the first false one. An error means the condition cannot be evaluated.
+func checkConditions(s sdk.Stage, a sdk.Action, params []sdk.Parameter) ([]string, bool, error) {
+	vars := sdk.ConditionVariables(params)
+	var logs []string
+	for _, c := range []struct{ kind, expr string }{{"Stage", s.Condition}, {"Action", a.Condition}} {
+		if c.expr == "" {
+			continue
+		}
+		ok, err := sdk.EvalCondition(c.expr, vars)
+		if err != nil {
+			log.Warning("checkConditions> Cannot evaluate condition %s of action %s: %s\n", c.expr, a.Name, err)
+			return append(logs, fmt.Sprintf("%s condition %s cannot be evaluated: %s\n", c.kind, c.expr, err)), false, err
+		}
+		log.Info("checkConditions> %s condition %s of action %s is %t\n", c.kind, c.expr, a.Name, ok)
+		logs = append(logs, fmt.Sprintf("%s condition %s is %t\n", c.kind, c.expr, ok))
+		if !ok {
+			return logs, false, nil
+		}
 	}
+	return logs, true, nil
+}
 
-	return &b, nil
+// timeoutParameter returns the timeout set by a build parameter, or def if it's not set or invalid
+func timeoutParameter(params []sdk.Parameter, name string, def int64) int64 {
+	for _, p := range params {
+		if p.Name != name {
+			continue
+		}
+		t, err := strconv.ParseInt(p.Value, 10, 64)
+		if err != nil || t < 0 {
+			log.Warning("timeoutParameter> invalid value %s of %s: %s\n", p.Value, name, err)
+			return def
+		}
+		return t
+	}
+	return def
 }
 
 func loadPipelineActionArguments(db database.Querier, pipelineActionID int64) ([]sdk.Parameter, error) {
@@ -428,7 +559,7 @@ func loadPipelineActionArguments(db database.Querier, pipelineActionID int64) ([
 
 // InsertBuild Insert new action build
 func InsertBuild(db database.QueryExecuter, b *sdk.ActionBuild) error {
-	query := `INSERT INTO action_build (pipeline_action_id, args, status, pipeline_build_id, queued, start, done) VALUES($1, $2, $3, $4, $5, $5, $6) RETURNING id`
+	query := `INSERT INTO action_build (pipeline_action_id, args, status, pipeline_build_id, queued, start, done, timeout, stage_timeout) VALUES($1, $2, $3, $4, $5, $5, $6, $7, $8) RETURNING id`
 
 	if b.PipelineActionID == 0 {
 		return fmt.Errorf("invalid pipeline action ID (0)")
@@ -458,12 +589,19 @@ func InsertBuild(db database.QueryExecuter, b *sdk.ActionBuild) error {
 		done = b.Done
 	}
 
-	err = db.QueryRow(query, b.PipelineActionID, string(argsJSON), b.Status.String(), b.PipelineBuildID, time.Now(), done).Scan(&b.ID)
+	err = db.QueryRow(query, b.PipelineActionID, string(argsJSON), b.Status.String(), b.PipelineBuildID, time.Now(), done, b.Timeout, b.StageTimeout).Scan(&b.ID)
 	if err != nil {
 		return err
 	}
 
 	notification.SendActionBuild(db, b, sdk.CreateNotifEvent, sdk.StatusWaiting)
+	build.PublishBuildEvent(sdk.BuildEvent{
+		Type:             sdk.BuildEventActionBuild,
+		PipelineBuildID:  b.PipelineBuildID,
+		ActionBuildID:    b.ID,
+		PipelineActionID: b.PipelineActionID,
+		Status:           b.Status,
+	})
 	return nil
 }
 
@@ -522,11 +660,115 @@ func Run(db *sql.Tx, projectKey string, app *sdk.Application, pipelineName strin
 		env = &sdk.DefaultEnv
 	}
 
+	// Check environment lock before creating the build, so a rejected build leaves no trace
+	var lockHolder int64
+	lockAppID := env.LockPolicy.LockApplicationID(app.ID)
+	if env.LockPolicy != nil {
+		lockHolder, err = environment.LockHolder(db, env.ID, lockAppID)
+		if err != nil {
+			log.Warning("scheduler.Run> Cannot load lock of environment %s: %s\n", env.Name, err)
+			return nil, err
+		}
+		if lockHolder != 0 && env.LockPolicy.OnConflict == sdk.LockReject {
+			log.Info("scheduler.Run> Cannot run %s/%s/%s: environment %s is locked by pipeline build %d\n", projectKey, app.Name, pipelineName, env.Name, lockHolder)
+			return nil, sdk.ErrEnvironmentLocked
+		}
+	}
+
 	pb, err := pipeline.InsertPipelineBuild(db, projectData, p, app, applicationPipelineParams, params, env, version, trigger)
 	if err != nil {
 		log.Warning("scheduler.Run> Cannot start pipeline %s: %s\n", pipelineName, err)
 		return nil, err
 	}
 
+	if env.LockPolicy != nil {
+		if err := lockEnvironment(db, env, lockAppID, lockHolder, &pb); err != nil {
+			log.Warning("scheduler.Run> Cannot lock environment %s: %s\n", env.Name, err)
+			return nil, err
+		}
+	}
+
 	return &pb, nil
 }
+
+// lockEnvironment gives the environment lock to a new pipeline build. If the lock is already held,
+// the new build is queued or the holder is stopped, according to the environment lock policy
+func lockEnvironment(db *sql.Tx, env *sdk.Environment, appID, holderID int64, pb *sdk.PipelineBuild) error {
+	if holderID != 0 && env.LockPolicy.OnConflict == sdk.LockQueue {
+		log.Info("scheduler.Run> %s #%d queued: environment %s is locked by pipeline build %d\n", pb.Pipeline.Name, pb.BuildNumber, env.Name, holderID)
+		if err := pipeline.UpdatePipelineBuildStatus(db, *pb, sdk.StatusWaiting); err != nil {
+			return err
+		}
+		pb.Status = sdk.StatusWaiting
+		return nil
+	}
+
+	if holderID != 0 {
+		holder, err := pipeline.LoadPipelineBuildByID(db, holderID)
+		if err != nil {
+			return err
+		}
+		log.Info("scheduler.Run> Stopping %s #%d on environment %s, replaced by %s #%d\n", holder.Pipeline.Name, holder.BuildNumber, env.Name, pb.Pipeline.Name, pb.BuildNumber)
+		if err := pipeline.StopPipelineBuild(db, holder.ID); err != nil {
+			return err
+		}
+		if err := pipeline.UpdatePipelineBuildStatus(db, holder, sdk.StatusFail); err != nil {
+			return err
+		}
+	}
+
+	return environment.Lock(db, env.ID, appID, pb.ID)
+}
+
+// scheduleQueuedBuilds releases locks held by ended deployments, and gives them to the oldest builds queued behind
+func scheduleQueuedBuilds(db *sql.DB) {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Warning("scheduleQueuedBuilds> Cannot start tx: %s\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := environment.ReleaseLocks(tx); err != nil {
+		log.Warning("scheduleQueuedBuilds> Cannot release environment locks: %s\n", err)
+		return
+	}
+
+	queued, err := environment.LoadQueuedBuilds(tx)
+	if err != nil {
+		log.Warning("scheduleQueuedBuilds> Cannot load queued builds: %s\n", err)
+		return
+	}
+
+	for _, q := range queued {
+		if q.Locked {
+			holder, err := environment.LockHolder(tx, q.EnvironmentID, q.ApplicationID)
+			if err != nil {
+				log.Warning("scheduleQueuedBuilds> Cannot load lock of environment %d: %s\n", q.EnvironmentID, err)
+				return
+			}
+			if holder != 0 {
+				continue
+			}
+			if err := environment.Lock(tx, q.EnvironmentID, q.ApplicationID, q.PipelineBuildID); err != nil {
+				log.Warning("scheduleQueuedBuilds> Cannot lock environment %d: %s\n", q.EnvironmentID, err)
+				return
+			}
+		}
+
+		pb, err := pipeline.LoadPipelineBuildByID(tx, q.PipelineBuildID)
+		if err != nil {
+			log.Warning("scheduleQueuedBuilds> Cannot load pipeline build %d: %s\n", q.PipelineBuildID, err)
+			return
+		}
+		log.Info("scheduleQueuedBuilds> %s #%d can deploy on environment %s\n", pb.Pipeline.Name, pb.BuildNumber, pb.Environment.Name)
+		if err := pipeline.UpdatePipelineBuildStatus(tx, pb, sdk.StatusBuilding); err != nil {
+			log.Warning("scheduleQueuedBuilds> Cannot update pipeline status: %s\n", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Warning("scheduleQueuedBuilds> Cannot commit tx: %s\n", err)
+	}
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestCheckConditions(t *testing.T) {
+	params := []sdk.Parameter{
+		{Name: "git.branch", Value: "release/1.2"},
+		{Name: "cds.version", Value: "42"},
+		{Name: "cds.matrix.os", Value: "linux"},
+		{Name: "cds.env.name", Value: "production"},
+		{Name: "cds.pattern", Value: "(["},
+	}
+
+	tests := []struct {
+		stage, action string
+		ok, err       bool
+		logs          int
+	}{
+		{"", "", true, false, 0},
+		{`git.branch matches "^release/"`, "", true, false, 1},
+		{`cds.version > 9`, `cds.matrix.os == "linux"`, true, false, 2},
+		{`cds.version <= 9`, `cds.matrix.os == "linux"`, false, false, 1},
+		{"", `!(cds.env.name contains "prod") || cds.matrix.os != 'linux'`, false, false, 1},
+		{"", `cds.missing == "" && cds.missing`, false, false, 1},
+		{"", `git.branch matches cds.env.name && true`, false, false, 1},
+		{"", `cds.env.name matches git.branch`, false, false, 1},
+		{"", `git.branch matches cds.pattern`, false, true, 1},
+		{`cds.version > 9`, `cds.env.name matches "(["`, false, true, 2},
+	}
+
+	for _, tt := range tests {
+		s := sdk.Stage{Name: "deploy", Condition: tt.stage}
+		a := sdk.Action{Name: "upload", Condition: tt.action}
+		logs, ok, err := checkConditions(s, a, params)
+		if (err != nil) != tt.err {
+			t.Errorf("%q / %q: unexpected error %v", tt.stage, tt.action, err)
+			continue
+		}
+		if ok != tt.ok {
+			t.Errorf("%q / %q: expected %t, got %t", tt.stage, tt.action, tt.ok, ok)
+		}
+		if len(logs) != tt.logs {
+			t.Errorf("%q / %q: expected %d logs, got %v", tt.stage, tt.action, tt.logs, logs)
+		}
+	}
+}
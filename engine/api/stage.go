@@ -37,6 +37,26 @@ func addStageHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *cont
 		return
 	}
 
+	if !stageData.Matrix.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidMatrix)
+		return
+	}
+
+	if stageData.Timeout < 0 {
+		WriteError(w, r, sdk.ErrInvalidTimeout)
+		return
+	}
+
+	if !sdk.IsValidCondition(stageData.Condition) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
+	if !stageData.Approval.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidApprovalPolicy)
+		return
+	}
+
 	// Check if pipeline exist
 	pipelineData, err := pipeline.LoadPipeline(db, projectKey, pipelineKey, true)
 	if err != nil {
@@ -190,6 +210,26 @@ func updateStageHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		return
 	}
 
+	if !stageData.Matrix.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidMatrix)
+		return
+	}
+
+	if stageData.Timeout < 0 {
+		WriteError(w, r, sdk.ErrInvalidTimeout)
+		return
+	}
+
+	if !sdk.IsValidCondition(stageData.Condition) {
+		WriteError(w, r, sdk.ErrInvalidCondition)
+		return
+	}
+
+	if !stageData.Approval.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidApprovalPolicy)
+		return
+	}
+
 	stageID, err := strconv.ParseInt(stageIDString, 10, 60)
 	if err != nil {
 		log.Warning("addStageHandler> Stage ID must be an int: %s", err)
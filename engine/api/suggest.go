@@ -57,7 +57,7 @@ func getVariablesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *
 			return
 		}
 
-		if !permission.AccessToApplication(applicationData.ID, c.User, permission.PermissionRead) {
+		if !permission.AccessToApplication(applicationData.ID, c.User, sdk.RoleActionRead) {
 			log.Warning("getVariablesHandler> Not allow to access to this application: %s\n", appName)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -50,7 +50,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		}
 		t.SrcApplication.ID = a.ID
 	}
-	if !permission.AccessToApplication(t.SrcApplication.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToApplication(t.SrcApplication.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> You don't have enought right on this application %s", t.SrcApplication.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -65,7 +65,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		}
 		t.SrcPipeline.ID = p.ID
 	}
-	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, t.SrcPipeline.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, t.SrcPipeline.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> You don't have enought right on this pipeline %s", t.SrcPipeline.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -82,7 +82,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 	} else if t.SrcEnvironment.ID == 0 {
 		t.SrcEnvironment = sdk.DefaultEnv
 	}
-	if t.SrcEnvironment.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(t.SrcEnvironment.ID, c.User, permission.PermissionReadWriteExecute) {
+	if t.SrcEnvironment.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(t.SrcEnvironment.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> No enought right on this environment %s: \n", t.SrcEnvironment.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -98,7 +98,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		}
 		t.DestApplication.ID = a.ID
 	}
-	if !permission.AccessToApplication(t.DestApplication.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToApplication(t.DestApplication.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> You don't have enought right on this application %s", t.DestApplication.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -113,7 +113,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		}
 		t.DestPipeline.ID = p.ID
 	}
-	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, t.DestPipeline.ID, c.User, permission.PermissionReadWriteExecute) {
+	if !permission.AccessToPipeline(sdk.DefaultEnv.ID, t.DestPipeline.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> You don't have enought right on this pipeline %s", t.DestPipeline.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -131,7 +131,7 @@ func addTriggerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		t.DestEnvironment = sdk.DefaultEnv
 	}
 
-	if t.DestEnvironment.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(t.DestEnvironment.ID, c.User, permission.PermissionReadWriteExecute) {
+	if t.DestEnvironment.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(t.DestEnvironment.ID, c.User, sdk.RoleActionEdit) {
 		log.Warning("addTriggersHandler> No enought right on this environment %s: \n", t.DestEnvironment.Name)
 		WriteError(w, r, sdk.ErrForbidden)
 		return
@@ -219,7 +219,7 @@ func getTriggersHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		}
 		envID = e.ID
 
-		if e.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(e.ID, c.User, permission.PermissionRead) {
+		if e.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(e.ID, c.User, sdk.RoleActionRead) {
 			log.Warning("getTriggersHandler> No enought right on this environment %s: \n", e.Name)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
@@ -353,7 +353,7 @@ func getTriggersAsSourceHandler(w http.ResponseWriter, r *http.Request, db *sql.
 		}
 		envID = e.ID
 
-		if e.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(e.ID, c.User, permission.PermissionRead) {
+		if e.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(e.ID, c.User, sdk.RoleActionRead) {
 			log.Warning("getTriggersAsSourceHandler> No enought right on this environment %s: \n", e.Name)
 			WriteError(w, r, sdk.ErrForbidden)
 			return
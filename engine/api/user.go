@@ -135,8 +135,8 @@ func GetUsers(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Con
 
 // AddUser creates a new user and generate verification email
 func AddUser(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
-	//returns forbidden if LDAP mode is activated
-	if _, ldap := router.authDriver.(*auth.LDAPClient); ldap {
+	//returns forbidden if LDAP or OpenID Connect mode is activated
+	if _, local := router.authDriver.(*auth.LocalClient); !local {
 		WriteError(w, r, sdk.ErrForbidden)
 		return
 	}
@@ -217,8 +217,8 @@ func AddUser(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Cont
 
 // ResetUser deletes auth secret, generates new ones and send them via email
 func ResetUser(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
-	//returns forbidden if LDAP mode is activated
-	if _, ldap := router.authDriver.(*auth.LDAPClient); ldap {
+	//returns forbidden if LDAP or OpenID Connect mode is activated
+	if _, local := router.authDriver.(*auth.LocalClient); !local {
 		WriteError(w, r, sdk.ErrForbidden)
 		return
 	}
@@ -276,11 +276,14 @@ func ResetUser(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Co
 
 }
 
-//AuthModeHandler returns the auth mode : local ok ldap
+//AuthModeHandler returns the auth mode : local, ldap or oidc
 func AuthModeHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	mode := "local"
-	if _, ldap := router.authDriver.(*auth.LDAPClient); ldap {
+	switch router.authDriver.(type) {
+	case *auth.LDAPClient:
 		mode = "ldap"
+	case *auth.OIDCClient:
+		mode = "oidc"
 	}
 	res := map[string]string{
 		"auth_mode": mode,
@@ -290,8 +293,8 @@ func AuthModeHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *cont
 
 // ConfirmUser verify token send via email and mark user as verified
 func ConfirmUser(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
-	//returns forbidden if LDAP mode is activated
-	if _, ldap := router.authDriver.(*auth.LDAPClient); ldap {
+	//returns forbidden if LDAP or OpenID Connect mode is activated
+	if _, local := router.authDriver.(*auth.LocalClient); !local {
 		WriteError(w, r, sdk.ErrForbidden)
 		return
 	}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// GenerateToken returns a new random API token and its hash, only the hash is stored
+func GenerateToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	clear := hex.EncodeToString(b)
+	return clear, HashToken(clear), nil
+}
+
+// HashToken returns the hash an API token is stored and searched with
+func HashToken(clear string) string {
+	sum := sha256.Sum256([]byte(clear))
+	return hex.EncodeToString(sum[:])
+}
+
+// InsertToken stores a token of given user
+func InsertToken(db database.QueryExecuter, userID int64, t *sdk.APIToken, hashedToken string) error {
+	query := `INSERT INTO "user_token" (user_id, name, hashed_token, scopes, created, expiry) VALUES($1,$2,$3,$4,$5,$6) RETURNING id`
+	return db.QueryRow(query, userID, t.Name, hashedToken, strings.Join(t.Scopes, ","), t.Created, t.Expiry).Scan(&t.ID)
+}
+
+// LoadTokens returns tokens of given user
+func LoadTokens(db database.Querier, userID int64) ([]sdk.APIToken, error) {
+	query := `SELECT id, name, scopes, created, expiry, last_used FROM "user_token" WHERE user_id = $1 ORDER BY name`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []sdk.APIToken{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// LoadTokenByHash returns the token with given hash and the id of its user
+func LoadTokenByHash(db database.Querier, hashedToken string) (*sdk.APIToken, int64, error) {
+	query := `SELECT id, name, scopes, created, expiry, last_used, user_id FROM "user_token" WHERE hashed_token = $1`
+	var userID int64
+	t, err := scanToken(db.QueryRow(query, hashedToken), &userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, userID, nil
+}
+
+func scanToken(s database.Scanner, dest ...interface{}) (*sdk.APIToken, error) {
+	var t sdk.APIToken
+	var scopes string
+	if err := s.Scan(append([]interface{}{&t.ID, &t.Name, &scopes, &t.Created, &t.Expiry, &t.LastUsed}, dest...)...); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	return &t, nil
+}
+
+// UpdateTokenLastUsed records the last time a token authentified a request
+func UpdateTokenLastUsed(db database.Executer, id int64, lastUsed time.Time) error {
+	query := `UPDATE "user_token" SET last_used = $2 WHERE id = $1`
+	_, err := db.Exec(query, id, lastUsed)
+	return err
+}
+
+// DeleteToken revokes a token of given user
+func DeleteToken(db database.Executer, userID, id int64) error {
+	query := `DELETE FROM "user_token" WHERE id = $1 AND user_id = $2`
+	res, err := db.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sdk.ErrNotFound
+	}
+	return nil
+}
+
+func deleteUserTokens(db database.Executer, u *sdk.User) error {
+	query := `DELETE FROM "user_token" WHERE user_id=$1`
+	_, err := db.Exec(query, u.ID)
+	return err
+}
@@ -137,6 +137,17 @@ func LoadUserAndAuth(db *sql.DB, name string) (*sdk.User, error) {
 	return u, nil
 }
 
+// LoadUserAndAuthByExternalID Load user with auth information from the identity given by an external auth driver
+func LoadUserAndAuthByExternalID(db *sql.DB, externalID string) (*sdk.User, error) {
+	query := `SELECT username FROM "user" WHERE external_id = $1`
+
+	var name string
+	if err := db.QueryRow(query, externalID).Scan(&name); err != nil {
+		return nil, err
+	}
+	return LoadUserAndAuth(db, name)
+}
+
 // FindUserIDByName retrieves only user ID in database
 func FindUserIDByName(db *sql.DB, name string) (int64, error) {
 	query := `SELECT id FROM "user" WHERE username = $1`
@@ -226,6 +237,12 @@ func DeleteUserWithDependencies(db database.Executer, u *sdk.User) error {
 		return err
 	}
 
+	err = deleteUserTokens(db, u)
+	if err != nil {
+		log.Warning("DeleteUserWithDependencies>Cannot remove user tokens: %s", err)
+		return err
+	}
+
 	err = deleteUser(db, u)
 	if err != nil {
 		log.Warning("DeleteUserWithDependencies> User cannot be removed from user table: %s", err)
@@ -259,6 +276,13 @@ func InsertUser(db *sql.DB, u *sdk.User, a *sdk.Auth) error {
 	return err
 }
 
+// UpdateExternalID binds user to its identity on an external auth driver
+func UpdateExternalID(db *sql.DB, userID int64, externalID string) error {
+	query := `UPDATE "user" SET external_id = $1 WHERE id = $2`
+	_, err := db.Exec(query, externalID, userID)
+	return err
+}
+
 // LoadUserPermissions retrieves all group memberships
 func LoadUserPermissions(db *sql.DB, user *sdk.User) error {
 	user.Groups = nil
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/sessionstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// oidcLoginTTL is how long, in seconds, a user has to log in on the issuer
+const oidcLoginTTL = 600
+
+// oidcStateCookie binds a login to the browser which started it
+const oidcStateCookie = "cds_oidc_state"
+
+// oidcPendingLogin is kept in cache between the redirection to the issuer and the callback
+type oidcPendingLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+func oidcDriver(w http.ResponseWriter, r *http.Request) (*auth.OIDCClient, bool) {
+	d, ok := router.authDriver.(*auth.OIDCClient)
+	if !ok {
+		WriteError(w, r, sdk.ErrForbidden)
+		return nil, false
+	}
+	return d, true
+}
+
+// oidcLoginHandler redirects the user to the issuer for an authorization code login
+func oidcLoginHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	d, ok := oidcDriver(w, r)
+	if !ok {
+		return
+	}
+
+	var login oidcPendingLogin
+	state, err := auth.NewPKCE()
+	if err == nil {
+		login.Nonce, err = auth.NewPKCE()
+	}
+	if err == nil {
+		login.Verifier, err = auth.NewPKCE()
+	}
+	if err != nil {
+		log.Warning("oidcLoginHandler> Cannot generate login secrets: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	cache.SetWithTTL(cache.Key("oidc", "login", state), login, oidcLoginTTL)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   oidcLoginTTL,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, d.AuthorizeURL(state, login.Nonce, login.Verifier), http.StatusFound)
+}
+
+// oidcCallbackHandler completes an authorization code login and opens a session
+func oidcCallbackHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	d, ok := oidcDriver(w, r)
+	if !ok {
+		return
+	}
+
+	state := r.FormValue("state")
+	key := cache.Key("oidc", "login", state)
+	var login oidcPendingLogin
+	cache.Get(key, &login)
+	cache.Delete(key)
+	if state == "" || login.Verifier == "" {
+		log.Warning("oidcCallbackHandler> Unknown state %s\n", state)
+		WriteError(w, r, sdk.ErrUnauthorized)
+		return
+	}
+
+	// The login has to be completed by the browser which started it
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true})
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		log.Warning("oidcCallbackHandler> State %s was not issued to this browser\n", state)
+		WriteError(w, r, sdk.ErrUnauthorized)
+		return
+	}
+	if e := r.FormValue("error"); e != "" {
+		log.Warning("oidcCallbackHandler> Login refused by issuer: %s %s\n", e, r.FormValue("error_description"))
+		WriteError(w, r, sdk.ErrUnauthorized)
+		return
+	}
+
+	claims, err := d.Exchange(r.FormValue("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		log.Warning("oidcCallbackHandler> Cannot exchange code: %s\n", err)
+		WriteError(w, r, sdk.ErrUnauthorized)
+		return
+	}
+
+	oidcNewSession(w, r, db, d, claims, false)
+}
+
+// oidcDeviceHandler starts a device login for headless terminals
+func oidcDeviceHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	d, ok := oidcDriver(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := d.DeviceAuthorization()
+	if err != nil {
+		log.Warning("oidcDeviceHandler> Cannot start device login: %s\n", err)
+		WriteError(w, r, sdk.ErrOIDCConn)
+		return
+	}
+
+	WriteJSON(w, r, res, http.StatusOK)
+}
+
+// oidcDeviceTokenHandler opens a persistent session once the user completed the device login.
+// It answers 202 while the user did not.
+func oidcDeviceTokenHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	d, ok := oidcDriver(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var req sdk.OIDCDeviceAuthorization
+	if err := json.Unmarshal(data, &req); err != nil || req.DeviceCode == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, err := d.DeviceToken(req.DeviceCode)
+	if err == auth.ErrOIDCAuthorizationPending {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		log.Warning("oidcDeviceTokenHandler> Device login failed: %s\n", err)
+		WriteError(w, r, sdk.ErrUnauthorized)
+		return
+	}
+
+	oidcNewSession(w, r, db, d, claims, true)
+}
+
+func oidcNewSession(w http.ResponseWriter, r *http.Request, db *sql.DB, d *auth.OIDCClient, claims *auth.OIDCClaims, persistent bool) {
+	u, err := d.ProvisionUser(db, claims)
+	if err != nil {
+		log.Warning("oidcNewSession> Cannot provision user %s: %s\n", claims.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	var sessionKey sessionstore.SessionKey
+	if persistent {
+		sessionKey, err = auth.NewPersistentSession(db, d, u)
+	} else {
+		sessionKey, err = auth.NewSession(d, u)
+	}
+	if err != nil {
+		log.Critical("Auth> Error while creating new session: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set(sdk.SessionTokenHeader, string(sessionKey))
+	response := sdk.UserAPIResponse{
+		User:  *u,
+		Token: string(sessionKey),
+	}
+	response.User.Auth = sdk.Auth{}
+	WriteJSON(w, r, response, http.StatusOK)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/user"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// loadTokenOwner returns the user named in the route, only this user or an admin manages its tokens
+func loadTokenOwner(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) (*sdk.User, bool) {
+	username := mux.Vars(r)["name"]
+	if c.User.Username != username && !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return nil, false
+	}
+
+	u, err := user.LoadUserWithoutAuth(db, username)
+	if err == sql.ErrNoRows {
+		WriteError(w, r, sdk.ErrNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Warning("loadTokenOwner> Cannot load user %s: %s\n", username, err)
+		WriteError(w, r, err)
+		return nil, false
+	}
+	return u, true
+}
+
+func getUserTokensHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	u, ok := loadTokenOwner(w, r, db, c)
+	if !ok {
+		return
+	}
+
+	tokens, err := user.LoadTokens(db, u.ID)
+	if err != nil {
+		log.Warning("getUserTokensHandler> Cannot load tokens of %s: %s\n", u.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, tokens, http.StatusOK)
+}
+
+func addUserTokenHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	u, ok := loadTokenOwner(w, r, db, c)
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var t sdk.APIToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t.Created = time.Now()
+	if t.Name == "" || len(t.Scopes) == 0 || !t.Expiry.After(t.Created) {
+		WriteError(w, r, sdk.ErrInvalidAPIToken)
+		return
+	}
+	for _, s := range t.Scopes {
+		if !sdk.IsValidTokenScope(s) {
+			WriteError(w, r, sdk.ErrInvalidAPIToken)
+			return
+		}
+	}
+
+	tokens, err := user.LoadTokens(db, u.ID)
+	if err != nil {
+		log.Warning("addUserTokenHandler> Cannot load tokens of %s: %s\n", u.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+	for i := range tokens {
+		if tokens[i].Name == t.Name {
+			WriteError(w, r, sdk.ErrConflict)
+			return
+		}
+	}
+
+	clear, hashed, err := user.GenerateToken()
+	if err != nil {
+		log.Warning("addUserTokenHandler> Cannot generate token: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	if err := user.InsertToken(db, u.ID, &t, hashed); err != nil {
+		log.Warning("addUserTokenHandler> Cannot insert token %s of %s: %s\n", t.Name, u.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// The clear token is only known now
+	t.Token = clear
+	WriteJSON(w, r, t, http.StatusCreated)
+}
+
+func deleteUserTokenHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	u, ok := loadTokenOwner(w, r, db, c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	if err := user.DeleteToken(db, u.ID, id); err != nil {
+		log.Warning("deleteUserTokenHandler> Cannot delete token %d of %s: %s\n", id, u.Username, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
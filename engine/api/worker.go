@@ -47,7 +47,7 @@ func registerWorkerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	}
 
 	// Try to register worker
-	worker, err := worker.RegisterWorker(db, params.Name, params.UserKey, params.Model, params.Hatchery, params.BinaryCapabilities)
+	worker, err := worker.RegisterWorker(db, params.Name, params.UserKey, params.Model, params.Hatchery, params.BinaryCapabilities, params.BookedActionBuild)
 	if err != nil {
 		log.Warning("registerWorkerHandler: [%s] Registering failed: %s\n", params.Name, err)
 		w.WriteHeader(http.StatusUnauthorized)
@@ -410,6 +410,12 @@ func addWorkerModelCapa(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 		return
 	}
 
+	if err := worker.SetWorkerModelNeedValidation(db, workerModelID); err != nil {
+		log.Warning("addWorkerModelCapa> cannot set worker model to validate: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
 	// Recompute warnings
 	go func() {
 		warnings, err := sanity.LoadAllWarnings(db, "")
@@ -425,6 +431,71 @@ func addWorkerModelCapa(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 
 }
 
+func validateWorkerModelHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	workerModelIDs := vars["id"]
+
+	workerModelID, err := strconv.ParseInt(workerModelIDs, 10, 64)
+	if err != nil {
+		log.Warning("validateWorkerModelHandler> modelID is no integer '%s': %s\n", workerModelIDs, err)
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	// Only a worker of this model can validate it
+	if c.WorkerID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+	wor, err := worker.LoadWorker(db, c.WorkerID)
+	if err != nil {
+		log.Warning("validateWorkerModelHandler> cannot load worker %s: %s\n", c.WorkerID, err)
+		WriteError(w, r, err)
+		return
+	}
+	if wor.Model != workerModelID {
+		log.Warning("validateWorkerModelHandler> worker %s is not a worker of model %d\n", wor.Name, workerModelID)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	// Get body
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Warning("validateWorkerModelHandler> cannot read body: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Unmarshal body
+	var v sdk.ModelValidation
+	if err := json.Unmarshal(data, &v); err != nil {
+		log.Warning("validateWorkerModelHandler> cannot unmarshal body data: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := worker.UpdateWorkerModelValidation(db, workerModelID, v); err != nil {
+		log.Warning("validateWorkerModelHandler> cannot update model %d validation: %s\n", workerModelID, err)
+		WriteError(w, r, err)
+		return
+	}
+	log.Notice("validateWorkerModelHandler> Model %d validated by %s (%d binaries found, %d capabilities missing)\n", workerModelID, wor.Name, len(v.Binaries), len(v.Missing))
+
+	// Recompute warnings
+	go func() {
+		warnings, err := sanity.LoadAllWarnings(db, "")
+		if err != nil {
+			log.Warning("validateWorkerModelHandler> cannot load warnings: %s\n", err)
+			return
+		}
+
+		for _, warning := range warnings {
+			sanity.CheckPipeline(db, &warning.Project, &warning.Pipeline)
+		}
+	}()
+}
+
 func getWorkerModelTypes(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	WriteJSON(w, r, sdk.AvailableWorkerModelType, http.StatusOK)
 }
@@ -478,6 +549,12 @@ func updateWorkerModelCapa(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		return
 	}
 
+	if err := worker.SetWorkerModelNeedValidation(db, workerModelID); err != nil {
+		log.Warning("updateWorkerModelCapa> cannot set worker model to validate: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
 	// Recompute warnings
 	go func() {
 		warnings, err := sanity.LoadAllWarnings(db, "")
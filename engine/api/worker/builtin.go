@@ -25,6 +25,12 @@ You can put #!/bin/bash, or #!/bin/perl at first line.
 Make sure that the binary used is in
 the pre-requisites of action`,
 		Type: sdk.TextParameter})
+	script.Parameter(sdk.Parameter{
+		Name: sdk.ScriptImageParameter,
+		Description: `Docker image to run the script in (optional).
+The working directory is mounted in the container, and build variables
+are exported as environment variables, cds.app.name as CDS_APP_NAME.`,
+		Type: sdk.StringParameter})
 	if err := checkBuiltinAction(db, script); err != nil {
 		return err
 	}
@@ -99,6 +105,34 @@ func checkBuiltinAction(db *sql.DB, a *sdk.Action) error {
 		if err != nil {
 			return err
 		}
+		return nil
+	}
+
+	return addBuiltinActionParameters(db, a)
+}
+
+// addBuiltinActionParameters adds parameters introduced by a new version to an existing builtin action
+func addBuiltinActionParameters(db *sql.DB, a *sdk.Action) error {
+	existing, err := action.LoadPublicAction(db, a.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range a.Parameters {
+		found := false
+		for _, e := range existing.Parameters {
+			if e.Name == p.Name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		log.Notice("checkBuiltinAction> Adding parameter %s to %s\n", p.Name, a.Name)
+		if err := action.InsertActionParameter(db, existing.ID, p); err != nil {
+			return err
+		}
 	}
 
 	return nil
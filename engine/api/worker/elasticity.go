@@ -21,13 +21,15 @@ func logTime(name string, then time.Time) {
 }
 
 // loadWorkerModelStatus loads from database the number of worker deployed for each model
+// Models which failed their validation are ignored
 func loadWorkerModelStatus(db *sql.DB, userID int64) ([]sdk.ModelStatus, error) {
 	defer logTime("loadWorkerModelStatus", time.Now())
 
 	query := `
-	SELECT worker_model.id, worker_model.name, COALESCE(waiting.count, 0) as waiting, COALESCE(building.count,0) as building FROM worker_model
+	SELECT worker_model.id, worker_model.name, worker_model.need_validation, worker_model.single_use, COALESCE(waiting.count, 0) as waiting, COALESCE(building.count,0) as building FROM worker_model
 	LEFT JOIN LATERAL (SELECT model, COUNT(id) as count FROM worker WHERE worker.status = 'Waiting' AND worker.model = worker_model.id AND worker.owner_id = $1 GROUP BY model) AS waiting ON waiting.model = worker_model.id
 	LEFT JOIN LATERAL (SELECT model, COUNT(id) as count FROM worker WHERE worker.status = 'Building' AND worker.model = worker_model.id AND worker.owner_id = $1 GROUP BY model) AS building ON building.model = worker_model.id
+	WHERE COALESCE(worker_model.validation_error, '') = ''
 	ORDER BY worker_model.name ASC;
 	`
 
@@ -40,7 +42,7 @@ func loadWorkerModelStatus(db *sql.DB, userID int64) ([]sdk.ModelStatus, error)
 	var status []sdk.ModelStatus
 	for rows.Next() {
 		var ms sdk.ModelStatus
-		err := rows.Scan(&ms.ModelID, &ms.ModelName, &ms.CurrentCount, &ms.BuildingCount)
+		err := rows.Scan(&ms.ModelID, &ms.ModelName, &ms.NeedValidation, &ms.SingleUse, &ms.CurrentCount, &ms.BuildingCount)
 		if err != nil {
 			return nil, err
 		}
@@ -50,7 +52,8 @@ func loadWorkerModelStatus(db *sql.DB, userID int64) ([]sdk.ModelStatus, error)
 	return status, nil
 }
 
-func modelCanRun(name string, req []sdk.Requirement, capa []sdk.Requirement) bool {
+// ModelCanRun returns true if worker model capabilities fulfill given requirements
+func ModelCanRun(name string, req []sdk.Requirement, capa []sdk.Requirement) bool {
 	defer logTime("compareRequirements", time.Now())
 	log.Info("Comparing %d requirements to %d capa\n", len(req), len(capa))
 	for _, r := range req {
@@ -131,7 +134,7 @@ func loadAllActionCount(db *sql.DB) ([]actioncount, error) {
 	SELECT COUNT(action_build.id), pipeline_action.action_id
 	FROM action_build
 	JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
-	WHERE action_build.status = $1
+	WHERE action_build.status = $1 AND (action_build.not_before IS NULL OR action_build.not_before <= NOW())
 	GROUP BY pipeline_action.action_id
 	LIMIT 1000
 	`
@@ -164,7 +167,7 @@ func loadUserActionCount(db *sql.DB, userID int64) ([]actioncount, error) {
   JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
 	JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 	JOIN group_user ON group_user.group_id = pipeline_group.group_id
-	WHERE action_build.status = $1 AND group_user.user_id = $2
+	WHERE action_build.status = $1 AND (action_build.not_before IS NULL OR action_build.not_before <= NOW()) AND group_user.user_id = $2
 	GROUP BY pipeline_action.action_id
 	LIMIT 1000
 	`
@@ -277,6 +280,11 @@ func EstimateWorkerModelNeeds(db *sql.DB, u *sdk.User) ([]sdk.ModelStatus, error
 			loopModels = false
 
 			for i := range ms {
+				// Model is not ready to take builds until a worker validates it
+				if ms[i].NeedValidation {
+					continue
+				}
+
 				modelCapaMutex.RLock()
 				capas, ok = modelCapabilities[ms[i].ModelID]
 				modelCapaMutex.RUnlock()
@@ -287,7 +295,7 @@ func EstimateWorkerModelNeeds(db *sql.DB, u *sdk.User) ([]sdk.ModelStatus, error
 					}
 				}
 
-				if modelCanRun(ms[i].ModelName, ac.Action.Requirements, capas) {
+				if ModelCanRun(ms[i].ModelName, ac.Action.Requirements, capas) {
 					if ac.Count > 0 {
 						ms[i].WantedCount++
 						ac.Count--
@@ -2,6 +2,8 @@ package worker
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ovh/cds/engine/api/database"
@@ -10,9 +12,11 @@ import (
 
 // InsertWorkerModel insert a new worker model in database
 func InsertWorkerModel(db *sql.DB, model *sdk.Model) error {
-	query := `INSERT INTO worker_model (type, name, image, owner_id) VALUES ($1, $2, $3, $4) RETURNING id`
+	model.Validated = false
+	model.NeedValidation = true
+	query := `INSERT INTO worker_model (type, name, image, owner_id, validated, need_validation, single_use) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
 
-	err := db.QueryRow(query, string(model.Type), model.Name, model.Image, model.OwnerID).Scan(&model.ID)
+	err := db.QueryRow(query, string(model.Type), model.Name, model.Image, model.OwnerID, model.Validated, model.NeedValidation, model.SingleUse).Scan(&model.ID)
 	if err != nil {
 		return err
 	}
@@ -22,7 +26,8 @@ func InsertWorkerModel(db *sql.DB, model *sdk.Model) error {
 
 // LoadWorkerModels retrieves models from database
 func LoadWorkerModels(db database.Querier) ([]sdk.Model, error) {
-	query := `SELECT worker_model.id, worker_model.type, worker_model.name, worker_model.image, worker_model.owner_id , "user".username
+	query := `SELECT worker_model.id, worker_model.type, worker_model.name, worker_model.image, worker_model.owner_id , "user".username,
+	          worker_model.validated, worker_model.need_validation, worker_model.validation_error, worker_model.single_use
 	          FROM worker_model
 	          JOIN "user" ON "user".id =  worker_model.owner_id
 	          ORDER BY worker_model.name
@@ -39,10 +44,12 @@ func LoadWorkerModels(db database.Querier) ([]sdk.Model, error) {
 		var m sdk.Model
 		var u sdk.User
 		var typeS string
-		err = rows.Scan(&m.ID, &typeS, &m.Name, &m.Image, &m.OwnerID, &u.Username)
+		var validationError sql.NullString
+		err = rows.Scan(&m.ID, &typeS, &m.Name, &m.Image, &m.OwnerID, &u.Username, &m.Validated, &m.NeedValidation, &validationError, &m.SingleUse)
 		if err != nil {
 			return nil, err
 		}
+		m.ValidationError = validationError.String
 		switch typeS {
 		case string(sdk.Docker):
 			m.Type = sdk.Docker
@@ -77,21 +84,33 @@ func LoadWorkerModels(db database.Querier) ([]sdk.Model, error) {
 
 // LoadWorkerModel retrieves a specific worker model in database
 func LoadWorkerModel(db *sql.DB, name string) (*sdk.Model, error) {
-	query := `SELECT worker_model.id, worker_model.type, worker_model.name, worker_model.image, worker_model.owner_id , "user".username
+	return loadWorkerModel(db, "worker_model.name = $1", name)
+}
+
+// LoadWorkerModelByID retrieves a specific worker model in database
+func LoadWorkerModelByID(db *sql.DB, id int64) (*sdk.Model, error) {
+	return loadWorkerModel(db, "worker_model.id = $1", id)
+}
+
+func loadWorkerModel(db *sql.DB, where string, arg interface{}) (*sdk.Model, error) {
+	query := `SELECT worker_model.id, worker_model.type, worker_model.name, worker_model.image, worker_model.owner_id , "user".username,
+		  worker_model.validated, worker_model.need_validation, worker_model.validation_error, worker_model.single_use
 		  FROM worker_model
 		  JOIN "user" ON "user".id = worker_model.owner_id
-		  WHERE name = $1`
+		  WHERE ` + where
 
 	var m sdk.Model
 	var u sdk.User
 	var typeS string
-	err := db.QueryRow(query, name).Scan(&m.ID, &typeS, &m.Name, &m.Image, &m.OwnerID, &u.Username)
+	var validationError sql.NullString
+	err := db.QueryRow(query, arg).Scan(&m.ID, &typeS, &m.Name, &m.Image, &m.OwnerID, &u.Username, &m.Validated, &m.NeedValidation, &validationError, &m.SingleUse)
 	if err != nil && err == sql.ErrNoRows {
 		return nil, sdk.ErrNoWorkerModel
 	}
 	if err != nil {
 		return nil, err
 	}
+	m.ValidationError = validationError.String
 	switch typeS {
 	case string(sdk.Docker):
 		m.Type = sdk.Docker
@@ -242,8 +261,9 @@ func UpdateWorkerModel(db *sql.DB, model sdk.Model) error {
 	}
 	defer tx.Rollback()
 
-	query := `UPDATE worker_model SET type=$1, name=$2, image=$3 WHERE id = $4`
-	_, err = tx.Exec(query, string(model.Type), model.Name, model.Image, model.ID)
+	// Model has to be validated again by a worker
+	query := `UPDATE worker_model SET type=$1, name=$2, image=$3, single_use=$4, validated=$5, need_validation=$6, validation_error=$7 WHERE id = $8`
+	_, err = tx.Exec(query, string(model.Type), model.Name, model.Image, model.SingleUse, false, true, "", model.ID)
 	if err != nil {
 		return err
 	}
@@ -270,3 +290,65 @@ func UpdateWorkerModel(db *sql.DB, model sdk.Model) error {
 
 	return tx.Commit()
 }
+
+// SetWorkerModelNeedValidation asks hatcheries to spawn a worker to validate the model again
+func SetWorkerModelNeedValidation(db database.Executer, modelID int64) error {
+	query := `UPDATE worker_model SET validated=$1, need_validation=$2, validation_error=$3 WHERE id = $4`
+	_, err := db.Exec(query, false, true, "", modelID)
+	return err
+}
+
+// UpdateWorkerModelValidation marks the model as valid or invalid given what a worker found,
+// and adds the binaries found to model capabilities
+func UpdateWorkerModelValidation(db *sql.DB, modelID int64, v sdk.ModelValidation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertBinaryCapabilities(tx, modelID, v.Binaries); err != nil {
+		return err
+	}
+
+	var validationError string
+	if len(v.Missing) > 0 {
+		validationError = fmt.Sprintf("missing capabilities: %s", strings.Join(v.Missing, ", "))
+	}
+
+	query := `UPDATE worker_model SET validated=$1, need_validation=$2, validation_error=$3 WHERE id = $4`
+	if _, err := tx.Exec(query, validationError == "", false, validationError, modelID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertBinaryCapabilities adds to worker model the binaries not already declared as capabilities
+func insertBinaryCapabilities(db database.QueryExecuter, modelID int64, binaries []string) error {
+	existingCapas, err := LoadWorkerModelCapabilities(db, modelID)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range binaries {
+		var found bool
+		for _, c := range existingCapas {
+			if b == c.Value {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		query := `INSERT INTO worker_capability (worker_model_id, name, argument, type) VALUES ($1, $2, $3, $4)`
+		if _, err := db.Exec(query, modelID, b, b, string(sdk.BinaryRequirement)); err != nil {
+			return err
+		}
+		existingCapas = append(existingCapas, sdk.Requirement{Name: b, Type: sdk.BinaryRequirement, Value: b})
+	}
+
+	return nil
+}
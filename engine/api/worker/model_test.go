@@ -107,3 +107,49 @@ func TestInsertWorkerModelCapacity(t *testing.T) {
 	insertCapacity(db, t, m.ID, capa)
 
 }
+
+func TestUpdateWorkerModelValidation(t *testing.T) {
+	db := test.Setup("TestUpdateWorkerModelValidation", t)
+	u := insertUser(t, db, "fakeUser")
+	insertWorkerModel(t, db, "Foo", u.ID)
+
+	m, err := LoadWorkerModel(db, "Foo")
+	if err != nil {
+		t.Fatalf("cannot load model: %s", err)
+	}
+	if !m.NeedValidation {
+		t.Fatalf("New model should need validation")
+	}
+
+	v := sdk.ModelValidation{Binaries: []string{"git"}, Missing: []string{"go"}}
+	if err := UpdateWorkerModelValidation(db, m.ID, v); err != nil {
+		t.Fatalf("cannot update model validation: %s", err)
+	}
+
+	m, err = LoadWorkerModel(db, "Foo")
+	if err != nil {
+		t.Fatalf("cannot load model: %s", err)
+	}
+	if m.NeedValidation || m.Validated || m.ValidationError == "" {
+		t.Fatalf("Model should be invalid, got %+v", m)
+	}
+	if len(m.Capabilities) != 1 || m.Capabilities[0].Value != "git" {
+		t.Fatalf("Expected git capability, got %+v", m.Capabilities)
+	}
+
+	v.Missing = nil
+	if err := UpdateWorkerModelValidation(db, m.ID, v); err != nil {
+		t.Fatalf("cannot update model validation: %s", err)
+	}
+
+	m, err = LoadWorkerModel(db, "Foo")
+	if err != nil {
+		t.Fatalf("cannot load model: %s", err)
+	}
+	if !m.Validated || m.ValidationError != "" {
+		t.Fatalf("Model should be valid, got %+v", m)
+	}
+	if len(m.Capabilities) != 1 {
+		t.Fatalf("Expected 1 capability, got %d", len(m.Capabilities))
+	}
+}
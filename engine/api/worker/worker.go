@@ -8,6 +8,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/log"
@@ -84,13 +85,15 @@ func InsertWorker(db database.Executer, w *sdk.Worker, userID int64) error {
 func LoadWorker(db database.Querier, id string) (*sdk.Worker, error) {
 	w := &sdk.Worker{}
 	var statusS string
-	query := `SELECT id, name, last_beat, owner_id, model, status, hatchery_id FROM worker WHERE worker.id = $1 FOR UPDATE`
+	var actionBuildID sql.NullInt64
+	query := `SELECT id, name, last_beat, owner_id, model, status, hatchery_id, action_build_id FROM worker WHERE worker.id = $1 FOR UPDATE`
 
-	err := db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.LastBeat, &w.OwnerID, &w.Model, &statusS, &w.HatcheryID)
+	err := db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.LastBeat, &w.OwnerID, &w.Model, &statusS, &w.HatcheryID, &actionBuildID)
 	if err != nil {
 		return nil, err
 	}
 	w.Status = sdk.StatusFromString(statusS)
+	w.ActionBuildID = actionBuildID.Int64
 
 	return w, nil
 }
@@ -226,10 +229,11 @@ type RegistrationForm struct {
 	Model              int64
 	Hatchery           int64
 	BinaryCapabilities []string
+	BookedActionBuild  int64
 }
 
 // RegisterWorker  Register new worker
-func RegisterWorker(db *sql.DB, name string, uk string, modelID int64, hatcheryID int64, binaryCapabilities []string) (*sdk.Worker, error) {
+func RegisterWorker(db *sql.DB, name string, uk string, modelID int64, hatcheryID int64, binaryCapabilities []string, bookedActionBuildID int64) (*sdk.Worker, error) {
 
 	if name == "" {
 		return nil, fmt.Errorf("cannot register worker with empty name")
@@ -282,6 +286,30 @@ func RegisterWorker(db *sql.DB, name string, uk string, modelID int64, hatcheryI
 		}
 	}
 
+	// Worker spawned for a specific action build is the only one able to take it
+	if bookedActionBuildID != 0 {
+		if err := build.BindActionBuild(tx, bookedActionBuildID, hatcheryID, userID, w.ID); err != nil {
+			log.Warning("registerWorker> Cannot bind worker %s to action build %d: %s\n", name, bookedActionBuildID, err)
+			return nil, err
+		}
+	}
+
+	// If the model has just been added or updated, the worker has to validate it
+	if modelID != 0 {
+		query := `SELECT need_validation FROM worker_model WHERE id = $1`
+		if err := tx.QueryRow(query, modelID).Scan(&w.ValidateModel); err != nil && err != sql.ErrNoRows {
+			log.Warning("registerWorker> Cannot load model %d: %s\n", modelID, err)
+			return nil, err
+		}
+		if w.ValidateModel {
+			w.Capabilities, err = LoadWorkerModelCapabilities(tx, modelID)
+			if err != nil {
+				log.Warning("registerWorker> Cannot load model %d capabilities: %s\n", modelID, err)
+				return nil, err
+			}
+		}
+	}
+
 	//If the worker is registered for a model and it gave us BinaryCapabilities...
 	if len(binaryCapabilities) > 0 && modelID != 0 {
 		go func() {
@@ -293,36 +321,11 @@ func RegisterWorker(db *sql.DB, name string, uk string, modelID int64, hatcheryI
 			}
 			defer ntx.Rollback()
 
-			existingCapas, err := LoadWorkerModelCapabilities(ntx, modelID)
-			if err != nil {
-				log.Warning("RegisterWorker> Unable to load worker model capabilities : %s", err)
+			if err := insertBinaryCapabilities(ntx, modelID, binaryCapabilities); err != nil {
+				//Ignore errors because we let the database to check constraints...
+				log.Info("registerWorker> Cannot insert into worker_capability: %s\n", err)
 				return
 			}
-
-			var newCapas []string
-			for _, b := range binaryCapabilities {
-				var found bool
-				for _, c := range existingCapas {
-					if b == c.Value {
-						found = true
-						break
-					}
-				}
-				if !found {
-					newCapas = append(newCapas, b)
-				}
-			}
-			if len(newCapas) > 0 {
-				log.Notice("Updating model %d binary capabilities with %d capabilities", modelID, len(newCapas))
-				for _, b := range newCapas {
-					query := `insert into worker_capability (worker_model_id, name, argument, type) values ($1, $2, $3, $4)`
-					if _, err := ntx.Exec(query, modelID, b, b, string(sdk.BinaryRequirement)); err != nil {
-						//Ignore errors because we let the database to check constraints...
-						log.Info("registerWorker> Cannot insert into worker_capability: %s\n", err)
-						return
-					}
-				}
-			}
 			if err := ntx.Commit(); err != nil {
 				log.Warning("RegisterWorker> Unable to commit transaction : %s", err)
 			}
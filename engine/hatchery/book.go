@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// spawnBookedWorkers books waiting action builds a single use model can run,
+// and spawns for each of them a worker which is the only one able to take it
+func spawnBookedWorkers(h HatcheryMode, ms sdk.ModelStatus) error {
+	if ms.WantedCount == 0 {
+		return nil
+	}
+
+	m, err := sdk.GetWorkerModel(ms.ModelName)
+	if err != nil {
+		return fmt.Errorf("cannot get model named '%s' (%s)", ms.ModelName, err)
+	}
+
+	if !h.CanSpawn(m, ms.Requirements) {
+		return nil
+	}
+
+	for i := int64(0); i < ms.WantedCount; i++ {
+		ab, err := sdk.BookActionBuild(h.ID(), m.ID)
+		if err != nil {
+			return fmt.Errorf("cannot book action build for %s (%s)", ms.ModelName, err)
+		}
+		if ab == nil {
+			return nil
+		}
+
+		var req []sdk.Requirement
+		for _, r := range ab.Requirements {
+			if r.Type == sdk.ServiceRequirement {
+				req = append(req, r)
+			}
+		}
+
+		log.Notice("Spawning a %s worker for action build %d\n", ms.ModelName, ab.ID)
+		if err := h.SpawnWorker(m, ab.ID, req); err != nil {
+			log.Warning("Cannot spawn %s for action build %d: %s\n", ms.ModelName, ab.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/hatchery"
+	"github.com/ovh/cds/sdk"
+)
+
+// fakeHatchery records spawned workers
+type fakeHatchery struct {
+	spawned map[int64][]sdk.Requirement
+}
+
+func (f *fakeHatchery) ParseConfig()                                          {}
+func (f *fakeHatchery) Init() error                                           { return nil }
+func (f *fakeHatchery) Refresh() error                                        { return nil }
+func (f *fakeHatchery) KillWorker(worker sdk.Worker) error                    { return nil }
+func (f *fakeHatchery) CanSpawn(model *sdk.Model, req []sdk.Requirement) bool { return true }
+func (f *fakeHatchery) WorkerStarted(model *sdk.Model) int                    { return 0 }
+func (f *fakeHatchery) SetWorkerModelID(int64)                                {}
+func (f *fakeHatchery) Hatchery() *hatchery.Hatchery                          { return &hatchery.Hatchery{ID: 1} }
+func (f *fakeHatchery) ID() int64                                             { return 1 }
+func (f *fakeHatchery) Mode() string                                          { return "fake" }
+func (f *fakeHatchery) SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
+	f.spawned[actionBuildID] = req
+	return nil
+}
+
+func TestSpawnBookedWorkers(t *testing.T) {
+	queue := []sdk.ActionBuild{
+		{ID: 10, Requirements: []sdk.Requirement{{Name: "pg", Type: sdk.ServiceRequirement, Value: "postgres:9.5"}, {Name: "go", Type: sdk.BinaryRequirement, Value: "go"}}},
+		{ID: 11},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/worker/model", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "golang", r.URL.Query().Get("name"))
+		json.NewEncoder(w).Encode(sdk.Model{ID: 42, Name: "golang", SingleUse: true})
+	})
+	router.HandleFunc("/hatchery/1/book/42", func(w http.ResponseWriter, r *http.Request) {
+		if len(queue) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(queue[0])
+		queue = queue[1:]
+	}).Methods("POST")
+	s := httptest.NewServer(router)
+	defer s.Close()
+	sdk.Options(s.URL, "", "", "")
+
+	h := &fakeHatchery{spawned: map[int64][]sdk.Requirement{}}
+	ms := sdk.ModelStatus{ModelID: 42, ModelName: "golang", WantedCount: 3, SingleUse: true}
+	assert.NoError(t, spawnBookedWorkers(h, ms))
+
+	// One worker per booked action build, with its service requirements only
+	assert.Len(t, h.spawned, 2)
+	assert.Equal(t, []sdk.Requirement{{Name: "pg", Type: sdk.ServiceRequirement, Value: "postgres:9.5"}}, h.spawned[10])
+	assert.Empty(t, h.spawned[11])
+}
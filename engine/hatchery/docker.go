@@ -55,15 +55,12 @@ func (hd *HatcheryDocker) Hatchery() *hatchery.Hatchery {
 }
 
 // CanSpawn return wether or not hatchery can spawn model
-// requirement are not supported
+// only service requirements are supported
 func (hd *HatcheryDocker) CanSpawn(model *sdk.Model, req []sdk.Requirement) bool {
 	if model.Type != sdk.Docker {
 		return false
 	}
-	if len(req) > 0 {
-		return false
-	}
-	return true
+	return onlyServiceRequirements(req)
 }
 
 // Init starts cleaning routine
@@ -134,7 +131,7 @@ func (hd *HatcheryDocker) Refresh() error {
 			diff := ms.WantedCount - ms.CurrentCount
 			log.Notice("I got to spawn %d %s worker !\n", diff, ms.ModelName)
 			for i := 0; i < int(diff); i++ {
-				err = hd.SpawnWorker(m, ms.Requirements)
+				err = hd.SpawnWorker(m, 0, ms.Requirements)
 				if err != nil {
 					return err
 				}
@@ -167,6 +164,7 @@ func (hd *HatcheryDocker) workerIndexCleanupRoutine() {
 			if cmd.ProcessState != nil && cmd.ProcessState.Exited() {
 				log.Info("HatcheryDocker.IndexCleanup: removing exited %s\n", name)
 				delete(hd.workers, name)
+				go removeServices(name)
 				break
 			}
 		}
@@ -214,6 +212,7 @@ func (hd *HatcheryDocker) killAwolWorker() {
 					if err != nil {
 						log.Warning("HatcheryDocker.killAwolWorker: cannot rm container %s: %s\n", name, err)
 					}
+					removeServices(name)
 				}()
 
 				delete(hd.workers, name)
@@ -238,7 +237,7 @@ func (hd *HatcheryDocker) WorkerStarted(model *sdk.Model) int {
 }
 
 // SpawnWorker starts a new worker in a docker container locally
-func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) error {
+func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
 	var err error
 	uk, err = sdk.GenerateWorkerKey(sdk.FirstUseExpire)
 	if err != nil {
@@ -259,6 +258,15 @@ func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) erro
 	}
 	name = wm.Name + "-" + name
 
+	// Start services on a network private to the worker
+	svcs := services(req)
+	if len(svcs) > 0 {
+		if err := startServices(name, svcs); err != nil {
+			removeServices(name)
+			return err
+		}
+	}
+
 	var args []string
 	args = append(args, "run", "--rm", "-a", "STDOUT", "-a", "STDERR")
 	//args = append(args, "run")
@@ -269,7 +277,16 @@ func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) erro
 	args = append(args, "-e", fmt.Sprintf("CDS_KEY=%s", uk))
 	args = append(args, "-e", fmt.Sprintf("CDS_MODEL=%d", wm.ID))
 	args = append(args, "-e", fmt.Sprintf("CDS_HATCHERY=%d", hd.hatch.ID))
+	if actionBuildID != 0 {
+		args = append(args, "-e", fmt.Sprintf("CDS_BOOKED_ACTION_BUILD=%d", actionBuildID))
+	}
 	args = append(args, fmt.Sprintf("--add-host=%s", viper.GetString("docker-add-host")))
+	if len(svcs) > 0 {
+		args = append(args, "--network", serviceNetwork(name))
+	}
+	for _, s := range svcs {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", serviceVariable(s.Name), s.Name))
+	}
 	args = append(args, wm.Image)
 	args = append(args, "sh", "-c", fmt.Sprintf("rm -f worker && echo 'Download worker' && curl %s/download/worker/`uname -m` -o worker && echo 'chmod worker' && chmod +x worker && echo 'starting worker' && ./worker", sdk.Host))
 
@@ -278,6 +295,7 @@ func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) erro
 
 	err = cmd.Start()
 	if err != nil {
+		removeServices(name)
 		return err
 	}
 	hd.Lock()
@@ -314,6 +332,7 @@ func (hd *HatcheryDocker) KillWorker(worker sdk.Worker) error {
 			if err != nil {
 				return fmt.Errorf("HatcheryDocker.KillWorker: cannot rm container %s: %s\n", name, err)
 			}
+			removeServices(name)
 
 			delete(hd.workers, worker.Name)
 			return nil
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+)
+
+// serviceLabel links service containers to their worker, so they can be removed with it
+const serviceLabel = "service_worker"
+
+//serviceNetwork is the network shared by a worker and its services
+func serviceNetwork(worker string) string {
+	return "cds-" + worker
+}
+
+//startServices creates the network of the worker, then starts services and waits for them to be healthy.
+//Services are reachable by their name on the network.
+func startServices(worker string, svcs []service) error {
+	network := serviceNetwork(worker)
+	if out, err := exec.Command("docker", "network", "create", "--label", serviceLabel+"="+worker, network).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot create network %s: %s (%s)", network, err, strings.TrimSpace(string(out)))
+	}
+
+	for _, s := range svcs {
+		container := worker + "-" + s.Name
+		args := []string{"run", "-d", "--name", container, "--network", network, "--network-alias", s.Name, "--label", serviceLabel + "=" + worker}
+		for _, e := range s.Env {
+			args = append(args, "-e", e)
+		}
+		args = append(args, s.Image)
+
+		log.Notice("startServices> Starting service %s (%s) for %s\n", s.Name, s.Image, worker)
+		if out, err := exec.Command("docker", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("cannot start service %s: %s (%s)", s.Name, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	for _, s := range svcs {
+		if err := waitForService(worker + "-" + s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//waitForService waits for the healthcheck of the image of a service to succeed.
+//A service without healthcheck is ready once running.
+func waitForService(container string) error {
+	format := "{{if .State.Health}}{{.State.Health.Status}}{{else}}{{.State.Status}}{{end}}"
+	timeout := time.Now().Add(serviceTimeout)
+	for time.Now().Before(timeout) {
+		out, err := exec.Command("docker", "inspect", "--format", format, container).Output()
+		if err != nil {
+			return fmt.Errorf("cannot inspect service %s: %s", container, err)
+		}
+
+		switch status := strings.TrimSpace(string(out)); status {
+		case "healthy", "running":
+			return nil
+		case "unhealthy", "exited", "dead":
+			return fmt.Errorf("service %s is %s", container, status)
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return fmt.Errorf("service %s is not healthy after %s", container, serviceTimeout)
+}
+
+//removeServices removes service containers of a worker, then its network
+func removeServices(worker string) {
+	out, err := exec.Command("docker", "ps", "-aq", "--filter", "label="+serviceLabel+"="+worker).Output()
+	if err != nil {
+		log.Warning("removeServices> Cannot list services of %s: %s\n", worker, err)
+		return
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		// Worker without service, or services already removed
+		exec.Command("docker", "network", "rm", serviceNetwork(worker)).Run()
+		return
+	}
+
+	if err := exec.Command("docker", append([]string{"rm", "-f"}, ids...)...).Run(); err != nil {
+		log.Warning("removeServices> Cannot remove services of %s: %s\n", worker, err)
+	}
+	if err := exec.Command("docker", "network", "rm", serviceNetwork(worker)).Run(); err != nil {
+		log.Warning("removeServices> Cannot remove network of %s: %s\n", worker, err)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/namesgenerator"
+
+	"github.com/ovh/cds/engine/api/hatchery"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+const kubernetesServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+//Labels set on pods spawned by kubernetes hatchery
+const (
+	kubernetesLabelHatchery    = "cds-hatchery"
+	kubernetesLabelWorkerModel = "cds-worker-model"
+	kubernetesLabelWorkerName  = "cds-worker-name"
+)
+
+var kubernetesInvalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+//HatcheryKubernetes spawns each worker as a pod in a kubernetes namespace
+type HatcheryKubernetes struct {
+	hatch  *hatchery.Hatchery
+	client *kubernetesClient
+	cpu    string
+}
+
+// ParseConfig for kubernetes mode
+func (k *HatcheryKubernetes) ParseConfig() {
+	host := os.Getenv("KUBERNETES_HOST")
+	inCluster := host == ""
+	if inCluster {
+		// Running in a pod, use kubernetes service and service account
+		if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+			sdk.Exit("KUBERNETES_HOST not provided, aborting\n")
+		}
+		host = fmt.Sprintf("https://%s:%s", os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"))
+	}
+
+	token := os.Getenv("KUBERNETES_TOKEN")
+	if token == "" && inCluster {
+		b, err := ioutil.ReadFile(kubernetesServiceAccountPath + "/token")
+		if err != nil {
+			sdk.Exit("Cannot read service account token: %s\n", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	namespace := os.Getenv("KUBERNETES_NAMESPACE")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	k.cpu = os.Getenv("KUBERNETES_WORKER_CPU")
+	if k.cpu == "" {
+		k.cpu = "500m"
+	}
+
+	tlsConfig := &tls.Config{}
+	if os.Getenv("KUBERNETES_INSECURE") == "true" {
+		tlsConfig.InsecureSkipVerify = true
+	} else if inCluster {
+		ca, err := ioutil.ReadFile(kubernetesServiceAccountPath + "/ca.crt")
+		if err != nil {
+			sdk.Exit("Cannot read service account certificate: %s\n", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(ca)
+	}
+
+	k.client = &kubernetesClient{
+		host:      strings.TrimSuffix(host, "/"),
+		token:     token,
+		namespace: namespace,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+// Init registers the hatchery and starts the killing routine of awol pods
+func (k *HatcheryKubernetes) Init() error {
+	if _, err := k.client.listPods(""); err != nil {
+		log.Critical("Unable to reach kubernetes API: %s\n", err)
+		return err
+	}
+
+	// Register without declaring model
+	name, err := os.Hostname()
+	if err != nil {
+		log.Warning("Cannot retrieve hostname: %s\n", err)
+		name = "cds-hatchery"
+	}
+	name += "-kubernetes"
+	k.hatch = &hatchery.Hatchery{
+		Name: name,
+	}
+
+	if err := register(k.hatch); err != nil {
+		log.Warning("Cannot register hatchery: %s\n", err)
+		return err
+	}
+
+	log.Notice("Kubernetes Hatchery ready to run in namespace %s !\n", k.client.namespace)
+
+	go k.killAwolWorkerRoutine()
+	return nil
+}
+
+// Refresh doesn't do anything
+func (k *HatcheryKubernetes) Refresh() error {
+	return nil
+}
+
+// KillWorker deletes the pod of the worker
+func (k *HatcheryKubernetes) KillWorker(worker sdk.Worker) error {
+	log.Notice("HatcheryKubernetes.KillWorker> Deleting pod %s\n", worker.Name)
+	return k.client.deletePod(worker.Name)
+}
+
+// CanSpawn checks if the model can be spawned by this hatchery
+func (k *HatcheryKubernetes) CanSpawn(model *sdk.Model, req []sdk.Requirement) bool {
+	if model.Type != sdk.Docker {
+		return false
+	}
+
+	pods, err := k.client.listPods(k.labelSelector(nil))
+	if err != nil {
+		log.Warning("HatcheryKubernetes.CanSpawn> Cannot list pods: %s\n", err)
+		return false
+	}
+	if countActivePods(pods) >= maxWorker {
+		return false
+	}
+
+	for _, r := range req {
+		if r.Type == sdk.ServiceRequirement && strings.Split(r.Value, " ")[0] == "" {
+			return false
+		}
+		//Worker hostname is the pod name, it never matches a hostname requirement
+		if r.Type == sdk.HostnameRequirement {
+			return false
+		}
+	}
+	return true
+}
+
+// SpawnWorker creates a pod running the worker, and service requirements as sidecar containers
+func (k *HatcheryKubernetes) SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
+	//uk is the worker key for worker auth
+	uk, err := sdk.GenerateWorkerKey(sdk.FirstUseExpire)
+	if err != nil {
+		return fmt.Errorf("SpawnWorker> Cannot generate worker key: %s", err)
+	}
+
+	//name is the name of the worker and the name of the pod
+	name := kubernetesPodName(model.Name, strings.Replace(namesgenerator.GetRandomName(0), "_", "-", -1))
+	log.Notice("HatcheryKubernetes.SpawnWorker> Spawning worker %s (%s) with requirements %v\n", name, model.Image, req)
+
+	pod := k.podSpec(name, uk, model, req)
+	if actionBuildID != 0 {
+		pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, EnvVar{Name: "CDS_BOOKED_ACTION_BUILD", Value: strconv.FormatInt(actionBuildID, 10)})
+	}
+	if err := k.client.createPod(pod); err != nil {
+		log.Warning("HatcheryKubernetes.SpawnWorker> Unable to create pod %s: %s\n", name, err)
+		return err
+	}
+
+	return nil
+}
+
+//podSpec translates worker model and requirements in a pod
+func (k *HatcheryKubernetes) podSpec(name, key string, model *sdk.Model, req []sdk.Requirement) *Pod {
+	worker := Container{
+		Name:    "worker",
+		Image:   model.Image,
+		Command: []string{"sh", "-c", fmt.Sprintf("rm -f worker && curl %s/download/worker/`uname -m` -o worker && chmod +x worker && exec ./worker", sdk.Host)},
+		Env: []EnvVar{
+			{Name: "CDS_API", Value: sdk.Host},
+			{Name: "CDS_NAME", Value: name},
+			{Name: "CDS_KEY", Value: key},
+			{Name: "CDS_MODEL", Value: strconv.FormatInt(model.ID, 10)},
+			{Name: "CDS_HATCHERY", Value: strconv.FormatInt(k.ID(), 10)},
+			{Name: "CDS_SINGLE_USE", Value: "1"},
+		},
+		Resources: ResourceRequirements{
+			Requests: map[string]string{
+				"cpu":    k.cpu,
+				"memory": fmt.Sprintf("%dMi", estimateMemory(model)),
+			},
+		},
+	}
+
+	pod := &Pod{
+		Metadata: ObjectMeta{
+			Name:   name,
+			Labels: k.labels(model, name),
+		},
+		Spec: PodSpec{
+			Containers:    []Container{worker},
+			RestartPolicy: "Never",
+		},
+	}
+
+	var aliases []string
+	for _, r := range req {
+		switch r.Type {
+		case sdk.ServiceRequirement:
+			//name= <alias> => the name of the host the worker uses to reach the service
+			//value= "postgres:latest env_1=blabla env_2=blabla" => we can add env variables in requirement value
+			tuple := strings.Split(r.Value, " ")
+			service := Container{
+				Name:  kubernetesPodName("service", r.Name),
+				Image: tuple[0],
+			}
+			for _, e := range tuple[1:] {
+				kv := strings.SplitN(e, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				service.Env = append(service.Env, EnvVar{Name: kv[0], Value: kv[1]})
+			}
+			pod.Spec.Containers = append(pod.Spec.Containers, service)
+			//Containers of a pod share the same network, services are reachable on localhost
+			aliases = append(aliases, r.Name)
+		}
+	}
+	if len(aliases) > 0 {
+		pod.Spec.HostAliases = []HostAlias{{IP: "127.0.0.1", Hostnames: aliases}}
+	}
+
+	return pod
+}
+
+// WorkerStarted returns the number of pods of the model not terminated yet
+func (k *HatcheryKubernetes) WorkerStarted(model *sdk.Model) int {
+	pods, err := k.client.listPods(k.labelSelector(model))
+	if err != nil {
+		log.Warning("HatcheryKubernetes.WorkerStarted> Cannot list pods: %s\n", err)
+		return 0
+	}
+	return countActivePods(pods)
+}
+
+// SetWorkerModelID does nothing
+func (k *HatcheryKubernetes) SetWorkerModelID(int64) {}
+
+// Hatchery returns Hatchery instances
+func (k *HatcheryKubernetes) Hatchery() *hatchery.Hatchery {
+	return k.hatch
+}
+
+// ID returns ID of the Hatchery
+func (k *HatcheryKubernetes) ID() int64 {
+	if k.hatch == nil {
+		return 0
+	}
+	return k.hatch.ID
+}
+
+// Mode returns KubernetesMode value
+func (k *HatcheryKubernetes) Mode() string {
+	return KubernetesMode
+}
+
+func (k *HatcheryKubernetes) labels(model *sdk.Model, name string) map[string]string {
+	return map[string]string{
+		kubernetesLabelHatchery:    strconv.FormatInt(k.ID(), 10),
+		kubernetesLabelWorkerModel: strconv.FormatInt(model.ID, 10),
+		kubernetesLabelWorkerName:  name,
+	}
+}
+
+//labelSelector selects pods of this hatchery, and of the model if not nil
+func (k *HatcheryKubernetes) labelSelector(model *sdk.Model) string {
+	s := fmt.Sprintf("%s=%d", kubernetesLabelHatchery, k.ID())
+	if model != nil {
+		s += fmt.Sprintf(",%s=%d", kubernetesLabelWorkerModel, model.ID)
+	}
+	return s
+}
+
+func (k *HatcheryKubernetes) killAwolWorkerRoutine() {
+	for {
+		time.Sleep(10 * time.Second)
+		if err := k.killAwolWorker(); err != nil {
+			log.Warning("HatcheryKubernetes.killAwolWorker> Cannot kill awol workers: %s\n", err)
+		}
+	}
+}
+
+//killAwolWorker deletes terminated pods, pods of disabled workers
+//and running pods whose worker did not register after a minute
+func (k *HatcheryKubernetes) killAwolWorker() error {
+	apiworkers, err := sdk.GetWorkers()
+	if err != nil {
+		return err
+	}
+
+	pods, err := k.client.listPods(k.labelSelector(nil))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pods {
+		name := p.Metadata.Labels[kubernetesLabelWorkerName]
+		if name == "" {
+			continue
+		}
+
+		var found, disabled bool
+		for _, w := range apiworkers {
+			if w.Name == name {
+				found = true
+				disabled = w.Status == sdk.StatusDisabled
+				break
+			}
+		}
+
+		var kill bool
+		switch {
+		case p.Status.Phase == PodSucceeded || p.Status.Phase == PodFailed:
+			kill = true
+		case disabled:
+			log.Info("Worker %s is disabled. Kill it with fire !\n", name)
+			kill = true
+		case !found && p.Status.Phase == PodRunning && p.Status.StartTime != nil && time.Since(*p.Status.StartTime) > 1*time.Minute:
+			kill = true
+		}
+
+		if !kill {
+			continue
+		}
+		if err := k.client.deletePod(p.Metadata.Name); err != nil {
+			log.Warning("HatcheryKubernetes.killAwolWorker> Cannot delete pod %s: %s\n", p.Metadata.Name, err)
+			continue
+		}
+		log.Notice("HatcheryKubernetes.killAwolWorker> Deleted pod %s\n", p.Metadata.Name)
+	}
+
+	return nil
+}
+
+func countActivePods(pods []Pod) int {
+	var x int
+	for _, p := range pods {
+		if p.Status.Phase != PodSucceeded && p.Status.Phase != PodFailed {
+			x++
+		}
+	}
+	return x
+}
+
+//kubernetesPodName builds a valid pod or container name (lower case alphanumeric and '-', 63 characters max)
+func kubernetesPodName(prefix, suffix string) string {
+	prefix = kubernetesInvalidNameChars.ReplaceAllString(strings.ToLower(prefix), "-")
+	suffix = kubernetesInvalidNameChars.ReplaceAllString(strings.ToLower(suffix), "-")
+	// Keep the suffix, it makes the name unique
+	if max := 62 - len(suffix); len(prefix) > max && max > 0 {
+		prefix = prefix[:max]
+	}
+	return strings.Trim(prefix+"-"+suffix, "-")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//Pod is a kubernetes pod, only fields used by the hatchery are declared
+type Pod struct {
+	APIVersion string     `json:"apiVersion,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	Metadata   ObjectMeta `json:"metadata"`
+	Spec       PodSpec    `json:"spec"`
+	Status     PodStatus  `json:"status,omitempty"`
+}
+
+//ObjectMeta is the metadata of a kubernetes object
+type ObjectMeta struct {
+	Name              string            `json:"name,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	CreationTimestamp *time.Time        `json:"creationTimestamp,omitempty"`
+}
+
+//PodSpec describes containers of a pod
+type PodSpec struct {
+	Containers    []Container `json:"containers"`
+	RestartPolicy string      `json:"restartPolicy,omitempty"`
+	HostAliases   []HostAlias `json:"hostAliases,omitempty"`
+}
+
+//Container is a container of a pod
+type Container struct {
+	Name      string               `json:"name"`
+	Image     string               `json:"image"`
+	Command   []string             `json:"command,omitempty"`
+	Env       []EnvVar             `json:"env,omitempty"`
+	Resources ResourceRequirements `json:"resources,omitempty"`
+}
+
+//EnvVar is an environment variable of a container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+//ResourceRequirements describes compute resources needed by a container
+type ResourceRequirements struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+//HostAlias adds entries to /etc/hosts of all containers of a pod
+type HostAlias struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+}
+
+//PodStatus is the observed state of a pod
+type PodStatus struct {
+	Phase     string     `json:"phase,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+//PodList is returned by pods listing
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+//Pod phases
+const (
+	PodPending   = "Pending"
+	PodRunning   = "Running"
+	PodSucceeded = "Succeeded"
+	PodFailed    = "Failed"
+)
+
+type kubernetesError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e kubernetesError) Error() string {
+	return fmt.Sprintf("kubernetes error %d: %s", e.Code, e.Message)
+}
+
+//kubernetesClient is a minimal client of kubernetes REST API, scoped on a namespace
+type kubernetesClient struct {
+	host      string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+func (k *kubernetesClient) podsURL() string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/pods", k.host, k.namespace)
+}
+
+func (k *kubernetesClient) do(method, path string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "CDS-HATCHERY/1.0")
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		kerr := kubernetesError{Code: resp.StatusCode}
+		if err := json.Unmarshal(data, &kerr); err != nil || kerr.Message == "" {
+			kerr.Message = resp.Status
+		}
+		return kerr
+	}
+
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (k *kubernetesClient) createPod(p *Pod) error {
+	p.APIVersion = "v1"
+	p.Kind = "Pod"
+	return k.do("POST", k.podsURL(), p, nil)
+}
+
+func (k *kubernetesClient) listPods(labelSelector string) ([]Pod, error) {
+	u := k.podsURL()
+	if labelSelector != "" {
+		u += "?labelSelector=" + url.QueryEscape(labelSelector)
+	}
+
+	var list PodList
+	if err := k.do("GET", u, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (k *kubernetesClient) deletePod(name string) error {
+	err := k.do("DELETE", k.podsURL()+"/"+name, nil, nil)
+	if kerr, ok := err.(kubernetesError); ok && kerr.Code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/hatchery"
+	"github.com/ovh/cds/sdk"
+)
+
+//fakeKubernetes is a fake kubernetes API server storing pods in memory
+type fakeKubernetes struct {
+	sync.Mutex
+	pods    map[string]Pod
+	workers []sdk.Worker
+}
+
+func (f *fakeKubernetes) router(t *testing.T) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/namespaces/cds/pods", func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+
+		if r.Method == "POST" {
+			var p Pod
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+			p.Status.Phase = PodPending
+			f.pods[p.Metadata.Name] = p
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		list := PodList{}
+		for _, p := range f.pods {
+			match := true
+			for _, s := range strings.Split(r.URL.Query().Get("labelSelector"), ",") {
+				kv := strings.SplitN(s, "=", 2)
+				if len(kv) == 2 && p.Metadata.Labels[kv[0]] != kv[1] {
+					match = false
+				}
+			}
+			if match {
+				list.Items = append(list.Items, p)
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	router.HandleFunc("/api/v1/namespaces/cds/pods/{name}", func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		name := mux.Vars(r)["name"]
+		if _, ok := f.pods[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","message":"pods \"` + name + `\" not found","code":404}`))
+			return
+		}
+		delete(f.pods, name)
+	}).Methods("DELETE")
+	router.HandleFunc("/worker", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(f.workers)
+	})
+	return router
+}
+
+func TestHatcheryKubernetes(t *testing.T) {
+	f := &fakeKubernetes{pods: map[string]Pod{}}
+	s := httptest.NewServer(f.router(t))
+	defer s.Close()
+
+	sdk.Options(s.URL, "", "", "")
+	maxWorker = 2
+
+	h := &HatcheryKubernetes{
+		hatch:  &hatchery.Hatchery{ID: 1},
+		client: &kubernetesClient{host: s.URL, token: "foo", namespace: "cds", http: http.DefaultClient},
+		cpu:    "500m",
+	}
+	model := &sdk.Model{
+		ID:           42,
+		Name:         "Golang_1.7",
+		Type:         sdk.Docker,
+		Image:        "golang:1.7",
+		Capabilities: []sdk.Requirement{{Type: sdk.BinaryRequirement, Value: "go"}},
+	}
+	req := []sdk.Requirement{
+		{Name: "pg", Type: sdk.ServiceRequirement, Value: "postgres:9.5 POSTGRES_USER=cds POSTGRES_PASSWORD=cds"},
+	}
+
+	p := h.podSpec(kubernetesPodName(model.Name, "foo-bar"), "key", model, req)
+	assert.Equal(t, "golang-1-7-foo-bar", p.Metadata.Name)
+	assert.Equal(t, "42", p.Metadata.Labels[kubernetesLabelWorkerModel])
+	assert.Equal(t, "3072Mi", p.Spec.Containers[0].Resources.Requests["memory"])
+	assert.Len(t, p.Spec.Containers, 2)
+	assert.Equal(t, "postgres:9.5", p.Spec.Containers[1].Image)
+	assert.Equal(t, []EnvVar{{Name: "POSTGRES_USER", Value: "cds"}, {Name: "POSTGRES_PASSWORD", Value: "cds"}}, p.Spec.Containers[1].Env)
+	assert.Equal(t, []HostAlias{{IP: "127.0.0.1", Hostnames: []string{"pg"}}}, p.Spec.HostAliases)
+
+	assert.NoError(t, h.client.createPod(p))
+	assert.NoError(t, h.client.createPod(h.podSpec("golang-1-7-other", "key", model, nil)))
+	assert.NoError(t, h.client.createPod(h.podSpec("other-model", "key", &sdk.Model{ID: 43, Name: "other"}, nil)))
+
+	assert.Equal(t, 2, h.WorkerStarted(model))
+	assert.False(t, h.CanSpawn(model, nil), "max worker should be reached")
+
+	// Terminated pods are not counted
+	f.Lock()
+	done := f.pods["golang-1-7-other"]
+	done.Status.Phase = PodSucceeded
+	f.pods["golang-1-7-other"] = done
+	f.Unlock()
+	assert.Equal(t, 1, h.WorkerStarted(model))
+
+	assert.NoError(t, h.KillWorker(sdk.Worker{Name: "other-model"}))
+	assert.NoError(t, h.KillWorker(sdk.Worker{Name: "unknown"}))
+	assert.True(t, h.CanSpawn(model, req))
+	assert.False(t, h.CanSpawn(model, []sdk.Requirement{{Name: "node", Type: sdk.HostnameRequirement, Value: "node-1"}}), "worker hostname is the pod name")
+
+	// Pods of disabled, terminated and not registered workers are killed
+	started := time.Now().Add(-2 * time.Minute)
+	f.Lock()
+	for _, name := range []string{"registered", "disabled", "awol", "starting"} {
+		p := h.podSpec(name, "key", model, nil)
+		p.Status = PodStatus{Phase: PodRunning, StartTime: &started}
+		f.pods[name] = *p
+	}
+	starting := f.pods["starting"]
+	starting.Status.Phase = PodPending
+	f.pods["starting"] = starting
+	f.workers = []sdk.Worker{
+		{Name: "registered", Status: sdk.StatusBuilding},
+		{Name: "disabled", Status: sdk.StatusDisabled},
+	}
+	f.Unlock()
+
+	assert.NoError(t, h.killAwolWorker())
+
+	f.Lock()
+	defer f.Unlock()
+	var names []string
+	for name := range f.pods {
+		names = append(names, name)
+	}
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, "registered")
+	assert.Contains(t, names, "starting")
+	assert.Contains(t, names, "golang-1-7-foo-bar")
+}
+
+func TestKubernetesPodName(t *testing.T) {
+	assert.Equal(t, "my-model-happy-turing", kubernetesPodName("My_Model", "happy-turing"))
+	name := kubernetesPodName(strings.Repeat("a", 80), "happy-turing")
+	assert.Len(t, name, 63)
+	assert.True(t, strings.HasSuffix(name, "-happy-turing"))
+}
@@ -81,7 +81,7 @@ func (h *HatcheryLocal) KillWorker(worker sdk.Worker) error {
 }
 
 // SpawnWorker starts a new worker process
-func (h *HatcheryLocal) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) error {
+func (h *HatcheryLocal) SpawnWorker(wm *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
 	var err error
 	uk, err = sdk.GenerateWorkerKey(sdk.FirstUseExpire)
 	if err != nil {
@@ -102,6 +102,9 @@ func (h *HatcheryLocal) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) error
 	args = append(args, fmt.Sprintf("--name=%s", wName))
 	args = append(args, fmt.Sprintf("--hatchery=%d", h.hatch.ID))
 	args = append(args, "--single-use")
+	if actionBuildID != 0 {
+		args = append(args, fmt.Sprintf("--booked-action-build=%d", actionBuildID))
+	}
 
 	cmd := exec.Command("worker", args...)
 
@@ -20,7 +20,7 @@ type HatcheryMode interface {
 	Init() error
 	Refresh() error
 	KillWorker(worker sdk.Worker) error
-	SpawnWorker(model *sdk.Model, req []sdk.Requirement) error
+	SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error
 	CanSpawn(model *sdk.Model, req []sdk.Requirement) bool
 	WorkerStarted(model *sdk.Model) int
 	SetWorkerModelID(int64)
@@ -31,11 +31,12 @@ type HatcheryMode interface {
 
 // Definition of different hatchery mode
 const (
-	LocalMode  = "local"
-	DockerMode = "docker"
-	SwarmMode  = "swarm"
-	MesosMode  = "mesos"
-	CloudMode  = "openstack"
+	LocalMode      = "local"
+	DockerMode     = "docker"
+	SwarmMode      = "swarm"
+	MesosMode      = "mesos"
+	CloudMode      = "openstack"
+	KubernetesMode = "kubernetes"
 )
 
 var (
@@ -57,7 +58,7 @@ func init() {
 	viper.SetEnvPrefix("hatchery")
 	viper.AutomaticEnv()
 
-	flags.String("mode", "", "Hatchery mode : local, docker, mesos")
+	flags.String("mode", "", "Hatchery mode : local, docker, mesos, kubernetes")
 	viper.BindPFlag("mode", flags.Lookup("mode"))
 
 	flags.String("docker-add-host", "", "Start worker with a custom host-to-IP mapping (host:ip)")
@@ -97,6 +98,23 @@ func hatcheryRoutine(h HatcheryMode) error {
 	provision := int64(viper.GetInt("provision"))
 
 	for _, ms := range wms {
+		// New or updated model, spawn a worker to check its capabilities
+		if ms.NeedValidation {
+			if err := spawnValidationWorker(h, ms); err != nil {
+				log.Warning("Cannot validate %s: %s\n", ms.ModelName, err)
+			}
+			continue
+		}
+		delete(validations, ms.ModelID)
+
+		// Single use workers are only spawned for a booked action build, no provisioning
+		if ms.SingleUse {
+			if err := spawnBookedWorkers(h, ms); err != nil {
+				log.Warning("%s\n", err)
+			}
+			continue
+		}
+
 		// Provisionning
 		ms.WantedCount += provision
 
@@ -124,7 +142,7 @@ func hatcheryRoutine(h HatcheryMode) error {
 			log.Notice("I got to spawn %d %s worker ! (%d/%d)\n", diff, ms.ModelName, ms.CurrentCount, ms.WantedCount)
 
 			for i := 0; i < int(diff); i++ {
-				if err := h.SpawnWorker(m, ms.Requirements); err != nil {
+				if err := h.SpawnWorker(m, 0, ms.Requirements); err != nil {
 					log.Warning("Cannot spawn %s: %s\n", ms.ModelName, err)
 					continue
 				}
@@ -227,6 +245,8 @@ func parseConfig() HatcheryMode {
 		h = &HatcheryCloud{}
 	case SwarmMode:
 		h = &HatcherySwarm{}
+	case KubernetesMode:
+		h = &HatcheryKubernetes{}
 	default:
 		sdk.Exit("Unknown hatchery mode, aborting\n")
 	}
@@ -98,6 +98,9 @@ func countOf(model string, apps []Application) int {
 	var count int
 
 	for i := range apps {
+		if isServiceApp(apps[i]) {
+			continue
+		}
 		if strings.Contains(apps[i].ID, "/"+strings.ToLower(model)+"-") {
 			count++
 		}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+)
+
+// marathonServiceLabel links service applications to their worker, so they can be deleted with it
+const marathonServiceLabel = "CDS_SERVICE_WORKER"
+
+type marathonPOSTServiceParams struct {
+	ID          string
+	Image       string
+	Env         map[string]string
+	NetworkName string
+	WorkerName  string
+}
+
+const marathonPOSTServiceTemplate = `
+{
+    "container": {
+        "docker": {
+            "forcePullImage": false,
+            "image": {{json .Image}},
+            "network": "USER"
+        },
+        "type": "DOCKER"
+    },
+    "ipAddress": {
+        "networkName": {{json .NetworkName}}
+    },
+    "cpus": 0.5,
+    "env": {{json .Env}},
+    "id": {{json .ID}},
+    "instances": 1,
+    "labels": {
+        "` + marathonServiceLabel + `": {{json .WorkerName}}
+    },
+    "mem": 512
+}
+`
+
+//isServiceApp returns true if the marathon application runs a service of a worker
+func isServiceApp(app Application) bool {
+	return app.Labels[marathonServiceLabel] != ""
+}
+
+//mesosDNSName returns the hostname given by Mesos-DNS to the tasks of an application:
+///cds/workers/app is app-workers-cds.marathon.mesos
+func mesosDNSName(appID string) string {
+	parts := strings.Split(strings.Trim(appID, "/"), "/")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "-") + ".marathon.mesos"
+}
+
+//startServiceApps creates an application for each service of a worker, on marathon user network,
+//then waits for them to run. It returns CDS_SERVICE_<NAME> variables with hostnames of services.
+func startServiceApps(workerName string, svcs []service) (map[string]string, error) {
+	if len(svcs) == 0 {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("marathonPOSTService").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(marathonPOSTServiceTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]string{}
+	var appIDs []string
+	for _, s := range svcs {
+		params := marathonPOSTServiceParams{
+			ID:          path.Join(marathonID, workerName+"-"+s.Name),
+			Image:       s.Image,
+			Env:         map[string]string{},
+			NetworkName: marathonNetwork,
+			WorkerName:  workerName,
+		}
+		for _, e := range s.Env {
+			kv := strings.SplitN(e, "=", 2)
+			params.Env[kv[0]] = kv[1]
+		}
+
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, params); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest("POST", marathonHost+"/v2/apps", &buffer)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "CDS-HATCHERY/1.0")
+		req.SetBasicAuth(marathonUser, marathonPassword)
+
+		log.Notice("startServiceApps> Starting service %s (%s) for %s\n", s.Name, s.Image, workerName)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("cannot start service %s: %s", s.Name, resp.Status)
+		}
+
+		hosts[serviceVariable(s.Name)] = mesosDNSName(params.ID)
+		appIDs = append(appIDs, params.ID)
+	}
+
+	return hosts, waitForServiceApps(appIDs)
+}
+
+//waitForServiceApps waits for service applications to run without failing health checks
+func waitForServiceApps(appIDs []string) error {
+	timeout := time.Now().Add(serviceTimeout)
+	for time.Now().Before(timeout) {
+		time.Sleep(5 * time.Second)
+
+		apps, err := getApps(marathonHost, marathonUser, marathonPassword, marathonID)
+		if err != nil {
+			return err
+		}
+
+		ready := 0
+		for _, id := range appIDs {
+			for _, app := range apps {
+				if strings.Trim(app.ID, "/") == strings.Trim(id, "/") && app.TasksRunning > 0 && app.TasksUnhealthy == 0 {
+					ready++
+					break
+				}
+			}
+		}
+		if ready == len(appIDs) {
+			return nil
+		}
+	}
+	return fmt.Errorf("services %s are not ready after %s", strings.Join(appIDs, ", "), serviceTimeout)
+}
+
+//deleteServiceApps deletes service applications of a worker
+func deleteServiceApps(workerName string) error {
+	apps, err := getApps(marathonHost, marathonUser, marathonPassword, marathonID)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		if app.Labels[marathonServiceLabel] != workerName {
+			continue
+		}
+		log.Notice("deleteServiceApps> Deleting service %s of %s\n", app.ID, workerName)
+		if err := deleteApp(marathonHost, marathonUser, marathonPassword, app.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//killOrphanServiceApps deletes service applications whose worker application is gone,
+//once the time given to start services has passed
+func killOrphanServiceApps(apps []Application) error {
+	for _, app := range apps {
+		if !isServiceApp(app) {
+			continue
+		}
+
+		workerID := path.Join(marathonID, app.Labels[marathonServiceLabel])
+		var found bool
+		for _, w := range apps {
+			if strings.Trim(w.ID, "/") == strings.Trim(workerID, "/") {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, app.Version)
+		if err != nil || time.Since(t) < serviceTimeout+time.Minute {
+			continue
+		}
+
+		log.Notice("killing orphan service %s\n", app.ID)
+		if err := deleteApp(marathonHost, marathonUser, marathonPassword, app.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ var (
 	marathonVHOST    string
 	marathonUser     string
 	marathonPassword string
+	marathonNetwork  string
 )
 
 type marathonPOSTAppParams struct {
@@ -32,10 +33,14 @@ type marathonPOSTAppParams struct {
 	WorkerName    string
 	WorkerModelID int64
 	HatcheryID    int64
+	ActionBuildID int64 // action build booked for the worker, if any
 
 	MarathonID    string
 	MarathonVHOST string
 	Memory        int
+
+	NetworkName string            // user network of the worker and its services, if any
+	Services    map[string]string // CDS_SERVICE_<NAME> variables giving hostnames of services
 }
 
 const marathonPOSTAppTemplate = `
@@ -44,11 +49,14 @@ const marathonPOSTAppTemplate = `
         "docker": {
             "forcePullImage": false,
             "image": "{{.DockerImage}}",
-            "network": "BRIDGE",
+            "network": "{{if .NetworkName}}USER{{else}}BRIDGE{{end}}",
 					  "portMapping": []
 				},
         "type": "DOCKER"
-    },
+    },{{if .NetworkName}}
+    "ipAddress": {
+        "networkName": "{{.NetworkName}}"
+    },{{end}}
 		"cmd": "rm -f worker && curl ${CDS_API}/download/worker/$(uname -m) -o worker &&  chmod +x worker && exec ./worker",
 		"cpus": 0.5,
     "env": {
@@ -57,6 +65,8 @@ const marathonPOSTAppTemplate = `
         "CDS_NAME": "{{.WorkerName}}",
         "CDS_MODEL": "{{.WorkerModelID}}",
         "CDS_HATCHERY": "{{.HatcheryID}}",
+        "CDS_BOOKED_ACTION_BUILD": "{{.ActionBuildID}}",{{range $name, $host := .Services}}
+        "{{$name}}": "{{$host}}",{{end}}
         "CDS_SINGLE_USE": "1"
     },
     "id": "{{.MarathonID}}/{{.WorkerName}}",
@@ -101,28 +111,31 @@ func (m *HatcheryMesos) Refresh() error {
 	return nil
 }
 
-// KillWorker deletes an application on mesos via marathon
+// KillWorker deletes an application on mesos via marathon, and applications of its services
 func (m *HatcheryMesos) KillWorker(worker sdk.Worker) error {
 	appID := path.Join(marathonID, worker.Name)
 	log.Notice("killMesosWorker> Killing %s\n", appID)
-	return deleteApp(marathonHost, marathonUser, marathonPassword, appID)
+	if err := deleteApp(marathonHost, marathonUser, marathonPassword, appID); err != nil {
+		return err
+	}
+	return deleteServiceApps(worker.Name)
 }
 
 // CanSpawn return wether or not hatchery can spawn model
-// requirements are not supported
+// only service requirements are supported, with a user network to run them on
 func (m *HatcheryMesos) CanSpawn(model *sdk.Model, req []sdk.Requirement) bool {
 	if model.Type != sdk.Docker {
 		return false
 	}
-	if len(req) > 0 {
+	if len(req) > 0 && marathonNetwork == "" {
 		return false
 	}
-	return true
+	return onlyServiceRequirements(req)
 }
 
 // SpawnWorker creates an application on mesos via marathon
-// requirements are not supported
-func (m *HatcheryMesos) SpawnWorker(model *sdk.Model, req []sdk.Requirement) error {
+// services are started first, each in its own application
+func (m *HatcheryMesos) SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
 	log.Notice("Spawning worker %s (%s)\n", model.Name, model.Image)
 	var err error
 	uk, err = sdk.GenerateWorkerKey(sdk.NeverExpire)
@@ -166,7 +179,7 @@ func (m *HatcheryMesos) SpawnWorker(model *sdk.Model, req []sdk.Requirement) err
 
 	switch model.Type {
 	case sdk.Docker:
-		return spawnMesosDockerWorker(model, m.hatch.ID)
+		return spawnMesosDockerWorker(model, m.hatch.ID, actionBuildID, services(req))
 	}
 
 	return fmt.Errorf("Model not handled\n")
@@ -182,6 +195,9 @@ func (m *HatcheryMesos) WorkerStarted(model *sdk.Model) int {
 
 	var x int
 	for _, app := range apps {
+		if isServiceApp(app) {
+			continue
+		}
 		if strings.Contains(app.ID, strings.ToLower(model.Name)) {
 			x++
 		}
@@ -217,6 +233,9 @@ func (m *HatcheryMesos) ParseConfig() {
 		sdk.Exit("marathon-password not provided, aborting\n")
 	}
 
+	// Optional, workers with service requirements are only spawned on a user network
+	marathonNetwork = os.Getenv("MARATHON_NETWORK")
+
 }
 
 // Init only starts killing routine of worker not registered
@@ -247,27 +266,21 @@ func suicide() {
 	os.Exit(0)
 }
 
-func spawnMesosDockerWorker(model *sdk.Model, hatcheryID int64) error {
+func spawnMesosDockerWorker(model *sdk.Model, hatcheryID int64, actionBuildID int64, svcs []service) error {
 	tmpl, err := template.New("marathonPOST").Parse(marathonPOSTAppTemplate)
 	if err != nil {
 		return err
 	}
 
-	// Estimate needed memory
-	memory := 1024
-	for _, c := range model.Capabilities {
-		if c.Value == "java" {
-			memory = 4096
-		}
-		if c.Value == "go" && memory < 3072 {
-			memory = 3072
-		}
-		if c.Value == "npm" && memory < 2048 {
-			memory = 2048
-		}
-		if c.Value == "python" && memory < 2048 {
-			memory = 2048
+	memory := estimateMemory(model)
+	workerName := fmt.Sprintf("%s-%s", strings.ToLower(model.Name), strings.Replace(namesgenerator.GetRandomName(0), "_", "-", -1))
+
+	hosts, err := startServiceApps(workerName, svcs)
+	if err != nil {
+		if errd := deleteServiceApps(workerName); errd != nil {
+			log.Warning("spawnMesosDockerWorker> Cannot delete services of %s: %s\n", workerName, errd)
 		}
+		return err
 	}
 
 	for {
@@ -275,12 +288,17 @@ func spawnMesosDockerWorker(model *sdk.Model, hatcheryID int64) error {
 			DockerImage:   model.Image,
 			APIEndpoint:   sdk.Host,
 			WorkerKey:     uk,
-			WorkerName:    fmt.Sprintf("%s-%s", strings.ToLower(model.Name), strings.Replace(namesgenerator.GetRandomName(0), "_", "-", -1)),
+			WorkerName:    workerName,
 			WorkerModelID: model.ID,
 			HatcheryID:    hatcheryID,
+			ActionBuildID: actionBuildID,
 			MarathonID:    marathonID,
 			MarathonVHOST: marathonVHOST,
 			Memory:        memory,
+			Services:      hosts,
+		}
+		if len(svcs) > 0 {
+			params.NetworkName = marathonNetwork
 		}
 
 		var buffer bytes.Buffer
@@ -320,6 +338,26 @@ func spawnMesosDockerWorker(model *sdk.Model, hatcheryID int64) error {
 
 }
 
+//estimateMemory returns the memory in MB needed by a worker given its model capabilities
+func estimateMemory(model *sdk.Model) int {
+	memory := 1024
+	for _, c := range model.Capabilities {
+		if c.Value == "java" {
+			memory = 4096
+		}
+		if c.Value == "go" && memory < 3072 {
+			memory = 3072
+		}
+		if c.Value == "npm" && memory < 2048 {
+			memory = 2048
+		}
+		if c.Value == "python" && memory < 2048 {
+			memory = 2048
+		}
+	}
+	return memory
+}
+
 func startKillAwolWorkerRoutine() {
 	if hatcheryMode != "mesos" {
 		return
@@ -372,6 +410,9 @@ func killDisabledWorkers() error {
 				if err != nil {
 					return err
 				}
+				if err := deleteServiceApps(w.Name); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -394,6 +435,10 @@ func killAwolWorkers() error {
 	var found bool
 	// then for each RUNNING marathon application
 	for i := range apps {
+		// Services are deleted with their worker
+		if isServiceApp(apps[i]) {
+			continue
+		}
 		// Worker is deploying, leave him alone
 		if apps[i].TasksRunning == 0 {
 			continue
@@ -419,9 +464,12 @@ func killAwolWorkers() error {
 			if err != nil {
 				return err
 			}
+			if err := deleteServiceApps(path.Base(apps[i].ID)); err != nil {
+				return err
+			}
 		}
 
 	}
 
-	return nil
+	return killOrphanServiceApps(apps)
 }
@@ -348,7 +348,7 @@ func (h *HatcheryCloud) KillWorker(worker sdk.Worker) error {
 
 // SpawnWorker creates a new cloud instances
 // requirements are not supported
-func (h *HatcheryCloud) SpawnWorker(model *sdk.Model, req []sdk.Requirement) error {
+func (h *HatcheryCloud) SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
 	var err error
 	var omd sdk.OpenstackModelData
 
@@ -421,7 +421,7 @@ cd $HOME
 # Download and start worker with curl
 curl  "{{.API}}/download/worker/$(uname -m)" -o worker --retry 10 --retry-max-time 0 -C - >> /tmp/user_data 2>&1
 chmod +x worker
-CDS_SINGLE_USE=1 ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model={{.Model}} --hatchery={{.Hatchery}} --single-use && exit 0
+CDS_SINGLE_USE=1 ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model={{.Model}} --hatchery={{.Hatchery}} --booked-action-build={{.ActionBuild}} --single-use && exit 0
 `
 	var udata = udataBegin + string(udataModel) + udataEnd
 
@@ -433,14 +433,16 @@ CDS_SINGLE_USE=1 ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model
 		API      string
 		Name     string
 		Key      string
-		Model    int64
-		Hatchery int64
+		Model       int64
+		Hatchery    int64
+		ActionBuild int64
 	}{
-		API:      api,
-		Name:     name,
-		Key:      uk,
-		Model:    model.ID,
-		Hatchery: h.hatch.ID,
+		API:         api,
+		Name:        name,
+		Key:         uk,
+		Model:       model.ID,
+		Hatchery:    h.hatch.ID,
+		ActionBuild: actionBuildID,
 	}
 	var buffer bytes.Buffer
 	err = tmpl.Execute(&buffer, udataParam)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// serviceTimeout is the time given to a service to be healthy before its worker is spawned
+const serviceTimeout = 5 * time.Minute
+
+// service is a container started with a worker from a service requirement
+type service struct {
+	Name  string   // hostname of the service for the worker
+	Image string   // docker image of the service
+	Env   []string // environment variables of the service, as KEY=value
+}
+
+//services returns services of requirements. A service requirement name is the service hostname,
+//its value is the image followed by environment variables: "postgres:9.5 POSTGRES_PASSWORD=cds"
+func services(req []sdk.Requirement) []service {
+	var s []service
+	for _, r := range req {
+		if r.Type != sdk.ServiceRequirement {
+			continue
+		}
+		tuple := strings.Fields(r.Value)
+		if len(tuple) == 0 {
+			continue
+		}
+		svc := service{Name: r.Name, Image: tuple[0]}
+		for _, e := range tuple[1:] {
+			if strings.Contains(e, "=") {
+				svc.Env = append(svc.Env, e)
+			}
+		}
+		s = append(s, svc)
+	}
+	return s
+}
+
+//onlyServiceRequirements returns true if all requirements are services with an image
+func onlyServiceRequirements(req []sdk.Requirement) bool {
+	for _, r := range req {
+		if r.Type != sdk.ServiceRequirement || len(strings.Fields(r.Value)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//serviceVariable returns the variable giving the hostname of a service to the worker, CDS_SERVICE_<NAME>
+func serviceVariable(name string) string {
+	return "CDS_SERVICE_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"text/template"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestServices(t *testing.T) {
+	req := []sdk.Requirement{
+		{Name: "pg", Type: sdk.ServiceRequirement, Value: "postgres:9.5 POSTGRES_USER=cds POSTGRES_PASSWORD=s3cr3t"},
+		{Name: "cache-1", Type: sdk.ServiceRequirement, Value: "redis"},
+	}
+	if !onlyServiceRequirements(req) {
+		t.Errorf("Service requirements should be supported")
+	}
+	if onlyServiceRequirements(append(req, sdk.Requirement{Name: "git", Type: sdk.BinaryRequirement, Value: "git"})) {
+		t.Errorf("Binary requirements should not be supported")
+	}
+	if onlyServiceRequirements([]sdk.Requirement{{Name: "pg", Type: sdk.ServiceRequirement}}) {
+		t.Errorf("Service requirement without image should not be supported")
+	}
+
+	svcs := services(req)
+	if len(svcs) != 2 {
+		t.Fatalf("Expected 2 services, got %v", svcs)
+	}
+	if svcs[0].Image != "postgres:9.5" || len(svcs[0].Env) != 2 || svcs[0].Env[1] != "POSTGRES_PASSWORD=s3cr3t" {
+		t.Errorf("Unexpected service %+v", svcs[0])
+	}
+	if v := serviceVariable(svcs[1].Name); v != "CDS_SERVICE_CACHE_1" {
+		t.Errorf("Expected CDS_SERVICE_CACHE_1, got %s", v)
+	}
+}
+
+func TestMesosDNSName(t *testing.T) {
+	if n := mesosDNSName("/cds/workers/go-worker-pg"); n != "go-worker-pg-workers-cds.marathon.mesos" {
+		t.Errorf("Unexpected name %s", n)
+	}
+}
+
+func TestMarathonPOSTAppTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("marathonPOST").Parse(marathonPOSTAppTemplate))
+	params := marathonPOSTAppParams{
+		DockerImage: "golang:1.7",
+		WorkerName:  "go-worker",
+		MarathonID:  "/cds",
+		Memory:      1024,
+		NetworkName: "cds",
+		Services:    map[string]string{"CDS_SERVICE_PG": "go-worker-pg-cds.marathon.mesos"},
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, params); err != nil {
+		t.Fatal(err)
+	}
+	var app struct {
+		IPAddress struct {
+			NetworkName string `json:"networkName"`
+		} `json:"ipAddress"`
+		Env map[string]string `json:"env"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &app); err != nil {
+		t.Fatalf("Invalid application: %s\n%s", err, buffer.String())
+	}
+	if app.IPAddress.NetworkName != "cds" || app.Env["CDS_SERVICE_PG"] != "go-worker-pg-cds.marathon.mesos" {
+		t.Errorf("Unexpected application %+v", app)
+	}
+}
@@ -161,7 +161,7 @@ func (h *HatcherySwarm) killAndRemove(ID string) error {
 }
 
 //SpawnWorker start a new docker container
-func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, req []sdk.Requirement) error {
+func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, actionBuildID int64, req []sdk.Requirement) error {
 	//uk is the worker key for worker auth
 	uk, err := sdk.GenerateWorkerKey(sdk.FirstUseExpire)
 	if err != nil {
@@ -177,6 +177,7 @@ func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, req []sdk.Requirement) err
 	//docker legacy links (https://docs.docker.com/engine/userguide/networking/default_network/dockerlinks/) are <name or id>:alias
 	links := []string{}
 	services := []string{}
+	serviceEnv := []string{}
 	for _, r := range req {
 		if r.Type == sdk.ServiceRequirement {
 			//name= <alias> => the name of the host put in /etc/hosts of the worker
@@ -201,6 +202,7 @@ func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, req []sdk.Requirement) err
 			}
 			services = append(services, serviceName)
 			links = append(links, serviceName+":"+r.Name)
+			serviceEnv = append(serviceEnv, serviceVariable(r.Name)+"="+r.Name)
 		}
 	}
 
@@ -215,6 +217,15 @@ func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, req []sdk.Requirement) err
 		"CDS_MODEL" + "=" + strconv.FormatInt(model.ID, 10),
 		"CDS_HATCHERY" + "=" + strconv.FormatInt(h.hatch.ID, 10),
 	}
+	env = append(env, serviceEnv...)
+
+	//Workers of a single use model only run the action build they are spawned for
+	if model.SingleUse {
+		env = append(env, "CDS_SINGLE_USE=1")
+	}
+	if actionBuildID != 0 {
+		env = append(env, "CDS_BOOKED_ACTION_BUILD"+"="+strconv.FormatInt(actionBuildID, 10))
+	}
 
 	//labels are used to make container cleanup easier
 	labels := map[string]string{
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// validationTimeout is the time given to a worker to validate its model
+// before another one is spawned
+const validationTimeout = 10 * time.Minute
+
+// validations holds when a worker has been spawned to validate a model
+var validations = map[int64]time.Time{}
+
+// spawnValidationWorker spawns a worker which only checks the capabilities of its model
+func spawnValidationWorker(h HatcheryMode, ms sdk.ModelStatus) error {
+	if t, ok := validations[ms.ModelID]; ok && time.Since(t) < validationTimeout {
+		return nil
+	}
+
+	m, err := sdk.GetWorkerModel(ms.ModelName)
+	if err != nil {
+		return fmt.Errorf("cannot get model named '%s' (%s)", ms.ModelName, err)
+	}
+
+	if !h.CanSpawn(m, nil) {
+		return nil
+	}
+
+	log.Notice("Spawning a worker to validate model %s\n", ms.ModelName)
+	validations[ms.ModelID] = time.Now()
+	return h.SpawnWorker(m, 0, nil)
+}
@@ -1 +1,55 @@
-ALTER TABLE action_build ADD COLUMN worker_model_name TEXT;
\ No newline at end of file
+ALTER TABLE action_build ADD COLUMN worker_model_name TEXT;
+ALTER TABLE pipeline_stage ADD COLUMN matrix TEXT;
+ALTER TABLE pipeline_action ADD COLUMN matrix TEXT;
+ALTER TABLE artifact ADD COLUMN created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP;
+CREATE TABLE IF NOT EXISTS "artifact_retention" (id BIGSERIAL PRIMARY KEY, project_id INT, application_id INT, keep_last_builds INT, keep_days INT, keep_deployed BOOLEAN);
+ALTER TABLE artifact_retention ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
+ALTER TABLE artifact_retention ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
+ALTER TABLE artifact ADD COLUMN sha256 TEXT;
+CREATE TABLE IF NOT EXISTS "artifact_blob" (sha256 TEXT PRIMARY KEY, size BIGINT, refcount INT, object_path TEXT);
+CREATE TABLE IF NOT EXISTS "build_cache" (id BIGSERIAL PRIMARY KEY, project_id INT, application_id INT, branch TEXT, cache_key TEXT, size BIGINT, object_path TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_used TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+ALTER TABLE build_cache ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
+ALTER TABLE build_cache ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
+select create_unique_index('build_cache','IDX_BUILD_CACHE_KEY','application_id,branch,cache_key');
+ALTER TABLE action_build ADD COLUMN lease_worker_id TEXT;
+ALTER TABLE action_build ADD COLUMN lease_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE pipeline_action ADD COLUMN timeout INT DEFAULT 0;
+ALTER TABLE pipeline_stage ADD COLUMN timeout INT DEFAULT 0;
+ALTER TABLE action_build ADD COLUMN timeout INT DEFAULT 0;
+ALTER TABLE action_build ADD COLUMN stage_timeout INT DEFAULT 0;
+ALTER TABLE pipeline_action ADD COLUMN retry TEXT;
+ALTER TABLE action_build ADD COLUMN attempt INT DEFAULT 1;
+ALTER TABLE action_build ADD COLUMN exit_code INT;
+ALTER TABLE action_build ADD COLUMN fail_cause TEXT;
+CREATE TABLE IF NOT EXISTS "action_build_attempt" (id BIGSERIAL PRIMARY KEY, action_build_id BIGINT, attempt INT, status TEXT, fail_cause TEXT, exit_code INT, start TIMESTAMP WITH TIME ZONE, done TIMESTAMP WITH TIME ZONE, tests TEXT);
+ALTER TABLE action_build_attempt ADD CONSTRAINT fk_action_build FOREIGN KEY (action_build_id) references action_build (id) ON delete cascade;
+select create_unique_index('action_build_attempt','IDX_ACTION_BUILD_ATTEMPT','action_build_id,attempt');
+ALTER TABLE pipeline_stage ADD COLUMN condition TEXT;
+ALTER TABLE pipeline_action ADD COLUMN condition TEXT;
+ALTER TABLE action_edge ADD COLUMN condition TEXT;
+ALTER TABLE action_build ADD COLUMN steps TEXT;
+ALTER TABLE worker_model ADD COLUMN validated BOOLEAN DEFAULT false;
+ALTER TABLE worker_model ADD COLUMN need_validation BOOLEAN DEFAULT false;
+ALTER TABLE worker_model ADD COLUMN validation_error TEXT;
+ALTER TABLE worker_model ADD COLUMN single_use BOOLEAN DEFAULT false;
+ALTER TABLE action_build ADD COLUMN booked_hatchery_id BIGINT;
+ALTER TABLE action_build ADD COLUMN booked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE action_build ADD COLUMN booked_worker_id TEXT;
+CREATE TABLE IF NOT EXISTS "user_token" (id BIGSERIAL PRIMARY KEY, user_id BIGINT, name TEXT, hashed_token TEXT, scopes TEXT, created TIMESTAMP WITH TIME ZONE, expiry TIMESTAMP WITH TIME ZONE, last_used TIMESTAMP WITH TIME ZONE);
+ALTER TABLE user_token ADD CONSTRAINT fk_user FOREIGN KEY (user_id) references "user" (id) ON delete cascade;
+select create_unique_index('user_token','IDX_USER_TOKEN_HASH','hashed_token');
+select create_unique_index('user_token','IDX_USER_TOKEN_NAME','user_id,name');
+CREATE TABLE IF NOT EXISTS "role" (id BIGSERIAL PRIMARY KEY, name TEXT UNIQUE, actions TEXT);
+select setval('role_id_seq', greatest(99, (select max(id) from role)));
+ALTER TABLE pipeline_stage ADD COLUMN approval TEXT;
+CREATE TABLE IF NOT EXISTS "pipeline_build_approval" (id BIGSERIAL PRIMARY KEY, pipeline_build_id BIGINT, stage_id BIGINT, user_id BIGINT, username TEXT, approved BOOLEAN, comment TEXT, created TIMESTAMP WITH TIME ZONE);
+ALTER TABLE pipeline_build_approval ADD CONSTRAINT fk_pipeline_build FOREIGN KEY (pipeline_build_id) references pipeline_build (id) ON delete cascade;
+select create_unique_index('pipeline_build_approval','IDX_PIPELINE_BUILD_APPROVAL','pipeline_build_id,stage_id,user_id');
+ALTER TABLE environment ADD COLUMN lock_policy TEXT;
+CREATE TABLE IF NOT EXISTS "environment_lock" (environment_id BIGINT, application_id BIGINT, pipeline_build_id BIGINT, locked TIMESTAMP WITH TIME ZONE, PRIMARY KEY(environment_id, application_id));
+ALTER TABLE environment_lock ADD CONSTRAINT fk_environment FOREIGN KEY (environment_id) references environment (id) ON delete cascade;
+ALTER TABLE environment_lock ADD CONSTRAINT fk_pipeline_build FOREIGN KEY (pipeline_build_id) references pipeline_build (id) ON delete cascade;
+ALTER TABLE "user" ADD COLUMN external_id TEXT;
+select create_unique_index('user','IDX_USER_EXTERNAL_ID','external_id');
+ALTER TABLE action_build ADD COLUMN not_before TIMESTAMP WITH TIME ZONE;
+ALTER TABLE group_user ADD COLUMN from_oidc BOOL DEFAULT false;
@@ -41,6 +41,7 @@ SELECT create_foreign_key('FK_APPLICATION_PIPELINE_NOTIF_ENVIRONMENT', 'applicat
 
 -- BUILD_LOG
 select create_foreign_key('FK_BUILD_LOG_ACTION_BUILD', 'build_log', 'action_build', 'action_build_id', 'id');
+select create_foreign_key('FK_BUILD_LOG_CHUNK_ACTION_BUILD', 'build_log_chunk', 'action_build', 'action_build_id', 'id');
 
 -- ENVIRONMENT
 select create_foreign_key('FK_ENVIRONMENT_PROJECT', 'environment', 'project', 'project_id', 'id');
@@ -148,3 +149,21 @@ ALTER TABLE warning ADD CONSTRAINT fk_action FOREIGN KEY (action_id) references
 ALTER TABLE project_variable_audit ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
 ALTER TABLE application_variable_audit ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
 ALTER TABLE environment_variable_audit ADD CONSTRAINT fk_environment FOREIGN KEY (environment_id) references environment (id) ON delete cascade;
+
+-- artifact retention
+ALTER TABLE artifact_retention ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
+ALTER TABLE artifact_retention ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
+
+-- build cache
+ALTER TABLE build_cache ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
+ALTER TABLE build_cache ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
+
+-- action build attempts
+ALTER TABLE action_build_attempt ADD CONSTRAINT fk_action_build FOREIGN KEY (action_build_id) references action_build (id) ON delete cascade;
+
+-- pipeline build approvals
+ALTER TABLE pipeline_build_approval ADD CONSTRAINT fk_pipeline_build FOREIGN KEY (pipeline_build_id) references pipeline_build (id) ON delete cascade;
+
+-- environment locks
+ALTER TABLE environment_lock ADD CONSTRAINT fk_environment FOREIGN KEY (environment_id) references environment (id) ON delete cascade;
+ALTER TABLE environment_lock ADD CONSTRAINT fk_pipeline_build FOREIGN KEY (pipeline_build_id) references pipeline_build (id) ON delete cascade;
@@ -23,6 +23,7 @@ select create_unique_index('action_build', 'IDX_ACTION_BUILD_PIPELINE_ACTION_ID_
 select create_index('artifact', 'IDX_ARTIFACT_PIPELINE_ID', 'pipeline_id');
 select create_index('artifact', 'IDX_ARTIFACT_APPLICATION_ID', 'application_id');
 select create_index('artifact','IDX_ARTIFACT_ENVIRONMENT', 'environment_id');
+select create_index('artifact','IDX_ARTIFACT_SHA256', 'sha256');
 
 -- APPLICATION
 select create_unique_index('application', 'IDX_APPLICATION_PROJECT_ID_NAME', 'project_id,name');
@@ -32,6 +33,7 @@ select create_unique_index('application_pipeline','IDX_APPLICATION_PIPELINE_APPL
 
 -- BUILD_LOG
 select create_index('build_log', 'IDX_ARTIFACT_ACTION_BUILD_ID', 'action_build_id');
+select create_index('build_log_chunk', 'IDX_BUILD_LOG_CHUNK_ACTION_BUILD_ID', 'action_build_id');
 
 -- ENVIRONMENT
 select create_unique_index('environment','IDX_ENVIRONMENT', 'name,project_id');
@@ -94,6 +96,7 @@ select create_index('system_log','IDX_SYS_LOG_LOGGED','logged');
 
 -- USER
 select create_unique_index('user','IDX_USER_USERNAME','username');
+select create_unique_index('user','IDX_USER_EXTERNAL_ID','external_id');
 
 -- USER KEY
 select create_index('user_key','IDX_USER_KEY_USER_KEY','user_key');
@@ -112,3 +115,12 @@ select create_index('worker_model','IDX_WORKER_MODEL_OWNER_ID','owner_id');
 
 -- REPOSITORIES_MANAGER_PROJECT
 select create_unique_index('repositories_manager_project', 'IDX_REPOSITORIES_MANAGER_PROJECT_ID' ,'id_repositories_manager, id_project');
+
+-- BUILD_CACHE
+select create_unique_index('build_cache','IDX_BUILD_CACHE_KEY','application_id,branch,cache_key');
+
+-- ACTION_BUILD_ATTEMPT
+select create_unique_index('action_build_attempt','IDX_ACTION_BUILD_ATTEMPT','action_build_id,attempt');
+
+-- PIPELINE_BUILD_APPROVAL
+select create_unique_index('pipeline_build_approval','IDX_PIPELINE_BUILD_APPROVAL','pipeline_build_id,stage_id,user_id');
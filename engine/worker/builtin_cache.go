@@ -0,0 +1,293 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// checksumRegexp matches {{checksum "pattern"}} placeholders of cache keys
+var checksumRegexp = regexp.MustCompile(`{{\s*checksum\s+"([^"]+)"\s*}}`)
+
+func runCacheSave(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusSuccess}
+	var key, paths string
+
+	for _, p := range a.Parameters {
+		switch p.Name {
+		case "key":
+			key = p.Value
+		case "path":
+			paths = p.Value
+		}
+	}
+	project, application, branch := cacheScope(actionBuild)
+
+	key, err := expandCacheKey(key)
+	if err != nil {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("Cannot compute cache key: %s\n", err))
+		return res
+	}
+	if key == "" {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("key variable is empty. aborting\n"))
+		return res
+	}
+
+	var files []string
+	for _, p := range strings.Split(paths, "\n") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			res.Status = sdk.StatusFail
+			sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("cannot perform globbing of pattern '%s': %s\n", p, err))
+			return res
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("No file to save in cache %s\n", key))
+		return res
+	}
+
+	sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("Saving cache %s of branch %s...\n", key, branch))
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeCacheArchive(writer, files))
+	}()
+
+	if err := sdk.UploadCache(project, application, branch, key, reader); err != nil {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.CacheSave, fmt.Sprintf("Cannot save cache %s: %s\n", key, err))
+		return res
+	}
+
+	return res
+}
+
+func runCacheRestore(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusSuccess}
+	var keys []string
+
+	for _, p := range a.Parameters {
+		if p.Name != "key" {
+			continue
+		}
+		for _, k := range strings.Split(p.Value, "\n") {
+			k, err := expandCacheKey(strings.TrimSpace(k))
+			if err != nil {
+				res.Status = sdk.StatusFail
+				sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("Cannot compute cache key: %s\n", err))
+				return res
+			}
+			if k != "" {
+				keys = append(keys, k)
+			}
+		}
+	}
+	project, application, branch := cacheScope(actionBuild)
+
+	if len(keys) == 0 {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("key variable is empty. aborting\n"))
+		return res
+	}
+
+	// A missing cache is not an error, build just runs without it
+	cache, err := sdk.MatchCache(project, application, branch, keys)
+	if err != nil {
+		sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("Cannot look up cache: %s\n", err))
+		return res
+	}
+	if cache == nil {
+		sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("No cache found for keys %s\n", strings.Join(keys, ", ")))
+		return res
+	}
+
+	sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("Restoring cache %s of branch %s (%d bytes)...\n", cache.Key, cache.Branch, cache.Size))
+	reader, err := sdk.DownloadCache(*cache)
+	if err != nil {
+		sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("Cannot download cache %s: %s\n", cache.Key, err))
+		return res
+	}
+	defer reader.Close()
+
+	if err := readCacheArchive(reader, "."); err != nil {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.CacheRestore, fmt.Sprintf("Cannot extract cache %s: %s\n", cache.Key, err))
+		return res
+	}
+
+	return res
+}
+
+// cacheScope returns project, application and branch of the build
+func cacheScope(actionBuild sdk.ActionBuild) (project, application, branch string) {
+	branch = "master"
+	for _, p := range actionBuild.Args {
+		switch p.Name {
+		case "cds.project":
+			project = p.Value
+		case "cds.application":
+			application = p.Value
+		case "git.branch":
+			if p.Value != "" {
+				branch = p.Value
+			}
+		}
+	}
+	return
+}
+
+// expandCacheKey replaces {{checksum "pattern"}} placeholders by the checksum of files matching pattern
+func expandCacheKey(key string) (string, error) {
+	var err error
+	key = checksumRegexp.ReplaceAllStringFunc(key, func(m string) string {
+		sum, e := checksumFiles(checksumRegexp.FindStringSubmatch(m)[1])
+		if e != nil {
+			err = e
+		}
+		return sum
+	})
+	return key, err
+}
+
+// checksumFiles returns the SHA-256 checksum of the content of files matching pattern
+func checksumFiles(pattern string) (string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("pattern '%s' matched no file", pattern)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeCacheArchive writes a tar.gz archive of files and directories, with their relative path
+func writeCacheArchive(w io.Writer, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		err := filepath.Walk(file, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(p); err != nil {
+					return err
+				}
+			}
+			hdr, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(p)
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readCacheArchive extracts a tar.gz archive in destdir, refusing files outside of it
+func readCacheArchive(r io.Reader, destdir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in cache: %s", hdr.Name)
+		}
+		target := filepath.Join(destdir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)|0700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			link := filepath.Join(filepath.Dir(name), hdr.Linkname)
+			if filepath.IsAbs(hdr.Linkname) || link == ".." || strings.HasPrefix(link, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("invalid link in cache: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
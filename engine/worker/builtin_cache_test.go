@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheArchive(t *testing.T) {
+	src, err := ioutil.TempDir("", "cds-cache-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "cds-cache-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(src); err != nil {
+		t.Fatal(err)
+	}
+
+	os.MkdirAll(filepath.Join("node_modules", "lib"), 0755)
+	ioutil.WriteFile(filepath.Join("node_modules", "lib", "index.js"), []byte("module.exports = {}"), 0644)
+	ioutil.WriteFile("package-lock.json", []byte("{}"), 0644)
+
+	key, err := expandCacheKey(`npm-{{checksum "package-lock.json"}}`)
+	if err != nil {
+		t.Fatalf("Cannot expand key: %s", err)
+	}
+	if key != "npm-44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a" {
+		t.Fatalf("Unexpected key: %s", key)
+	}
+	if _, err := expandCacheKey(`{{checksum "missing.lock"}}`); err == nil {
+		t.Fatalf("Missing file should not be ignored")
+	}
+
+	var buf bytes.Buffer
+	if err := writeCacheArchive(&buf, []string{"node_modules"}); err != nil {
+		t.Fatalf("Cannot write archive: %s", err)
+	}
+	if err := readCacheArchive(&buf, dst); err != nil {
+		t.Fatalf("Cannot read archive: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dst, "node_modules", "lib", "index.js"))
+	if err != nil {
+		t.Fatalf("File not restored: %s", err)
+	}
+	if string(data) != "module.exports = {}" {
+		t.Fatalf("Unexpected content: %s", data)
+	}
+}
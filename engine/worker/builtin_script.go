@@ -9,6 +9,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"github.com/kardianos/osext"
 
@@ -35,6 +36,14 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		return res
 	}
 
+	// Script may run in a container
+	image := sdk.ParameterValue(a.Parameters, sdk.ScriptImageParameter)
+	if image != "" && runtime.GOOS == "windows" {
+		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("script in a docker image is not supported on windows, aborting\n"))
+		res.Status = sdk.StatusFail
+		return res
+	}
+
 	// Default shell is sh
 	shell := "/bin/sh"
 	var opts []string
@@ -106,17 +115,12 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		return res
 	}
 	log.Notice("runScriptAction> %s %s", shell, strings.Trim(fmt.Sprint(opts), "[]"))
-	sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("Executing %s %s", shell, strings.Trim(fmt.Sprint(opts), "[]")))
-
-	cmd := exec.Command(shell, opts...)
-	res.Status = sdk.StatusUnknown
 
 	// worker export http port
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", WorkerServerPort, exportport))
+	workerEnv := []string{fmt.Sprintf("%s=%d", WorkerServerPort, exportport)}
 	if pkey != "" && gitssh != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", pKEY, pkey))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", GitSSH, gitssh))
+		workerEnv = append(workerEnv, fmt.Sprintf("%s=%s", pKEY, pkey))
+		workerEnv = append(workerEnv, fmt.Sprintf("%s=%s", GitSSH, gitssh))
 	}
 	workerpath, err := osext.Executable()
 	if err != nil {
@@ -127,12 +131,33 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 	}
 	log.Notice("Worker binary path: %s\n", path.Dir(workerpath))
 
-	for i := range cmd.Env {
-		if strings.HasPrefix(cmd.Env[i], "PATH") {
-			cmd.Env[i] = fmt.Sprintf("%s:%s", cmd.Env[i], path.Dir(workerpath))
-			break
+	var cmd *exec.Cmd
+	if image != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Warning("runScriptAction> cannot get working directory: %s\n", err)
+			sendLog(actionBuild.ID, sdk.ScriptAction, "Failure due to internal error (Working directory)")
+			res.Status = sdk.StatusFail
+			return res
+		}
+		container := containerName(actionBuild)
+		defer removeContainer(container)
+		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("Executing %s %s in a container of image %s", shell, scriptPath, image))
+		cmd = dockerScriptCommand(image, container, wd, shell, scriptPath, workerpath, workerEnv, actionBuild)
+	} else {
+		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("Executing %s %s", shell, strings.Trim(fmt.Sprint(opts), "[]")))
+		cmd = exec.Command(shell, opts...)
+		cmd.Env = append(os.Environ(), workerEnv...)
+
+		for i := range cmd.Env {
+			if strings.HasPrefix(cmd.Env[i], "PATH") {
+				cmd.Env[i] = fmt.Sprintf("%s:%s", cmd.Env[i], path.Dir(workerpath))
+				break
+			}
 		}
 	}
+	setProcessGroup(cmd)
+	res.Status = sdk.StatusUnknown
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -186,15 +211,44 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		return res
 	}
 
-	_ = <-outchan
-	_ = <-errchan
-	err = cmd.Wait()
+	done := make(chan error, 1)
+	go func() {
+		_ = <-outchan
+		_ = <-errchan
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-cancelChan:
+		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("%s: stopping script (SIGTERM, then SIGKILL after %s)\n", cancelStatus, killGracePeriod))
+		stopProcessGroup(cmd, done, killGracePeriod)
+		res.Status = cancelStatus
+		return res
+	}
+	//In a container, the process is only the docker client: the workload is not a child of the worker
+	if image == "" {
+		res.Usage = resourceUsage(cmd.ProcessState)
+	}
 	if err != nil {
 		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("%s\n", err))
 		res.Status = sdk.StatusFail
+		res.ExitCode = exitCode(err)
 		return res
 	}
 
 	res.Status = sdk.StatusSuccess
 	return res
 }
+
+// exitCode returns the exit code of a command which exited with an error, 0 if unknown
+func exitCode(err error) int {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+		return status.ExitStatus()
+	}
+	return 0
+}
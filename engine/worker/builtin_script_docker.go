@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// dockerScriptCommand returns the command running a script in a container of given image, with
+// working directory and script mounted. Build variables are exported by name only,
+// so that their values, secrets included, do not show on docker command line.
+// Worker settings are exported the same way, and the worker binary and ssh key are mounted,
+// so that worker export and git over ssh work in the container. Container shares the worker network
+// to reach the export server listening on localhost.
+func dockerScriptCommand(image, container, wd, shell, scriptPath, workerPath string, workerEnv []string, actionBuild sdk.ActionBuild) *exec.Cmd {
+	args := []string{"run", "--rm", "--name", container, "--network", "host",
+		"-v", wd + ":" + wd, "-w", wd,
+		"-v", scriptPath + ":" + scriptPath + ":ro",
+		"-v", workerPath + ":/usr/local/bin/worker:ro",
+		"-e", "HOME=" + wd,
+	}
+	if pkey != "" && gitssh != "" {
+		args = append(args, "-v", pkey+":"+pkey+":ro", "-v", gitssh+":"+gitssh+":ro")
+	}
+	env := os.Environ()
+	for _, e := range workerEnv {
+		args = append(args, "-e", strings.SplitN(e, "=", 2)[0])
+		env = append(env, e)
+	}
+	for _, v := range stepVariables(actionBuild) {
+		name := envName(v.Name)
+		args = append(args, "-e", name)
+		env = append(env, name+"="+v.Value)
+	}
+	args = append(args, image, shell, scriptPath)
+
+	cmd := exec.Command("docker", args...)
+	cmd.Env = env
+	return cmd
+}
+
+// removeContainer removes the container of a script step, it's already gone unless the step was stopped
+func removeContainer(container string) {
+	exec.Command("docker", "rm", "-f", container).Run()
+}
+
+// stepVariables returns action build arguments and build variables exported by previous steps
+func stepVariables(actionBuild sdk.ActionBuild) []sdk.Variable {
+	var vars []sdk.Variable
+	for _, p := range actionBuild.Args {
+		vars = append(vars, sdk.Variable{Name: p.Name, Value: p.Value})
+	}
+	for _, v := range buildVariables {
+		vars = append(vars, sdk.Variable{Name: "cds.build." + v.Name, Value: v.Value})
+	}
+	return vars
+}
+
+// envName returns the environment variable name of a build variable, cds.app.name is CDS_APP_NAME
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+func containerName(actionBuild sdk.ActionBuild) string {
+	gen, _ := generateWorkingDirectory()
+	return fmt.Sprintf("cds-step-%d-%s", actionBuild.ID, gen)
+}
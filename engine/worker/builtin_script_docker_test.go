@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestDockerScriptCommand(t *testing.T) {
+	buildVariables = []sdk.Variable{{Name: "version", Value: "1.2.3"}}
+	pkey, gitssh = "/tmp/keys/id_rsa", "/tmp/keys/gitssh.sh"
+	defer func() { buildVariables, pkey, gitssh = nil, "", "" }()
+
+	ab := sdk.ActionBuild{
+		ID: 42,
+		Args: []sdk.Parameter{
+			{Name: "cds.app.name", Value: "api"},
+			{Name: "cds.proj.token", Value: "s3cr3t"},
+		},
+	}
+	workerEnv := []string{"CDS_EXPORT_PORT=8081", "PKEY=/tmp/keys/id_rsa", "GIT_SSH=/tmp/keys/gitssh.sh"}
+	cmd := dockerScriptCommand("node:6", "cds-step-42-x", "/tmp/wd", "/bin/bash", "/tmp/cds-123", "/opt/cds/worker", workerEnv, ab)
+
+	args := strings.Join(cmd.Args, " ")
+	expected := "docker run --rm --name cds-step-42-x --network host -v /tmp/wd:/tmp/wd -w /tmp/wd -v /tmp/cds-123:/tmp/cds-123:ro " +
+		"-v /opt/cds/worker:/usr/local/bin/worker:ro -e HOME=/tmp/wd " +
+		"-v /tmp/keys/id_rsa:/tmp/keys/id_rsa:ro -v /tmp/keys/gitssh.sh:/tmp/keys/gitssh.sh:ro " +
+		"-e CDS_EXPORT_PORT -e PKEY -e GIT_SSH -e CDS_APP_NAME -e CDS_PROJ_TOKEN -e CDS_BUILD_VERSION node:6 /bin/bash /tmp/cds-123"
+	if args != expected {
+		t.Errorf("Expected command\n%s\ngot\n%s", expected, args)
+	}
+
+	env := strings.Join(cmd.Env, "\n")
+	for _, e := range []string{"CDS_EXPORT_PORT=8081", "PKEY=/tmp/keys/id_rsa", "CDS_APP_NAME=api", "CDS_PROJ_TOKEN=s3cr3t", "CDS_BUILD_VERSION=1.2.3"} {
+		if !strings.Contains(env, e) {
+			t.Errorf("Expected %s in environment", e)
+		}
+	}
+}
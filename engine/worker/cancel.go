@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// killGracePeriod is how long a script has to exit after SIGTERM before being killed
+const killGracePeriod = 10 * time.Second
+
+var (
+	// cancelChan is closed when API stops the current action build, on timeout or user request
+	cancelChan chan struct{}
+	// cancelStatus is the status of the action build stopped by API
+	cancelStatus sdk.Status
+)
+
+// watchActionBuild checks every few seconds that the action build being run has not been stopped by API,
+// until stop is closed
+func watchActionBuild(id int64, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(5 * time.Second):
+		}
+
+		status, err := sdk.GetActionBuildStatus(id)
+		if err != nil {
+			log.Notice("watchActionBuild> Cannot get status of action build %d: %s\n", id, err)
+			continue
+		}
+		if status == sdk.StatusBuilding {
+			continue
+		}
+
+		sendLog(id, "SYSTEM", fmt.Sprintf("Action build stopped by CDS engine (status: %s)\n", status))
+		cancelStatus = status
+		close(cancelChan)
+		return
+	}
+}
+
+// buildCanceled returns true if API stopped the current action build
+func buildCanceled() bool {
+	select {
+	case <-cancelChan:
+		return true
+	default:
+		return false
+	}
+}
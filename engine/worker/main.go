@@ -25,8 +25,10 @@ var (
 	api      string
 	model    int64
 	hatchery int64
-	basedir  string
-	logChan  chan sdk.Log
+	// action build the worker has been spawned for by hatchery
+	bookedActionBuild int64
+	basedir           string
+	logChan           chan sdk.Log
 	// port of variable exporter HTTP server
 	exportport int
 	// current actionBuild is here to allow var export
@@ -85,6 +87,12 @@ var mainCmd = &cobra.Command{
 
 		model = int64(viper.GetInt("model"))
 
+		// A worker spawned for an action build only runs it
+		bookedActionBuild = int64(viper.GetInt("booked_action_build"))
+		if bookedActionBuild != 0 {
+			viper.Set("single_use", true)
+		}
+
 		port, err := exportHandler()
 		if err != nil {
 			sdk.Exit("cannot bind port for worker export: %s\n", err)
@@ -130,6 +138,9 @@ func init() {
 	flags.Int("hatchery", 0, "Hatchery spawing worker")
 	viper.BindPFlag("hatchery", flags.Lookup("hatchery"))
 
+	flags.Int("booked-action-build", 0, "Action build booked by hatchery for this worker")
+	viper.BindPFlag("booked_action_build", flags.Lookup("booked-action-build"))
+
 	flags.String("basedir", "", "Worker working directory")
 	viper.BindPFlag("basedir", flags.Lookup("basedir"))
 
@@ -140,8 +151,8 @@ func main() {
 	mainCmd.Execute()
 }
 
-// Will be removed when websocket conn is implemented
-// for now, poll the /queue
+// queuePolling waits for the API to offer an action build matching worker capabilities.
+// Until API supports it, worker falls back to polling the /queue
 func queuePolling() {
 	for {
 		if WorkerID == "" {
@@ -154,9 +165,72 @@ func queuePolling() {
 			}
 		}
 
-		checkQueue()
-		time.Sleep(5 * time.Second)
+		if bookedActionBuild != 0 {
+			takeBookedAction()
+		}
+
+		b, err := sdk.NextActionBuild(capabilities(), 30*time.Second)
+		if err != nil {
+			log.Debug("queuePolling> Cannot wait for next action build, polling queue: %s\n", err)
+			checkQueue()
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if b == nil {
+			continue
+		}
+
+		if !checkRequirements(*b) {
+			if err := sdk.ReleaseActionBuild(b.ID); err != nil {
+				log.Notice("queuePolling> Cannot release action build %d: %s\n", b.ID, err)
+			}
+			continue
+		}
+		takeAction(*b)
+	}
+}
+
+// takeBookedAction runs the action build worker has been spawned for, then exits
+func takeBookedAction() {
+	takeAction(sdk.ActionBuild{ID: bookedActionBuild})
+
+	// takeAction exits once the action build is done, so it could not be taken
+	log.Notice("takeBookedAction> Cannot take action build %d, exiting\n", bookedActionBuild)
+	if err := unregister(); err != nil {
+		log.Warning("takeBookedAction> could not unregister: %s\n", err)
+	}
+	os.Exit(0)
+}
+
+// capabilities returns what API needs to know to offer action builds to worker
+func capabilities() sdk.WorkerCapabilities {
+	requirements, err := sdk.GetRequirements()
+	if err != nil {
+		log.Warning("capabilities> unable to get requirements: %s\n", err)
+	}
+	hostname, _ := os.Hostname()
+	return sdk.WorkerCapabilities{
+		Binaries: LoopPath(requirements),
+		Hostname: hostname,
+	}
+}
+
+// checkRequirements returns true if worker fulfills all requirements of an action build
+func checkRequirements(b sdk.ActionBuild) bool {
+	requirementsOK := true
+	for _, r := range b.Requirements {
+		ok, err := checkRequirement(r)
+		if err != nil {
+			postCheckRequirementError(&r, err)
+			requirementsOK = false
+			continue
+		}
+		if !ok {
+			requirementsOK = false
+			continue
+		}
 	}
+	return requirementsOK
 }
 
 func checkQueue() {
@@ -170,22 +244,7 @@ func checkQueue() {
 	}
 
 	for i := range queue {
-		requirementsOK := true
-		// Check requirement
-		for _, r := range queue[i].Requirements {
-			ok, err := checkRequirement(r)
-			if err != nil {
-				postCheckRequirementError(&r, err)
-				requirementsOK = false
-				continue
-			}
-			if !ok {
-				requirementsOK = false
-				continue
-			}
-		}
-
-		if requirementsOK {
+		if checkRequirements(queue[i]) {
 			takeAction(queue[i])
 		}
 	}
@@ -220,7 +279,16 @@ func takeAction(b sdk.ActionBuild) {
 	// Reset build variables
 	ab = abi.ActionBuild
 	buildVariables = nil
+
+	// Watch action build to stop it if API does
+	cancelChan = make(chan struct{})
+	stopWatch := make(chan struct{})
+	go watchActionBuild(b.ID, stopWatch)
 	res := run(abi.Action, abi.ActionBuild, abi.Secrets)
+	close(stopWatch)
+	if buildCanceled() {
+		res.Status = cancelStatus
+	}
 	// Give time to buffered logs to be sent
 	time.Sleep(3 * time.Second)
 
@@ -0,0 +1,28 @@
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup runs the command in its own process group, so that it can be stopped with all its children
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopProcessGroup sends SIGTERM to the process group of a started command, then SIGKILL
+// if it's still running after grace period. done receives the result of cmd.Wait().
+func stopProcessGroup(cmd *exec.Cmd, done chan error, grace time.Duration) {
+	pgid := -cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGTERM)
+
+	select {
+	case <-done:
+	case <-time.After(grace):
+		syscall.Kill(pgid, syscall.SIGKILL)
+		<-done
+	}
+}
@@ -0,0 +1,29 @@
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStopProcessGroup(t *testing.T) {
+	// Script ignores SIGTERM, it and its child must be killed after grace period
+	cmd := exec.Command("/bin/sh", "-c", "trap '' TERM; sleep 30 & wait")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Cannot start script: %s", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	start := time.Now()
+	stopProcessGroup(cmd, done, 100*time.Millisecond)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Script stopped after %s", d)
+	}
+	if cmd.ProcessState == nil || cmd.ProcessState.Success() {
+		t.Fatalf("Script should have been killed")
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup does nothing on windows, there is no process group to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// stopProcessGroup kills a started command, windows processes cannot be asked to terminate.
+// done receives the result of cmd.Wait().
+func stopProcessGroup(cmd *exec.Cmd, done chan error, grace time.Duration) {
+	cmd.Process.Kill()
+	<-done
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/ovh/cds/engine/api/worker"
@@ -31,6 +32,7 @@ func register(cdsURI string, name string, uk string) error {
 		Model:              model,
 		Hatchery:           hatchery,
 		BinaryCapabilities: binaryCapabilities,
+		BookedActionBuild:  bookedActionBuild,
 	}
 
 	body, err := json.MarshalIndent(in, " ", " ")
@@ -60,9 +62,45 @@ func register(cdsURI string, name string, uk string) error {
 	WorkerID = w.ID
 	sdk.Authorization(w.ID)
 	log.Notice("Registered: %s\n", data)
+
+	if w.ValidateModel {
+		validateModel(modelValidation(w.Capabilities, binaryCapabilities))
+	}
 	return nil
 }
 
+// modelValidation checks the capabilities declared on worker model
+func modelValidation(capabilities []sdk.Requirement, binaries []string) sdk.ModelValidation {
+	v := sdk.ModelValidation{
+		Binaries: binaries,
+		Missing:  []string{},
+	}
+
+	for _, c := range capabilities {
+		if ok, _ := checkRequirement(c); !ok {
+			log.Notice("modelValidation> Capability %s (%s) not found\n", c.Name, c.Value)
+			v.Missing = append(v.Missing, c.Name)
+		}
+	}
+
+	return v
+}
+
+// validateModel sends to engine what has been found on worker model,
+// then exits since worker has only been spawned for validation
+func validateModel(v sdk.ModelValidation) {
+	if err := sdk.ValidateWorkerModel(model, v); err != nil {
+		log.Warning("validateModel> Cannot send model validation: %s\n", err)
+	}
+
+	if err := unregister(); err != nil {
+		log.Warning("validateModel> Cannot unregister: %s\n", err)
+	}
+
+	log.Notice("validateModel> Model validation done (%d capabilities missing), exiting\n", len(v.Missing))
+	os.Exit(0)
+}
+
 // LoopPath return the list of evailable command in path
 func LoopPath(reqs []sdk.Requirement) []string {
 	binaries := []string{}
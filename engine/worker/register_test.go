@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestModelValidation(t *testing.T) {
+	capabilities := []sdk.Requirement{
+		{Name: "Go", Type: sdk.BinaryRequirement, Value: "go"},
+		{Name: "Foo", Type: sdk.BinaryRequirement, Value: "foo"},
+	}
+
+	v := modelValidation(capabilities, []string{"go", "git"})
+	if len(v.Binaries) != 2 {
+		t.Fatalf("Expected 2 binaries, got %d", len(v.Binaries))
+	}
+	if len(v.Missing) != 1 || v.Missing[0] != "Foo" {
+		t.Fatalf("Expected Foo to be missing, got %v", v.Missing)
+	}
+
+	v = modelValidation(capabilities[:1], nil)
+	if len(v.Missing) != 0 {
+		t.Fatalf("Expected no missing capability, got %v", v.Missing)
+	}
+}
@@ -95,6 +95,12 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		BuildID: actionBuild.ID,
 	}
 
+	// Do not run anything once API stopped the action build
+	if buildCanceled() {
+		r.Status = cancelStatus
+		return r
+	}
+
 	// Replace build variable placeholder that may have been added by last step
 	replaceBuildVariablesPlaceholder(a)
 
@@ -111,7 +117,9 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		return r
 	}
 
-	var nbDisabledChildren int
+	var nbDisabledChildren, nbSkippedChildren int
+	var stepStatus sdk.Status
+	var steps []sdk.StepResult
 
 	finalActions := []sdk.Action{}
 	var doNotRunChildrenAnymore bool
@@ -119,6 +127,7 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		if !child.Enabled {
 			childName := fmt.Sprintf("%s/%s-%d", a.Name, child.Name, i+1)
 			sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Step %s is disabled\n", name, childName))
+			steps = append(steps, stepResults(childName, time.Now(), sdk.Result{Status: sdk.StatusDisabled})...)
 			nbDisabledChildren++
 			continue
 		}
@@ -128,10 +137,25 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		} else {
 			if !doNotRunChildrenAnymore {
 				childName := fmt.Sprintf("%s/%s-%d", a.Name, child.Name, i+1)
-				log.Printf("Running %s\n", childName)
-				sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Starting step %s...\n", name, childName))
-				r = startAction(&child, actionBuild)
-				sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Step %s finished (status: %s)\n", name, childName, r.Status))
+				// Previous steps succeeded, otherwise this one would not run
+				ok, err := checkStepCondition(&child, childName, actionBuild, sdk.StatusSuccess, stepStatus)
+				if err == nil && !ok {
+					sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Step %s skipped\n", name, childName))
+					steps = append(steps, stepResults(childName, time.Now(), sdk.Result{Status: sdk.StatusSkipped})...)
+					nbSkippedChildren++
+					continue
+				}
+				start := time.Now()
+				if err != nil {
+					r = sdk.Result{Status: sdk.StatusFail, BuildID: actionBuild.ID}
+				} else {
+					log.Printf("Running %s\n", childName)
+					sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Starting step %s...\n", name, childName))
+					r = startAction(&child, actionBuild)
+					sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Step %s finished (status: %s)\n", name, childName, r.Status))
+				}
+				steps = append(steps, stepResults(childName, start, r)...)
+				stepStatus = r.Status
 				if r.Status != sdk.StatusSuccess {
 					log.Printf("Stopping %s at step %s", a.Name, childName)
 					doNotRunChildrenAnymore = true
@@ -143,35 +167,90 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 	//If all steps are disabled, set action status to disabled
 	if nbDisabledChildren >= (len(a.Actions) - len(finalActions)) {
 		r.Status = sdk.StatusDisabled
+	} else if nbDisabledChildren+nbSkippedChildren >= (len(a.Actions) - len(finalActions)) {
+		// No step has run, none has failed
+		r.Status = sdk.StatusSuccess
 	}
 
 	for i, child := range finalActions {
 		childName := fmt.Sprintf("%s/%s-%d", a.Name, child.Name, i+1)
-		log.Printf("Running final action : %s\n", childName)
-		sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Starting final step %s...\n", name, childName))
-		finalActionResult := startAction(&child, actionBuild)
+		ok, err := checkStepCondition(&child, childName, actionBuild, r.Status, stepStatus)
+		if err == nil && !ok {
+			sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Final step %s skipped\n", name, childName))
+			steps = append(steps, stepResults(childName, time.Now(), sdk.Result{Status: sdk.StatusSkipped})...)
+			continue
+		}
+		start := time.Now()
+		var finalActionResult sdk.Result
+		if err != nil {
+			finalActionResult = sdk.Result{Status: sdk.StatusFail, BuildID: actionBuild.ID}
+		} else {
+			log.Printf("Running final action : %s\n", childName)
+			sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Starting final step %s...\n", name, childName))
+			finalActionResult = startAction(&child, actionBuild)
+		}
+		steps = append(steps, stepResults(childName, start, finalActionResult)...)
+		stepStatus = finalActionResult.Status
 		//If action is success or disabled we consider final action status
 		if r.Status == sdk.StatusSuccess || r.Status == sdk.StatusDisabled {
 			r = finalActionResult
 		}
 		if finalActionResult.Status != sdk.StatusSuccess {
 			log.Printf("Stoping %s at final step %s", a.Name, childName)
+			r.Steps = steps
 			return r
 		}
 		sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Final step %s finished (status: %s)\n", name, childName, finalActionResult.Status))
 	}
 
+	r.Steps = steps
 	return r
 }
 
-var logsecrets []sdk.Variable
+// stepResults returns the result of a step started at given time. Steps of a joined action
+// used as a step are returned instead, so that results are flat.
+func stepResults(stepName string, start time.Time, r sdk.Result) []sdk.StepResult {
+	if len(r.Steps) > 0 {
+		return r.Steps
+	}
+	return []sdk.StepResult{{
+		Name:     stepName,
+		Status:   r.Status,
+		ExitCode: r.ExitCode,
+		Start:    start,
+		Done:     time.Now(),
+		Usage:    r.Usage,
+	}}
+}
+
+// checkStepCondition evaluates the condition of a step with build variables, the status of the job so far
+// and the one of previous step. Result is sent in step logs.
+func checkStepCondition(step *sdk.Action, stepName string, actionBuild sdk.ActionBuild, jobStatus, previousStatus sdk.Status) (bool, error) {
+	if step.Condition == "" {
+		return true, nil
+	}
 
-func sendLog(buildid int64, step string, value string) error {
-	for i := range logsecrets {
-		if len(logsecrets[i].Value) >= 6 {
-			value = strings.Replace(value, logsecrets[i].Value, "**"+logsecrets[i].Name+"**", -1)
-		}
+	vars := sdk.ConditionVariables(actionBuild.Args)
+	for _, v := range buildVariables {
+		vars["cds.build."+v.Name] = v.Value
 	}
+	vars[sdk.ConditionStatusVariable] = jobStatus.String()
+	vars[sdk.ConditionStepStatusVariable] = previousStatus.String()
+
+	ok, err := sdk.EvalCondition(step.Condition, vars)
+	if err != nil {
+		sendLog(actionBuild.ID, stepName, fmt.Sprintf("%s: Cannot evaluate condition %s of step %s: %s\n", name, step.Condition, stepName, err))
+		return false, err
+	}
+	sendLog(actionBuild.ID, stepName, fmt.Sprintf("%s: Condition %s of step %s is %t\n", name, step.Condition, stepName, ok))
+	return ok, nil
+}
+
+// logMasker redacts secrets of the running action build from its logs
+var logMasker *sdk.SecretMasker
+
+func sendLog(buildid int64, step string, value string) error {
+	value = logMasker.Mask(value)
 
 	l := sdk.NewLog(buildid, step, value)
 	logChan <- *l
@@ -299,6 +378,10 @@ func workingDirectory(basedir string, ab sdk.ActionBuild) string {
 }
 
 func run(a sdk.Action, ab sdk.ActionBuild, secrets []sdk.Variable) sdk.Result {
+	// Secrets are masked in all logs, they may be echoed by scripts
+	logMasker = sdk.NewSecretMasker(secrets)
+	defer func() { logMasker = nil }()
+
 	// REPLACE ALL VARIABLE EVEN SECRETS HERE
 	err := processActionVariables(&a, nil, ab, secrets)
 	if err != nil {
@@ -352,10 +435,13 @@ func run(a sdk.Action, ab sdk.ActionBuild, secrets []sdk.Variable) sdk.Result {
 		return sdk.Result{Status: sdk.StatusFail}
 	}
 
-	logsecrets = secrets
+	start := time.Now()
 	res := startAction(&a, ab)
 	close(doneChan)
-	logsecrets = nil
+	// An action which is not joined is its only step
+	if len(res.Steps) == 0 {
+		res.Steps = stepResults(a.Name, start, res)
+	}
 
 	err = teardownBuildDirectory(wd)
 	if err != nil {
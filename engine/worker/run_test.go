@@ -0,0 +1,154 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestStepConditions(t *testing.T) {
+	logChan = make(chan sdk.Log, 100)
+	defer func() { logChan = nil }()
+	cancelChan = make(chan struct{})
+
+	ab := sdk.ActionBuild{
+		ID:   1,
+		Args: []sdk.Parameter{{Name: "git.branch", Value: "feature/retry"}},
+	}
+	success := sdk.Action{Name: "success", Type: sdk.DefaultAction, Enabled: true}
+	failure := sdk.Action{Name: "failure", Type: sdk.BuiltinAction, Enabled: true}
+
+	step := func(a sdk.Action, condition string, final bool) sdk.Action {
+		a.Condition = condition
+		a.Final = final
+		return a
+	}
+
+	tests := []struct {
+		name   string
+		steps  []sdk.Action
+		status sdk.Status
+		logs   []string
+	}{
+		{
+			name:   "skipped step",
+			steps:  []sdk.Action{step(success, "", false), step(failure, `git.branch == "master"`, false)},
+			status: sdk.StatusSuccess,
+			logs:   []string{`Condition git.branch == "master" of step job/failure-2 is false`, "Step job/failure-2 skipped"},
+		},
+		{
+			name:   "all steps skipped",
+			steps:  []sdk.Action{step(failure, `git.branch matches "^master$"`, false)},
+			status: sdk.StatusSuccess,
+		},
+		{
+			name:   "run step",
+			steps:  []sdk.Action{step(failure, `git.branch contains "feature" && cds.step.status == ""`, false)},
+			status: sdk.StatusFail,
+			logs:   []string{"of step job/failure-1 is true"},
+		},
+		{
+			name:   "final step on failure only",
+			steps:  []sdk.Action{step(success, "", false), step(failure, `cds.status == "Fail"`, true)},
+			status: sdk.StatusSuccess,
+			logs:   []string{"Final step job/failure-1 skipped"},
+		},
+		{
+			name:   "final step after failed step",
+			steps:  []sdk.Action{step(failure, "", false), step(success, `cds.status == "Fail" && cds.step.status == "Fail"`, true)},
+			status: sdk.StatusFail,
+			logs:   []string{"of step job/success-1 is true"},
+		},
+	}
+
+	for _, tt := range tests {
+		job := sdk.Action{Name: "job", Type: sdk.JoinedAction, Enabled: true, Actions: tt.steps}
+		res := runAction(&job, ab)
+		close(logChan)
+		var logs []string
+		for l := range logChan {
+			logs = append(logs, l.Value)
+		}
+		logChan = make(chan sdk.Log, 100)
+
+		if res.Status != tt.status {
+			t.Errorf("%s: expected status %s, got %s", tt.name, tt.status, res.Status)
+		}
+		all := strings.Join(logs, "")
+		for _, l := range tt.logs {
+			if !strings.Contains(all, l) {
+				t.Errorf("%s: expected log %q in:\n%s", tt.name, l, all)
+			}
+		}
+	}
+}
+
+func TestSendLogMasksSecrets(t *testing.T) {
+	logChan = make(chan sdk.Log, 10)
+	defer func() { logChan = nil }()
+	logMasker = sdk.NewSecretMasker([]sdk.Variable{
+		{Name: "cds.proj.token", Value: "s3cr3t/t0ken+", Type: sdk.SecretVariable},
+		{Name: "cds.app.key", Value: "-----BEGIN KEY-----\nMIIEpAIBAAKCAQEA\n-----END KEY-----", Type: sdk.KeyVariable},
+		{Name: "cds.env.short", Value: "abc", Type: sdk.SecretVariable},
+	})
+	defer func() { logMasker = nil }()
+
+	tests := []struct {
+		value, expected string
+	}{
+		{"token=s3cr3t/t0ken+\n", "token=**cds.proj.token**\n"},
+		{"Authorization: czNjcjN0L3Qwa2VuKw==\n", "Authorization: **cds.proj.token**\n"},
+		{"curl https://host/?t=s3cr3t%2Ft0ken%2B\n", "curl https://host/?t=**cds.proj.token**\n"},
+		{"MIIEpAIBAAKCAQEA\n", "**cds.app.key**\n"},
+		{"abc is too short to be masked\n", "abc is too short to be masked\n"},
+	}
+	for _, tt := range tests {
+		sendLog(1, "step", tt.value)
+		if l := <-logChan; l.Value != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, l.Value)
+		}
+	}
+}
+
+func TestStepResults(t *testing.T) {
+	logChan = make(chan sdk.Log, 100)
+	defer func() { logChan = nil }()
+	cancelChan = make(chan struct{})
+
+	success := sdk.Action{Name: "success", Type: sdk.DefaultAction, Enabled: true}
+	failure := sdk.Action{Name: "failure", Type: sdk.BuiltinAction, Enabled: true}
+	disabled := sdk.Action{Name: "disabled", Type: sdk.DefaultAction}
+	nested := sdk.Action{Name: "nested", Type: sdk.JoinedAction, Enabled: true, Actions: []sdk.Action{success, success}}
+	final := sdk.Action{Name: "final", Type: sdk.DefaultAction, Enabled: true, Final: true}
+
+	job := sdk.Action{Name: "job", Type: sdk.JoinedAction, Enabled: true, Actions: []sdk.Action{success, disabled, nested, failure, success, final}}
+	res := runAction(&job, sdk.ActionBuild{ID: 1})
+
+	expected := []struct {
+		name   string
+		status sdk.Status
+	}{
+		{"job/success-1", sdk.StatusSuccess},
+		{"job/disabled-2", sdk.StatusDisabled},
+		{"nested/success-1", sdk.StatusSuccess},
+		{"nested/success-2", sdk.StatusSuccess},
+		{"job/failure-4", sdk.StatusFail},
+		{"job/final-1", sdk.StatusSuccess},
+	}
+	if res.Status != sdk.StatusFail {
+		t.Errorf("Expected status %s, got %s", sdk.StatusFail, res.Status)
+	}
+	if len(res.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), res.Steps)
+	}
+	for i, e := range expected {
+		s := res.Steps[i]
+		if s.Name != e.name || s.Status != e.status {
+			t.Errorf("Step %d: expected %s %s, got %s %s", i, e.name, e.status, s.Name, s.Status)
+		}
+		if s.Start.IsZero() || s.Duration() < 0 {
+			t.Errorf("Step %s: invalid times %s - %s", s.Name, s.Start, s.Done)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// resourceUsage returns peak memory and cpu time of an exited process and its waited children.
+// It is read from the worker host, so it does not account for a script run in a container.
+func resourceUsage(state *os.ProcessState) *sdk.ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return nil
+	}
+	return &sdk.ResourceUsage{
+		MaxRSS:     int64(ru.Maxrss),
+		UserTime:   ru.Utime.Nano() / 1e6,
+		SystemTime: ru.Stime.Nano() / 1e6,
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package main
+
+import (
+	"os"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// resourceUsage is only reported on Linux, rusage fields differ on other systems
+func resourceUsage(state *os.ProcessState) *sdk.ResourceUsage {
+	return nil
+}
@@ -20,6 +20,10 @@ type Action struct {
 	PipelineStageID  int64         `json:"pipeline_stage_id" yaml:"-"`
 	PipelineActionID int64         `json:"pipeline_action_id" yaml:"-"`
 	Final            bool          `json:"final" yaml:"-"`
+	Matrix           *Matrix       `json:"matrix,omitempty" yaml:"-"`
+	Timeout          int64         `json:"timeout,omitempty" yaml:"-"` // seconds, 0 means no timeout
+	Retry            *RetryPolicy  `json:"retry,omitempty" yaml:"-"`
+	Condition        string        `json:"condition,omitempty" yaml:"-"`
 	LastModified     int64         `json:"last_modified"`
 }
 
@@ -62,6 +66,12 @@ const (
 	JUnitAction  = "JUnit"
 )
 
+// ScriptImageParameter is the parameter of a script step running it in a container of given docker image
+const ScriptImageParameter = "image"
+
+// DockerRequirement is added to actions with a script step run in a container
+var DockerRequirement = Requirement{Name: "docker", Type: BinaryRequirement, Value: "docker"}
+
 // RequirementType define the type of requirement for an action to be run
 type RequirementType string
 
@@ -278,8 +278,8 @@ func RemoveGroupFromApplication(projectKey, appName, groupName string) error {
 // UpdateGroupInApplication  call api to update group permission for the given application
 func UpdateGroupInApplication(projectKey, appName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role)")
 	}
 
 	groupApplication := GroupPermission{
@@ -313,8 +313,8 @@ func UpdateGroupInApplication(projectKey, appName, groupName string, permission
 // AddGroupInApplication  add a group in an application
 func AddGroupInApplication(projectKey, appName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 ")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role)")
 	}
 
 	groupPipeline := GroupPermission{
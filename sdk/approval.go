@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ApprovalPolicy pauses a pipeline build before a stage until enough users allowed to
+// approve deployments on the environment approved it. The build fails as soon as
+// Rejections users rejected it, a single rejection is enough if Rejections is not set.
+type ApprovalPolicy struct {
+	Approvals  int `json:"approvals" yaml:"approvals"`
+	Rejections int `json:"rejections,omitempty" yaml:"rejections,omitempty"`
+}
+
+// Approval is the decision of a user on a stage of a pipeline build waiting for approval
+type Approval struct {
+	ID              int64     `json:"id"`
+	PipelineBuildID int64     `json:"pipeline_build_id"`
+	StageID         int64     `json:"stage_id"`
+	UserID          int64     `json:"user_id"`
+	Username        string    `json:"username"`
+	Approved        bool      `json:"approved"`
+	Comment         string    `json:"comment,omitempty"`
+	Created         time.Time `json:"created"`
+}
+
+// ApprovalRequest is the body sent to approve or reject a pipeline build
+type ApprovalRequest struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// IsValid checks at least one approval is required
+func (p *ApprovalPolicy) IsValid() bool {
+	if p == nil {
+		return true
+	}
+	return p.Approvals >= 1 && p.Rejections >= 0
+}
+
+// Decision returns StatusSuccess when the stage got enough approvals, StatusFail when it
+// got enough rejections, StatusWaitingApproval otherwise
+func (p *ApprovalPolicy) Decision(approvals []Approval, stageID int64) Status {
+	if p == nil {
+		return StatusSuccess
+	}
+
+	rejectionsNeeded := p.Rejections
+	if rejectionsNeeded < 1 {
+		rejectionsNeeded = 1
+	}
+
+	var approved, rejected int
+	for _, a := range approvals {
+		if a.StageID != stageID {
+			continue
+		}
+		if a.Approved {
+			approved++
+		} else {
+			rejected++
+		}
+	}
+
+	switch {
+	case rejected >= rejectionsNeeded:
+		return StatusFail
+	case approved >= p.Approvals:
+		return StatusSuccess
+	default:
+		return StatusWaitingApproval
+	}
+}
+
+// ApprovePipelineBuild approves or rejects a pipeline build waiting for approval
+func ApprovePipelineBuild(key, app, pip, env string, bn int, approved bool, comment string) error {
+	data, err := json.Marshal(ApprovalRequest{Approved: approved, Comment: comment})
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/approval?envName=%s", key, app, pip, bn, url.QueryEscape(env))
+	data, code, err := Request("POST", uri, data)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package sdk
+
+import (
+	"testing"
+)
+
+func TestApprovalDecision(t *testing.T) {
+	approve := func(stageID int64, user string) Approval {
+		return Approval{StageID: stageID, Username: user, Approved: true}
+	}
+	reject := func(stageID int64, user string) Approval {
+		return Approval{StageID: stageID, Username: user}
+	}
+
+	tests := []struct {
+		policy    *ApprovalPolicy
+		approvals []Approval
+		status    Status
+	}{
+		{nil, nil, StatusSuccess},
+		{&ApprovalPolicy{Approvals: 1}, nil, StatusWaitingApproval},
+		{&ApprovalPolicy{Approvals: 1}, []Approval{approve(1, "alice")}, StatusSuccess},
+		{&ApprovalPolicy{Approvals: 1}, []Approval{approve(2, "alice")}, StatusWaitingApproval},
+		{&ApprovalPolicy{Approvals: 2}, []Approval{approve(1, "alice")}, StatusWaitingApproval},
+		{&ApprovalPolicy{Approvals: 2}, []Approval{approve(1, "alice"), approve(1, "bob")}, StatusSuccess},
+		{&ApprovalPolicy{Approvals: 2}, []Approval{approve(1, "alice"), reject(1, "bob")}, StatusFail},
+		{&ApprovalPolicy{Approvals: 1, Rejections: 2}, []Approval{reject(1, "bob")}, StatusWaitingApproval},
+		{&ApprovalPolicy{Approvals: 1, Rejections: 2}, []Approval{reject(1, "bob"), reject(1, "carol")}, StatusFail},
+		{&ApprovalPolicy{Approvals: 1, Rejections: 2}, []Approval{reject(1, "bob"), approve(1, "alice")}, StatusSuccess},
+	}
+
+	for i, tt := range tests {
+		if got := tt.policy.Decision(tt.approvals, 1); got != tt.status {
+			t.Errorf("#%d: expected %s, got %s", i, tt.status, got)
+		}
+	}
+}
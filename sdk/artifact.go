@@ -3,6 +3,7 @@ package sdk
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,7 @@ type Artifact struct {
 	Size         int64  `json:"size,omitempty"`
 	Perm         uint32 `json:"perm,omitempty"`
 	MD5sum       string `json:"md5sum,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
 	ObjectPath   string `json:"object_path,omitempty"`
 }
 
@@ -70,13 +72,15 @@ func download(project, app, pip string, a Artifact, destdir string) error {
 	var lasterr error
 
 	for retry := 5; retry >= 0; retry-- {
-		uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/artifact/download/%d", project, app, pip, a.ID)
+		// content is fetched from objectstore when API can redirect to it
+		uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/artifact/download/%d?redirect=true", project, app, pip, a.ID)
 		reader, code, err := Stream("GET", uri, nil)
 		if err != nil {
 			lasterr = err
 			continue
 		}
 		if code >= 300 {
+			reader.Close()
 			lasterr = fmt.Errorf("HTTP %d", code)
 			continue
 		}
@@ -87,21 +91,29 @@ func download(project, app, pip string, a Artifact, destdir string) error {
 			mode = os.FileMode(a.Perm)
 		}
 
-		f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY, mode)
+		f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 		if err != nil {
+			reader.Close()
 			lasterr = err
 			continue
 		}
 
-		_, err = io.Copy(f, reader)
+		shaHash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(f, shaHash), reader)
+		reader.Close()
+		f.Close()
 		if err != nil {
 			lasterr = err
+			continue
 		}
 
-		f.Close()
-		if err == nil {
-			return nil
+		if a.SHA256 != "" {
+			if sum := hex.EncodeToString(shaHash.Sum(nil)); sum != a.SHA256 {
+				lasterr = fmt.Errorf("artifact %s digest mismatch: expected %s, got %s", a.Name, a.SHA256, sum)
+				continue
+			}
 		}
+		return nil
 	}
 
 	return fmt.Errorf("x5: %s", lasterr)
@@ -182,14 +194,33 @@ func uploadArtifact(project string, pipeline string, application string, tag str
 		return err
 	}
 
-	//Compute md5sum
+	//Compute md5sum and sha256
 	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	shaHash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(hash, shaHash), file); err != nil {
 		return err
 	}
 	hashInBytes := hash.Sum(nil)[:16]
 	md5sumStr := hex.EncodeToString(hashInBytes)
+	sha256Str := hex.EncodeToString(shaHash.Sum(nil))
 	file.Close()
+	_, name := filepath.Split(filePath)
+
+	//Content is not uploaded if API already stores it
+	linked, err := linkArtifact(uri, Artifact{
+		Name:        name,
+		Environment: env,
+		Size:        stat.Size(),
+		Perm:        uint32(stat.Mode().Perm()),
+		MD5sum:      md5sumStr,
+		SHA256:      sha256Str,
+	})
+	if err != nil {
+		return err
+	}
+	if linked {
+		return nil
+	}
 
 	//Reopen the file because we already read it for md5
 	file, err = os.Open(filePath)
@@ -197,7 +228,6 @@ func uploadArtifact(project string, pipeline string, application string, tag str
 		return err
 	}
 	defer file.Close()
-	_, name := filepath.Split(filePath)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -211,6 +241,7 @@ func uploadArtifact(project string, pipeline string, application string, tag str
 	writer.WriteField("size", strconv.FormatInt(stat.Size(), 10))
 	writer.WriteField("perm", strconv.FormatUint(uint64(stat.Mode().Perm()), 10))
 	writer.WriteField("md5sum", md5sumStr)
+	writer.WriteField("sha256", sha256Str)
 
 	if err := writer.Close(); err != nil {
 		return err
@@ -227,3 +258,24 @@ func uploadArtifact(project string, pipeline string, application string, tag str
 
 	return nil
 }
+
+// linkArtifact saves an artifact whose content is already stored by API, it returns false
+// if content must be uploaded
+func linkArtifact(uri string, art Artifact) (bool, error) {
+	data, err := json.Marshal(art)
+	if err != nil {
+		return false, err
+	}
+
+	_, code, err := Request("POST", uri+"/link", data)
+	if code == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if code >= 300 {
+		return false, fmt.Errorf("HTTP Error %d\n", code)
+	}
+	return true, nil
+}
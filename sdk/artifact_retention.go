@@ -0,0 +1,75 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ArtifactRetention defines which artifacts are kept by the garbage collector. An artifact is kept
+// if any of the rules matches, a zero rule is ignored. Without any rule, artifacts are kept forever.
+type ArtifactRetention struct {
+	// KeepLastBuilds keeps artifacts of the last builds of each pipeline, environment and branch
+	KeepLastBuilds int `json:"keep_last_builds"`
+	// KeepDays keeps artifacts uploaded during the last days
+	KeepDays int `json:"keep_days"`
+	// KeepDeployed keeps artifacts from builds whose version has been deployed to an environment
+	KeepDeployed bool `json:"keep_deployed"`
+}
+
+// IsEmpty returns true if retention has no rule
+func (r ArtifactRetention) IsEmpty() bool {
+	return r.KeepLastBuilds <= 0 && r.KeepDays <= 0 && !r.KeepDeployed
+}
+
+// GetProjectArtifactRetention retrieves artifact retention rules of a project
+func GetProjectArtifactRetention(key string) (*ArtifactRetention, error) {
+	return getArtifactRetention(fmt.Sprintf("/project/%s/artifact/retention", key))
+}
+
+// UpdateProjectArtifactRetention sets artifact retention rules of a project
+func UpdateProjectArtifactRetention(key string, r ArtifactRetention) error {
+	return updateArtifactRetention(fmt.Sprintf("/project/%s/artifact/retention", key), r)
+}
+
+// GetApplicationArtifactRetention retrieves artifact retention rules of an application,
+// falling back to project rules
+func GetApplicationArtifactRetention(key, appName string) (*ArtifactRetention, error) {
+	return getArtifactRetention(fmt.Sprintf("/project/%s/application/%s/artifact/retention", key, appName))
+}
+
+// UpdateApplicationArtifactRetention sets artifact retention rules of an application, overriding project rules
+func UpdateApplicationArtifactRetention(key, appName string, r ArtifactRetention) error {
+	return updateArtifactRetention(fmt.Sprintf("/project/%s/application/%s/artifact/retention", key, appName), r)
+}
+
+func getArtifactRetention(uri string) (*ArtifactRetention, error) {
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	r := &ArtifactRetention{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func updateArtifactRetention(uri string, r ArtifactRetention) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, code, err := Request("PUT", uri, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
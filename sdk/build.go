@@ -3,6 +3,7 @@ package sdk
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 )
 
@@ -23,6 +24,10 @@ type ActionBuild struct {
 	Done             time.Time     `json:"done,omitempty"`
 	Logs             string        `json:"logs,omitempty"`
 	Model            string        `json:"model,omitempty"`
+	Timeout          int64         `json:"timeout,omitempty"`
+	StageTimeout     int64         `json:"stage_timeout,omitempty"`
+	Attempt          int           `json:"attempt,omitempty"`
+	Steps            []StepResult  `json:"steps,omitempty"`
 }
 
 // BuildState define struct returned when looking for build state informations
@@ -52,6 +57,10 @@ func StatusFromString(in string) Status {
 		return StatusDisabled
 	case StatusSkipped.String():
 		return StatusSkipped
+	case StatusTimeout.String():
+		return StatusTimeout
+	case StatusWaitingApproval.String():
+		return StatusWaitingApproval
 	default:
 		return StatusUnknown
 	}
@@ -61,16 +70,33 @@ func (t Status) String() string {
 	return string(t)
 }
 
+// IsFinal returns true if a build with this status has ended, successfully or not
+func (t Status) IsFinal() bool {
+	switch t {
+	case StatusSuccess, StatusFail, StatusDisabled, StatusSkipped, StatusTimeout:
+		return true
+	}
+	return false
+}
+
 // Action status in queue
 const (
-	StatusWaiting    Status = "Waiting"
-	StatusBuilding   Status = "Building"
-	StatusSuccess    Status = "Success"
-	StatusFail       Status = "Fail"
-	StatusDisabled   Status = "Disabled"
-	StatusNeverBuilt Status = "Never Built"
-	StatusUnknown    Status = "Unknown"
-	StatusSkipped    Status = "Skipped"
+	StatusWaiting         Status = "Waiting"
+	StatusBuilding        Status = "Building"
+	StatusSuccess         Status = "Success"
+	StatusFail            Status = "Fail"
+	StatusDisabled        Status = "Disabled"
+	StatusNeverBuilt      Status = "Never Built"
+	StatusUnknown         Status = "Unknown"
+	StatusSkipped         Status = "Skipped"
+	StatusTimeout         Status = "Timeout"
+	StatusWaitingApproval Status = "Waiting approval"
+)
+
+// Build parameters overriding timeouts of pipeline actions and stages, in seconds
+const (
+	TimeoutParameter      = "cds.timeout"
+	StageTimeoutParameter = "cds.stage.timeout"
 )
 
 // GetBuildQueue retrieves current CDS build in queue
@@ -95,6 +121,70 @@ func GetBuildQueue() ([]ActionBuild, error) {
 	return q, nil
 }
 
+// NextActionBuild waits for the API to offer an action build matching worker capabilities.
+// The action build is leased to the worker, which must take it or release it. It returns
+// nil if no action build has been offered before timeout.
+func NextActionBuild(caps WorkerCapabilities, timeout time.Duration) (*ActionBuild, error) {
+	body, err := json.Marshal(caps)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/queue/next?timeout=%d", int(timeout.Seconds()))
+	data, code, err := Request("POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusNoContent {
+		return nil, nil
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	b := &ActionBuild{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ReleaseActionBuild gives back an action build offered to the worker that it cannot run
+func ReleaseActionBuild(id int64) error {
+	path := fmt.Sprintf("/queue/%d/release", id)
+	_, code, err := Request("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
+
+// ActionBuildStatus is the status of an action build, as followed by the worker running it
+type ActionBuildStatus struct {
+	Status Status `json:"status"`
+}
+
+// GetActionBuildStatus returns the current status of an action build
+func GetActionBuildStatus(id int64) (Status, error) {
+	path := fmt.Sprintf("/queue/%d/status", id)
+	data, code, err := Request("GET", path, nil)
+	if err != nil {
+		return StatusUnknown, err
+	}
+	if code >= 300 {
+		return StatusUnknown, fmt.Errorf("HTTP %d", code)
+	}
+
+	var s ActionBuildStatus
+	if err := json.Unmarshal(data, &s); err != nil {
+		return StatusUnknown, err
+	}
+	return s.Status, nil
+}
+
 // GetBuildState Get the state of given build
 func GetBuildState(projectKey, appName, pipelineName, env, buildID string) (PipelineBuild, error) {
 	var buildState PipelineBuild
@@ -0,0 +1,21 @@
+package sdk
+
+import (
+	"testing"
+)
+
+func TestStatusIsFinal(t *testing.T) {
+	final := []Status{StatusSuccess, StatusFail, StatusDisabled, StatusSkipped, StatusTimeout}
+	for _, s := range final {
+		if !s.IsFinal() {
+			t.Errorf("%s should be final", s)
+		}
+	}
+
+	running := []Status{StatusWaiting, StatusBuilding, StatusWaitingApproval, StatusUnknown}
+	for _, s := range running {
+		if s.IsFinal() {
+			t.Errorf("%s should not be final", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Builtin build cache actions
+const (
+	CacheSave    = "Cache Save"
+	CacheRestore = "Cache Restore"
+)
+
+// Cache is an archive of files saved by a build to be restored by next builds of the same application.
+// Caches are scoped by branch and identified by a user defined key.
+type Cache struct {
+	ID          int64     `json:"id"`
+	Project     string    `json:"project"`
+	Application string    `json:"application"`
+	Branch      string    `json:"branch"`
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	Created     time.Time `json:"created"`
+	LastUsed    time.Time `json:"last_used"`
+}
+
+// ListCaches returns all build caches of an application
+func ListCaches(project, application string) ([]Cache, error) {
+	uri := fmt.Sprintf("/project/%s/application/%s/cache", project, application)
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var caches []Cache
+	if err := json.Unmarshal(data, &caches); err != nil {
+		return nil, err
+	}
+	return caches, nil
+}
+
+// MatchCache returns the cache to restore for given branch and keys. Keys are tried in order,
+// first an exact match then as a prefix of the most recently used cache, on branch then on
+// application default branch. It returns nil if no cache matches.
+func MatchCache(project, application, branch string, keys []string) (*Cache, error) {
+	query := url.Values{"branch": {branch}, "key": keys}
+	uri := fmt.Sprintf("/project/%s/application/%s/cache/match?%s", project, application, query.Encode())
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusNotFound {
+		return nil, nil
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	c := &Cache{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DownloadCache streams the archive of a build cache
+func DownloadCache(c Cache) (io.ReadCloser, error) {
+	uri := fmt.Sprintf("/project/%s/application/%s/cache/%d", c.Project, c.Application, c.ID)
+	reader, code, err := Stream("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		reader.Close()
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+	return reader, nil
+}
+
+// UploadCache saves content as build cache of an application branch, replacing the cache with the same key
+func UploadCache(project, application, branch, key string, content io.ReadCloser) error {
+	query := url.Values{"branch": {branch}, "key": {key}}
+	uri := fmt.Sprintf("/project/%s/application/%s/cache?%s", project, application, query.Encode())
+	data, code, err := Upload("POST", uri, content, SetHeader("Content-Type", "application/octet-stream"))
+	if err != nil {
+		return err
+	}
+	if e := DecodeError(data); e != nil {
+		return e
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
+
+// DeleteCache removes a build cache
+func DeleteCache(project, application string, id int64) error {
+	uri := fmt.Sprintf("/project/%s/application/%s/cache/%d", project, application, id)
+	data, code, err := Request("DELETE", uri, nil)
+	if err != nil {
+		return err
+	}
+	if e := DecodeError(data); e != nil {
+		return e
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
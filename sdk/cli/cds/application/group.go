@@ -28,7 +28,7 @@ func init() {
 func cmdApplicationAddGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "cds application group add <projectKey> <applicationName> <groupKey> <permission (4:read, 5:read+exec, 7:all)>",
+		Short: "cds application group add <projectKey> <applicationName> <groupKey> <permission (4:read, 5:read+exec, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   addGroupInApplication,
 	}
@@ -83,7 +83,7 @@ func removeGroupFromApplication(cmd *cobra.Command, args []string) {
 func cmdApplicationUpdateGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
-		Short: "cds application group update <projectKey> <applicationName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all)>",
+		Short: "cds application group update <projectKey> <applicationName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   updateGroupInApplication,
 	}
@@ -20,6 +20,7 @@ func Cmd() *cobra.Command {
 	cmd.AddCommand(environmentShowCmd())
 	cmd.AddCommand(environmentVariableCmd)
 	cmd.AddCommand(environmentGroupCmd)
+	cmd.AddCommand(environmentLockCmd)
 
 	return cmd
 }
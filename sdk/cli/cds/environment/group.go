@@ -28,7 +28,7 @@ func init() {
 func cmdEnvironmentAddGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "cds environment group add <projectKey> <environmentName> <groupKey> <permission (4:read, 5:read+exec, 7:all)>",
+		Short: "cds environment group add <projectKey> <environmentName> <groupKey> <permission (4:read, 5:read+exec, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   addGroupInEnvironment,
 	}
@@ -83,7 +83,7 @@ func removeGroupFromEnvironment(cmd *cobra.Command, args []string) {
 func cmdEnvironmentUpdateGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
-		Short: "cds environment group update <projectKey> <environmentName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all)>",
+		Short: "cds environment group update <projectKey> <environmentName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   updateGroupInEnvironment,
 	}
@@ -0,0 +1,146 @@
+package environment
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// environmentLockCmd Command to manage deployment locks of an environment
+var environmentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Prevent concurrent deployments on an environment",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	environmentLockCmd.AddCommand(cmdEnvironmentLockShow())
+	environmentLockCmd.AddCommand(cmdEnvironmentLockSet())
+	environmentLockCmd.AddCommand(cmdEnvironmentLockUnset())
+	environmentLockCmd.AddCommand(cmdEnvironmentLockRelease())
+}
+
+func cmdEnvironmentLockShow() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "cds environment lock show <projectKey> <environmentName>",
+		Long:  ``,
+		Run:   showEnvironmentLock,
+	}
+	return cmd
+}
+
+func showEnvironmentLock(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	env, err := sdk.GetEnvironment(args[0], args[1])
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve environment %s (%s)\n", args[1], err)
+	}
+
+	if env.LockPolicy == nil {
+		fmt.Printf("Environment %s is not locked during deployments\n", env.Name)
+		return
+	}
+	fmt.Printf("Lock by %s, on conflict: %s\n", env.LockPolicy.Scope, env.LockPolicy.OnConflict)
+
+	for _, l := range env.Locks {
+		if l.Application != "" {
+			fmt.Printf("- %s\n", l.Application)
+		} else {
+			fmt.Printf("- %s\n", env.Name)
+		}
+		if l.Holder != nil {
+			fmt.Printf("  holder: %s/%s #%d [%s] since %s\n", l.Holder.Application, l.Holder.Pipeline, l.Holder.BuildNumber, l.Holder.Status, l.Holder.Since)
+		}
+		for _, b := range l.Queue {
+			fmt.Printf("  queued: %s/%s #%d since %s\n", b.Application, b.Pipeline, b.BuildNumber, b.Since)
+		}
+	}
+}
+
+func cmdEnvironmentLockSet() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "cds environment lock set <projectKey> <environmentName> <environment|application> <queue|cancel|reject>",
+		Long: `Allow only one deployment at a time on the environment, or on each application of the environment.
+When a new build starts while the lock is held, it is queued until the lock is released,
+the build holding the lock is stopped, or the new build is rejected.`,
+		Run: setEnvironmentLock,
+	}
+	return cmd
+}
+
+func setEnvironmentLock(cmd *cobra.Command, args []string) {
+	if len(args) != 4 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	policy := &sdk.EnvironmentLockPolicy{
+		Scope:      args[2],
+		OnConflict: args[3],
+	}
+	if !policy.IsValid() {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	if err := sdk.SetEnvironmentLockPolicy(args[0], args[1], policy); err != nil {
+		sdk.Exit("Error: cannot set lock policy of environment %s (%s)\n", args[1], err)
+	}
+	fmt.Printf("OK\n")
+}
+
+func cmdEnvironmentLockUnset() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unset",
+		Short: "cds environment lock unset <projectKey> <environmentName>",
+		Long:  ``,
+		Run:   unsetEnvironmentLock,
+	}
+	return cmd
+}
+
+func unsetEnvironmentLock(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	if err := sdk.SetEnvironmentLockPolicy(args[0], args[1], nil); err != nil {
+		sdk.Exit("Error: cannot remove lock policy of environment %s (%s)\n", args[1], err)
+	}
+	fmt.Printf("OK\n")
+}
+
+func cmdEnvironmentLockRelease() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "cds environment lock release <projectKey> <environmentName> [applicationName] (admin only)",
+		Long: `Force the release of an environment lock, or of an application lock on the environment.
+The next queued build starts even if the build holding the lock is still running.`,
+		Run: releaseEnvironmentLock,
+	}
+	return cmd
+}
+
+func releaseEnvironmentLock(cmd *cobra.Command, args []string) {
+	if len(args) != 2 && len(args) != 3 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	var appName string
+	if len(args) == 3 {
+		appName = args[2]
+	}
+
+	if err := sdk.ReleaseEnvironmentLock(args[0], args[1], appName); err != nil {
+		sdk.Exit("Error: cannot release lock of environment %s (%s)\n", args[1], err)
+	}
+	fmt.Printf("OK\n")
+}
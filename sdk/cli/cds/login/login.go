@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/howeyc/gopass"
 	"github.com/spf13/cobra"
@@ -21,10 +22,20 @@ var Cmd = &cobra.Command{
 	Short: "Ease up creation of ~/.cds/config.json",
 	Long:  "",
 	Run: func(cmd *cobra.Command, args []string) {
+		if device {
+			runDeviceLogin()
+			return
+		}
 		runLogin()
 	},
 }
 
+var device bool
+
+func init() {
+	Cmd.Flags().BoolVarP(&device, "device", "", false, "Log in through OpenID Connect from another device, for headless terminals")
+}
+
 type config struct {
 	User     string `json:"user"`
 	Password string `json:"password,omitempty"`
@@ -50,12 +61,6 @@ func runLogin() {
 		conf.Host = defaultEndpoint
 	}
 
-	home := os.Getenv("HOME")
-	err = os.Mkdir(path.Join(home, ".cds"), 0700)
-	if err != nil && !os.IsExist(err) {
-		sdk.Exit("Error: Cannot create config folder (%s)\n", err)
-	}
-
 	sdk.InitEndpoint(conf.Host)
 
 	loginOK, res, err := sdk.LoginUser(username, string(password))
@@ -70,6 +75,64 @@ func runLogin() {
 		conf.Password = string(password)
 	}
 
+	writeConfig(conf)
+}
+
+// runDeviceLogin logs in with the OpenID Connect device flow:
+// the user logs in with a browser while the CLI polls for its session
+func runDeviceLogin() {
+	conf := config{}
+	defaultEndpoint := ""
+
+	fmt.Printf("CDS endpoint [%s]: ", defaultEndpoint)
+	conf.Host = readline()
+	if conf.Host == "" {
+		conf.Host = defaultEndpoint
+	}
+	sdk.InitEndpoint(conf.Host)
+
+	d, err := sdk.StartDeviceLogin()
+	if err != nil {
+		sdk.Exit("Error: Login failed (%s)\n", err)
+	}
+	if d.VerificationURIComplete != "" {
+		fmt.Printf("Open %s to log in\n", d.VerificationURIComplete)
+	} else {
+		fmt.Printf("Open %s and enter code %s to log in\n", d.VerificationURI, d.UserCode)
+	}
+
+	interval := time.Duration(d.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(d.ExpiresIn) * time.Second)
+	for {
+		if time.Now().After(deadline) {
+			sdk.Exit("Error: Login failed (code expired)\n")
+		}
+		time.Sleep(interval)
+
+		res, err := sdk.DeviceLogin(d.DeviceCode)
+		if err != nil {
+			sdk.Exit("Error: Login failed (%s)\n", err)
+		}
+		if res != nil {
+			conf.User = res.User.Username
+			conf.Token = res.Token
+			break
+		}
+	}
+
+	writeConfig(conf)
+}
+
+func writeConfig(conf config) {
+	home := os.Getenv("HOME")
+	err := os.Mkdir(path.Join(home, ".cds"), 0700)
+	if err != nil && !os.IsExist(err) {
+		sdk.Exit("Error: Cannot create config folder (%s)\n", err)
+	}
+
 	data, err := json.MarshalIndent(conf, " ", " ")
 	if err != nil {
 		sdk.Exit("Error: Cannot create config file (%s)\n", err)
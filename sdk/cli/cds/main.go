@@ -20,6 +20,7 @@ import (
 	"github.com/ovh/cds/sdk/cli/cds/plugin"
 	"github.com/ovh/cds/sdk/cli/cds/project"
 	"github.com/ovh/cds/sdk/cli/cds/repositoriesmanager"
+	"github.com/ovh/cds/sdk/cli/cds/role"
 	"github.com/ovh/cds/sdk/cli/cds/track"
 	"github.com/ovh/cds/sdk/cli/cds/trigger"
 	"github.com/ovh/cds/sdk/cli/cds/update"
@@ -61,6 +62,7 @@ func main() {
 	rootCmd.AddCommand(pipeline.Cmd())
 	rootCmd.AddCommand(project.Cmd)
 	rootCmd.AddCommand(group.Cmd)
+	rootCmd.AddCommand(role.Cmd())
 	rootCmd.AddCommand(user.Cmd)
 	rootCmd.AddCommand(worker.Cmd)
 	rootCmd.AddCommand(update.Cmd)
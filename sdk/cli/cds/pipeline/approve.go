@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	approveReject  bool
+	approveComment string
+)
+
+func pipelineApproveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "approve",
+		Short: "cds pipeline approve <projectKey> <appName> <pipelineName> [envName] <buildNumber> [--reject] [--comment <comment>]",
+		Long:  `Approve, or reject, the stage a pipeline build is waiting for`,
+		Run:   approvePipeline,
+	}
+
+	cmd.Flags().BoolVarP(&approveReject, "reject", "", false, "Reject the build instead of approving it")
+	cmd.Flags().StringVarP(&approveComment, "comment", "m", "", "Comment recorded with the decision")
+
+	return cmd
+}
+
+func approvePipeline(cmd *cobra.Command, args []string) {
+	if len(args) < 4 {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	pk := args[0]
+	app := args[1]
+	name := args[2]
+	var env string
+	var bnS string
+	if len(args) > 4 {
+		env = args[3]
+		bnS = args[4]
+	} else {
+		bnS = args[3]
+	}
+
+	bn, err := strconv.Atoi(bnS)
+	if err != nil {
+		sdk.Exit("%s is not a valid build number (%s)\n", bnS, err)
+	}
+
+	if err := sdk.ApprovePipelineBuild(pk, app, name, env, bn, !approveReject, approveComment); err != nil {
+		sdk.Exit("Cannot approve pipeline build (%s)\n", err)
+	}
+
+	if approveReject {
+		fmt.Printf("%s #%d rejected\n", name, bn)
+		return
+	}
+	fmt.Printf("%s #%d approved\n", name, bn)
+}
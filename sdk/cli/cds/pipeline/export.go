@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var exportFormat, exportOutput string
+
+func pipelineExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "cds pipeline export <projectKey> <pipelineName> [--format yaml|json] [--output file]",
+		Long:  ``,
+		Run:   exportPipeline,
+	}
+
+	cmd.Flags().StringVarP(&exportFormat, "format", "", sdk.FormatYAML, "Definition format {yaml,json}")
+	cmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write definition to file instead of standard output")
+	return cmd
+}
+
+func exportPipeline(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	data, err := sdk.ExportPipeline(args[0], args[1], exportFormat)
+	if err != nil {
+		sdk.Exit("Error: cannot export pipeline %s (%s)\n", args[1], err)
+	}
+
+	if exportOutput == "" {
+		fmt.Printf("%s", data)
+		return
+	}
+
+	if err := ioutil.WriteFile(exportOutput, data, 0644); err != nil {
+		sdk.Exit("Error: cannot write %s (%s)\n", exportOutput, err)
+	}
+}
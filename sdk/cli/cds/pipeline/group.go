@@ -28,7 +28,7 @@ func init() {
 func cmdPipelineAddGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "cds pipeline group add <projectKey> <pipelineName> <groupKey> <permission (4:read, 5:read+exec, 7:all)>",
+		Short: "cds pipeline group add <projectKey> <pipelineName> <groupKey> <permission (4:read, 5:read+exec, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   addGroupInPipeline,
 	}
@@ -83,7 +83,7 @@ func removeGroupFromPipeline(cmd *cobra.Command, args []string) {
 func cmdPipelineUpdateGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
-		Short: "cds pipeline group update <projectKey> <pipelineName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all)>",
+		Short: "cds pipeline group update <projectKey> <pipelineName> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   updateGroupInPipeline,
 	}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var importFormat string
+var importDryRun bool
+
+func pipelineImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "cds pipeline import <projectKey> <file> [--format yaml|json] [--dry-run]",
+		Long:  `Create or update a pipeline from its definition. Stages, jobs and parameters absent from the definition are removed.`,
+		Run:   importPipeline,
+	}
+
+	cmd.Flags().StringVarP(&importFormat, "format", "", "", "Definition format {yaml,json}, guessed from file extension by default")
+	cmd.Flags().BoolVarP(&importDryRun, "dry-run", "", false, "Only display changes")
+	return cmd
+}
+
+func importPipeline(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	data, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		sdk.Exit("Error: cannot read %s (%s)\n", args[1], err)
+	}
+
+	format := importFormat
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(args[1]), ".")
+	}
+
+	changes, err := sdk.ImportPipeline(args[0], data, format, importDryRun)
+	if err != nil {
+		sdk.Exit("Error: cannot import pipeline (%s)\n", err)
+	}
+
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) == 0 {
+		fmt.Println("Pipeline is up to date.")
+	}
+}
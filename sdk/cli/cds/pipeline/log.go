@@ -6,21 +6,28 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/ovh/cds/sdk"
 )
 
+var followLogs bool
+
 func pipelineShowBuildCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "logs",
-		Short:   "cds pipeline logs <projectKey> <applicationName> <pipelineName> [envName] [buildID]",
-		Long:    ``,
+		Use:   "logs",
+		Short: "cds pipeline logs <projectKey> <applicationName> <pipelineName> [envName] [buildID]",
+		Long: `Display logs of a pipeline build until it ends.
+
+With --follow, logs of a running build are pushed by the API as they are received instead of being polled.`,
 		Aliases: []string{"log"},
 		Run:     showBuildPipeline,
 	}
 
+	cmd.Flags().BoolVarP(&followLogs, "follow", "f", false, "Follow live logs of a running build")
+
 	return cmd
 }
 
@@ -51,12 +58,25 @@ func showBuildPipeline(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	w := tabwriter.NewWriter(os.Stdout, 27, 1, 2, ' ', 0)
+
+	if followLogs {
+		events, err := sdk.FollowPipelineBuild(projectKey, appName, pipelineName, env, buildNumber)
+		// Archived builds cannot be followed, display them as usual
+		if err == nil {
+			followBuildPipeline(w, events)
+			return
+		}
+		if e, ok := err.(sdk.Error); !ok || e.ID != sdk.ErrNoPipelineBuild.ID {
+			sdk.Exit("Error: Cannot follow logs: %s\n", err)
+		}
+	}
+
 	logChan, err := sdk.StreamPipelineBuild(projectKey, appName, pipelineName, env, buildNumber, false)
 	if err != nil {
 		sdk.Exit("Error: Cannot retrieve logs: %s\n", err)
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 27, 1, 2, ' ', 0)
 	titles := []string{"DATE", "ACTION", "LOG"}
 	fmt.Fprintln(w, strings.Join(titles, "\t"))
 
@@ -75,3 +95,33 @@ func showBuildPipeline(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+func followBuildPipeline(w *tabwriter.Writer, events chan sdk.BuildEvent) {
+	titles := []string{"DATE", "ACTION", "LOG"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	var status sdk.Status
+	for e := range events {
+		switch e.Type {
+		case sdk.BuildEventLog:
+			fmt.Fprintf(w, "%s\t%s\t%s",
+				[]byte(e.Log.Timestamp.String())[:19],
+				e.Log.Step,
+				e.Log.Value,
+			)
+		case sdk.BuildEventPipelineBuild:
+			status = e.Status
+			fmt.Fprintf(w, "%s\t%s\tBuild finished with status: %s\n", []byte(time.Now().String())[:19], "SYSTEM", e.Status)
+		}
+		w.Flush()
+	}
+
+	if status == "" {
+		sdk.Exit("Error: Lost connection to the build log stream\n")
+	}
+
+	// Exit 1 if pipeline fail
+	if status == sdk.StatusFail || status == sdk.StatusTimeout {
+		sdk.Exit("")
+	}
+}
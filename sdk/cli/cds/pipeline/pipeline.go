@@ -16,11 +16,14 @@ func Cmd() *cobra.Command {
 	cmd.AddCommand(pipelineActionCmd)
 	cmd.AddCommand(pipelineAddCmd())
 	cmd.AddCommand(pipelineDeleteCmd())
+	cmd.AddCommand(pipelineExportCmd())
 	cmd.AddCommand(pipelineGroupCmd)
 	cmd.AddCommand(pipelineHistoryCmd())
+	cmd.AddCommand(pipelineImportCmd())
 	cmd.AddCommand(pipelineListCmd())
 	cmd.AddCommand(pipelineRunCmd())
 	cmd.AddCommand(pipelineRestartCmd())
+	cmd.AddCommand(pipelineApproveCmd())
 	cmd.AddCommand(pipelineShowBuildCmd())
 	cmd.AddCommand(pipelineCommitsCmd())
 	cmd.AddCommand(pipelineShowCmd())
@@ -2,6 +2,11 @@ package pipeline
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -11,9 +16,12 @@ import (
 
 func pipelineShowCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "show",
-		Short:   "cds pipeline show <projectKey> <pipelineName>",
-		Long:    ``,
+		Use:   "show",
+		Short: "cds pipeline show <projectKey> <pipelineName> [applicationName] [envName] [buildNumber]",
+		Long: `Display a pipeline.
+
+With an application, display the steps of each action of the last build, or given one:
+their status, exit code, duration, and on Linux workers their peak memory and cpu time.`,
 		Aliases: []string{"describe"},
 		Run:     showPipeline,
 	}
@@ -21,12 +29,17 @@ func pipelineShowCmd() *cobra.Command {
 }
 
 func showPipeline(cmd *cobra.Command, args []string) {
-	if len(args) != 2 {
+	if len(args) < 2 || len(args) > 5 {
 		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
 	}
 
 	projectKey := args[0]
 	pipelineName := args[1]
+	if len(args) > 2 {
+		showPipelineBuildSteps(projectKey, pipelineName, args[2:])
+		return
+	}
+
 	p, err := sdk.GetPipeline(projectKey, pipelineName)
 	if err != nil {
 		sdk.Exit("Error: cannot retrieve pipeline informations: %s\n", err)
@@ -39,3 +52,59 @@ func showPipeline(cmd *cobra.Command, args []string) {
 
 	fmt.Println(string(data))
 }
+
+func showPipelineBuildSteps(projectKey, pipelineName string, args []string) {
+	appName := args[0]
+	buildNumber := "last"
+	var env string
+	switch len(args) {
+	case 2:
+		if _, err := strconv.Atoi(args[1]); err == nil {
+			buildNumber = args[1]
+		} else {
+			env = args[1]
+		}
+	case 3:
+		env = args[1]
+		buildNumber = args[2]
+	}
+
+	pb, err := sdk.GetBuildState(projectKey, appName, pipelineName, env, buildNumber)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve build: %s\n", err)
+	}
+
+	fmt.Printf("Build #%d (version %d): %s\n\n", pb.BuildNumber, pb.Version, pb.Status)
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 2, ' ', 0)
+	titles := []string{"STAGE", "ACTION", "STEP", "STATUS", "EXIT", "DURATION", "MAX RSS", "CPU"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	for _, s := range pb.Stages {
+		for _, ab := range s.ActionBuilds {
+			if len(ab.Steps) == 0 {
+				fmt.Fprintf(w, "%s\t%s\t-\t%s\t\t%s\t\t\n", s.Name, ab.ActionName, ab.Status, duration(ab.Start, ab.Done))
+				continue
+			}
+			for _, st := range ab.Steps {
+				var exit, rss, cpu string
+				if st.ExitCode != 0 {
+					exit = fmt.Sprintf("%d", st.ExitCode)
+				}
+				if st.Usage != nil {
+					rss = fmt.Sprintf("%.1f MB", float64(st.Usage.MaxRSS)/1024)
+					cpu = (time.Duration(st.Usage.UserTime+st.Usage.SystemTime) * time.Millisecond).String()
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, ab.ActionName, st.Name, st.Status, exit, duration(st.Start, st.Done), rss, cpu)
+			}
+		}
+	}
+	w.Flush()
+}
+
+func duration(start, done time.Time) string {
+	if start.IsZero() || done.Before(start) {
+		return ""
+	}
+	return done.Sub(start).String()
+}
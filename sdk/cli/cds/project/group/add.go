@@ -14,7 +14,7 @@ var recursive bool
 func cmdProjectAddGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "cds project group add <projectKey> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all)>",
+		Short: "cds project group add <projectKey> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   addGroupInProject,
 	}
@@ -11,7 +11,7 @@ import (
 func cmdProjectUpdateGroup() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
-		Short: "cds project group update <projectKey> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all)>",
+		Short: "cds project group update <projectKey> <groupKey> <permission (4:read, 5:read+exec, 6:read+write, 7:all, or a custom role id)>",
 		Long:  ``,
 		Run:   updateGroupInProject,
 	}
@@ -33,7 +33,8 @@ func connectReposManager(cmd *cobra.Command, args []string) {
 	}
 	fmt.Printf("Go to the following link in your browser\n - %s\n", url)
 
-	if strings.HasPrefix(url, "https://github.com") {
+	//OAuth2 repositories managers (github, gitlab) call back the API by themselves
+	if strings.HasPrefix(url, "https://github.com") || strings.Contains(url, "/oauth/authorize?") {
 		fmt.Println("And follow instructions.")
 		os.Exit(0)
 	}
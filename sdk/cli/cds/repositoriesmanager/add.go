@@ -12,8 +12,14 @@ import (
 func addReposManagerCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "cds reposmanager add <STASH|GITHUB> <name> <url> <option=value> ...",
-		Long:  ``,
+		Short: "cds reposmanager add <STASH|GITHUB|GITLAB> <name> <url> <option=value> ...",
+		Long: `Add a repositories manager. Options depend on the type:
+ - STASH: key=<private key>
+ - GITHUB: client-id=<id> client-secret=<secret> [with-hooks=<bool>] [with-polling=<bool>]
+ - GITLAB: client-id=<application id> client-secret=<secret> [with-hooks=<bool>] [with-polling=<bool>]
+
+For GITLAB, url is the base url of the instance (e.g. https://gitlab.example.com) and the application
+must be declared in GitLab with <api url>/repositories_manager/oauth2/callback as redirect URI and the api scope.`,
 		Run:   addReposManager,
 	}
 
@@ -0,0 +1,140 @@
+package role
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var roleActionsHelp = `
+Available actions, every role allows to read:
+ - run: run, restart, stop and rollback pipelines
+ - edit: modify the project, application, pipeline or environment
+ - manage_variables: add, modify and delete variables
+ - manage_groups: give and remove roles to groups
+ - approve_deployment: approve a deployment waiting for a manual approval
+`
+
+//Cmd returns the root cobra command for role management
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "role",
+		Short:   "Role management",
+		Long:    ``,
+		Aliases: []string{},
+	}
+
+	cmd.AddCommand(cmdRoleList())
+	cmd.AddCommand(cmdRoleAdd())
+	cmd.AddCommand(cmdRoleUpdate())
+	cmd.AddCommand(cmdRoleRemove())
+	return cmd
+}
+
+func cmdRoleList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "cds role list",
+		Long:    ``,
+		Aliases: []string{"ls"},
+		Run:     listRoles,
+	}
+
+	return cmd
+}
+
+func listRoles(cmd *cobra.Command, args []string) {
+	roles, err := sdk.ListRoles()
+	if err != nil {
+		sdk.Exit("Error: cannot list roles (%s)\n", err)
+	}
+
+	for _, r := range roles {
+		builtIn := ""
+		if r.BuiltIn {
+			builtIn = " (built-in)"
+		}
+		fmt.Printf("- %d %s%s [%s]\n", r.ID, r.Name, builtIn, strings.Join(r.Actions, " "))
+	}
+}
+
+func cmdRoleAdd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "cds role add <name> <action> [<action>...] (admin only)",
+		Long:  "cds role add <name> <action> [<action>...] (admin only)\n" + roleActionsHelp,
+		Run:   addRole,
+	}
+
+	return cmd
+}
+
+func addRole(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		sdk.Exit("Wrong usage, see:\n%s\n", cmd.Long)
+	}
+	for _, a := range args[1:] {
+		if !sdk.IsValidRoleAction(a) {
+			sdk.Exit("Invalid action %s, see:\n%s\n", a, cmd.Long)
+		}
+	}
+
+	r, err := sdk.AddRole(args[0], args[1:])
+	if err != nil {
+		sdk.Exit("Error: cannot create role %s (%s)\n", args[0], err)
+	}
+	fmt.Printf("Role %s created with id %d\n", r.Name, r.ID)
+}
+
+func cmdRoleUpdate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "cds role update <name> <action> [<action>...] (admin only)",
+		Long:  "cds role update <name> <action> [<action>...] (admin only)\n" + roleActionsHelp,
+		Run:   updateRole,
+	}
+
+	return cmd
+}
+
+func updateRole(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		sdk.Exit("Wrong usage, see:\n%s\n", cmd.Long)
+	}
+	for _, a := range args[1:] {
+		if !sdk.IsValidRoleAction(a) {
+			sdk.Exit("Invalid action %s, see:\n%s\n", a, cmd.Long)
+		}
+	}
+
+	if err := sdk.UpdateRole(args[0], args[1:]); err != nil {
+		sdk.Exit("Error: cannot update role %s (%s)\n", args[0], err)
+	}
+	fmt.Printf("Role %s updated\n", args[0])
+}
+
+func cmdRoleRemove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "remove",
+		Short:   "cds role remove <name> (admin only)",
+		Long:    ``,
+		Aliases: []string{"rm", "delete", "del"},
+		Run:     removeRole,
+	}
+
+	return cmd
+}
+
+func removeRole(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	if err := sdk.DeleteRole(args[0]); err != nil {
+		sdk.Exit("Error: cannot remove role %s (%s)\n", args[0], err)
+	}
+	fmt.Printf("Role %s removed\n", args[0])
+}
@@ -0,0 +1,126 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	tokenScopes []string
+	tokenExpiry int
+)
+
+func cmdUserToken() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "cds user token",
+		Long:  ``,
+	}
+
+	cmd.AddCommand(cmdUserTokenAdd())
+	cmd.AddCommand(cmdUserTokenList())
+	cmd.AddCommand(cmdUserTokenRevoke())
+	return cmd
+}
+
+func cmdUserTokenAdd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "cds user token add <username> <name> --scope <scope> [--expiry <days>]",
+		Long: `cds user token add <username> <name> --scope <scope> [--expiry <days>]
+
+Available scopes, restricted to a project when suffixed with :<project key>:
+ - read: read only requests
+ - run: run, restart, stop and rollback pipelines
+ - variables: manage project, application and environment variables
+`,
+		Run: addUserToken,
+	}
+
+	cmd.Flags().StringSliceVarP(&tokenScopes, "scope", "", nil, "Token scope, repeat for many scopes")
+	cmd.Flags().IntVarP(&tokenExpiry, "expiry", "", 90, "Token lifetime in days")
+	return cmd
+}
+
+func addUserToken(cmd *cobra.Command, args []string) {
+	if len(args) != 2 || len(tokenScopes) == 0 {
+		sdk.Exit("Wrong usage, see:\n%s\n", cmd.Long)
+	}
+	for _, s := range tokenScopes {
+		if !sdk.IsValidTokenScope(s) {
+			sdk.Exit("Invalid scope %s, see:\n%s\n", s, cmd.Long)
+		}
+	}
+
+	expiry := time.Now().Add(time.Duration(tokenExpiry) * 24 * time.Hour)
+	t, err := sdk.CreateUserToken(args[0], args[1], expiry, tokenScopes)
+	if err != nil {
+		sdk.Exit("Error: cannot create token (%s)\n", err)
+	}
+
+	fmt.Printf("%s\n", t.Token)
+}
+
+func cmdUserTokenList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "cds user token list <username>",
+		Long:    ``,
+		Aliases: []string{"ls"},
+		Run:     listUserTokens,
+	}
+
+	return cmd
+}
+
+func listUserTokens(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	tokens, err := sdk.ListUserTokens(args[0])
+	if err != nil {
+		sdk.Exit("Error: cannot list tokens (%s)\n", err)
+	}
+
+	for _, t := range tokens {
+		lastUsed := "never used"
+		if t.LastUsed != nil {
+			lastUsed = "last used " + t.LastUsed.Format(time.RFC3339)
+		}
+		fmt.Printf("- %d %s [%s] expires %s, %s\n", t.ID, t.Name, strings.Join(t.Scopes, " "), t.Expiry.Format(time.RFC3339), lastUsed)
+	}
+}
+
+func cmdUserTokenRevoke() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "revoke",
+		Short:   "cds user token revoke <username> <token id>",
+		Long:    ``,
+		Aliases: []string{"remove", "rm", "delete", "del"},
+		Run:     revokeUserToken,
+	}
+
+	return cmd
+}
+
+func revokeUserToken(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		sdk.Exit("Error: invalid token id %s\n", args[1])
+	}
+
+	if err := sdk.RevokeUserToken(args[0], id); err != nil {
+		sdk.Exit("Error: cannot revoke token %d (%s)\n", id, err)
+	}
+	fmt.Printf("Token %d revoked\n", id)
+}
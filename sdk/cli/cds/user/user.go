@@ -10,6 +10,7 @@ func init() {
 	Cmd.AddCommand(cmdUserGenerate())
 	Cmd.AddCommand(cmdUserUpdate())
 	Cmd.AddCommand(cmdUserDelete())
+	Cmd.AddCommand(cmdUserToken())
 }
 
 // Cmd user
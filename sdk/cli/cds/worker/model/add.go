@@ -14,6 +14,7 @@ var (
 	imageP                 string
 	openstackFlavorP       string
 	openstackUserDataFileP string
+	singleUseP             bool
 )
 
 func cmdWorkerModelAdd() *cobra.Command {
@@ -31,6 +32,7 @@ func cmdWorkerModelAdd() *cobra.Command {
 	cmd.Flags().StringVar(&imageP, "image", "", "Image value (docker or openstack)")
 	cmd.Flags().StringVar(&openstackFlavorP, "flavor", "", "Flavor value (openstack)")
 	cmd.Flags().StringVar(&openstackUserDataFileP, "userdata", "", "Path to UserData file (openstack)")
+	cmd.Flags().BoolVar(&singleUseP, "single-use", false, "Workers only run the action build they are spawned for")
 
 	return cmd
 }
@@ -82,7 +84,7 @@ func addWorkerModel(cmd *cobra.Command, args []string) {
 		sdk.Exit("Unknown worker type: %s\n", modelType)
 	}
 
-	_, err := sdk.AddWorkerModel(name, t, image)
+	_, err := sdk.AddWorkerModel(name, t, image, singleUseP)
 	if err != nil {
 		sdk.Exit("Error: cannot add worker model (%s)\n", err)
 	}
@@ -33,7 +33,7 @@ func listWorkerModel(cmd *cobra.Command, args []string) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 27, 1, 2, ' ', 0)
-	titles := []string{"NAME", "TYPE", "IMAGE"}
+	titles := []string{"NAME", "TYPE", "IMAGE", "VALIDATION"}
 	fmt.Fprintln(w, strings.Join(titles, "\t"))
 
 	for _, m := range models {
@@ -41,12 +41,26 @@ func listWorkerModel(cmd *cobra.Command, args []string) {
 			m.Image = m.Image[:97] + "..."
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
 			m.Name,
 			m.Type,
 			m.Image,
+			validation(m),
 		)
 
 		w.Flush()
 	}
 }
+
+func validation(m sdk.Model) string {
+	switch {
+	case m.NeedValidation:
+		return "pending"
+	case m.Validated:
+		return "valid"
+	case m.ValidationError != "":
+		return "invalid: " + m.ValidationError
+	default:
+		return "-"
+	}
+}
@@ -58,13 +58,14 @@ func cmdWorkerModelUpdate() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
 		Short: "cds worker model update <oldname> <name> <type>",
-		Long:  `Update name, type and image value only.`,
+		Long:  `Update name, type, image value and single use policy only.`,
 		Run:   updateWorkerModel,
 	}
 
 	cmd.Flags().StringVar(&imageP, "image", "", "Image value (docker or openstack)")
 	cmd.Flags().StringVar(&openstackFlavorP, "flavor", "", "Flavor value (openstack)")
 	cmd.Flags().StringVar(&openstackUserDataFileP, "userdata", "", "Path to UserData file (openstack)")
+	cmd.Flags().BoolVar(&singleUseP, "single-use", false, "Workers only run the action build they are spawned for")
 	return cmd
 }
 
@@ -125,7 +126,7 @@ func updateWorkerModel(cmd *cobra.Command, args []string) {
 	if err != nil {
 		sdk.Exit("Error: cannot retrieve worker model %s (%s)\n", workerModelName, err)
 	}
-	err = sdk.UpdateWorkerModel(m.ID, name, t, value)
+	err = sdk.UpdateWorkerModel(m.ID, name, t, value, singleUseP)
 	if err != nil {
 		sdk.Exit("Error: cannot update model (%s)\n", err)
 	}
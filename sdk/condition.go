@@ -0,0 +1,401 @@
+package sdk
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Build variables set while evaluating conditions of steps
+const (
+	ConditionStatusVariable     = "cds.status"      // status of the job so far, Success or Fail
+	ConditionStepStatusVariable = "cds.step.status" // status of the previous step, empty for the first one
+)
+
+// Condition is a boolean expression deciding whether a stage, a joined action or a step runs.
+// It compares build variables with strings and numbers:
+//
+//	git.branch == "master" && (cds.env.region matches "^eu-" || cds.status != "Success")
+//
+// Operators are == != < <= > >= contains matches, combined with && || ! and parenthesis.
+// Values are compared as numbers if both are, as strings otherwise. A missing variable is an empty string,
+// and a value alone is true unless it is empty, "false" or "0".
+type Condition struct {
+	expr string
+	root conditionNode
+}
+
+type conditionNode interface {
+	eval(vars map[string]string) (string, error)
+}
+
+// ParseCondition checks the syntax of a condition
+func ParseCondition(expr string) (*Condition, error) {
+	tokens, err := lexCondition(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s at end of condition", p.tokens[p.pos].value)
+	}
+	return &Condition{expr: expr, root: root}, nil
+}
+
+// Eval evaluates the condition with given build variables
+func (c *Condition) Eval(vars map[string]string) (bool, error) {
+	v, err := c.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// String returns the condition expression
+func (c *Condition) String() string {
+	return c.expr
+}
+
+// IsValidCondition returns true if expr is empty or a valid condition
+func IsValidCondition(expr string) bool {
+	if strings.TrimSpace(expr) == "" {
+		return true
+	}
+	_, err := ParseCondition(expr)
+	return err == nil
+}
+
+// EvalCondition parses and evaluates a condition, an empty one is true
+func EvalCondition(expr string, vars map[string]string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	c, err := ParseCondition(expr)
+	if err != nil {
+		return false, err
+	}
+	return c.Eval(vars)
+}
+
+// ConditionVariables returns build parameters as condition variables
+func ConditionVariables(params []Parameter) map[string]string {
+	vars := make(map[string]string, len(params))
+	for _, p := range params {
+		vars[p.Name] = p.Value
+	}
+	return vars
+}
+
+func truthy(v string) bool {
+	return v != "" && v != "false" && v != "0"
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+type conditionLiteral string
+
+func (l conditionLiteral) eval(map[string]string) (string, error) {
+	return string(l), nil
+}
+
+type conditionVariable string
+
+func (v conditionVariable) eval(vars map[string]string) (string, error) {
+	return vars[string(v)], nil
+}
+
+type conditionNot struct {
+	node conditionNode
+}
+
+func (n conditionNot) eval(vars map[string]string) (string, error) {
+	v, err := n.node.eval(vars)
+	if err != nil {
+		return "", err
+	}
+	return boolString(!truthy(v)), nil
+}
+
+type conditionBinary struct {
+	op          string
+	left, right conditionNode
+	re          *regexp.Regexp // compiled once for matches with a literal pattern
+}
+
+func (b conditionBinary) eval(vars map[string]string) (string, error) {
+	l, err := b.left.eval(vars)
+	if err != nil {
+		return "", err
+	}
+
+	// Logical operators do not evaluate right operand if not needed
+	switch b.op {
+	case "&&":
+		if !truthy(l) {
+			return "false", nil
+		}
+	case "||":
+		if truthy(l) {
+			return "true", nil
+		}
+	}
+
+	r, err := b.right.eval(vars)
+	if err != nil {
+		return "", err
+	}
+
+	switch b.op {
+	case "&&", "||":
+		return boolString(truthy(r)), nil
+	case "contains":
+		return boolString(strings.Contains(l, r)), nil
+	case "matches":
+		re := b.re
+		if re == nil {
+			if re, err = regexp.Compile(r); err != nil {
+				return "", fmt.Errorf("invalid regular expression %s: %s", r, err)
+			}
+		}
+		return boolString(re.MatchString(l)), nil
+	}
+
+	cmp := strings.Compare(l, r)
+	lf, errl := strconv.ParseFloat(l, 64)
+	rf, errr := strconv.ParseFloat(r, 64)
+	if errl == nil && errr == nil {
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch b.op {
+	case "==":
+		return boolString(cmp == 0), nil
+	case "!=":
+		return boolString(cmp != 0), nil
+	case "<":
+		return boolString(cmp < 0), nil
+	case "<=":
+		return boolString(cmp <= 0), nil
+	case ">":
+		return boolString(cmp > 0), nil
+	case ">=":
+		return boolString(cmp >= 0), nil
+	}
+	return "", fmt.Errorf("unknown operator %s", b.op)
+}
+
+type conditionTokenType int
+
+const (
+	tokenOperator conditionTokenType = iota
+	tokenString
+	tokenNumber
+	tokenIdentifier
+)
+
+type conditionToken struct {
+	typ   conditionTokenType
+	value string
+}
+
+func lexCondition(expr string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	s := []rune(expr)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, conditionToken{tokenOperator, string(c)})
+			i++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(s) && s[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" {
+				return nil, fmt.Errorf("unexpected = at %d, use ==", i)
+			}
+			tokens = append(tokens, conditionToken{tokenOperator, op})
+			i += len(op)
+		case c == '&' || c == '|':
+			if i+1 >= len(s) || s[i+1] != c {
+				return nil, fmt.Errorf("unexpected %c at %d, use %c%c", c, i, c, c)
+			}
+			tokens = append(tokens, conditionToken{tokenOperator, string(c) + string(c)})
+			i += 2
+		case c == '"' || c == '\'':
+			var value []rune
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				value = append(value, s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, conditionToken{tokenString, string(value)})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(s[i+1])):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{tokenNumber, string(s[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(s) && (unicode.IsLetter(s[j]) || unicode.IsDigit(s[j]) || strings.ContainsRune("._-", s[j])) {
+				j++
+			}
+			word := string(s[i:j])
+			switch word {
+			case "contains", "matches":
+				tokens = append(tokens, conditionToken{tokenOperator, word})
+			default:
+				tokens = append(tokens, conditionToken{tokenIdentifier, word})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %c at %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peekOperator(ops ...string) string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].typ != tokenOperator {
+		return ""
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].value == op {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("||") != "" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("&&") != "" {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = conditionBinary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (conditionNode, error) {
+	if p.peekOperator("!") != "" {
+		p.pos++
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return conditionNot{n}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (conditionNode, error) {
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peekOperator("==", "!=", "<", "<=", ">", ">=", "contains", "matches")
+	if op == "" {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	b := conditionBinary{op: op, left: left, right: right}
+	if l, ok := right.(conditionLiteral); ok && op == "matches" {
+		if b.re, err = regexp.Compile(string(l)); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %s", l, err)
+		}
+	}
+	return b, nil
+}
+
+func (p *conditionParser) parseValue() (conditionNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.typ {
+	case tokenString, tokenNumber:
+		return conditionLiteral(t.value), nil
+	case tokenIdentifier:
+		if t.value == "true" || t.value == "false" {
+			return conditionLiteral(t.value), nil
+		}
+		return conditionVariable(t.value), nil
+	}
+
+	if t.value != "(" {
+		return nil, fmt.Errorf("unexpected %s", t.value)
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peekOperator(")") == "" {
+		return nil, fmt.Errorf("missing )")
+	}
+	p.pos++
+	return n, nil
+}
@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexCondition(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tokens []conditionToken
+	}{
+		{`a==b`, []conditionToken{{tokenIdentifier, "a"}, {tokenOperator, "=="}, {tokenIdentifier, "b"}}},
+		{`git.branch != "feat/x"`, []conditionToken{{tokenIdentifier, "git.branch"}, {tokenOperator, "!="}, {tokenString, "feat/x"}}},
+		{`'it\'s' contains "s"`, []conditionToken{{tokenString, "it's"}, {tokenOperator, "contains"}, {tokenString, "s"}}},
+		{`!(x>=-1.5)`, []conditionToken{{tokenOperator, "!"}, {tokenOperator, "("}, {tokenIdentifier, "x"}, {tokenOperator, ">="}, {tokenNumber, "-1.5"}, {tokenOperator, ")"}}},
+		{`a<b&&c||d matches e`, []conditionToken{{tokenIdentifier, "a"}, {tokenOperator, "<"}, {tokenIdentifier, "b"}, {tokenOperator, "&&"}, {tokenIdentifier, "c"}, {tokenOperator, "||"}, {tokenIdentifier, "d"}, {tokenOperator, "matches"}, {tokenIdentifier, "e"}}},
+		{`my-var_2`, []conditionToken{{tokenIdentifier, "my-var_2"}}},
+		{`  `, nil},
+	}
+
+	for _, tt := range tests {
+		tokens, err := lexCondition(tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) {
+			t.Errorf("%s: got %v, want %v", tt.expr, tokens, tt.tokens)
+		}
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	vars := map[string]string{
+		"git.branch":     "master",
+		"cds.status":     "Success",
+		"cds.env.region": "eu-west",
+		"count":          "10",
+		"version":        "9",
+		"empty":          "",
+		"zero":           "0",
+		"pattern":        "(",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Empty condition
+		{``, true},
+		{`   `, true},
+
+		// Values alone
+		{`true`, true},
+		{`false`, false},
+		{`"0"`, false},
+		{`"yes"`, true},
+		{`git.branch`, true},
+		{`empty`, false},
+		{`zero`, false},
+
+		// String comparisons
+		{`git.branch == "master"`, true},
+		{`git.branch == 'master'`, true},
+		{`git.branch != "master"`, false},
+		{`git.branch < "dev"`, false},
+		{`"abc" < "abd"`, true},
+		{`git.branch contains "ast"`, true},
+		{`cds.env.region matches "^eu-"`, true},
+		{`cds.env.region matches "^us-"`, false},
+		{`git.branch matches git.branch`, true},
+
+		// Number comparisons, strings would order "10" before "9"
+		{`count > version`, true},
+		{`count > 9`, true},
+		{`count >= 10`, true},
+		{`count <= 9.5`, false},
+		{`count == 10.0`, true},
+		{`-1 < 0`, true},
+		{`"10" > "9"`, true},
+		{`count > "9a"`, false},
+
+		// Unknown variables are empty strings
+		{`unknown`, false},
+		{`unknown == ""`, true},
+		{`unknown != "master"`, true},
+		{`!unknown`, true},
+
+		// Precedence: ! before comparisons before && before ||
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && false || true`, true},
+		{`!false && false`, false},
+		{`!(false && false)`, true},
+		{`!git.branch == "master"`, false},
+		{`!!git.branch`, true},
+		{`git.branch == "dev" || cds.status == "Success" && count > 9`, true},
+		{`(git.branch == "dev" || cds.status == "Success") && count < 9`, false},
+
+		// Right operand is not evaluated if not needed
+		{`false && unknown matches pattern`, false},
+		{`true || unknown matches pattern`, true},
+	}
+
+	for _, tt := range tests {
+		got, err := EvalCondition(tt.expr, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestConditionSyntaxErrors(t *testing.T) {
+	tests := []string{
+		`a = b`,
+		`a & b`,
+		`a | b`,
+		`a == "master`,
+		`a == 'master`,
+		`a # b`,
+		`a ==`,
+		`== a`,
+		`(a == b`,
+		`a == b)`,
+		`a b`,
+		`()`,
+		`!`,
+		`a && || b`,
+		`a matches "("`,
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("%s: expected a syntax error", expr)
+		}
+		if IsValidCondition(expr) {
+			t.Errorf("%s: should not be valid", expr)
+		}
+		if _, err := EvalCondition(expr, nil); err == nil {
+			t.Errorf("%s: expected an error on eval", expr)
+		}
+	}
+
+	// Pattern only known when evaluating
+	c, err := ParseCondition(`a matches b`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.Eval(map[string]string{"b": "("}); err == nil {
+		t.Errorf("expected an invalid regular expression error")
+	}
+}
@@ -8,14 +8,16 @@ import (
 
 // Environment represent a deployment environment
 type Environment struct {
-	ID                int64             `json:"id" yaml:"-"`
-	Name              string            `json:"name" yaml:"name"`
-	EnvironmentGroups []GroupPermission `json:"groups,omitempty" yaml:"groups"`
-	Variable          []Variable        `json:"variables,omitempty" yaml:"variables"`
-	ProjectID         int64             `json:"-" yaml:"-"`
-	ProjectKey        string            `json:"-" yaml:"-"`
-	Permission        int               `json:"permission"`
-	LastModified      int64             `json:"last_modified"`
+	ID                int64                  `json:"id" yaml:"-"`
+	Name              string                 `json:"name" yaml:"name"`
+	EnvironmentGroups []GroupPermission      `json:"groups,omitempty" yaml:"groups"`
+	Variable          []Variable             `json:"variables,omitempty" yaml:"variables"`
+	ProjectID         int64                  `json:"-" yaml:"-"`
+	ProjectKey        string                 `json:"-" yaml:"-"`
+	Permission        int                    `json:"permission"`
+	LastModified      int64                  `json:"last_modified"`
+	LockPolicy        *EnvironmentLockPolicy `json:"lock_policy,omitempty" yaml:"lock_policy,omitempty"`
+	Locks             []EnvironmentLock      `json:"locks,omitempty" yaml:"locks,omitempty"`
 }
 
 // NewEnvironment instanciate a new Environment
@@ -257,8 +259,8 @@ func RemoveGroupFromEnvironment(projectKey, envName, groupName string) error {
 // UpdateGroupInEnvironment  call api to update group permission for the given environment
 func UpdateGroupInEnvironment(projectKey, envName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role)")
 	}
 
 	groupApplication := GroupPermission{
@@ -292,8 +294,8 @@ func UpdateGroupInEnvironment(projectKey, envName, groupName string, permission
 // AddGroupInEnvironment  add a group in an environment
 func AddGroupInEnvironment(projectKey, envName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 \n")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role) \n")
 	}
 
 	groupPipeline := GroupPermission{
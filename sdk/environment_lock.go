@@ -0,0 +1,112 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Scopes of an environment lock
+const (
+	LockScopeEnvironment = "environment" // one deployment at a time on the environment
+	LockScopeApplication = "application" // one deployment at a time per application on the environment
+)
+
+// What to do with a new pipeline build when the environment lock is already held
+const (
+	LockQueue  = "queue"  // wait for the lock to be released
+	LockCancel = "cancel" // stop the build holding the lock and take it
+	LockReject = "reject" // refuse to run the new build
+)
+
+// EnvironmentLockPolicy prevents concurrent deployments on an environment
+type EnvironmentLockPolicy struct {
+	Scope      string `json:"scope" yaml:"scope"`
+	OnConflict string `json:"on_conflict" yaml:"on_conflict"`
+}
+
+// EnvironmentLock is the pipeline build deploying on an environment, or on an application
+// of the environment, and the pipeline builds queued behind it
+type EnvironmentLock struct {
+	Application string                 `json:"application,omitempty" yaml:"application,omitempty"`
+	Holder      *EnvironmentLockBuild  `json:"holder,omitempty" yaml:"holder,omitempty"`
+	Queue       []EnvironmentLockBuild `json:"queue,omitempty" yaml:"queue,omitempty"`
+}
+
+// EnvironmentLockBuild is a pipeline build holding or waiting for an environment lock
+type EnvironmentLockBuild struct {
+	PipelineBuildID int64     `json:"pipeline_build_id" yaml:"pipeline_build_id"`
+	Application     string    `json:"application" yaml:"application"`
+	Pipeline        string    `json:"pipeline" yaml:"pipeline"`
+	BuildNumber     int64     `json:"build_number" yaml:"build_number"`
+	Status          Status    `json:"status" yaml:"status"`
+	Since           time.Time `json:"since" yaml:"since"`
+}
+
+// IsValid checks scope and conflict resolution are known
+func (p *EnvironmentLockPolicy) IsValid() bool {
+	if p == nil {
+		return true
+	}
+	switch p.Scope {
+	case LockScopeEnvironment, LockScopeApplication:
+	default:
+		return false
+	}
+	switch p.OnConflict {
+	case LockQueue, LockCancel, LockReject:
+		return true
+	}
+	return false
+}
+
+// LockApplicationID returns the application part of the lock taken by a build of given application,
+// 0 when the whole environment is locked
+func (p *EnvironmentLockPolicy) LockApplicationID(appID int64) int64 {
+	if p != nil && p.Scope == LockScopeApplication {
+		return appID
+	}
+	return 0
+}
+
+// SetEnvironmentLockPolicy configures how concurrent deployments on an environment are handled, nil removes the lock
+func SetEnvironmentLockPolicy(key, envName string, policy *EnvironmentLockPolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/project/%s/environment/%s/lock/policy", key, envName)
+	data, code, err := Request("PUT", path, data)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
+
+// ReleaseEnvironmentLock force the release of the lock of an environment, or of an application on
+// the environment if appName is set, so the next queued build can run (admin only)
+func ReleaseEnvironmentLock(key, envName, appName string) error {
+	path := fmt.Sprintf("/project/%s/environment/%s/lock?application=%s", key, envName, url.QueryEscape(appName))
+	data, code, err := Request("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
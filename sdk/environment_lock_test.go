@@ -0,0 +1,31 @@
+package sdk
+
+import (
+	"testing"
+)
+
+func TestEnvironmentLockPolicy(t *testing.T) {
+	tests := []struct {
+		policy *EnvironmentLockPolicy
+		valid  bool
+		appID  int64
+	}{
+		{nil, true, 0},
+		{&EnvironmentLockPolicy{Scope: LockScopeEnvironment, OnConflict: LockQueue}, true, 0},
+		{&EnvironmentLockPolicy{Scope: LockScopeApplication, OnConflict: LockCancel}, true, 42},
+		{&EnvironmentLockPolicy{Scope: LockScopeApplication, OnConflict: LockReject}, true, 42},
+		{&EnvironmentLockPolicy{Scope: "project", OnConflict: LockQueue}, false, 0},
+		{&EnvironmentLockPolicy{Scope: LockScopeEnvironment, OnConflict: "wait"}, false, 0},
+	}
+
+	for i, tt := range tests {
+		if got := tt.policy.IsValid(); got != tt.valid {
+			t.Errorf("#%d: expected valid %t, got %t", i, tt.valid, got)
+		}
+		if tt.valid {
+			if got := tt.policy.LockApplicationID(42); got != tt.appID {
+				t.Errorf("#%d: expected lock on application %d, got %d", i, tt.appID, got)
+			}
+		}
+	}
+}
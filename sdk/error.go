@@ -85,6 +85,23 @@ var (
 	ErrInfiniteTriggerLoop          = &Error{ID: 71, Status: http.StatusBadRequest}
 	ErrInvalidResetUser             = &Error{ID: 72, Status: http.StatusBadRequest}
 	ErrUserConflict                 = &Error{ID: 73, Status: http.StatusBadRequest}
+	ErrInvalidMatrix                = &Error{ID: 74, Status: http.StatusBadRequest}
+	ErrInvalidPipelineDefinition    = &Error{ID: 75, Status: http.StatusBadRequest}
+	ErrUnsupportedFormat            = &Error{ID: 76, Status: http.StatusBadRequest}
+	ErrCacheTooLarge                = &Error{ID: 77, Status: http.StatusRequestEntityTooLarge}
+	ErrInvalidTimeout               = &Error{ID: 78, Status: http.StatusBadRequest}
+	ErrInvalidRetryPolicy           = &Error{ID: 79, Status: http.StatusBadRequest}
+	ErrInvalidCondition             = &Error{ID: 80, Status: http.StatusBadRequest}
+	ErrOIDCConn                     = &Error{ID: 81, Status: http.StatusInternalServerError}
+	ErrInvalidAPIToken              = &Error{ID: 82, Status: http.StatusBadRequest}
+	ErrRoleNotFound                 = &Error{ID: 83, Status: http.StatusNotFound}
+	ErrInvalidRole                  = &Error{ID: 84, Status: http.StatusBadRequest}
+	ErrRoleUsed                     = &Error{ID: 85, Status: http.StatusForbidden}
+	ErrInvalidApprovalPolicy        = &Error{ID: 86, Status: http.StatusBadRequest}
+	ErrApprovalBySelf               = &Error{ID: 87, Status: http.StatusForbidden}
+	ErrNotWaitingApproval           = &Error{ID: 88, Status: http.StatusConflict}
+	ErrEnvironmentLocked            = &Error{ID: 89, Status: http.StatusConflict}
+	ErrInvalidLockPolicy            = &Error{ID: 90, Status: http.StatusBadRequest}
 )
 
 // SupportedLanguages on API errors
@@ -169,6 +186,23 @@ You can safely use them in a String or Text parameter`,
 	ErrInfiniteTriggerLoop.ID:          "infinite trigger loop are forbidden",
 	ErrInvalidResetUser.ID:             "invalid user or email",
 	ErrUserConflict.ID:                 "this user already exist",
+	ErrInvalidMatrix.ID:                "invalid build matrix: axes must have a unique name and values",
+	ErrInvalidPipelineDefinition.ID:    "invalid pipeline definition: stages and jobs must have a unique name",
+	ErrUnsupportedFormat.ID:            "unsupported format",
+	ErrCacheTooLarge.ID:                "build cache exceeds maximum size",
+	ErrInvalidTimeout.ID:               "invalid timeout: it must be a positive number of seconds",
+	ErrInvalidRetryPolicy.ID:           "invalid retry policy: at least one attempt is required and backoff must be positive",
+	ErrInvalidCondition.ID:             "invalid condition expression",
+	ErrOIDCConn.ID:                     "cannot reach OpenID Connect issuer",
+	ErrInvalidAPIToken.ID:              "invalid token: it needs a name, valid scopes and a future expiry",
+	ErrRoleNotFound.ID:                 "role not found",
+	ErrInvalidRole.ID:                  "invalid role: it needs a name and known actions",
+	ErrRoleUsed.ID:                     "role is still given to groups",
+	ErrInvalidApprovalPolicy.ID:        "invalid approval policy: at least one approval is required",
+	ErrApprovalBySelf.ID:               "a build cannot be approved by the user who triggered or committed it",
+	ErrNotWaitingApproval.ID:           "pipeline build is not waiting for approval",
+	ErrEnvironmentLocked.ID:            "another deployment is running on this environment",
+	ErrInvalidLockPolicy.ID:            "invalid lock policy: scope must be environment or application, and conflicts resolved by queue, cancel or reject",
 }
 
 var errorsFrench = map[int]string{
@@ -247,6 +281,23 @@ Vous pouvez les utiliser sans problème dans un paramêtre de type String ou Tex
 	ErrInfiniteTriggerLoop.ID:          "création d'une boucle de trigger infinie interdite",
 	ErrInvalidResetUser.ID:             "mauvaise combinaison compte/mail utilisateur",
 	ErrUserConflict.ID:                 "cet utilisateur existe deja",
+	ErrInvalidMatrix.ID:                "matrice de build invalide: les axes doivent avoir un nom unique et des valeurs",
+	ErrInvalidPipelineDefinition.ID:    "définition de pipeline invalide: les stages et jobs doivent avoir un nom unique",
+	ErrUnsupportedFormat.ID:            "format non supporté",
+	ErrCacheTooLarge.ID:                "le cache de build dépasse la taille maximale",
+	ErrInvalidTimeout.ID:               "timeout invalide: il doit être un nombre positif de secondes",
+	ErrInvalidRetryPolicy.ID:           "politique de relance invalide: au moins une tentative est requise et le délai doit être positif",
+	ErrInvalidCondition.ID:             "expression de condition invalide",
+	ErrOIDCConn.ID:                     "impossible de joindre le fournisseur OpenID Connect",
+	ErrInvalidAPIToken.ID:              "token invalide: il doit avoir un nom, des scopes valides et une expiration future",
+	ErrRoleNotFound.ID:                 "role inexistant",
+	ErrInvalidRole.ID:                  "role invalide: il doit avoir un nom et des actions connues",
+	ErrRoleUsed.ID:                     "ce role est encore donne a des groupes",
+	ErrInvalidApprovalPolicy.ID:        "politique d'approbation invalide: au moins une approbation est requise",
+	ErrApprovalBySelf.ID:               "un build ne peut pas être approuvé par l'utilisateur qui l'a déclenché ou commité",
+	ErrNotWaitingApproval.ID:           "le build de pipeline n'attend pas d'approbation",
+	ErrEnvironmentLocked.ID:            "un autre déploiement est en cours sur cet environnement",
+	ErrInvalidLockPolicy.ID:            "politique de verrou invalide: la portée doit être environment ou application, et les conflits résolus par queue, cancel ou reject",
 }
 
 var matcher = language.NewMatcher(SupportedLanguages)
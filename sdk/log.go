@@ -23,3 +23,26 @@ func NewLog(buildid int64, step string, value string) *Log {
 
 	return l
 }
+
+// BuildEventType is the kind of event pushed on a pipeline build stream
+type BuildEventType string
+
+// Pipeline build stream events
+const (
+	BuildEventLog           BuildEventType = "log"
+	BuildEventStep          BuildEventType = "step"
+	BuildEventActionBuild   BuildEventType = "action_build"
+	BuildEventPipelineBuild BuildEventType = "pipeline_build"
+)
+
+// BuildEvent is pushed to clients following a pipeline build: a new log line,
+// a new step starting in an action build or a status change of an action build or of the pipeline build
+type BuildEvent struct {
+	Type             BuildEventType `json:"type"`
+	PipelineBuildID  int64          `json:"pipeline_build_id"`
+	ActionBuildID    int64          `json:"action_build_id,omitempty"`
+	PipelineActionID int64          `json:"pipeline_action_id,omitempty"`
+	Step             string         `json:"step,omitempty"`
+	Status           Status         `json:"status,omitempty"`
+	Log              *Log           `json:"log,omitempty"`
+}
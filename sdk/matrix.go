@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"sort"
+	"strings"
+)
+
+// MatrixVariablePrefix is the prefix of build variables holding matrix axis values
+const MatrixVariablePrefix = "cds.matrix."
+
+// MatrixAxis is a build parameter and the list of values an action is run with
+type MatrixAxis struct {
+	Name   string   `json:"name" yaml:"name"`
+	Values []string `json:"values" yaml:"values"`
+}
+
+// Matrix runs an action once for each combination of its axes values.
+// Failures of combinations matching one of AllowFailures do not fail the stage.
+type Matrix struct {
+	Axes          []MatrixAxis        `json:"axes" yaml:"axes"`
+	AllowFailures []map[string]string `json:"allow_failures,omitempty" yaml:"allow_failures,omitempty"`
+}
+
+// IsValid checks axes are named, unique and have values
+func (m *Matrix) IsValid() bool {
+	if m == nil {
+		return true
+	}
+	names := map[string]bool{}
+	for _, a := range m.Axes {
+		if a.Name == "" || len(a.Values) == 0 || names[a.Name] || strings.ContainsAny(a.Name, " .") {
+			return false
+		}
+		names[a.Name] = true
+	}
+	for _, f := range m.AllowFailures {
+		for k := range f {
+			if !names[k] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Combinations returns all combinations of axes values, in axes order
+func (m *Matrix) Combinations() []map[string]string {
+	if m == nil || len(m.Axes) == 0 {
+		return nil
+	}
+
+	cells := []map[string]string{{}}
+	for _, a := range m.Axes {
+		var next []map[string]string
+		for _, c := range cells {
+			for _, v := range a.Values {
+				n := make(map[string]string, len(c)+1)
+				for k := range c {
+					n[k] = c[k]
+				}
+				n[a.Name] = v
+				next = append(next, n)
+			}
+		}
+		cells = next
+	}
+	return cells
+}
+
+// AllowFailure returns true if the combination matches one of allowed failures.
+// An allowed failure matches when all its axes have the same value in the combination
+func (m *Matrix) AllowFailure(cell map[string]string) bool {
+	if m == nil || len(cell) == 0 {
+		return false
+	}
+	for _, f := range m.AllowFailures {
+		if len(f) == 0 {
+			continue
+		}
+		match := true
+		for k, v := range f {
+			if cell[k] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// MatrixParameters returns cds.matrix.* build parameters of a combination
+func MatrixParameters(cell map[string]string) []Parameter {
+	names := make([]string, 0, len(cell))
+	for k := range cell {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	params := make([]Parameter, 0, len(cell))
+	for _, k := range names {
+		params = append(params, Parameter{
+			Name:  MatrixVariablePrefix + k,
+			Type:  StringParameter,
+			Value: cell[k],
+		})
+	}
+	return params
+}
+
+// MatrixCell returns the matrix combination of an action build from its parameters
+func MatrixCell(params []Parameter) map[string]string {
+	var cell map[string]string
+	for _, p := range params {
+		if !strings.HasPrefix(p.Name, MatrixVariablePrefix) {
+			continue
+		}
+		if cell == nil {
+			cell = map[string]string{}
+		}
+		cell[strings.TrimPrefix(p.Name, MatrixVariablePrefix)] = p.Value
+	}
+	return cell
+}
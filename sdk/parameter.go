@@ -86,3 +86,13 @@ func NewStringParameter(s string) (Parameter, error) {
 
 	return p, nil
 }
+
+// ParameterValue returns the value of the parameter with given name, empty if not found
+func ParameterValue(params []Parameter, name string) string {
+	for _, p := range params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
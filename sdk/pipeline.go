@@ -1,9 +1,14 @@
 package sdk
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -39,7 +44,8 @@ type PipelineBuild struct {
 	Application Application `json:"application"`
 	Environment Environment `json:"environment"`
 
-	Trigger PipelineBuildTrigger `json:"trigger"`
+	Trigger   PipelineBuildTrigger `json:"trigger"`
+	Approvals []Approval           `json:"approvals,omitempty"`
 }
 
 // PipelineBuildTrigger Struct for history table
@@ -445,6 +451,98 @@ func StreamPipelineBuild(key, appName, pipelineName, env string, buildID int, fo
 	return ch, nil
 }
 
+// FollowPipelineBuild listens to server-sent events of a building pipeline and push them in returned channel.
+// Channel is closed once the pipeline build event holding the final status has been pushed
+func FollowPipelineBuild(key, appName, pipelineName, env string, buildID int) (chan BuildEvent, error) {
+	build := "last"
+	if buildID != 0 {
+		build = fmt.Sprintf("%d", buildID)
+	}
+
+	open := func(offset int64) (io.ReadCloser, error) {
+		path := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%s/log/stream?offset=%d", key, appName, pipelineName, build, offset)
+		if env != "" {
+			path = fmt.Sprintf("%s&envName=%s", path, env)
+		}
+		body, code, err := Stream("GET", path, nil, SetHeader("Accept", "text/event-stream"))
+		if err != nil {
+			return nil, err
+		}
+		if code >= 300 {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			if e := DecodeError(data); e != nil {
+				return nil, e
+			}
+			return nil, fmt.Errorf("HTTP %d", code)
+		}
+		return body, nil
+	}
+
+	body, err := open(0)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan BuildEvent)
+	go func() {
+		defer close(ch)
+
+		var lastID int64
+		for {
+			done := readBuildEvents(body, func(e BuildEvent) bool {
+				if e.Log != nil && e.Log.ID > lastID {
+					lastID = e.Log.ID
+				}
+				ch <- e
+				return e.Type == BuildEventPipelineBuild && e.Status.IsFinal()
+			})
+			body.Close()
+			if done {
+				return
+			}
+
+			// Stream has been closed before the end of the build, resume from last log
+			time.Sleep(1 * time.Second)
+			body, err = open(lastID)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// readBuildEvents decodes a text/event-stream body and calls f on each event until f returns true
+func readBuildEvents(body io.Reader, f func(BuildEvent) bool) bool {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var e BuildEvent
+			err := json.Unmarshal(data.Bytes(), &e)
+			data.Reset()
+			if err != nil {
+				continue
+			}
+			if f(e) {
+				return true
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return false
+}
+
 // DeletePipeline remove given pipeline from CDS
 func DeletePipeline(key, name string) error {
 	path := fmt.Sprintf("/project/%s/pipeline/%s", key, name)
@@ -482,8 +580,8 @@ func RemoveGroupFromPipeline(projectKey, pipelineName, groupName string) error {
 // UpdateGroupInPipeline  call api to update group permission on pipeline
 func UpdateGroupInPipeline(projectKey, pipelineName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 \n")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role) \n")
 	}
 
 	groupPipeline := GroupPermission{
@@ -517,8 +615,8 @@ func UpdateGroupInPipeline(projectKey, pipelineName, groupName string, permissio
 // AddGroupInPipeline  add a group in a pipeline
 func AddGroupInPipeline(projectKey, pipelineName, groupName string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 \n")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role) \n")
 	}
 
 	groupPipeline := GroupPermission{
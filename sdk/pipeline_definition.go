@@ -0,0 +1,292 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Pipeline definition formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// PipelineDefinition is the pipeline as code representation of a pipeline, without any database identifier
+type PipelineDefinition struct {
+	Name       string                `json:"name" yaml:"name"`
+	Type       PipelineType          `json:"type,omitempty" yaml:"type,omitempty"`
+	Parameters []ParameterDefinition `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Stages     []StageDefinition     `json:"stages" yaml:"stages"`
+}
+
+// ParameterDefinition is a parameter of a pipeline, a job or a step in a pipeline definition
+type ParameterDefinition struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        ParameterType `json:"type,omitempty" yaml:"type,omitempty"`
+	Value       string        `json:"value" yaml:"value"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// StageDefinition is a stage in a pipeline definition
+type StageDefinition struct {
+	Name          string          `json:"name" yaml:"name"`
+	Disabled      bool            `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Prerequisites []Prerequisite  `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty"`
+	Matrix        *Matrix         `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+	Timeout       int64           `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Condition     string          `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Approval      *ApprovalPolicy `json:"approval,omitempty" yaml:"approval,omitempty"`
+	Jobs          []JobDefinition `json:"jobs" yaml:"jobs"`
+}
+
+// JobDefinition is an action of a stage. A joined action is defined by its steps,
+// other actions are referenced by their name with Action field, and the job has the action name
+type JobDefinition struct {
+	Name         string                  `json:"name" yaml:"name"`
+	Action       string                  `json:"action,omitempty" yaml:"action,omitempty"`
+	Description  string                  `json:"description,omitempty" yaml:"description,omitempty"`
+	Disabled     bool                    `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Requirements []RequirementDefinition `json:"requirements,omitempty" yaml:"requirements,omitempty"`
+	Parameters   []ParameterDefinition   `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Matrix       *Matrix                 `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+	Timeout      int64                   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Retry        *RetryPolicy            `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Condition    string                  `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Steps        []StepDefinition        `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+// RequirementDefinition is a requirement of a job
+type RequirementDefinition struct {
+	Name  string          `json:"name" yaml:"name"`
+	Type  RequirementType `json:"type" yaml:"type"`
+	Value string          `json:"value" yaml:"value"`
+}
+
+// StepDefinition is a step of a joined action, referencing an action by its name
+type StepDefinition struct {
+	Action     string                `json:"action" yaml:"action"`
+	Disabled   bool                  `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Final      bool                  `json:"final,omitempty" yaml:"final,omitempty"`
+	Condition  string                `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Parameters []ParameterDefinition `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// NewPipelineDefinition returns the definition of a pipeline loaded with its stages
+func NewPipelineDefinition(p *Pipeline) *PipelineDefinition {
+	d := &PipelineDefinition{
+		Name:       p.Name,
+		Type:       p.Type,
+		Parameters: newParameterDefinitions(p.Parameter),
+		Stages:     []StageDefinition{},
+	}
+
+	for _, s := range p.Stages {
+		sd := StageDefinition{
+			Name:          s.Name,
+			Disabled:      !s.Enabled,
+			Prerequisites: s.Prerequisites,
+			Matrix:        s.Matrix,
+			Timeout:       s.Timeout,
+			Condition:     s.Condition,
+			Approval:      s.Approval,
+			Jobs:          []JobDefinition{},
+		}
+		for _, a := range s.Actions {
+			sd.Jobs = append(sd.Jobs, NewJobDefinition(a))
+		}
+		d.Stages = append(d.Stages, sd)
+	}
+
+	return d
+}
+
+// NewJobDefinition returns the definition of an action attached to a stage
+func NewJobDefinition(a Action) JobDefinition {
+	j := JobDefinition{
+		Name:       a.Name,
+		Disabled:   !a.Enabled,
+		Parameters: newParameterDefinitions(a.Parameters),
+		Matrix:     a.Matrix,
+		Timeout:    a.Timeout,
+		Retry:      a.Retry,
+		Condition:  a.Condition,
+	}
+	if a.Type != JoinedAction {
+		j.Action = a.Name
+		return j
+	}
+
+	j.Description = a.Description
+	for _, r := range a.Requirements {
+		j.Requirements = append(j.Requirements, RequirementDefinition{Name: r.Name, Type: r.Type, Value: r.Value})
+	}
+	for _, c := range a.Actions {
+		j.Steps = append(j.Steps, StepDefinition{
+			Action:     c.Name,
+			Disabled:   !c.Enabled,
+			Final:      c.Final,
+			Condition:  c.Condition,
+			Parameters: newParameterDefinitions(c.Parameters),
+		})
+	}
+	return j
+}
+
+// JoinedAction returns the action described by the job. For a joined action, steps are children actions
+// referenced by name, without ID
+func (j *JobDefinition) JoinedAction() *Action {
+	a := &Action{
+		Name:        j.Name,
+		Type:        JoinedAction,
+		Description: j.Description,
+		Enabled:     !j.Disabled,
+		Parameters:  Parameters(j.Parameters),
+		Matrix:      j.Matrix,
+		Timeout:     j.Timeout,
+		Retry:       j.Retry,
+		Condition:   j.Condition,
+	}
+	for _, r := range j.Requirements {
+		a.Requirements = append(a.Requirements, Requirement{Name: r.Name, Type: r.Type, Value: r.Value})
+	}
+	for _, s := range j.Steps {
+		a.Actions = append(a.Actions, Action{
+			Name:       s.Action,
+			Enabled:    !s.Disabled,
+			Final:      s.Final,
+			Condition:  s.Condition,
+			Parameters: Parameters(s.Parameters),
+		})
+	}
+	return a
+}
+
+// IsJoined returns true if the job is a joined action defined by its steps
+func (j *JobDefinition) IsJoined() bool {
+	return j.Action == ""
+}
+
+// IsValid checks names are set and unique
+func (d *PipelineDefinition) IsValid() error {
+	if d.Name == "" {
+		return ErrInvalidPipelineDefinition
+	}
+	stages := map[string]bool{}
+	for _, s := range d.Stages {
+		if s.Name == "" || stages[s.Name] || !s.Matrix.IsValid() || s.Timeout < 0 || !IsValidCondition(s.Condition) || !s.Approval.IsValid() {
+			return ErrInvalidPipelineDefinition
+		}
+		stages[s.Name] = true
+
+		jobs := map[string]bool{}
+		for _, j := range s.Jobs {
+			if j.Name == "" || jobs[j.Name] || !j.Matrix.IsValid() || j.Timeout < 0 || !j.Retry.IsValid() || !IsValidCondition(j.Condition) {
+				return ErrInvalidPipelineDefinition
+			}
+			for _, st := range j.Steps {
+				if !IsValidCondition(st.Condition) {
+					return ErrInvalidPipelineDefinition
+				}
+			}
+			if j.Action != "" && (j.Name != j.Action || len(j.Steps) > 0) {
+				return ErrInvalidPipelineDefinition
+			}
+			jobs[j.Name] = true
+		}
+	}
+	return nil
+}
+
+// ParsePipelineDefinition reads a yaml or json pipeline definition
+func ParsePipelineDefinition(data []byte, format string) (*PipelineDefinition, error) {
+	d := &PipelineDefinition{}
+	var err error
+	switch format {
+	case FormatYAML, "yml", "":
+		err = yaml.Unmarshal(data, d)
+	case FormatJSON:
+		err = json.Unmarshal(data, d)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Jobs using a public action are named after it
+	for i := range d.Stages {
+		for k := range d.Stages[i].Jobs {
+			if j := &d.Stages[i].Jobs[k]; j.Name == "" {
+				j.Name = j.Action
+			}
+		}
+	}
+	return d, d.IsValid()
+}
+
+// Marshal returns the yaml or json pipeline definition
+func (d *PipelineDefinition) Marshal(format string) ([]byte, error) {
+	switch format {
+	case FormatYAML, "yml", "":
+		return yaml.Marshal(d)
+	case FormatJSON:
+		return json.MarshalIndent(d, "", "  ")
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func newParameterDefinitions(params []Parameter) []ParameterDefinition {
+	var defs []ParameterDefinition
+	for _, p := range params {
+		defs = append(defs, ParameterDefinition{Name: p.Name, Type: p.Type, Value: p.Value, Description: p.Description})
+	}
+	return defs
+}
+
+// Parameters returns the parameters described by the definitions, with string type as default
+func Parameters(defs []ParameterDefinition) []Parameter {
+	var params []Parameter
+	for _, d := range defs {
+		t := d.Type
+		if t == "" {
+			t = StringParameter
+		}
+		params = append(params, Parameter{Name: d.Name, Type: t, Value: d.Value, Description: d.Description})
+	}
+	return params
+}
+
+// ExportPipeline returns the definition of a pipeline in given format
+func ExportPipeline(projectKey, pipelineName, format string) ([]byte, error) {
+	path := fmt.Sprintf("/project/%s/pipeline/%s/export?format=%s", projectKey, pipelineName, url.QueryEscape(format))
+	data, code, err := Request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+	return data, nil
+}
+
+// ImportPipeline creates or updates a pipeline from its definition, and returns applied changes.
+// With dryRun, changes are computed but not applied
+func ImportPipeline(projectKey string, definition []byte, format string, dryRun bool) ([]string, error) {
+	path := fmt.Sprintf("/project/%s/import/pipeline?format=%s&dryRun=%t", projectKey, url.QueryEscape(format), dryRun)
+	data, code, err := Request("POST", path, definition)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var changes []string
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
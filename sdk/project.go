@@ -174,8 +174,8 @@ func RemoveGroupFromProject(projectKey, groupname string) error {
 // UpdateGroupInProject  call api to update group permission on project
 func UpdateGroupInProject(projectKey, groupname string, permission int) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 \n")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role) \n")
 	}
 
 	groupProject := GroupPermission{
@@ -210,8 +210,8 @@ func UpdateGroupInProject(projectKey, groupname string, permission int) error {
 // AddGroupInProject  add a group in a project
 func AddGroupInProject(projectKey, groupname string, permission int, recursive bool) error {
 
-	if permission < 4 || permission > 7 {
-		return fmt.Errorf("Permission should be between 4-7 \n")
+	if permission < RoleReadID {
+		return fmt.Errorf("Permission should be a role id (4, 5, 7 or a custom role) \n")
 	}
 
 	groupProject := GroupPermission{
@@ -15,6 +15,8 @@ const (
 	Stash RepositoriesManagerType = "STASH"
 	//Github is valued to "GITHUB"
 	Github RepositoriesManagerType = "GITHUB"
+	//Gitlab is valued to "GITLAB"
+	Gitlab RepositoriesManagerType = "GITLAB"
 )
 
 //RepositoriesManager is the struct for every repositories manager.
@@ -316,7 +318,7 @@ type VCSRepo struct {
 	ID           string `json:"id"`
 	Name         string `json:"name"`     //On Github: Name = Slug
 	Slug         string `json:"slug"`     //On Github: Slug = Name
-	Fullname     string `json:"fullname"` //On Stash : projectkey/slug, on Github : owner/slug
+	Fullname     string `json:"fullname"` //On Stash : projectkey/slug, on Github : owner/slug, on Gitlab : namespace/slug
 	URL          string `json:"url"`      //Web URL
 	HTTPCloneURL string `json:"http_url"` //Git clone URL  "https://<baseURL>/scm/PRJ/my-repo.git"
 	SSHCloneURL  string `json:"ssh_url"`  //Git clone URL  "ssh://git@<baseURL>/PRJ/my-repo.git"
@@ -23,6 +23,7 @@ var (
 	user           string
 	password       string
 	token          string
+	apiToken       string
 	hash           string
 	skipReadConfig bool
 	// AuthHeader is used as HTTP header
@@ -60,11 +61,25 @@ func init() {
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
-		client = &http.Client{Transport: tr}
+		client = &http.Client{Transport: tr, CheckRedirect: checkRedirect}
 		return
 	}
 
-	client = http.DefaultClient
+	client = &http.Client{CheckRedirect: checkRedirect}
+}
+
+// checkRedirect does not forward CDS credentials when API redirects to another host,
+// like an objectstore presigned URL
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del(AuthHeader)
+		req.Header.Del(SessionTokenHeader)
+		req.Header.Del("Authorization")
+	}
+	return nil
 }
 
 func initRequest(req *http.Request) {
@@ -95,6 +110,9 @@ func readConfig() error {
 		if viper.GetString("token") != "" {
 			token = viper.GetString("token")
 		}
+		if viper.GetString("api_token") != "" {
+			apiToken = viper.GetString("api_token")
+		}
 	}
 
 	if val := os.Getenv("CDS_USER"); val != "" {
@@ -106,11 +124,18 @@ func readConfig() error {
 	if val := os.Getenv("CDS_TOKEN"); val != "" {
 		token = val
 	}
+	if val := os.Getenv("CDS_API_TOKEN"); val != "" {
+		apiToken = val
+	}
 
 	if user != "" && (password != "" || token != "") {
 		return nil
 	}
 
+	if apiToken != "" {
+		return nil
+	}
+
 	if hash != "" {
 		return nil
 	}
@@ -220,6 +245,9 @@ func Stream(method string, path string, args []byte, mods ...RequestModifier) (i
 				req.Header.Add(SessionTokenHeader, token)
 				req.SetBasicAuth(user, token)
 			}
+			if apiToken != "" {
+				req.Header.Set("Authorization", "Bearer "+apiToken)
+			}
 		}
 
 		//resp, err := http.DefaultClient.Do(req)
@@ -302,6 +330,9 @@ func UploadMultiPart(method string, path string, body *bytes.Buffer, mods ...Req
 		req.Header.Add(SessionTokenHeader, token)
 		req.SetBasicAuth(user, token)
 	}
+	if apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, 0, err
@@ -1,9 +1,37 @@
 package sdk
 
+import "time"
+
 // Result refers to an build result after completion
 type Result struct {
-	ID      int64  `json:"id" yaml:"-"`
-	BuildID int64  `json:"build_id" yaml:"build"`
-	Status  Status `json:"status"`
-	Version int64  `json:"version"`
+	ID       int64          `json:"id" yaml:"-"`
+	BuildID  int64          `json:"build_id" yaml:"build"`
+	Status   Status         `json:"status"`
+	Version  int64          `json:"version"`
+	ExitCode int            `json:"exit_code,omitempty"` // exit code of the failed script, if any
+	Usage    *ResourceUsage `json:"usage,omitempty"`     // resources used by the script, if any
+	Steps    []StepResult   `json:"steps,omitempty"`
+}
+
+// StepResult is the result of a step of a joined action
+type StepResult struct {
+	Name     string         `json:"name"`
+	Status   Status         `json:"status"`
+	ExitCode int            `json:"exit_code,omitempty"`
+	Start    time.Time      `json:"start"`
+	Done     time.Time      `json:"done"`
+	Usage    *ResourceUsage `json:"usage,omitempty"`
+}
+
+// ResourceUsage is the peak memory and cpu time used by the processes of a step, only reported on Linux
+// and for steps not run in a container
+type ResourceUsage struct {
+	MaxRSS     int64 `json:"max_rss"`     // peak resident set size in kilobytes
+	UserTime   int64 `json:"user_time"`   // user cpu time in milliseconds
+	SystemTime int64 `json:"system_time"` // system cpu time in milliseconds
+}
+
+// Duration returns the time spent running the step
+func (s StepResult) Duration() time.Duration {
+	return s.Done.Sub(s.Start)
 }
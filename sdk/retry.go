@@ -0,0 +1,79 @@
+package sdk
+
+import (
+	"time"
+)
+
+// Causes of an action build failure, deciding if it can be retried
+const (
+	FailureResult     = "result"      // worker reported a failure
+	FailureWorkerLost = "worker_lost" // worker vanished while building
+)
+
+// maxRetryDelay caps the delay between two attempts
+const maxRetryDelay = time.Hour
+
+// RetryPolicy runs again a failed action build, up to MaxAttempts times in total.
+// The first retry waits Backoff seconds, then delay doubles for each next attempt.
+// Failures are retried if the script exited with one of ExitCodes, or on any failure if
+// ExitCodes is empty. Action builds whose worker vanished are only retried if WorkerLost is set.
+type RetryPolicy struct {
+	MaxAttempts int   `json:"max_attempts" yaml:"max_attempts"`
+	Backoff     int64 `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	ExitCodes   []int `json:"exit_codes,omitempty" yaml:"exit_codes,omitempty"`
+	WorkerLost  bool  `json:"worker_lost,omitempty" yaml:"worker_lost,omitempty"`
+}
+
+// ActionBuildAttempt is a previous failed attempt of an action build which has been retried
+type ActionBuildAttempt struct {
+	ActionBuildID int64     `json:"action_build_id"`
+	Attempt       int       `json:"attempt"`
+	Status        Status    `json:"status"`
+	Cause         string    `json:"cause"`
+	ExitCode      int       `json:"exit_code"`
+	Start         time.Time `json:"start,omitempty"`
+	Done          time.Time `json:"done,omitempty"`
+	Tests         *Tests    `json:"tests,omitempty"`
+}
+
+// IsValid checks number of attempts and backoff
+func (r *RetryPolicy) IsValid() bool {
+	if r == nil {
+		return true
+	}
+	return r.MaxAttempts >= 1 && r.Backoff >= 0
+}
+
+// ShouldRetry returns true if a failed attempt can be run again
+func (r *RetryPolicy) ShouldRetry(attempt int, cause string, exitCode int) bool {
+	if r == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+
+	switch cause {
+	case FailureWorkerLost:
+		return r.WorkerLost
+	case FailureResult:
+		if len(r.ExitCodes) == 0 {
+			return true
+		}
+		for _, c := range r.ExitCodes {
+			if c == exitCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Delay returns how long to wait before running the attempt following given one
+func (r *RetryPolicy) Delay(attempt int) time.Duration {
+	d := time.Duration(r.Backoff) * time.Second
+	for i := 1; i < attempt && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
+}
@@ -0,0 +1,172 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Actions a Role grants on the project, application, pipeline or environment it is given on
+const (
+	// RoleActionRead allows to see the resource, every role grants it
+	RoleActionRead = "read"
+	// RoleActionRun allows to run, restart, stop and rollback pipelines
+	RoleActionRun = "run"
+	// RoleActionEdit allows to modify the resource
+	RoleActionEdit = "edit"
+	// RoleActionManageVariables allows to add, modify and delete variables
+	RoleActionManageVariables = "manage_variables"
+	// RoleActionManageGroups allows to give and remove roles to groups
+	RoleActionManageGroups = "manage_groups"
+	// RoleActionApproveDeployment allows to approve a deployment waiting for a manual approval
+	RoleActionApproveDeployment = "approve_deployment"
+)
+
+// RoleActions lists all actions a role can grant
+var RoleActions = []string{
+	RoleActionRead,
+	RoleActionRun,
+	RoleActionEdit,
+	RoleActionManageVariables,
+	RoleActionManageGroups,
+	RoleActionApproveDeployment,
+}
+
+// IDs of built-in roles, they are the former permission levels so groups keep their rights
+const (
+	RoleReadID             = 4
+	RoleReadExecuteID      = 5
+	RoleReadWriteExecuteID = 7
+)
+
+// Role is a named set of actions given to a group on a project, application, pipeline or environment
+type Role struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+	BuiltIn bool     `json:"built_in"`
+}
+
+// BuiltInRoles are the roles every CDS knows, they cannot be modified
+var BuiltInRoles = []Role{
+	{ID: RoleReadID, Name: "read", Actions: []string{RoleActionRead}, BuiltIn: true},
+	{ID: RoleReadExecuteID, Name: "read-execute", Actions: []string{RoleActionRead, RoleActionRun}, BuiltIn: true},
+	{ID: RoleReadWriteExecuteID, Name: "read-write-execute", Actions: RoleActions, BuiltIn: true},
+}
+
+// Can returns true if role grants given action
+func (r *Role) Can(action string) bool {
+	if action == RoleActionRead {
+		return true
+	}
+	for _, a := range r.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRoleAction returns true if action is a known action
+func IsValidRoleAction(action string) bool {
+	for _, a := range RoleActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRoles returns built-in and custom roles
+func ListRoles() ([]Role, error) {
+	data, code, err := Request("GET", "/role", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var roles []Role
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// AddRole creates a custom role granting given actions
+func AddRole(name string, actions []string) (*Role, error) {
+	r := Role{
+		Name:    name,
+		Actions: actions,
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, code, err := Request("POST", "/role", data)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusCreated {
+		if e := DecodeError(data); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdateRole replaces actions of a custom role
+func UpdateRole(name string, actions []string) error {
+	data, err := json.Marshal(Role{Name: name, Actions: actions})
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/role/%s", name)
+	data, code, err := Request("PUT", path, data)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+
+	return nil
+}
+
+// DeleteRole deletes a custom role no longer given to any group
+func DeleteRole(name string) error {
+	path := fmt.Sprintf("/role/%s", name)
+	data, code, err := Request("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package sdk
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SecretMinLength is the minimum length of a secret value masked in logs, shorter ones would mask common words
+const SecretMinLength = 6
+
+// SecretMasker redacts secret values from build logs, replacing them with **name**.
+// Base64 and URL encoded forms of values are redacted too, and each line of a multiline value.
+type SecretMasker struct {
+	replacer *strings.Replacer
+}
+
+// NewSecretMasker returns a masker of given secret variables
+func NewSecretMasker(secrets []Variable) *SecretMasker {
+	forms := map[string]string{}
+	for _, s := range secrets {
+		values := []string{s.Value}
+		if strings.Contains(s.Value, "\n") {
+			values = append(values, strings.Split(s.Value, "\n")...)
+		}
+		for _, v := range values {
+			v = strings.TrimSpace(v)
+			if len(v) < SecretMinLength {
+				continue
+			}
+			for _, f := range []string{
+				v,
+				base64.StdEncoding.EncodeToString([]byte(v)),
+				base64.URLEncoding.EncodeToString([]byte(v)),
+				base64.RawStdEncoding.EncodeToString([]byte(v)),
+				base64.RawURLEncoding.EncodeToString([]byte(v)),
+				url.QueryEscape(v),
+				url.PathEscape(v),
+			} {
+				forms[f] = "**" + s.Name + "**"
+			}
+		}
+	}
+	if len(forms) == 0 {
+		return &SecretMasker{}
+	}
+
+	// Longest forms first, so a padded base64 form is not partially replaced by the raw one
+	values := make([]string, 0, len(forms))
+	for f := range forms {
+		values = append(values, f)
+	}
+	sort.Sort(byLengthDesc(values))
+	oldnew := make([]string, 0, 2*len(values))
+	for _, f := range values {
+		oldnew = append(oldnew, f, forms[f])
+	}
+	return &SecretMasker{replacer: strings.NewReplacer(oldnew...)}
+}
+
+// Mask returns s with all secrets redacted
+func (m *SecretMasker) Mask(s string) string {
+	if m == nil || m.replacer == nil {
+		return s
+	}
+	return m.replacer.Replace(s)
+}
+
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int      { return len(s) }
+func (s byLengthDesc) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byLengthDesc) Less(i, j int) bool {
+	if len(s[i]) != len(s[j]) {
+		return len(s[i]) > len(s[j])
+	}
+	return s[i] < s[j]
+}
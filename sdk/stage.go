@@ -8,15 +8,19 @@ import (
 
 // Stage Pipeline step that parallelize actions by order
 type Stage struct {
-	ID            int64          `json:"id" yaml:"pipeline_stage_id"`
-	Name          string         `json:"name"`
-	PipelineID    int64          `json:"-" yaml:"-"`
-	BuildOrder    int            `json:"build_order"`
-	Enabled       bool           `json:"enabled"`
-	Actions       []Action       `json:"actions"`
-	ActionBuilds  []ActionBuild  `json:"builds"`
-	Prerequisites []Prerequisite `json:"prerequisites"`
-	LastModified  int64          `json:"last_modified"`
+	ID            int64           `json:"id" yaml:"pipeline_stage_id"`
+	Name          string          `json:"name"`
+	PipelineID    int64           `json:"-" yaml:"-"`
+	BuildOrder    int             `json:"build_order"`
+	Enabled       bool            `json:"enabled"`
+	Actions       []Action        `json:"actions"`
+	ActionBuilds  []ActionBuild   `json:"builds"`
+	Prerequisites []Prerequisite  `json:"prerequisites"`
+	Matrix        *Matrix         `json:"matrix,omitempty"`
+	Timeout       int64           `json:"timeout,omitempty"` // seconds, 0 means no timeout
+	Condition     string          `json:"condition,omitempty"`
+	Approval      *ApprovalPolicy `json:"approval,omitempty"`
+	LastModified  int64           `json:"last_modified"`
 }
 
 // NewStage instanciate a new Stage
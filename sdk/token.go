@@ -0,0 +1,122 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scopes of an APIToken. Suffixed with ":<project key>" a scope is restricted to this project,
+// otherwise it applies to every project of the user.
+const (
+	// TokenScopeRead allows read only requests
+	TokenScopeRead = "read"
+	// TokenScopeRun allows to run, restart, stop and rollback pipelines
+	TokenScopeRun = "run"
+	// TokenScopeVariables allows to manage project, application and environment variables
+	TokenScopeVariables = "variables"
+)
+
+// APIToken is a personal access token authenticating automation on behalf of a user.
+// Its scopes restrict what it can do, user group permissions still apply.
+type APIToken struct {
+	ID       int64      `json:"id"`
+	Name     string     `json:"name"`
+	Token    string     `json:"token,omitempty"`
+	Scopes   []string   `json:"scopes"`
+	Created  time.Time  `json:"created"`
+	Expiry   time.Time  `json:"expiry"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// IsValidTokenScope returns true if scope is a known scope, optionally restricted to a project
+func IsValidTokenScope(scope string) bool {
+	if strings.ContainsAny(scope, ", ") {
+		return false
+	}
+	t := strings.SplitN(scope, ":", 2)
+	if len(t) == 2 && t[1] == "" {
+		return false
+	}
+	switch t[0] {
+	case TokenScopeRead, TokenScopeRun, TokenScopeVariables:
+		return true
+	}
+	return false
+}
+
+// CreateUserToken creates a token for given user, the clear token is only returned now
+func CreateUserToken(username, name string, expiry time.Time, scopes []string) (*APIToken, error) {
+	t := APIToken{
+		Name:   name,
+		Expiry: expiry,
+		Scopes: scopes,
+	}
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/user/%s/token", username)
+	data, code, err := Request("POST", path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusCreated {
+		if e := DecodeError(data); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ListUserTokens returns tokens of given user
+func ListUserTokens(username string) ([]APIToken, error) {
+	path := fmt.Sprintf("/user/%s/token", username)
+	data, code, err := Request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var tokens []APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeUserToken deletes a token of given user
+func RevokeUserToken(username string, id int64) error {
+	path := fmt.Sprintf("/user/%s/token/%d", username, id)
+	data, code, err := Request("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+
+	return nil
+}
@@ -8,8 +8,8 @@ import (
 
 // Prerequisite defines a expected value to one triggering pipeline parameter
 type Prerequisite struct {
-	Parameter     string `json:"parameter"`
-	ExpectedValue string `json:"expected_value"`
+	Parameter     string `json:"parameter" yaml:"parameter"`
+	ExpectedValue string `json:"expected_value" yaml:"expected_value"`
 }
 
 // PipelineTrigger represent a pipeline trigger
@@ -37,6 +37,17 @@ type UserAPIResponse struct {
 	Token    string `json:"token,omitempty"`
 }
 
+// OIDCDeviceAuthorization is returned when starting an OpenID Connect device login:
+// the user opens VerificationURI and enters UserCode while the CLI polls with DeviceCode
+type OIDCDeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
 // UserEmailPattern  pattern for user email address
 const UserEmailPattern = "(\\w[-._\\w]*\\w@\\w[-._\\w]*\\w\\.\\w{2,3})"
 
@@ -93,6 +104,57 @@ func LoginUser(username, password string) (bool, *UserAPIResponse, error) {
 	return true, loginResponse, nil
 }
 
+// StartDeviceLogin starts an OpenID Connect device login
+func StartDeviceLogin() (*OIDCDeviceAuthorization, error) {
+	data, code, err := Request("POST", "/auth/oidc/device", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	d := &OIDCDeviceAuthorization{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling reponse: %s", err)
+	}
+
+	return d, nil
+}
+
+// DeviceLogin polls the API for the session of a device login, nil while the user did not complete it
+func DeviceLogin(deviceCode string) (*UserAPIResponse, error) {
+	request := OIDCDeviceAuthorization{
+		DeviceCode: deviceCode,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	data, code, err := Request("POST", "/auth/oidc/device/token", data)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == http.StatusAccepted {
+		return nil, nil
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	loginResponse := &UserAPIResponse{}
+	if err := json.Unmarshal(data, loginResponse); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling reponse: %s", err)
+	}
+
+	return loginResponse, nil
+}
+
 // DeleteUser Call API to delete the given user
 func DeleteUser(name string) error {
 
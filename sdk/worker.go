@@ -3,6 +3,7 @@ package sdk
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 )
 
@@ -16,6 +17,18 @@ type Worker struct {
 	Model      int64     `json:"model"`
 	HatcheryID int64     `json:"hatchery_id"`
 	Status     Status    `json:"status"` // Waiting, Building, Disabled, Unknown
+	// Action build being run by the worker, 0 if it is not building
+	ActionBuildID int64 `json:"action_build_id,omitempty"`
+	// Set at registration when the worker has been spawned to validate its model
+	ValidateModel bool          `json:"validate_model,omitempty"`
+	Capabilities  []Requirement `json:"capabilities,omitempty"`
+}
+
+// WorkerCapabilities are sent by a worker waiting for an action build,
+// so that API only offers action builds whose requirements it can fulfill
+type WorkerCapabilities struct {
+	Binaries []string `json:"binaries"`
+	Hostname string   `json:"hostname"`
 }
 
 // WorkerType defines where worker can be started
@@ -49,16 +62,29 @@ type Model struct {
 	OwnerID      int64         `json:"-"`
 	Owner        User          `json:"owner"`
 	Validated    bool          `json:"validated"` // Model is tested and marked as functionnal
+	// NeedValidation is set when the model is added or updated, until a worker reports its capabilities
+	NeedValidation  bool   `json:"need_validation"`
+	ValidationError string `json:"validation_error,omitempty"`
+	// SingleUse workers only run the action build they have been spawned for
+	SingleUse bool `json:"single_use"`
+}
+
+// ModelValidation is sent by a worker spawned to validate its model
+type ModelValidation struct {
+	Binaries []string `json:"binaries"` // binaries found in worker PATH
+	Missing  []string `json:"missing"`  // declared capabilities not found
 }
 
 // ModelStatus sums up the number of worker deployed and wanted for a given model
 type ModelStatus struct {
-	ModelID       int64         `json:"model_id" yaml:"-"`
-	ModelName     string        `json:"model_name" yaml:"name"`
-	CurrentCount  int64         `json:"current_count" yaml:"current"`
-	WantedCount   int64         `json:"wanted_count" yaml:"wanted"`
-	BuildingCount int64         `json:"building_count" yaml:"building"`
-	Requirements  []Requirement `json:"requirements"`
+	ModelID        int64         `json:"model_id" yaml:"-"`
+	ModelName      string        `json:"model_name" yaml:"name"`
+	CurrentCount   int64         `json:"current_count" yaml:"current"`
+	WantedCount    int64         `json:"wanted_count" yaml:"wanted"`
+	BuildingCount  int64         `json:"building_count" yaml:"building"`
+	NeedValidation bool          `json:"need_validation" yaml:"need_validation"`
+	SingleUse      bool          `json:"single_use" yaml:"single_use"`
+	Requirements   []Requirement `json:"requirements"`
 }
 
 // OpenstackModelData type details the "Image" field of Openstack type model
@@ -111,13 +137,14 @@ func DisableWorker(workerID string) error {
 }
 
 // AddWorkerModel registers a new worker model available
-func AddWorkerModel(name string, t WorkerType, img string) (*Model, error) {
+func AddWorkerModel(name string, t WorkerType, img string, singleUse bool) (*Model, error) {
 	uri := fmt.Sprintf("/worker/model")
 
 	m := Model{
-		Name:  name,
-		Type:  t,
-		Image: img,
+		Name:      name,
+		Type:      t,
+		Image:     img,
+		SingleUse: singleUse,
 	}
 	data, err := json.Marshal(m)
 	if err != nil {
@@ -162,10 +189,10 @@ func GetWorkerModel(name string) (*Model, error) {
 }
 
 // UpdateWorkerModel updates all characteristics of a worker model
-func UpdateWorkerModel(id int64, name string, t WorkerType, value string) error {
+func UpdateWorkerModel(id int64, name string, t WorkerType, value string, singleUse bool) error {
 	uri := fmt.Sprintf("/worker/model/%d", id)
 
-	data, err := json.Marshal(Model{ID: id, Name: name, Type: t, Image: value})
+	data, err := json.Marshal(Model{ID: id, Name: name, Type: t, Image: value, SingleUse: singleUse})
 	if err != nil {
 		return err
 	}
@@ -296,3 +323,47 @@ func GetWorkerModelStatus() ([]ModelStatus, error) {
 
 	return ms, nil
 }
+
+// ValidateWorkerModel sends to engine the capabilities found by a worker spawned to validate its model
+func ValidateWorkerModel(modelID int64, v ModelValidation) error {
+	uri := fmt.Sprintf("/worker/model/%d/validation", modelID)
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, code, err := Request("POST", uri, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+
+	return nil
+}
+
+// BookActionBuild books for given hatchery a waiting action build the worker model can run,
+// so that the worker spawned for it is the only one able to take it. It returns nil if there is none.
+func BookActionBuild(hatcheryID int64, modelID int64) (*ActionBuild, error) {
+	uri := fmt.Sprintf("/hatchery/%d/book/%d", hatcheryID, modelID)
+
+	data, code, err := Request("POST", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusNoContent {
+		return nil, nil
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var ab ActionBuild
+	if err := json.Unmarshal(data, &ab); err != nil {
+		return nil, err
+	}
+
+	return &ab, nil
+}